@@ -23,18 +23,59 @@ func InitDB() {
 
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=True", username, password, host, port, name)
 
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
 
 	if err != nil {
 		log.Fatal("Failed to connect DB: ", err)
 	}
 
-	DB.AutoMigrate(
+	MigrateDB(DB)
+
+	log.Println("Success connecting to DB")
+}
+
+// MigrateDB brings the schema of db up to date. It is split from InitDB so
+// tests can migrate a database of their own.
+func MigrateDB(db *gorm.DB) {
+	runSchemaMigrations(db)
+
+	if err := db.SetupJoinTable(&models.Item{}, "Categories", &models.ItemCategory{}); err != nil {
+		log.Fatal("Failed to set up item categories: ", err)
+	}
+	if err := db.SetupJoinTable(&models.Item{}, "Tags", &models.ItemTag{}); err != nil {
+		log.Fatal("Failed to set up item tags: ", err)
+	}
+
+	db.AutoMigrate(
 		&models.User{},
 		&models.Item{},
+		&models.ItemVariant{},
+		&models.ItemImage{},
+		&models.ImportJob{},
+		&models.StockMovement{},
+		&models.LowStockAlert{},
 		&models.Transaction{},
 		&models.TransactionDetail{},
+		&models.IdempotencyKey{},
+		&models.TransactionStatusHistory{},
+		&models.PaymentEvent{},
+		&models.PaymentRefund{},
+		&models.Cart{},
+		&models.CartItem{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
+		&models.TaxRate{},
+		&models.Address{},
+		&models.FulfillmentEvent{},
+		&models.Invoice{},
+		&models.InvoiceSequence{},
+		&models.EmailMessage{},
+		&models.PasswordResetToken{},
+		&models.Category{},
+		&models.Tag{},
+		&models.ItemCategory{},
+		&models.ItemTag{},
 	)
 
-	log.Println("Success connecting to DB")
+	runMigrations(db)
 }
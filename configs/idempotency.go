@@ -0,0 +1,26 @@
+package configs
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+const defaultIdempotencyKeyTTL = 24 * time.Hour
+
+// IdempotencyKeyTTL reads IDEMPOTENCY_KEY_TTL as a Go duration (e.g. "24h",
+// "90m"). Keys older than this are forgotten and may be reused.
+func IdempotencyKeyTTL() time.Duration {
+	value := os.Getenv("IDEMPOTENCY_KEY_TTL")
+	if value == "" {
+		return defaultIdempotencyKeyTTL
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		log.Printf("Invalid IDEMPOTENCY_KEY_TTL %q, using %s", value, defaultIdempotencyKeyTTL)
+		return defaultIdempotencyKeyTTL
+	}
+
+	return ttl
+}
@@ -0,0 +1,59 @@
+package configs
+
+import (
+	"log"
+	"ordent/repositories"
+	"os"
+	"strconv"
+)
+
+const (
+	defaultImportMaxBytes    = 20 << 20
+	defaultImportSyncMaxRows = 200
+)
+
+// InitImports marks item imports that were cut off by the last shutdown as
+// failed. It must run after InitDB.
+func InitImports() {
+	interrupted, err := repositories.NewImportJobRepository(DB).FailInterruptedImportJobs()
+	if err != nil {
+		log.Fatal("Failed to clean up import jobs: ", err)
+	}
+	if interrupted > 0 {
+		log.Printf("Marked %d interrupted import jobs as failed", interrupted)
+	}
+}
+
+// ImportMaxBytes reads IMPORT_MAX_BYTES, the largest item import file that
+// may be uploaded. It defaults to 20 MiB.
+func ImportMaxBytes() int64 {
+	value := os.Getenv("IMPORT_MAX_BYTES")
+	if value == "" {
+		return defaultImportMaxBytes
+	}
+
+	maxBytes, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || maxBytes <= 0 {
+		log.Printf("Invalid IMPORT_MAX_BYTES %q, using %d", value, defaultImportMaxBytes)
+		return defaultImportMaxBytes
+	}
+
+	return maxBytes
+}
+
+// ImportSyncMaxRows reads IMPORT_SYNC_MAX_ROWS. Imports with more rows run
+// as background jobs. It defaults to 200.
+func ImportSyncMaxRows() int {
+	value := os.Getenv("IMPORT_SYNC_MAX_ROWS")
+	if value == "" {
+		return defaultImportSyncMaxRows
+	}
+
+	maxRows, err := strconv.Atoi(value)
+	if err != nil || maxRows < 0 {
+		log.Printf("Invalid IMPORT_SYNC_MAX_ROWS %q, using %d", value, defaultImportSyncMaxRows)
+		return defaultImportSyncMaxRows
+	}
+
+	return maxRows
+}
@@ -0,0 +1,62 @@
+package configs
+
+import (
+	"context"
+	"log"
+	"net/mail"
+	"net/url"
+	"ordent/inventory"
+	"ordent/repositories"
+	"os"
+	"strings"
+)
+
+// InitInventory starts the worker that delivers low-stock alerts to the sink
+// chosen by LOW_STOCK_ALERT_SINK. It must run after InitNotifications.
+//
+//   - log:     write alerts to the application log (default)
+//   - email:   email LOW_STOCK_ALERT_EMAILS, a comma-separated list
+//   - webhook: POST alerts as JSON to LOW_STOCK_WEBHOOK_URL, signed with
+//     LOW_STOCK_WEBHOOK_SECRET when it is set
+func InitInventory() {
+	driver := os.Getenv("LOW_STOCK_ALERT_SINK")
+	if driver == "" {
+		driver = "log"
+	}
+
+	var sink inventory.Sink
+	switch driver {
+	case "log":
+		sink = inventory.NewLogSink()
+	case "email":
+		var recipients []string
+		for _, recipient := range strings.Split(os.Getenv("LOW_STOCK_ALERT_EMAILS"), ",") {
+			recipient = strings.TrimSpace(recipient)
+			if recipient == "" {
+				continue
+			}
+			if _, err := mail.ParseAddress(recipient); err != nil {
+				log.Fatalf("Invalid address %q in LOW_STOCK_ALERT_EMAILS", recipient)
+			}
+			recipients = append(recipients, recipient)
+		}
+		if len(recipients) == 0 {
+			log.Fatal("LOW_STOCK_ALERT_EMAILS is required when LOW_STOCK_ALERT_SINK is email")
+		}
+		sink = inventory.NewEmailSink(Notifier, recipients)
+	case "webhook":
+		webhookURL := os.Getenv("LOW_STOCK_WEBHOOK_URL")
+		if webhookURL == "" {
+			log.Fatal("LOW_STOCK_WEBHOOK_URL is required when LOW_STOCK_ALERT_SINK is webhook")
+		}
+		if parsedURL, err := url.Parse(webhookURL); err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+			log.Fatalf("Invalid LOW_STOCK_WEBHOOK_URL %q", webhookURL)
+		}
+		sink = inventory.NewWebhookSink(webhookURL, os.Getenv("LOW_STOCK_WEBHOOK_SECRET"))
+	default:
+		log.Fatalf("Unknown LOW_STOCK_ALERT_SINK %q", driver)
+	}
+
+	worker := inventory.NewAlertWorker(sink, repositories.NewLowStockAlertRepository(DB), inventory.Options{})
+	go worker.Run(context.Background())
+}
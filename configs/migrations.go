@@ -0,0 +1,192 @@
+package configs
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"ordent/models"
+	"ordent/money"
+	"ordent/search"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// runSchemaMigrations converts existing columns whose data AutoMigrate would
+// damage if it changed their type itself. It must run before AutoMigrate.
+func runSchemaMigrations(db *gorm.DB) {
+	if err := migrateMoneyColumns(db); err != nil {
+		log.Fatalf("Failed to run migration %q: %v", "money columns to minor units", err)
+	}
+}
+
+// runMigrations applies the data migrations AutoMigrate cannot express. Each
+// one checks the schema first so it is safe to run on every start.
+func runMigrations(db *gorm.DB) {
+	migrations := []struct {
+		name string
+		run  func(db *gorm.DB) error
+	}{
+		{"transaction status from is_success_paid", migrateTransactionStatus},
+		{"transaction subtotals", backfillTransactionSubtotals},
+		{"transaction net amounts", backfillTransactionNetAmounts},
+		{"fulfillment queue for paid transactions", backfillFulfillmentStatus},
+		{"cart line index with variants", dropCartItemIndex},
+		{"full-text index on item names", addItemNameFullTextIndex},
+	}
+
+	for _, migration := range migrations {
+		if err := migration.run(db); err != nil {
+			log.Fatalf("Failed to run migration %q: %v", migration.name, err)
+		}
+	}
+}
+
+// migrateTransactionStatus replaces the old is_success_paid flag with the
+// status column: paid rows become paid, everything else pending. Each row also
+// gets its first status history entry.
+func migrateTransactionStatus(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.Transaction{}, "is_success_paid") {
+		return nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`UPDATE transactions SET status = CASE WHEN is_success_paid THEN ? ELSE ? END`,
+			models.TransactionStatusPaid, models.TransactionStatusPending).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(`INSERT INTO transaction_status_histories (id, transaction_id, from_status, to_status, note, created_at, updated_at)
+			SELECT UUID(), t.id, '', t.status, 'Migrated from is_success_paid', t.created_at, t.created_at
+			FROM transactions t
+			WHERE NOT EXISTS (SELECT 1 FROM transaction_status_histories h WHERE h.transaction_id = t.id)`).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return db.Migrator().DropColumn(&models.Transaction{}, "is_success_paid")
+}
+
+// backfillTransactionSubtotals gives transactions from before discounts
+// existed a subtotal equal to their total.
+func backfillTransactionSubtotals(db *gorm.DB) error {
+	return db.Exec(`UPDATE transactions SET subtotal_price = total_price
+		WHERE subtotal_price = 0 AND discount_amount = 0 AND total_price <> 0`).Error
+}
+
+// backfillTransactionNetAmounts gives transactions from before taxes existed
+// a net amount equal to their total, since nothing on them was taxed.
+func backfillTransactionNetAmounts(db *gorm.DB) error {
+	if err := db.Exec(`UPDATE transactions SET net_amount = total_price
+		WHERE net_amount = 0 AND tax_amount = 0 AND total_price <> 0`).Error; err != nil {
+		return err
+	}
+
+	return db.Exec(`UPDATE transaction_details SET net_amount = total_price
+		WHERE net_amount = 0 AND tax_amount = 0 AND total_price <> 0`).Error
+}
+
+// backfillFulfillmentStatus puts transactions that were paid before
+// fulfillment was tracked into the fulfillment queue, with a first timeline
+// entry for each.
+func backfillFulfillmentStatus(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`INSERT INTO fulfillment_events (id, transaction_id, from_status, to_status, note, created_at, updated_at)
+			SELECT UUID(), id, '', ?, 'Migrated paid transaction', created_at, created_at
+			FROM transactions
+			WHERE (fulfillment_status = '' OR fulfillment_status IS NULL) AND status IN ?`,
+			models.FulfillmentStatusAwaiting, []models.TransactionStatus{models.TransactionStatusPaid, models.TransactionStatusPartiallyRefunded}).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(`UPDATE transactions SET fulfillment_status = ?
+			WHERE (fulfillment_status = '' OR fulfillment_status IS NULL) AND status IN ?`,
+			models.FulfillmentStatusAwaiting, []models.TransactionStatus{models.TransactionStatusPaid, models.TransactionStatusPartiallyRefunded}).Error
+	})
+}
+
+// dropCartItemIndex removes the old unique (cart_id, item_id) index, which
+// would stop a cart from holding two variants of the same item. AutoMigrate
+// has already created idx_cart_item_variant in its place.
+func dropCartItemIndex(db *gorm.DB) error {
+	if !db.Migrator().HasIndex(&models.CartItem{}, "idx_cart_item") {
+		return nil
+	}
+	return db.Migrator().DropIndex(&models.CartItem{}, "idx_cart_item")
+}
+
+// addItemNameFullTextIndex creates the FULLTEXT index the MySQL searcher
+// needs. AutoMigrate cannot create it portably, and other databases search
+// with the in-memory index instead.
+func addItemNameFullTextIndex(db *gorm.DB) error {
+	if db.Dialector.Name() != "mysql" || db.Migrator().HasIndex(&models.Item{}, search.FullTextIndexName) {
+		return nil
+	}
+	return db.Exec(fmt.Sprintf("CREATE FULLTEXT INDEX `%s` ON `items` (`name`)", search.FullTextIndexName)).Error
+}
+
+// migrateMoneyColumns turns the old float price columns into BIGINT minor
+// units of the store currency. Values are copied into a new column with
+// ROUND(value * 10^exponent) and the columns are swapped in one ALTER, so a
+// run that stops halfway can simply be repeated.
+func migrateMoneyColumns(db *gorm.DB) error {
+	columns := []struct {
+		model  interface{}
+		table  string
+		column string
+	}{
+		{&models.Item{}, "items", "price"},
+		{&models.Transaction{}, "transactions", "total_price"},
+		{&models.TransactionDetail{}, "transaction_details", "price_per_unit"},
+		{&models.TransactionDetail{}, "transaction_details", "total_price"},
+	}
+
+	factor := int64(math.Pow10(money.DefaultCurrency().Exponent))
+
+	for _, c := range columns {
+		if !db.Migrator().HasTable(c.model) {
+			continue
+		}
+
+		columnTypes, err := db.Migrator().ColumnTypes(c.model)
+		if err != nil {
+			return err
+		}
+
+		isFloat := false
+		for _, columnType := range columnTypes {
+			if columnType.Name() != c.column {
+				continue
+			}
+			switch strings.ToLower(columnType.DatabaseTypeName()) {
+			case "float", "double", "real", "decimal":
+				isFloat = true
+			}
+		}
+
+		if !isFloat {
+			continue
+		}
+
+		minorColumn := c.column + "_minor"
+		if !db.Migrator().HasColumn(c.model, minorColumn) {
+			if err := db.Exec(fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `%s` BIGINT NULL", c.table, minorColumn)).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := db.Exec(fmt.Sprintf("UPDATE `%s` SET `%s` = ROUND(`%s` * ?)", c.table, minorColumn, c.column), factor).Error; err != nil {
+			return err
+		}
+
+		if err := db.Exec(fmt.Sprintf("ALTER TABLE `%s` DROP COLUMN `%s`, CHANGE COLUMN `%s` `%s` BIGINT NOT NULL",
+			c.table, c.column, minorColumn, c.column)).Error; err != nil {
+			return err
+		}
+
+		log.Printf("Converted %s.%s to minor units", c.table, c.column)
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package configs
+
+import (
+	"log"
+	"ordent/money"
+	"os"
+)
+
+// InitCurrency sets the store currency from CURRENCY (default IDR). It must run
+// before InitDB because stored amounts are minor units of this currency.
+func InitCurrency() {
+	code := os.Getenv("CURRENCY")
+	if code == "" {
+		code = "IDR"
+	}
+
+	if err := money.SetDefaultCurrency(code); err != nil {
+		log.Fatal("Invalid CURRENCY: ", err)
+	}
+}
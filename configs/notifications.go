@@ -0,0 +1,113 @@
+package configs
+
+import (
+	"context"
+	"log"
+	"ordent/notifications"
+	"ordent/repositories"
+	"os"
+	"strconv"
+	"time"
+)
+
+var Notifier *notifications.Notifier
+
+const defaultPasswordResetTTL = time.Hour
+
+// InitNotifications sets up the mailer chosen by MAIL_DRIVER and starts the
+// worker that delivers queued emails. It must run after InitDB.
+//
+//   - smtp:   deliver through SMTP_HOST:SMTP_PORT
+//   - file:   write .eml files to MAIL_FILE_DIR (default "mail")
+//   - memory: keep messages in memory only
+func InitNotifications() {
+	driver := os.Getenv("MAIL_DRIVER")
+	if driver == "" {
+		driver = "file"
+	}
+
+	var mailer notifications.Mailer
+	switch driver {
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			log.Fatal("SMTP_HOST is required when MAIL_DRIVER is smtp")
+		}
+
+		port := 587
+		if value := os.Getenv("SMTP_PORT"); value != "" {
+			parsedPort, err := strconv.Atoi(value)
+			if err != nil || parsedPort <= 0 {
+				log.Fatalf("Invalid SMTP_PORT %q", value)
+			}
+			port = parsedPort
+		}
+
+		mailer = notifications.NewSMTPMailer(notifications.SMTPConfig{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		})
+	case "file":
+		dir := os.Getenv("MAIL_FILE_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+
+		fileMailer, err := notifications.NewFileMailer(dir)
+		if err != nil {
+			log.Fatalf("Failed to prepare MAIL_FILE_DIR %q: %v", dir, err)
+		}
+		mailer = fileMailer
+	case "memory":
+		mailer = notifications.NewMemoryMailer()
+	default:
+		log.Fatalf("Unknown MAIL_DRIVER %q", driver)
+	}
+
+	storeName := StoreLetterhead().Name
+
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = storeName + " <no-reply@localhost>"
+	}
+
+	notifier, err := notifications.NewNotifier(mailer, repositories.NewEmailMessageRepository(DB), repositories.NewUserRepository(DB), notifications.Options{
+		From:      from,
+		StoreName: storeName,
+	})
+	if err != nil {
+		log.Fatal("Failed to set up notifications: ", err)
+	}
+
+	Notifier = notifier
+	go Notifier.Run(context.Background())
+}
+
+// PasswordResetTTL is how long a password reset link stays valid, read from
+// PASSWORD_RESET_TTL.
+func PasswordResetTTL() time.Duration {
+	value := os.Getenv("PASSWORD_RESET_TTL")
+	if value == "" {
+		return defaultPasswordResetTTL
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		log.Printf("Invalid PASSWORD_RESET_TTL %q, using %s", value, defaultPasswordResetTTL)
+		return defaultPasswordResetTTL
+	}
+
+	return ttl
+}
+
+// PasswordResetURL is the page users open from the reset email; the token is
+// appended as the token query parameter. It defaults to
+// APP_BASE_URL/reset-password.
+func PasswordResetURL() string {
+	if resetURL := os.Getenv("PASSWORD_RESET_URL"); resetURL != "" {
+		return resetURL
+	}
+	return AppBaseURL() + "/reset-password"
+}
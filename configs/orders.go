@@ -0,0 +1,27 @@
+package configs
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+const defaultPendingOrderTTL = 30 * time.Minute
+
+// PendingOrderTTL reads PENDING_ORDER_TTL as a Go duration (e.g. "30m",
+// "2h"). Orders whose payment is not confirmed within this time expire and
+// release their stock and coupon uses.
+func PendingOrderTTL() time.Duration {
+	value := os.Getenv("PENDING_ORDER_TTL")
+	if value == "" {
+		return defaultPendingOrderTTL
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		log.Printf("Invalid PENDING_ORDER_TTL %q, using %s", value, defaultPendingOrderTTL)
+		return defaultPendingOrderTTL
+	}
+
+	return ttl
+}
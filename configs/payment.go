@@ -0,0 +1,78 @@
+package configs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"ordent/payments"
+	"os"
+	"strings"
+)
+
+var PaymentProvider payments.Provider
+
+// MockPaymentServer is set when PAYMENT_MOCK_SERVER is true. The stand-in
+// gateway is then served by this application under /mock-payments, where
+// anyone can settle or refund any charge, so it is for local development
+// only.
+var MockPaymentServer *payments.MockServer
+
+// InitPayments sets up the payment provider chosen by PAYMENT_PROVIDER, which
+// must be set.
+//
+//   - mock: a stand-in gateway at PAYMENT_MOCK_URL, or served by this
+//     application itself when PAYMENT_MOCK_SERVER is true
+func InitPayments() {
+	provider := os.Getenv("PAYMENT_PROVIDER")
+	if provider == "" {
+		log.Fatal("PAYMENT_PROVIDER is required")
+	}
+
+	webhookSecret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+
+	switch provider {
+	case "mock":
+		mockURL := os.Getenv("PAYMENT_MOCK_URL")
+		if os.Getenv("PAYMENT_MOCK_SERVER") == "true" {
+			if mockURL != "" {
+				log.Fatal("PAYMENT_MOCK_URL and PAYMENT_MOCK_SERVER cannot both be set")
+			}
+			log.Println("PAYMENT_MOCK_SERVER is true: serving the mock payment gateway under /mock-payments. Never enable this in production")
+
+			if webhookSecret == "" {
+				webhookSecret = randomSecret()
+				log.Println("PAYMENT_WEBHOOK_SECRET is empty, using a random secret for the in-process mock gateway")
+			}
+
+			mockURL = AppBaseURL() + "/mock-payments"
+			MockPaymentServer = payments.NewMockServer(AppBaseURL()+"/api/v1/payments/webhook", webhookSecret)
+		} else if mockURL == "" {
+			log.Fatal("PAYMENT_MOCK_URL is required when PAYMENT_PROVIDER is mock, unless PAYMENT_MOCK_SERVER is true")
+		}
+
+		PaymentProvider = payments.NewMockProvider(mockURL, webhookSecret)
+	default:
+		log.Fatalf("Unknown PAYMENT_PROVIDER %q", provider)
+	}
+
+	if webhookSecret == "" {
+		log.Fatal("PAYMENT_WEBHOOK_SECRET is required")
+	}
+}
+
+// AppBaseURL is the externally reachable address of this application, used to
+// build callback URLs. It defaults to http://localhost:<PORT>.
+func AppBaseURL() string {
+	if baseURL := os.Getenv("APP_BASE_URL"); baseURL != "" {
+		return strings.TrimRight(baseURL, "/")
+	}
+	return "http://localhost:" + os.Getenv("PORT")
+}
+
+func randomSecret() string {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatal("Failed to generate secret: ", err)
+	}
+	return hex.EncodeToString(secret)
+}
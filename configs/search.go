@@ -0,0 +1,53 @@
+package configs
+
+import (
+	"context"
+	"log"
+	"ordent/models"
+	"ordent/search"
+	"os"
+)
+
+var Searcher search.Searcher
+
+// InitSearch sets up the catalog search chosen by SEARCH_DRIVER. It must run
+// after InitDB.
+//
+//   - mysql:  use the FULLTEXT index on items.name (default on MySQL)
+//   - memory: keep an inverted index in this process, filled from the items
+//     table at start; only suitable for a single instance (default on other
+//     databases, which have no MATCH ... AGAINST)
+func InitSearch() {
+	dialect := DB.Dialector.Name()
+	driver := os.Getenv("SEARCH_DRIVER")
+	if driver == "" {
+		driver = "memory"
+		if dialect == "mysql" {
+			driver = "mysql"
+		}
+	}
+
+	switch driver {
+	case "mysql":
+		if dialect != "mysql" {
+			log.Fatalf("SEARCH_DRIVER=mysql needs a MySQL database, not %s", dialect)
+		}
+		Searcher = search.NewMySQLSearcher(DB)
+	case "memory":
+		var docs []search.Document
+		if err := DB.Model(&models.Item{}).Select("id", "name").Scan(&docs).Error; err != nil {
+			log.Fatal("Failed to load items for search: ", err)
+		}
+
+		index := search.NewMemoryIndex()
+		for _, doc := range docs {
+			if err := index.Index(context.Background(), doc); err != nil {
+				log.Fatal("Failed to index items for search: ", err)
+			}
+		}
+		log.Printf("Indexed %d items for search", len(docs))
+		Searcher = index
+	default:
+		log.Fatalf("Unknown SEARCH_DRIVER %q", driver)
+	}
+}
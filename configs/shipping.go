@@ -0,0 +1,50 @@
+package configs
+
+import (
+	"log"
+	"ordent/money"
+	"ordent/shipping"
+	"os"
+)
+
+var ShippingRateProvider shipping.RateProvider
+
+// InitShipping sets up the table rate provider. SHIPPING_RATES_FILE points to
+// a JSON list of zones; without it every order pays SHIPPING_FLAT_RATE, which
+// defaults to free shipping.
+func InitShipping() {
+	provider := os.Getenv("SHIPPING_PROVIDER")
+	if provider == "" {
+		provider = "table"
+	}
+
+	switch provider {
+	case "table":
+		if ratesFile := os.Getenv("SHIPPING_RATES_FILE"); ratesFile != "" {
+			file, err := os.Open(ratesFile)
+			if err != nil {
+				log.Fatalf("Failed to open SHIPPING_RATES_FILE: %v", err)
+			}
+			defer file.Close()
+
+			table, err := shipping.LoadTable(file)
+			if err != nil {
+				log.Fatalf("Invalid SHIPPING_RATES_FILE: %v", err)
+			}
+			ShippingRateProvider = table
+			return
+		}
+
+		flatRate := money.Money(0)
+		if value := os.Getenv("SHIPPING_FLAT_RATE"); value != "" {
+			parsed, err := money.Parse(value)
+			if err != nil || parsed.IsNegative() {
+				log.Fatalf("Invalid SHIPPING_FLAT_RATE %q", value)
+			}
+			flatRate = parsed
+		}
+		ShippingRateProvider = shipping.NewFlatRateProvider(flatRate)
+	default:
+		log.Fatalf("Unknown SHIPPING_PROVIDER %q", provider)
+	}
+}
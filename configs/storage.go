@@ -0,0 +1,131 @@
+package configs
+
+import (
+	"log"
+	"ordent/storage"
+	"os"
+	"strconv"
+)
+
+var Storage storage.Storage
+
+// LocalStorage is set when files are kept on this machine and served by this
+// application under /uploads.
+var LocalStorage *storage.LocalStorage
+
+// MockS3Server is set when STORAGE_DRIVER is s3 without an S3_ENDPOINT. The
+// stand-in bucket is then served by this application under /mock-s3.
+var MockS3Server *storage.S3StandIn
+
+const (
+	defaultImageMaxBytes      = 5 << 20
+	defaultImageThumbnailSize = 320
+)
+
+// InitStorage sets up the file storage chosen by STORAGE_DRIVER.
+//
+//   - local: keep files in STORAGE_LOCAL_DIR (default "uploads")
+//   - s3:    keep files in S3_BUCKET at S3_ENDPOINT
+//
+// STORAGE_PUBLIC_URL overrides the address files are downloaded from, e.g.
+// a CDN in front of the bucket.
+func InitStorage() {
+	driver := os.Getenv("STORAGE_DRIVER")
+	if driver == "" {
+		driver = "local"
+	}
+
+	publicURL := os.Getenv("STORAGE_PUBLIC_URL")
+
+	switch driver {
+	case "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		if publicURL == "" {
+			publicURL = AppBaseURL() + "/uploads"
+		}
+
+		localStorage, err := storage.NewLocalStorage(dir, publicURL)
+		if err != nil {
+			log.Fatalf("Failed to prepare STORAGE_LOCAL_DIR %q: %v", dir, err)
+		}
+		LocalStorage = localStorage
+		Storage = localStorage
+	case "s3":
+		config := storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicURL: publicURL,
+		}
+
+		if value := os.Getenv("S3_FORCE_PATH_STYLE"); value != "" {
+			pathStyle, err := strconv.ParseBool(value)
+			if err != nil {
+				log.Fatalf("Invalid S3_FORCE_PATH_STYLE %q", value)
+			}
+			config.PathStyle = pathStyle
+		}
+
+		if config.Endpoint == "" {
+			log.Println("S3_ENDPOINT is empty, using the in-process S3 stand-in")
+			if config.Region == "" {
+				config.Region = "us-east-1"
+			}
+			if config.Bucket == "" {
+				config.Bucket = "ordent"
+			}
+			config.Endpoint = AppBaseURL() + "/mock-s3"
+			config.AccessKey = randomSecret()[:20]
+			config.SecretKey = randomSecret()
+			config.PathStyle = true
+			MockS3Server = storage.NewS3StandIn("/mock-s3", config.Region, config.AccessKey, config.SecretKey)
+		}
+
+		s3Storage, err := storage.NewS3Storage(config)
+		if err != nil {
+			log.Fatal("Failed to set up S3 storage: ", err)
+		}
+		Storage = s3Storage
+	default:
+		log.Fatalf("Unknown STORAGE_DRIVER %q", driver)
+	}
+}
+
+// ImageMaxBytes reads IMAGE_MAX_BYTES, the largest image file that may be
+// uploaded. It defaults to 5 MiB.
+func ImageMaxBytes() int64 {
+	value := os.Getenv("IMAGE_MAX_BYTES")
+	if value == "" {
+		return defaultImageMaxBytes
+	}
+
+	maxBytes, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || maxBytes <= 0 {
+		log.Printf("Invalid IMAGE_MAX_BYTES %q, using %d", value, defaultImageMaxBytes)
+		return defaultImageMaxBytes
+	}
+
+	return maxBytes
+}
+
+// ImageThumbnailSize reads IMAGE_THUMBNAIL_SIZE, the longest side of a
+// thumbnail in pixels. It defaults to 320.
+func ImageThumbnailSize() int {
+	value := os.Getenv("IMAGE_THUMBNAIL_SIZE")
+	if value == "" {
+		return defaultImageThumbnailSize
+	}
+
+	size, err := strconv.Atoi(value)
+	if err != nil || size <= 0 {
+		log.Printf("Invalid IMAGE_THUMBNAIL_SIZE %q, using %d", value, defaultImageThumbnailSize)
+		return defaultImageThumbnailSize
+	}
+
+	return size
+}
@@ -0,0 +1,23 @@
+package configs
+
+import (
+	"ordent/invoice"
+	"os"
+)
+
+// StoreLetterhead is the seller information printed on invoices, read from
+// the STORE_* variables. Invoices keep the letterhead they were issued with.
+func StoreLetterhead() invoice.Letterhead {
+	name := os.Getenv("STORE_NAME")
+	if name == "" {
+		name = "Ordent"
+	}
+
+	return invoice.Letterhead{
+		Name:    name,
+		Address: os.Getenv("STORE_ADDRESS"),
+		Email:   os.Getenv("STORE_EMAIL"),
+		Phone:   os.Getenv("STORE_PHONE"),
+		TaxID:   os.Getenv("STORE_TAX_ID"),
+	}
+}
@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/repositories"
+	"ordent/utils"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type AddressController struct {
+	addressRepo repositories.AddressRepository
+}
+
+func NewAddressController(addressRepo repositories.AddressRepository) *AddressController {
+	return &AddressController{
+		addressRepo: addressRepo,
+	}
+}
+
+// CreateAddress godoc
+// @Summary Add an address to my address book
+// @Description Save a shipping address. The first address, or one sent with is_default=true, becomes the default used at checkout. This endpoint can only be accessed by users with isAdmin=false.
+// @Tags address
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param address body dto.AddressRequestBody true "Address details"
+// @Success 201 {object} dto.AddressResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/addresses [post]
+func (ac *AddressController) CreateAddress(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	var addressBody dto.AddressRequestBody
+	if err := c.Bind(&addressBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	address, apiErr := addressFromBody(addressBody)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+	address.UserID = userPayload.UserID
+
+	if err := ac.addressRepo.CreateAddress(address); err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to create address"))
+	}
+
+	response, err := ac.addressRepo.GetAddressDetail(userPayload.UserID, address.ID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch address"))
+	}
+
+	return c.JSON(http.StatusCreated, response)
+}
+
+// GetMyAddresses godoc
+// @Summary Get my addresses
+// @Description Get the logged in user's address book, default address first. This endpoint can only be accessed by users with isAdmin=false.
+// @Tags address
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} dto.AddressResponse
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/addresses [get]
+func (ac *AddressController) GetMyAddresses(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	addresses, err := ac.addressRepo.GetAddressesByUserID(userPayload.UserID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch addresses"))
+	}
+
+	return c.JSON(http.StatusOK, addresses)
+}
+
+// GetAddressByID godoc
+// @Summary Get one of my addresses
+// @Description Get a single address from the logged in user's address book. This endpoint can only be accessed by users with isAdmin=false.
+// @Tags address
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Address ID"
+// @Success 200 {object} dto.AddressResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/addresses/{id} [get]
+func (ac *AddressController) GetAddressByID(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	parsedAddressID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid address ID"))
+	}
+
+	address, err := ac.addressRepo.GetAddressDetail(userPayload.UserID, parsedAddressID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Address not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch address"))
+	}
+
+	return c.JSON(http.StatusOK, address)
+}
+
+// EditAddress godoc
+// @Summary Edit one of my addresses
+// @Description Replace an address in the logged in user's address book. Orders already placed keep the address they were shipped to. This endpoint can only be accessed by users with isAdmin=false.
+// @Tags address
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Address ID"
+// @Param address body dto.AddressRequestBody true "Address details"
+// @Success 200 {object} dto.AddressResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/addresses/{id} [put]
+func (ac *AddressController) EditAddress(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	parsedAddressID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid address ID"))
+	}
+
+	var addressBody dto.AddressRequestBody
+	if err := c.Bind(&addressBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	address, apiErr := addressFromBody(addressBody)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	existing, err := ac.addressRepo.GetAddressByID(userPayload.UserID, parsedAddressID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Address not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch address"))
+	}
+
+	// The default moves by marking another address as default, never by
+	// unmarking the current one.
+	address.IsDefault = address.IsDefault || existing.IsDefault
+
+	if err := ac.addressRepo.EditAddress(address, userPayload.UserID, parsedAddressID); err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to update address"))
+	}
+
+	response, err := ac.addressRepo.GetAddressDetail(userPayload.UserID, parsedAddressID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch address"))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// DeleteAddress godoc
+// @Summary Delete one of my addresses
+// @Description Remove an address from the logged in user's address book. If it was the default, the newest remaining address becomes the default. This endpoint can only be accessed by users with isAdmin=false.
+// @Tags address
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Address ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/addresses/{id} [delete]
+func (ac *AddressController) DeleteAddress(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	parsedAddressID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid address ID"))
+	}
+
+	if err := ac.addressRepo.DeleteAddress(userPayload.UserID, parsedAddressID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Address not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to delete address"))
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Address success deleted",
+	})
+}
+
+func addressFromBody(addressBody dto.AddressRequestBody) (*models.Address, *utils.APIError) {
+	address := &models.Address{
+		Label: strings.TrimSpace(addressBody.Label),
+		PostalAddress: models.PostalAddress{
+			RecipientName: strings.TrimSpace(addressBody.RecipientName),
+			Phone:         strings.TrimSpace(addressBody.Phone),
+			Line1:         strings.TrimSpace(addressBody.Line1),
+			Line2:         strings.TrimSpace(addressBody.Line2),
+			City:          strings.TrimSpace(addressBody.City),
+			Province:      strings.TrimSpace(addressBody.Province),
+			PostalCode:    strings.TrimSpace(addressBody.PostalCode),
+			Country:       strings.ToUpper(strings.TrimSpace(addressBody.Country)),
+		},
+		IsDefault: addressBody.IsDefault,
+	}
+
+	switch {
+	case address.RecipientName == "":
+		return nil, utils.NewBadRequestError("Recipient name is required")
+	case address.Phone == "":
+		return nil, utils.NewBadRequestError("Phone is required")
+	case address.Line1 == "":
+		return nil, utils.NewBadRequestError("Address line 1 is required")
+	case address.City == "":
+		return nil, utils.NewBadRequestError("City is required")
+	case address.PostalCode == "":
+		return nil, utils.NewBadRequestError("Postal code is required")
+	case len(address.Country) != 2:
+		return nil, utils.NewBadRequestError("Country must be a two-letter ISO code")
+	}
+
+	return address, nil
+}
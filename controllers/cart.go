@@ -0,0 +1,354 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/notifications"
+	"ordent/payments"
+	"ordent/repositories"
+	"ordent/shipping"
+	"ordent/utils"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type CartController struct {
+	checkout    *checkout
+	cartRepo    repositories.CartRepository
+	itemRepo    repositories.ItemRepository
+	variantRepo repositories.ItemVariantRepository
+}
+
+func NewCartController(txManager repositories.TxManager, paymentProvider payments.Provider, cartRepo repositories.CartRepository, itemRepo repositories.ItemRepository, variantRepo repositories.ItemVariantRepository, stockMovementRepo repositories.StockMovementRepository, transactionRepo repositories.TransactionRepository, transactionDetailRepo repositories.TransactionDetailRepository, couponRepo repositories.CouponRepository, taxRateRepo repositories.TaxRateRepository, addressRepo repositories.AddressRepository, shippingProvider shipping.RateProvider, notifier *notifications.Notifier) *CartController {
+	return &CartController{
+		checkout:    newCheckout(txManager, paymentProvider, itemRepo, variantRepo, stockMovementRepo, transactionRepo, transactionDetailRepo, couponRepo, taxRateRepo, addressRepo, shippingProvider, notifier),
+		cartRepo:    cartRepo,
+		itemRepo:    itemRepo,
+		variantRepo: variantRepo,
+	}
+}
+
+// GetCart godoc
+// @Summary Get my cart
+// @Description Get the logged in user's cart with live prices, subtotals and stock warnings. Lines whose item was deleted are dropped. This endpoint can only be accessed by users with isAdmin=false.
+// @Tags cart
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} dto.CartResponse
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/cart [get]
+func (cc *CartController) GetCart(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	cart, err := cc.cartRepo.GetCartDetail(userPayload.UserID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch cart"))
+	}
+
+	return c.JSON(http.StatusOK, cart)
+}
+
+// AddCartItem godoc
+// @Summary Add an item to my cart
+// @Description Add an item to the cart. Items with variants need variant_id. Adding an item or variant that is already in the cart increases its quantity. This endpoint can only be accessed by users with isAdmin=false.
+// @Tags cart
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param cartItem body dto.CartItemRequestBody true "Item and quantity"
+// @Success 200 {object} dto.CartResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/cart/items [post]
+func (cc *CartController) AddCartItem(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	var cartItemBody dto.CartItemRequestBody
+	if err := c.Bind(&cartItemBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	if cartItemBody.ItemID == "" {
+		return utils.HandlerError(c, utils.NewBadRequestError("Item ID is required"))
+	}
+
+	parsedItemID, err := uuid.Parse(cartItemBody.ItemID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid Item ID format"))
+	}
+
+	if cartItemBody.Quantity <= 0 {
+		return utils.HandlerError(c, utils.NewBadRequestError("Quantity must be greater than 0"))
+	}
+
+	item, err := cc.itemRepo.GetItemByID(parsedItemID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Item not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch item"))
+	}
+
+	parsedVariantID, apiErr := cc.resolveCartVariant(item, cartItemBody.VariantID)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	cart, err := cc.cartRepo.GetOrCreateCart(userPayload.UserID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch cart"))
+	}
+
+	if err := cc.cartRepo.AddCartItem(cart.ID, parsedItemID, parsedVariantID, cartItemBody.Quantity); err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to add item to cart"))
+	}
+
+	return cc.GetCart(c)
+}
+
+// UpdateCartItem godoc
+// @Summary Change the quantity of a cart line
+// @Description Set the quantity of an item that is already in the cart. This endpoint can only be accessed by users with isAdmin=false.
+// @Tags cart
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param itemId path string true "Item ID"
+// @Param variant_id query string false "Variant ID, for items with variants"
+// @Param cartItem body dto.UpdateCartItemRequestBody true "New quantity"
+// @Success 200 {object} dto.CartResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/cart/items/{itemId} [put]
+func (cc *CartController) UpdateCartItem(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	parsedItemID, err := uuid.Parse(c.Param("itemId"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid item ID"))
+	}
+
+	parsedVariantID, apiErr := parseCartVariantParam(c)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	var cartItemBody dto.UpdateCartItemRequestBody
+	if err := c.Bind(&cartItemBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	if cartItemBody.Quantity <= 0 {
+		return utils.HandlerError(c, utils.NewBadRequestError("Quantity must be greater than 0"))
+	}
+
+	cart, err := cc.cartRepo.GetOrCreateCart(userPayload.UserID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch cart"))
+	}
+
+	if err := cc.cartRepo.UpdateCartItem(cart.ID, parsedItemID, parsedVariantID, cartItemBody.Quantity); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Item is not in the cart"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to update cart item"))
+	}
+
+	return cc.GetCart(c)
+}
+
+// RemoveCartItem godoc
+// @Summary Remove an item from my cart
+// @Description Remove an item from the cart. This endpoint can only be accessed by users with isAdmin=false.
+// @Tags cart
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param itemId path string true "Item ID"
+// @Param variant_id query string false "Variant ID, for items with variants"
+// @Success 200 {object} dto.CartResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/cart/items/{itemId} [delete]
+func (cc *CartController) RemoveCartItem(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	parsedItemID, err := uuid.Parse(c.Param("itemId"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid item ID"))
+	}
+
+	parsedVariantID, apiErr := parseCartVariantParam(c)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	cart, err := cc.cartRepo.GetOrCreateCart(userPayload.UserID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch cart"))
+	}
+
+	if err := cc.cartRepo.RemoveCartItem(cart.ID, parsedItemID, parsedVariantID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Item is not in the cart"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to remove cart item"))
+	}
+
+	return cc.GetCart(c)
+}
+
+// ClearCart godoc
+// @Summary Empty my cart
+// @Description Remove every line from the cart. This endpoint can only be accessed by users with isAdmin=false.
+// @Tags cart
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} dto.CartResponse
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/cart [delete]
+func (cc *CartController) ClearCart(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	cart, err := cc.cartRepo.GetOrCreateCart(userPayload.UserID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch cart"))
+	}
+
+	if err := cc.cartRepo.ClearCart(cart.ID); err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to clear cart"))
+	}
+
+	return cc.GetCart(c)
+}
+
+// Checkout godoc
+// @Summary Check out my cart
+// @Description Turn the cart into a transaction using the same rules as POST /api/v1/transactions. The ordered lines are removed from the cart in the same database transaction that creates the order; if the cart changes while the order is placed, nothing is ordered and 409 is returned. This endpoint can only be accessed by users with isAdmin=false.
+// @Tags cart
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Unique key that identifies this checkout attempt"
+// @Param checkout body dto.CartCheckoutRequestBody true "Amount the buyer agrees to pay"
+// @Success 201 {object} dto.TransactionResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 409 {object} utils.APIError "The cart changed during checkout, or a request with the same Idempotency-Key is in progress"
+// @Failure 422 {object} utils.APIError "Idempotency-Key reused with a different request"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/cart/checkout [post]
+func (cc *CartController) Checkout(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	var checkoutBody dto.CartCheckoutRequestBody
+	if err := c.Bind(&checkoutBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	cart, err := cc.cartRepo.GetOrCreateCart(userPayload.UserID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch cart"))
+	}
+
+	if len(cart.CartItems) == 0 {
+		return utils.HandlerError(c, utils.NewBadRequestError("Cart is empty"))
+	}
+
+	transactionBody := dto.TransactionRequestBody{
+		PaidAmount:  checkoutBody.PaidAmount,
+		CouponCode:  checkoutBody.CouponCode,
+		CouponCodes: checkoutBody.CouponCodes,
+		AddressID:   checkoutBody.AddressID,
+	}
+	for _, cartItem := range cart.CartItems {
+		detail := dto.TransactionDetailRequestBody{
+			ItemID:   cartItem.ItemID.String(),
+			Quantity: cartItem.Quantity,
+		}
+		if cartItem.VariantID != uuid.Nil {
+			detail.VariantID = cartItem.VariantID.String()
+		}
+		transactionBody.TransactionDetailRequestBody = append(transactionBody.TransactionDetailRequestBody, detail)
+	}
+
+	transaction, apiErr := cc.checkout.placeOrder(c.Request().Context(), userPayload.UserID, transactionBody, func(tx *gorm.DB) error {
+		if err := cc.cartRepo.WithTx(tx).RemoveCheckedOutItems(cart.ID, cart.CartItems); err != nil {
+			if errors.Is(err, repositories.ErrCartChanged) {
+				return utils.NewConflictError("Cart changed during checkout, please review it and try again")
+			}
+			return err
+		}
+		return nil
+	})
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	return c.JSON(http.StatusCreated, transaction)
+}
+
+// resolveCartVariant checks the variant chosen for a cart line. It returns
+// uuid.Nil for items without variants, which take no variant.
+func (cc *CartController) resolveCartVariant(item *models.Item, variantID string) (uuid.UUID, *utils.APIError) {
+	if variantID == "" {
+		variantCount, err := cc.variantRepo.CountVariants(item.ID)
+		if err != nil {
+			return uuid.Nil, utils.NewInternalError("Failed to fetch variants")
+		}
+		if variantCount > 0 {
+			return uuid.Nil, utils.NewBadRequestError("Choose a variant of " + item.Name)
+		}
+		return uuid.Nil, nil
+	}
+
+	parsedVariantID, err := uuid.Parse(variantID)
+	if err != nil {
+		return uuid.Nil, utils.NewBadRequestError("Invalid Variant ID format")
+	}
+
+	if _, err := cc.variantRepo.GetVariantByID(item.ID, parsedVariantID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, utils.NewNotFoundError("Variant not found")
+		}
+		return uuid.Nil, utils.NewInternalError("Failed to fetch variant")
+	}
+
+	return parsedVariantID, nil
+}
+
+// parseCartVariantParam reads the variant_id query parameter that picks the
+// line of a variant. It returns uuid.Nil when it is not given.
+func parseCartVariantParam(c echo.Context) (uuid.UUID, *utils.APIError) {
+	variantID := c.QueryParam("variant_id")
+	if variantID == "" {
+		return uuid.Nil, nil
+	}
+
+	parsedVariantID, err := uuid.Parse(variantID)
+	if err != nil {
+		return uuid.Nil, utils.NewBadRequestError("Invalid variant ID")
+	}
+	return parsedVariantID, nil
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"errors"
+	"ordent/money"
+	"ordent/repositories"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestRemoveCheckedOutItemsKeepsLinesAddedMeanwhile(t *testing.T) {
+	db := openTestDB(t)
+	cartRepo := repositories.NewCartRepository(db)
+
+	buyer := createTestBuyer(t, db)
+	mug := createTestItem(t, db, money.MustParse("10.00"), 10)
+	hat := createTestItem(t, db, money.MustParse("20.00"), 10)
+
+	cart, err := cartRepo.GetOrCreateCart(buyer.ID)
+	if err != nil {
+		t.Fatalf("get cart: %v", err)
+	}
+	if err := cartRepo.AddCartItem(cart.ID, mug.ID, uuid.Nil, 1); err != nil {
+		t.Fatalf("add mug: %v", err)
+	}
+
+	lineCount := func() int {
+		t.Helper()
+
+		cart, err := cartRepo.GetOrCreateCart(buyer.ID)
+		if err != nil {
+			t.Fatalf("get cart: %v", err)
+		}
+		return len(cart.CartItems)
+	}
+
+	tests := []struct {
+		name string
+		// change runs between reading the cart and removing its lines.
+		change  func()
+		wantErr error
+		want    int
+	}{
+		{
+			name:    "a line added meanwhile",
+			change:  func() { addLine(t, cartRepo, cart.ID, hat.ID) },
+			wantErr: repositories.ErrCartChanged,
+			want:    2,
+		},
+		{
+			name:    "a quantity raised meanwhile",
+			change:  func() { addLine(t, cartRepo, cart.ID, mug.ID) },
+			wantErr: repositories.ErrCartChanged,
+			want:    2,
+		},
+		{
+			name:   "an unchanged cart",
+			change: func() {},
+			want:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkedOut, err := cartRepo.GetOrCreateCart(buyer.ID)
+			if err != nil {
+				t.Fatalf("get cart: %v", err)
+			}
+
+			tt.change()
+
+			err = db.Transaction(func(tx *gorm.DB) error {
+				return cartRepo.WithTx(tx).RemoveCheckedOutItems(cart.ID, checkedOut.CartItems)
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("remove checked out lines: %v, want %v", err, tt.wantErr)
+			}
+			if got := lineCount(); got != tt.want {
+				t.Errorf("cart has %d lines, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func addLine(t *testing.T, cartRepo repositories.CartRepository, cartID uuid.UUID, itemID uuid.UUID) {
+	t.Helper()
+
+	if err := cartRepo.AddCartItem(cartID, itemID, uuid.Nil, 1); err != nil {
+		t.Fatalf("add cart line: %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/repositories"
+	"ordent/utils"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type CategoryController struct {
+	categoryRepo repositories.CategoryRepository
+}
+
+func NewCategoryController(categoryRepo repositories.CategoryRepository) *CategoryController {
+	return &CategoryController{
+		categoryRepo: categoryRepo,
+	}
+}
+
+// CreateCategory godoc
+// @Summary Create new category
+// @Description Create a category, optionally below a parent category. The slug is derived from the name when left out. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags category
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param category body dto.CategoryRequestBody true "Category details"
+// @Success 201 {object} models.Category
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/categories [post]
+func (cc *CategoryController) CreateCategory(c echo.Context) error {
+	var categoryBody dto.CategoryRequestBody
+	if err := c.Bind(&categoryBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	category, apiErr := cc.categoryFromBody(categoryBody)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	if err := cc.categoryRepo.CreateCategory(category); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return utils.HandlerError(c, utils.NewBadRequestError("Category slug already exists"))
+		}
+		// The parent was deleted meanwhile.
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Parent category not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to create category"))
+	}
+
+	return c.JSON(http.StatusCreated, category)
+}
+
+// GetCategoryTree godoc
+// @Summary Get the category tree
+// @Description Get all categories as a tree, siblings ordered by sort order and name. Each category counts the items filed directly under it and the items in it or any of its subcategories. No authentication required.
+// @Tags category
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} dto.CategoryTreeResponse
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/categories [get]
+func (cc *CategoryController) GetCategoryTree(c echo.Context) error {
+	tree, err := cc.categoryRepo.GetCategoryTree()
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch categories"))
+	}
+
+	return c.JSON(http.StatusOK, tree)
+}
+
+// EditCategory godoc
+// @Summary Edit an existing category
+// @Description Rename, move or reorder a category. A category cannot be moved below itself or one of its subcategories. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags category
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Param category body dto.CategoryRequestBody true "Category details"
+// @Success 200 {object} models.Category
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/categories/{id} [put]
+func (cc *CategoryController) EditCategory(c echo.Context) error {
+	parsedCategoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid category ID"))
+	}
+
+	var categoryBody dto.CategoryRequestBody
+	if err := c.Bind(&categoryBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	category, apiErr := cc.categoryFromBody(categoryBody)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	if _, err := cc.categoryRepo.GetCategoryByID(parsedCategoryID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Category not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch category"))
+	}
+
+	if err := cc.categoryRepo.EditCategory(category, parsedCategoryID); err != nil {
+		if errors.Is(err, repositories.ErrCategoryCycle) {
+			return utils.HandlerError(c, utils.NewBadRequestError("A category cannot be moved below itself or one of its subcategories"))
+		}
+		// The category or its new parent was deleted meanwhile.
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Category not found"))
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return utils.HandlerError(c, utils.NewBadRequestError("Category slug already exists"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to update category"))
+	}
+
+	updated, err := cc.categoryRepo.GetCategoryByID(parsedCategoryID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch category"))
+	}
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+// DeleteCategory godoc
+// @Summary Delete an existing category
+// @Description Delete a category that has no subcategories. Its items are kept and only taken out of the category. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags category
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/categories/{id} [delete]
+func (cc *CategoryController) DeleteCategory(c echo.Context) error {
+	parsedCategoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid category ID"))
+	}
+
+	if err := cc.categoryRepo.DeleteCategory(parsedCategoryID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Category not found"))
+		}
+		if errors.Is(err, repositories.ErrCategoryHasChildren) {
+			return utils.HandlerError(c, utils.NewBadRequestError("Move or delete the subcategories first"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to delete category"))
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Category success deleted",
+	})
+}
+
+func (cc *CategoryController) categoryFromBody(categoryBody dto.CategoryRequestBody) (*models.Category, *utils.APIError) {
+	name := strings.TrimSpace(categoryBody.Name)
+	if name == "" {
+		return nil, utils.NewBadRequestError("Name is required")
+	}
+
+	slug, apiErr := slugFromBody(name, categoryBody.Slug)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	category := &models.Category{
+		Name:      name,
+		Slug:      slug,
+		SortOrder: categoryBody.SortOrder,
+	}
+
+	if categoryBody.ParentID == "" {
+		return category, nil
+	}
+
+	parsedParentID, err := uuid.Parse(categoryBody.ParentID)
+	if err != nil {
+		return nil, utils.NewBadRequestError("Invalid parent category ID")
+	}
+
+	if _, err := cc.categoryRepo.GetCategoryByID(parsedParentID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Parent category not found")
+		}
+		return nil, utils.NewInternalError("Failed to fetch parent category")
+	}
+
+	category.ParentID = &parsedParentID
+	return category, nil
+}
+
+// slugFromBody returns the slug sent with a category or tag, or one derived
+// from its name. Sent slugs must already be in slug form.
+func slugFromBody(name, slug string) (string, *utils.APIError) {
+	if slug == "" {
+		slug = utils.Slugify(name)
+		if slug == "" {
+			return "", utils.NewBadRequestError("Name must contain a letter or digit")
+		}
+		return slug, nil
+	}
+
+	if utils.Slugify(slug) != slug {
+		return "", utils.NewBadRequestError("Slug may only contain lower case letters, digits and single hyphens")
+	}
+	return slug, nil
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"errors"
+	"ordent/models"
+	"ordent/repositories"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func createTestCategory(t *testing.T, db *gorm.DB, name string) *models.Category {
+	t.Helper()
+
+	category := &models.Category{Name: name, Slug: name + "-" + uuid.NewString()}
+	if err := db.Create(category).Error; err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+
+	return category
+}
+
+func TestEditCategoryConcurrentMovesDoNotFormCycle(t *testing.T) {
+	db := openTestDB(t)
+	categoryRepo := repositories.NewCategoryRepository(db)
+
+	a := createTestCategory(t, db, "a")
+	b := createTestCategory(t, db, "b")
+
+	// Move A under B and B under A at the same time.
+	moves := []struct {
+		category *models.Category
+		parent   *models.Category
+	}{
+		{a, b},
+		{b, a},
+	}
+
+	errs := make([]error, len(moves))
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, move := range moves {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			category := *move.category
+			category.ParentID = &move.parent.ID
+			errs[i] = categoryRepo.EditCategory(&category, category.ID)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("%d moves succeeded, want 1 (errors: %v)", succeeded, errs)
+	}
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, repositories.ErrCategoryCycle) {
+			t.Errorf("losing move failed with %v, want ErrCategoryCycle", err)
+		}
+	}
+
+	for _, category := range []*models.Category{a, b} {
+		if _, err := categoryRepo.GetDescendantIDs(category.ID); err != nil {
+			t.Errorf("descendants of %s: %v", category.Name, err)
+		}
+	}
+}
+
+func TestEditCategoryRejectsCycle(t *testing.T) {
+	db := openTestDB(t)
+	categoryRepo := repositories.NewCategoryRepository(db)
+
+	a := createTestCategory(t, db, "a")
+	b := createTestCategory(t, db, "b")
+	c := createTestCategory(t, db, "c")
+
+	move := func(category *models.Category, parent *models.Category) error {
+		t.Helper()
+
+		moved := *category
+		moved.ParentID = &parent.ID
+		if err := categoryRepo.EditCategory(&moved, moved.ID); err != nil {
+			return err
+		}
+		category.ParentID = moved.ParentID
+		return nil
+	}
+
+	// Build a > b > c.
+	if err := move(b, a); err != nil {
+		t.Fatalf("move b under a: %v", err)
+	}
+	if err := move(c, b); err != nil {
+		t.Fatalf("move c under b: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		category *models.Category
+		parent   *models.Category
+	}{
+		{"under itself", a, a},
+		{"under its child", a, b},
+		{"under its grandchild", a, c},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := move(tt.category, tt.parent); !errors.Is(err, repositories.ErrCategoryCycle) {
+				t.Errorf("move = %v, want ErrCategoryCycle", err)
+			}
+		})
+	}
+}
+
+func TestDeleteCategoryConcurrentWithNewSubcategoryLeavesNoOrphan(t *testing.T) {
+	db := openTestDB(t)
+	categoryRepo := repositories.NewCategoryRepository(db)
+
+	parent := createTestCategory(t, db, "parent")
+	sibling := createTestCategory(t, db, "sibling")
+
+	// Delete the parent while a subcategory is created below it and another
+	// category is moved below it.
+	child := &models.Category{Name: "child", Slug: "child-" + uuid.NewString(), ParentID: &parent.ID}
+	moved := *sibling
+	moved.ParentID = &parent.ID
+
+	var deleteErr, createErr, moveErr error
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, run := range []func(){
+		func() { deleteErr = categoryRepo.DeleteCategory(parent.ID) },
+		func() { createErr = categoryRepo.CreateCategory(child) },
+		func() { moveErr = categoryRepo.EditCategory(&moved, moved.ID) },
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			run()
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for name, err := range map[string]error{"delete": deleteErr, "create": createErr, "move": moveErr} {
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && !errors.Is(err, repositories.ErrCategoryHasChildren) {
+			t.Errorf("%s failed with %v", name, err)
+		}
+	}
+
+	var parentCount int64
+	if err := db.Model(&models.Category{}).Where("id = ?", parent.ID).Count(&parentCount).Error; err != nil {
+		t.Fatalf("count parent: %v", err)
+	}
+	if parentCount > 0 {
+		return
+	}
+
+	var orphans int64
+	if err := db.Model(&models.Category{}).Where("parent_id = ?", parent.ID).Count(&orphans).Error; err != nil {
+		t.Fatalf("count orphans: %v", err)
+	}
+	if orphans != 0 {
+		t.Errorf("%d categories were left below the deleted parent (create: %v, move: %v)", orphans, createErr, moveErr)
+	}
+}
@@ -0,0 +1,557 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/money"
+	"ordent/notifications"
+	"ordent/payments"
+	"ordent/pricing"
+	"ordent/repositories"
+	"ordent/shipping"
+	"ordent/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// checkout turns a validated order request into a transaction. It is shared
+// by every endpoint that places orders so they all apply the same rules.
+type checkout struct {
+	txManager             repositories.TxManager
+	paymentProvider       payments.Provider
+	itemRepo              repositories.ItemRepository
+	variantRepo           repositories.ItemVariantRepository
+	stockMovementRepo     repositories.StockMovementRepository
+	transactionRepo       repositories.TransactionRepository
+	transactionDetailRepo repositories.TransactionDetailRepository
+	couponRepo            repositories.CouponRepository
+	taxRateRepo           repositories.TaxRateRepository
+	addressRepo           repositories.AddressRepository
+	shippingProvider      shipping.RateProvider
+	notifier              *notifications.Notifier
+}
+
+func newCheckout(txManager repositories.TxManager, paymentProvider payments.Provider, itemRepo repositories.ItemRepository, variantRepo repositories.ItemVariantRepository, stockMovementRepo repositories.StockMovementRepository, transactionRepo repositories.TransactionRepository, transactionDetailRepo repositories.TransactionDetailRepository, couponRepo repositories.CouponRepository, taxRateRepo repositories.TaxRateRepository, addressRepo repositories.AddressRepository, shippingProvider shipping.RateProvider, notifier *notifications.Notifier) *checkout {
+	return &checkout{
+		txManager:             txManager,
+		paymentProvider:       paymentProvider,
+		itemRepo:              itemRepo,
+		variantRepo:           variantRepo,
+		stockMovementRepo:     stockMovementRepo,
+		transactionRepo:       transactionRepo,
+		transactionDetailRepo: transactionDetailRepo,
+		couponRepo:            couponRepo,
+		taxRateRepo:           taxRateRepo,
+		addressRepo:           addressRepo,
+		shippingProvider:      shippingProvider,
+		notifier:              notifier,
+	}
+}
+
+// placeOrder validates the request, reserves stock and records the
+// transaction in one database transaction, then starts the payment.
+// afterCreate, when given, runs inside the same database transaction once the
+// order has been written, so callers can make their own changes atomic with
+// it.
+func (co *checkout) placeOrder(ctx context.Context, userID uuid.UUID, transactionBody dto.TransactionRequestBody, afterCreate func(tx *gorm.DB) error) (*dto.TransactionResponse, *utils.APIError) {
+	if transactionBody.PaidAmount < 0 {
+		return nil, utils.NewBadRequestError("Paid amount must be greater than or equal to 0")
+	}
+
+	if len(transactionBody.TransactionDetailRequestBody) == 0 {
+		return nil, utils.NewBadRequestError("Transaction detail is required")
+	}
+
+	orderLines, apiErr := mergeOrderLines(transactionBody.TransactionDetailRequestBody)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	couponCodes, apiErr := normalizeCouponCodes(transactionBody)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	address, apiErr := resolveShippingAddress(co.addressRepo, userID, transactionBody.AddressID)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	// Lock items in a stable order so concurrent checkouts touching the same
+	// items cannot deadlock each other. Each variant is locked after its item.
+	lockOrder := make([]int, len(orderLines))
+	for i := range lockOrder {
+		lockOrder[i] = i
+	}
+	sort.SliceStable(lockOrder, func(a, b int) bool {
+		lineA, lineB := orderLines[lockOrder[a]], orderLines[lockOrder[b]]
+		if lineA.itemID != lineB.itemID {
+			return lineA.itemID.String() < lineB.itemID.String()
+		}
+		return lineA.variantID.String() < lineB.variantID.String()
+	})
+
+	var transactionID uuid.UUID
+	err := co.txManager.WithinTransaction(func(tx *gorm.DB) error {
+		itemRepo := co.itemRepo.WithTx(tx)
+		variantRepo := co.variantRepo.WithTx(tx)
+		stockMovementRepo := co.stockMovementRepo.WithTx(tx)
+		transactionRepo := co.transactionRepo.WithTx(tx)
+		transactionDetailRepo := co.transactionDetailRepo.WithTx(tx)
+
+		couponRepo := co.couponRepo.WithTx(tx)
+
+		items := make([]*models.Item, len(orderLines))
+		variants := make([]*models.ItemVariant, len(orderLines))
+		lines := make([]pricing.Line, len(orderLines))
+		weightGrams := 0
+
+		// Every line is checked before giving up so the buyer learns about
+		// all missing items and variants and short stock at once.
+		var missingErrors, variantErrors, stockErrors []utils.LineError
+		for _, i := range lockOrder {
+			orderLine := orderLines[i]
+			lineError := utils.LineError{Lines: orderLine.lines, ItemID: orderLine.itemID.String()}
+			if orderLine.variantID != uuid.Nil {
+				lineError.VariantID = orderLine.variantID.String()
+			}
+
+			item, err := itemRepo.GetItemByIDForUpdate(orderLine.itemID)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					lineError.Message = "Item not found"
+					missingErrors = append(missingErrors, lineError)
+					continue
+				}
+				return utils.NewInternalError("Failed to fetch item")
+			}
+
+			name, stock, unitPrice := item.Name, item.Stock, item.Price
+			if orderLine.variantID != uuid.Nil {
+				variant, err := variantRepo.GetVariantByIDForUpdate(item.ID, orderLine.variantID)
+				if err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						lineError.Message = "Variant not found"
+						missingErrors = append(missingErrors, lineError)
+						continue
+					}
+					return utils.NewInternalError("Failed to fetch variant")
+				}
+				variants[i] = variant
+				name, stock, unitPrice = item.Name+" ("+variant.Label()+")", variant.Stock, variant.UnitPrice(item)
+			} else {
+				variantCount, err := variantRepo.CountVariants(item.ID)
+				if err != nil {
+					return utils.NewInternalError("Failed to fetch variants")
+				}
+				if variantCount > 0 {
+					lineError.Message = "Choose a variant of " + item.Name
+					variantErrors = append(variantErrors, lineError)
+					continue
+				}
+			}
+
+			if stock < orderLine.quantity {
+				available := stock
+				lineError.Message = fmt.Sprintf("Only %d of %s left in stock", stock, name)
+				lineError.Requested = orderLine.quantity
+				lineError.Available = &available
+				stockErrors = append(stockErrors, lineError)
+				continue
+			}
+
+			items[i] = item
+			weightGrams += item.WeightGrams * orderLine.quantity
+			lines[i] = pricing.Line{
+				ItemID:    item.ID,
+				Quantity:  orderLine.quantity,
+				UnitPrice: unitPrice,
+			}
+		}
+
+		if len(missingErrors) > 0 {
+			return utils.NewNotFoundError("Item not found").WithLineErrors(sortLineErrors(append(append(missingErrors, variantErrors...), stockErrors...)))
+		}
+		if len(variantErrors) > 0 {
+			return utils.NewBadRequestError("Variant is required").WithLineErrors(sortLineErrors(append(variantErrors, stockErrors...)))
+		}
+		if len(stockErrors) > 0 {
+			return utils.NewBadRequestError("Insufficient stock").WithLineErrors(sortLineErrors(stockErrors))
+		}
+
+		if apiErr := co.setLineTaxes(co.taxRateRepo.WithTx(tx), items, lines); apiErr != nil {
+			return apiErr
+		}
+
+		appliedCoupons, apiErr := co.applyCoupons(couponRepo, userID, couponCodes, lines)
+		if apiErr != nil {
+			return apiErr
+		}
+
+		pricing.ApplyTax(lines)
+
+		shippingQuote, apiErr := quoteShipping(ctx, co.shippingProvider, address.PostalAddress, weightGrams)
+		if apiErr != nil {
+			return apiErr
+		}
+
+		subtotal := pricing.Subtotal(lines)
+		discount := pricing.TotalDiscount(lines)
+		totalRequiredPrice := pricing.TotalGross(lines).Add(shippingQuote.Fee)
+
+		if transactionBody.PaidAmount != totalRequiredPrice {
+			return utils.NewBadRequestError("Paid amount does not match total price")
+		}
+
+		transaction := &models.Transaction{
+			UserID:              userID,
+			SubtotalPrice:       subtotal,
+			DiscountAmount:      discount,
+			NetAmount:           pricing.TotalNet(lines),
+			TaxAmount:           pricing.TotalTax(lines),
+			TotalPrice:          totalRequiredPrice,
+			Currency:            money.DefaultCurrency().Code,
+			Status:              models.TransactionStatusPending,
+			ShippingFee:         shippingQuote.Fee,
+			ShippingProvider:    co.shippingProvider.Name(),
+			ShippingService:     shippingQuote.Service,
+			ShippingWeightGrams: weightGrams,
+			ShippingAddress:     address.PostalAddress,
+		}
+
+		createdTransactionID, err := transactionRepo.CreateTransaction(transaction)
+		if err != nil {
+			return utils.NewInternalError("Failed to create transaction")
+		}
+
+		parsedTransactionID, err := uuid.Parse(createdTransactionID)
+		if err != nil {
+			return utils.NewInternalError("Failed to parse transaction ID")
+		}
+		transactionID = parsedTransactionID
+		source := stockSource{reason: models.StockMovementSale, userID: &userID, transactionID: &parsedTransactionID}
+
+		for i, orderLine := range orderLines {
+			item := items[i]
+			variant := variants[i]
+
+			transactionDetail := &models.TransactionDetail{
+				TransactionID:  parsedTransactionID,
+				ItemID:         item.ID,
+				Quantity:       orderLine.quantity,
+				PricePerUnit:   lines[i].UnitPrice,
+				DiscountAmount: lines[i].Discount,
+				TaxMode:        item.TaxMode,
+				TaxRateBps:     lines[i].TaxRateBps,
+				NetAmount:      lines[i].Net(),
+				TaxAmount:      lines[i].Tax,
+				TotalPrice:     lines[i].Gross(),
+			}
+
+			if variant != nil {
+				transactionDetail.VariantID = &variant.ID
+			}
+
+			if err := transactionDetailRepo.CreateTransactionDetail(transactionDetail); err != nil {
+				return utils.NewInternalError("Failed to create transaction detail")
+			}
+
+			if variant != nil {
+				if err := variantRepo.DecrementStock(variant.ID, orderLine.quantity); err != nil {
+					if errors.Is(err, repositories.ErrInsufficientStock) {
+						return utils.NewBadRequestError("Insufficient stock")
+					}
+					return utils.NewInternalError("Failed to update variant stock")
+				}
+				if err := stockMovementRepo.RecordMovement(source.movement(item.ID, &variant.ID, -orderLine.quantity)); err != nil {
+					return utils.NewInternalError("Failed to record stock movement")
+				}
+			}
+
+			if err := itemRepo.DecrementStock(item.ID, orderLine.quantity); err != nil {
+				if errors.Is(err, repositories.ErrInsufficientStock) {
+					return utils.NewBadRequestError("Insufficient stock")
+				}
+				return utils.NewInternalError("Failed to update item stock")
+			}
+			if err := stockMovementRepo.RecordMovement(source.movement(item.ID, nil, -orderLine.quantity)); err != nil {
+				return utils.NewInternalError("Failed to record stock movement")
+			}
+		}
+
+		for _, applied := range appliedCoupons {
+			if err := couponRepo.RedeemCoupon(&models.CouponRedemption{
+				CouponID:       applied.Coupon.ID,
+				UserID:         userID,
+				TransactionID:  parsedTransactionID,
+				DiscountAmount: applied.Discount,
+			}); err != nil {
+				if errors.Is(err, repositories.ErrCouponUsageLimitReached) {
+					return utils.NewBadRequestError("Coupon " + applied.Coupon.Code + " has reached its usage limit")
+				}
+				return utils.NewInternalError("Failed to redeem coupon")
+			}
+		}
+
+		if afterCreate != nil {
+			return afterCreate(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		var apiErr *utils.APIError
+		if errors.As(err, &apiErr) {
+			return nil, apiErr
+		}
+		return nil, utils.NewInternalError("Failed to create transaction")
+	}
+
+	transaction, err := co.transactionRepo.GetTransactionByID(transactionID)
+	if err != nil {
+		return nil, utils.NewInternalError("Failed to fetch transaction")
+	}
+
+	if err := co.startPayment(ctx, transaction); err != nil {
+		log.Printf("failed to start payment for transaction %s: %v", transaction.ID, err)
+		return nil, utils.NewInternalError("Failed to create payment")
+	}
+
+	transaction, err = co.transactionRepo.GetTransactionByID(transactionID)
+	if err != nil {
+		return nil, utils.NewInternalError("Failed to fetch transaction")
+	}
+
+	co.notifier.OrderConfirmation(transaction)
+
+	return transaction, nil
+}
+
+// startPayment charges the committed, still pending transaction through the
+// payment provider. The provider reports the outcome through the webhook. If
+// the charge cannot even be started, the transaction is failed and its stock
+// released so the buyer can simply try again.
+func (co *checkout) startPayment(ctx context.Context, transaction *dto.TransactionResponse) error {
+	charge, err := co.paymentProvider.CreateCharge(ctx, payments.ChargeRequest{
+		Reference:   transaction.ID.String(),
+		Amount:      transaction.TotalPrice,
+		Currency:    transaction.Currency,
+		Description: "Order " + transaction.ID.String(),
+	})
+	if err == nil {
+		err = co.transactionRepo.SetPaymentCharge(transaction.ID, co.paymentProvider.Name(), charge.ID)
+	}
+	if err == nil && charge.Status == payments.ChargeStatusAuthorized {
+		_, err = co.paymentProvider.CaptureCharge(ctx, charge.ID)
+	}
+	if err == nil {
+		return nil
+	}
+
+	releaseErr := co.txManager.WithinTransaction(func(tx *gorm.DB) error {
+		return releaseTransaction(co.transactionRepo.WithTx(tx), co.itemRepo.WithTx(tx), co.variantRepo.WithTx(tx), co.stockMovementRepo.WithTx(tx), co.couponRepo.WithTx(tx), transaction, models.TransactionStatusFailed, "Payment could not be started")
+	})
+	if releaseErr != nil && !errors.Is(releaseErr, repositories.ErrIllegalStatusTransition) {
+		return errors.Join(err, releaseErr)
+	}
+
+	return err
+}
+
+// orderLine is the total quantity of one item, or one variant of an item, in
+// an order, together with the request lines it was merged from. variantID is
+// uuid.Nil for items ordered without a variant.
+type orderLine struct {
+	itemID    uuid.UUID
+	variantID uuid.UUID
+	quantity  int
+	lines     []int
+}
+
+// mergeOrderLines validates the requested lines and merges lines of the same
+// item and variant, so stock is checked against the total quantity. Items
+// keep the position of their first line. All invalid lines are reported
+// together.
+func mergeOrderLines(details []dto.TransactionDetailRequestBody) ([]orderLine, *utils.APIError) {
+	var orderLines []orderLine
+	var lineErrors []utils.LineError
+	positions := map[[2]uuid.UUID]int{}
+
+	for i, detail := range details {
+		if detail.ItemID == "" {
+			lineErrors = append(lineErrors, utils.LineError{Lines: []int{i}, Message: "Item ID is required"})
+			continue
+		}
+
+		parsedItemID, err := uuid.Parse(detail.ItemID)
+		if err != nil {
+			lineErrors = append(lineErrors, utils.LineError{Lines: []int{i}, ItemID: detail.ItemID, Message: "Invalid Item ID format"})
+			continue
+		}
+
+		var parsedVariantID uuid.UUID
+		if detail.VariantID != "" {
+			parsedVariantID, err = uuid.Parse(detail.VariantID)
+			if err != nil {
+				lineErrors = append(lineErrors, utils.LineError{Lines: []int{i}, ItemID: detail.ItemID, VariantID: detail.VariantID, Message: "Invalid Variant ID format"})
+				continue
+			}
+		}
+
+		if detail.Quantity <= 0 {
+			lineErrors = append(lineErrors, utils.LineError{Lines: []int{i}, ItemID: detail.ItemID, VariantID: detail.VariantID, Message: "Quantity must be greater than 0", Requested: detail.Quantity})
+			continue
+		}
+
+		key := [2]uuid.UUID{parsedItemID, parsedVariantID}
+		position, ok := positions[key]
+		if !ok {
+			position = len(orderLines)
+			positions[key] = position
+			orderLines = append(orderLines, orderLine{itemID: parsedItemID, variantID: parsedVariantID})
+		}
+		orderLines[position].quantity += detail.Quantity
+		orderLines[position].lines = append(orderLines[position].lines, i)
+	}
+
+	if len(lineErrors) == 1 {
+		return nil, utils.NewBadRequestError(lineErrors[0].Message).WithLineErrors(lineErrors)
+	}
+	if len(lineErrors) > 1 {
+		return nil, utils.NewBadRequestError(fmt.Sprintf("%d order lines are invalid", len(lineErrors))).WithLineErrors(lineErrors)
+	}
+
+	return orderLines, nil
+}
+
+// sortLineErrors orders errors by their first line, as they appear in the
+// request.
+func sortLineErrors(lineErrors []utils.LineError) []utils.LineError {
+	sort.SliceStable(lineErrors, func(a, b int) bool {
+		return lineErrors[a].Lines[0] < lineErrors[b].Lines[0]
+	})
+	return lineErrors
+}
+
+// normalizeCouponCodes merges coupon_code and coupon_codes into one list of
+// upper-case codes and rejects a code given twice.
+func normalizeCouponCodes(transactionBody dto.TransactionRequestBody) ([]string, *utils.APIError) {
+	rawCodes := transactionBody.CouponCodes
+	if transactionBody.CouponCode != "" {
+		rawCodes = append([]string{transactionBody.CouponCode}, rawCodes...)
+	}
+
+	var codes []string
+	seen := map[string]bool{}
+	for _, rawCode := range rawCodes {
+		code := strings.ToUpper(strings.TrimSpace(rawCode))
+		if code == "" {
+			continue
+		}
+
+		if seen[code] {
+			return nil, utils.NewBadRequestError("Coupon " + code + " is used more than once")
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// setLineTaxes gives every line the tax rate of its item, or the default
+// rate when the item has none. Exempt items are not taxed.
+func (co *checkout) setLineTaxes(taxRateRepo repositories.TaxRateRepository, items []*models.Item, lines []pricing.Line) *utils.APIError {
+	defaultRate, err := taxRateRepo.GetDefaultTaxRate()
+	if err != nil {
+		return utils.NewInternalError("Failed to fetch tax rates")
+	}
+
+	var taxRateIDs []uuid.UUID
+	for _, item := range items {
+		if item.TaxRateID != nil {
+			taxRateIDs = append(taxRateIDs, *item.TaxRateID)
+		}
+	}
+
+	taxRates, err := taxRateRepo.GetTaxRatesByIDs(taxRateIDs)
+	if err != nil {
+		return utils.NewInternalError("Failed to fetch tax rates")
+	}
+
+	rateBps := map[uuid.UUID]int{}
+	for _, taxRate := range taxRates {
+		rateBps[taxRate.ID] = taxRate.RateBps
+	}
+
+	for i, item := range items {
+		if item.TaxMode == models.TaxModeExempt {
+			continue
+		}
+
+		switch {
+		case item.TaxRateID != nil:
+			bps, ok := rateBps[*item.TaxRateID]
+			if !ok {
+				return utils.NewInternalError("Tax rate of " + item.Name + " no longer exists")
+			}
+			lines[i].TaxRateBps = bps
+		case defaultRate != nil:
+			lines[i].TaxRateBps = defaultRate.RateBps
+		}
+		lines[i].TaxInclusive = item.TaxMode == models.TaxModeInclusive
+	}
+
+	return nil
+}
+
+// applyCoupons locks the requested coupons, checks them against the buyer's
+// previous use and adds their discounts to lines.
+func (co *checkout) applyCoupons(couponRepo repositories.CouponRepository, userID uuid.UUID, codes []string, lines []pricing.Line) ([]pricing.AppliedCoupon, *utils.APIError) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+
+	coupons, err := couponRepo.GetCouponsByCodesForUpdate(codes)
+	if err != nil {
+		return nil, utils.NewInternalError("Failed to fetch coupons")
+	}
+
+	couponsByCode := map[string]models.Coupon{}
+	for _, coupon := range coupons {
+		couponsByCode[coupon.Code] = coupon
+	}
+
+	usages := make([]pricing.CouponUsage, 0, len(codes))
+	for _, code := range codes {
+		coupon, ok := couponsByCode[code]
+		if !ok {
+			return nil, utils.NewNotFoundError("Coupon " + code + " not found")
+		}
+
+		redemptions, err := couponRepo.CountUserRedemptions(coupon.ID, userID)
+		if err != nil {
+			return nil, utils.NewInternalError("Failed to check coupon usage")
+		}
+
+		usages = append(usages, pricing.CouponUsage{Coupon: coupon, UserRedemptions: int(redemptions)})
+	}
+
+	applied, err := pricing.ApplyCoupons(lines, usages, time.Now())
+	if err != nil {
+		var couponErr *pricing.CouponError
+		if errors.As(err, &couponErr) {
+			return nil, utils.NewBadRequestError("Coupon " + couponErr.Code + " " + couponErr.Message)
+		}
+		return nil, utils.NewInternalError("Failed to apply coupons")
+	}
+
+	return applied, nil
+}
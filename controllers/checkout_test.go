@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"context"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/money"
+	"ordent/utils"
+	"sync"
+	"testing"
+)
+
+func TestPlaceOrderDoesNotOversellUnderConcurrency(t *testing.T) {
+	db := openTestDB(t)
+
+	const (
+		buyers = 20
+		stock  = 3
+	)
+	price := money.MustParse("10.00")
+
+	provider, _ := startMockGateway(t, "", "test-secret")
+	co := newTestCheckout(t, db, provider)
+	buyer := createTestBuyer(t, db)
+	item := createTestItem(t, db, price, stock)
+
+	body := dto.TransactionRequestBody{
+		PaidAmount: price,
+		TransactionDetailRequestBody: []dto.TransactionDetailRequestBody{
+			{ItemID: item.ID.String(), Quantity: 1},
+		},
+	}
+
+	results := make([]*utils.APIError, buyers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range buyers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, results[i] = co.placeOrder(context.Background(), buyer.ID, body, nil)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for i, apiErr := range results {
+		switch {
+		case apiErr == nil:
+			succeeded++
+		case apiErr.Message != "Insufficient stock":
+			t.Errorf("checkout %d failed with %q, want \"Insufficient stock\"", i, apiErr.Message)
+		}
+	}
+	if succeeded != stock {
+		t.Errorf("%d checkouts succeeded, want %d", succeeded, stock)
+	}
+
+	var stored models.Item
+	if err := db.First(&stored, "id = ?", item.ID).Error; err != nil {
+		t.Fatalf("reload item: %v", err)
+	}
+	if stored.Stock != 0 {
+		t.Errorf("stock is %d after checkout, want 0", stored.Stock)
+	}
+
+	var sold int64
+	if err := db.Model(&models.TransactionDetail{}).Where("item_id = ?", item.ID).Select("COALESCE(SUM(quantity), 0)").Scan(&sold).Error; err != nil {
+		t.Fatalf("sum sold quantity: %v", err)
+	}
+	if sold != stock {
+		t.Errorf("%d units were sold, want %d", sold, stock)
+	}
+}
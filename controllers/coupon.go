@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/repositories"
+	"ordent/utils"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type CouponController struct {
+	couponRepo repositories.CouponRepository
+	itemRepo   repositories.ItemRepository
+}
+
+func NewCouponController(couponRepo repositories.CouponRepository, itemRepo repositories.ItemRepository) *CouponController {
+	return &CouponController{
+		couponRepo: couponRepo,
+		itemRepo:   itemRepo,
+	}
+}
+
+// CreateCoupon godoc
+// @Summary Create new coupon
+// @Description Create a percentage or fixed-amount coupon for the whole order or a single item. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags coupon
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param coupon body dto.CouponRequestBody true "Coupon details"
+// @Success 201 {object} models.Coupon
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/coupons [post]
+func (cc *CouponController) CreateCoupon(c echo.Context) error {
+	var couponBody dto.CouponRequestBody
+	if err := c.Bind(&couponBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	coupon, apiErr := cc.couponFromBody(couponBody)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	if err := cc.couponRepo.CreateCoupon(coupon); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return utils.HandlerError(c, utils.NewBadRequestError("Coupon code already exists"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to create coupon"))
+	}
+
+	return c.JSON(http.StatusCreated, coupon)
+}
+
+// GetAllCoupons godoc
+// @Summary Get all coupons
+// @Description Get a list of all coupons with their usage counts. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags coupon
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} models.Coupon
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/coupons [get]
+func (cc *CouponController) GetAllCoupons(c echo.Context) error {
+	coupons, err := cc.couponRepo.GetAllCoupons()
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch coupons"))
+	}
+
+	return c.JSON(http.StatusOK, coupons)
+}
+
+// GetCouponByID godoc
+// @Summary Get coupon by ID
+// @Description Get a single coupon. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags coupon
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Coupon ID"
+// @Success 200 {object} models.Coupon
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/coupons/{id} [get]
+func (cc *CouponController) GetCouponByID(c echo.Context) error {
+	parsedCouponID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid coupon ID"))
+	}
+
+	coupon, err := cc.couponRepo.GetCouponByID(parsedCouponID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Coupon not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch coupon"))
+	}
+
+	return c.JSON(http.StatusOK, coupon)
+}
+
+// EditCoupon godoc
+// @Summary Edit an existing coupon
+// @Description Replace the settings of an existing coupon. Its usage count is kept. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags coupon
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Coupon ID"
+// @Param coupon body dto.CouponRequestBody true "Coupon details"
+// @Success 200 {object} models.Coupon
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/coupons/{id} [put]
+func (cc *CouponController) EditCoupon(c echo.Context) error {
+	parsedCouponID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid coupon ID"))
+	}
+
+	var couponBody dto.CouponRequestBody
+	if err := c.Bind(&couponBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	coupon, apiErr := cc.couponFromBody(couponBody)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	if _, err := cc.couponRepo.GetCouponByID(parsedCouponID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Coupon not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch coupon"))
+	}
+
+	if err := cc.couponRepo.EditCoupon(coupon, parsedCouponID); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return utils.HandlerError(c, utils.NewBadRequestError("Coupon code already exists"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to update coupon"))
+	}
+
+	updated, err := cc.couponRepo.GetCouponByID(parsedCouponID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch coupon"))
+	}
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+// DeleteCoupon godoc
+// @Summary Delete an existing coupon
+// @Description Delete a coupon so it can no longer be used. Orders that already used it keep their discount. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags coupon
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Coupon ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/coupons/{id} [delete]
+func (cc *CouponController) DeleteCoupon(c echo.Context) error {
+	parsedCouponID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid coupon ID"))
+	}
+
+	if err := cc.couponRepo.DeleteCoupon(parsedCouponID); err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to delete coupon"))
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Coupon success deleted",
+	})
+}
+
+func (cc *CouponController) couponFromBody(couponBody dto.CouponRequestBody) (*models.Coupon, *utils.APIError) {
+	code := strings.ToUpper(strings.TrimSpace(couponBody.Code))
+	if code == "" {
+		return nil, utils.NewBadRequestError("Code is required")
+	}
+
+	coupon := &models.Coupon{
+		Code:         code,
+		Description:  couponBody.Description,
+		Type:         models.CouponType(couponBody.Type),
+		MinSpend:     couponBody.MinSpend,
+		Scope:        models.CouponScope(couponBody.Scope),
+		StartsAt:     couponBody.StartsAt,
+		EndsAt:       couponBody.EndsAt,
+		UsageLimit:   couponBody.UsageLimit,
+		PerUserLimit: couponBody.PerUserLimit,
+		Stackable:    couponBody.Stackable,
+		IsActive:     couponBody.IsActive == nil || *couponBody.IsActive,
+	}
+
+	switch coupon.Type {
+	case models.CouponTypePercentage:
+		if couponBody.PercentageBps <= 0 || couponBody.PercentageBps > 10000 {
+			return nil, utils.NewBadRequestError("Percentage must be between 1 and 10000 basis points")
+		}
+		if couponBody.MaxDiscount < 0 {
+			return nil, utils.NewBadRequestError("Max discount cannot be negative")
+		}
+		coupon.PercentageBps = couponBody.PercentageBps
+		coupon.MaxDiscount = couponBody.MaxDiscount
+	case models.CouponTypeFixed:
+		if couponBody.AmountOff <= 0 {
+			return nil, utils.NewBadRequestError("Amount off must be greater than 0")
+		}
+		coupon.AmountOff = couponBody.AmountOff
+	default:
+		return nil, utils.NewBadRequestError("Type must be percentage or fixed")
+	}
+
+	if coupon.MinSpend < 0 {
+		return nil, utils.NewBadRequestError("Min spend cannot be negative")
+	}
+
+	if coupon.UsageLimit < 0 || coupon.PerUserLimit < 0 {
+		return nil, utils.NewBadRequestError("Usage limits cannot be negative")
+	}
+
+	if coupon.StartsAt != nil && coupon.EndsAt != nil && !coupon.EndsAt.After(*coupon.StartsAt) {
+		return nil, utils.NewBadRequestError("End date must be after start date")
+	}
+
+	switch coupon.Scope {
+	case "":
+		coupon.Scope = models.CouponScopeOrder
+	case models.CouponScopeOrder:
+	case models.CouponScopeItem:
+		parsedItemID, err := uuid.Parse(couponBody.ItemID)
+		if err != nil {
+			return nil, utils.NewBadRequestError("Item coupons need a valid item ID")
+		}
+
+		if _, err := cc.itemRepo.GetItemByID(parsedItemID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, utils.NewNotFoundError("Item not found")
+			}
+			return nil, utils.NewInternalError("Failed to fetch item")
+		}
+		coupon.ItemID = &parsedItemID
+	default:
+		return nil, utils.NewBadRequestError("Scope must be order or item")
+	}
+
+	return coupon, nil
+}
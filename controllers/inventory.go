@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"net/http"
+	"ordent/dto"
+	"ordent/repositories"
+	"ordent/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InventoryController struct {
+	lowStockAlertRepo repositories.LowStockAlertRepository
+}
+
+func NewInventoryController(lowStockAlertRepo repositories.LowStockAlertRepository) *InventoryController {
+	return &InventoryController{
+		lowStockAlertRepo: lowStockAlertRepo,
+	}
+}
+
+// GetLowStockItems godoc
+// @Summary List items that are low on stock
+// @Description Lists the items whose stock is at or below their reorder threshold, emptiest first. Items with a reorder threshold of 0 are never listed. alerted_at tells when the item's low-stock alert was raised; an item raises one alert when its stock falls to the threshold and no more until it has been restocked above it. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags inventory
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} dto.LowStockListResponse
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/admin/inventory/low-stock [get]
+func (ic *InventoryController) GetLowStockItems(c echo.Context) error {
+	items, err := ic.lowStockAlertRepo.GetLowStockItems()
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch low-stock items"))
+	}
+
+	return c.JSON(http.StatusOK, dto.LowStockListResponse{Items: items})
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ordent/dto"
+	"ordent/invoice"
+	"ordent/models"
+	"ordent/repositories"
+	"ordent/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type InvoiceController struct {
+	transactionRepo repositories.TransactionRepository
+	invoiceRepo     repositories.InvoiceRepository
+	userRepo        repositories.UserRepository
+	letterhead      invoice.Letterhead
+}
+
+func NewInvoiceController(transactionRepo repositories.TransactionRepository, invoiceRepo repositories.InvoiceRepository, userRepo repositories.UserRepository, letterhead invoice.Letterhead) *InvoiceController {
+	return &InvoiceController{
+		transactionRepo: transactionRepo,
+		invoiceRepo:     invoiceRepo,
+		userRepo:        userRepo,
+		letterhead:      letterhead,
+	}
+}
+
+// GetInvoicePDF godoc
+// @Summary Download the invoice of a transaction
+// @Description Get the PDF invoice of a paid transaction. The invoice gets the next sequential number the first time it is requested and is never changed afterwards. Clients can only get invoices of their own transactions, admins can get any.
+// @Tags transaction
+// @Produce  application/pdf
+// @Security BearerAuth
+// @Param id path string true "Transaction ID"
+// @Success 200 {file} file
+// @Success 304 "Not Modified"
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/transactions/{id}/invoice.pdf [get]
+func (ic *InvoiceController) GetInvoicePDF(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	parsedTransactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid transaction ID"))
+	}
+
+	transaction, err := ic.transactionRepo.GetTransactionByID(parsedTransactionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Transaction not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch transaction"))
+	}
+
+	if !userPayload.IsAdmin && transaction.UserID != userPayload.UserID {
+		return utils.HandlerError(c, utils.NewNotFoundError("Transaction not found"))
+	}
+
+	issued, err := ic.invoiceRepo.GetInvoiceByTransactionID(parsedTransactionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		issued, err = ic.issueInvoice(transaction)
+		var apiErr *utils.APIError
+		if errors.As(err, &apiErr) {
+			return utils.HandlerError(c, apiErr)
+		}
+	}
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to get invoice"))
+	}
+
+	header := c.Response().Header()
+	header.Set("Cache-Control", "private, max-age=31536000, immutable")
+	header.Set("Content-Disposition", `inline; filename="`+issued.Number+`.pdf"`)
+
+	if utils.NotModified(c, `"`+issued.Checksum+`"`, issued.IssuedAt) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.Blob(http.StatusOK, "application/pdf", issued.PDF)
+}
+
+func (ic *InvoiceController) issueInvoice(transaction *dto.TransactionResponse) (*models.Invoice, error) {
+	switch models.TransactionStatus(transaction.Status) {
+	case models.TransactionStatusPaid, models.TransactionStatusPartiallyRefunded, models.TransactionStatusRefunded:
+	default:
+		return nil, utils.NewBadRequestError("The invoice is available once the transaction is paid")
+	}
+
+	buyer, err := ic.userRepo.GetUserByID(transaction.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ic.invoiceRepo.IssueInvoice(transaction.ID, func(number string, issuedAt time.Time) ([]byte, error) {
+		return invoice.Render(ic.buildInvoice(number, issuedAt, transaction, buyer)), nil
+	})
+}
+
+func (ic *InvoiceController) buildInvoice(number string, issuedAt time.Time, transaction *dto.TransactionResponse, buyer *models.User) invoice.Invoice {
+	inv := invoice.Invoice{
+		Number:        number,
+		IssuedAt:      issuedAt,
+		TransactionID: transaction.ID.String(),
+		OrderedAt:     transaction.CreatedAt,
+		PaymentStatus: strings.ReplaceAll(transaction.Status, "_", " "),
+		Currency:      transaction.Currency,
+		Seller:        ic.letterhead,
+		Buyer: invoice.Party{
+			Name:  buyer.FullName,
+			Email: buyer.Email,
+		},
+		Subtotal: transaction.SubtotalPrice,
+		Discount: transaction.DiscountAmount,
+		Tax:      transaction.TaxAmount,
+		Shipping: transaction.ShippingFee,
+		Total:    transaction.TotalPrice,
+	}
+
+	if address := transaction.ShippingAddress; address != nil {
+		inv.Buyer.Address = []string{
+			address.Line1,
+			address.Line2,
+			strings.Join([]string{address.City, address.Province, address.PostalCode}, ", "),
+			address.Country,
+		}
+	}
+
+	for _, detail := range transaction.TransactionDetails {
+		description := detail.Item.Name
+		if detail.Variant != nil {
+			description += " (" + detail.Variant.Label + ")"
+		}
+
+		inv.Lines = append(inv.Lines, invoice.Line{
+			Description: description,
+			Quantity:    detail.Quantity,
+			UnitPrice:   detail.PricePerUnit,
+			Discount:    detail.DiscountAmount,
+			Tax:         detail.TaxAmount,
+			Total:       detail.TotalPrice,
+		})
+	}
+
+	return inv
+}
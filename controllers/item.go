@@ -1,23 +1,48 @@
 package controllers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"ordent/dto"
 	"ordent/models"
+	"ordent/money"
 	"ordent/repositories"
+	"ordent/search"
+	"ordent/storage"
 	"ordent/utils"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 type ItemController struct {
-	itemRepo repositories.ItemRepository
+	itemWriter
+	txManager repositories.TxManager
+	storage   storage.Storage
+	searcher  search.Searcher
 }
 
-func NewItemController(itemRepo repositories.ItemRepository) *ItemController {
+func NewItemController(txManager repositories.TxManager, itemRepo repositories.ItemRepository, variantRepo repositories.ItemVariantRepository, stockMovementRepo repositories.StockMovementRepository, lowStockAlertRepo repositories.LowStockAlertRepository, taxRateRepo repositories.TaxRateRepository, categoryRepo repositories.CategoryRepository, tagRepo repositories.TagRepository, storage storage.Storage, searcher search.Searcher) *ItemController {
 	return &ItemController{
-		itemRepo: itemRepo,
+		itemWriter: itemWriter{
+			itemRepo:          itemRepo,
+			variantRepo:       variantRepo,
+			stockMovementRepo: stockMovementRepo,
+			lowStockAlertRepo: lowStockAlertRepo,
+			taxRateRepo:       taxRateRepo,
+			categoryRepo:      categoryRepo,
+			tagRepo:           tagRepo,
+		},
+		txManager: txManager,
+		storage:   storage,
+		searcher:  searcher,
 	}
 }
 
@@ -33,6 +58,7 @@ func NewItemController(itemRepo repositories.ItemRepository) *ItemController {
 // @Failure 400 {object} utils.APIError "Bad Request"
 // @Failure 401 {object} utils.APIError "Unauthorized"
 // @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
 // @Failure 500 {object} utils.APIError "Internal Server Error"
 // @Router /api/v1/items [post]
 func (ic *ItemController) CreateItem(c echo.Context) error {
@@ -42,52 +68,128 @@ func (ic *ItemController) CreateItem(c echo.Context) error {
 		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
 	}
 
-	if itemBody.Name == "" {
-		return utils.HandlerError(c, utils.NewBadRequestError("Name is required"))
-	}
-
-	if itemBody.Price == 0 {
-		return utils.HandlerError(c, utils.NewBadRequestError("Price is required"))
+	change, apiErr := ic.prepareItem(itemBody)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
 	}
+	newItem := change.item
 
-	if itemBody.Stock == 0 {
-		return utils.HandlerError(c, utils.NewBadRequestError("Quantity is required"))
-	}
-
-	newItem := &models.Item{
-		Name:  itemBody.Name,
-		Price: itemBody.Price,
-		Stock: itemBody.Stock,
-	}
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+	source := stockSource{reason: models.StockMovementRestock, userID: &userPayload.UserID, note: "Item created"}
 
-	if err := ic.itemRepo.CreateItem(newItem); err != nil {
+	err := ic.txManager.WithinTransaction(func(tx *gorm.DB) error {
+		return ic.createItem(tx, change, source)
+	})
+	if err != nil {
+		var apiErr *utils.APIError
+		if errors.As(err, &apiErr) {
+			return utils.HandlerError(c, apiErr)
+		}
 		return utils.HandlerError(c, utils.NewInternalError("Failed to create item"))
 	}
 
+	ic.indexItem(c, newItem)
+
 	return c.JSON(http.StatusCreated, newItem)
 }
 
 // GetAllItems godoc
 // @Summary Get all items
-// @Description Get a list of all items. No authentication required.
+// @Description Get one page of items. Pages are numbered with page, or followed with cursor: pass pagination.next_cursor from the previous page together with the same sort and filters. Cursor pages stay consistent while items are added. The response carries an ETag header; send it back as If-None-Match to get 304 Not Modified while the page is unchanged. No authentication required.
 // @Tags item
 // @Accept  json
 // @Produce  json
-// @Success 200 {array} models.Item
+// @Param page query int false "Page number, starting at 1 (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param cursor query string false "Cursor returned as pagination.next_cursor by the previous page"
+// @Param sort query string false "Sort column, prefixed with - for descending (default -created_at)" Enums(name, -name, price, -price, stock, -stock, created_at, -created_at)
+// @Param q query string false "Search item names"
+// @Param min_price query number false "Only items costing at least this amount"
+// @Param max_price query number false "Only items costing at most this amount"
+// @Param in_stock query bool false "Only items that are in stock"
+// @Param category query string false "Only items in this category or its subcategories, by ID or slug"
+// @Param tag query string false "Only items with this tag, by slug"
+// @Param If-None-Match header string false "ETag of the cached copy"
+// @Success 200 {object} dto.ItemListResponse
+// @Success 304 "Not Modified"
+// @Failure 400 {object} utils.APIError "Bad Request"
 // @Failure 500 {object} utils.APIError "Internal Server Error"
 // @Router /api/v1/items [get]
 func (ic *ItemController) GetAllItems(c echo.Context) error {
-	items, err := ic.itemRepo.GetAllItems()
+	filter, apiErr := parseItemFilter(c)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	if apiErr := ic.setTaxonomyFilter(c, filter); apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	items, err := ic.itemRepo.GetItems(*filter)
 	if err != nil {
+		if errors.Is(err, utils.ErrInvalidCursor) {
+			return utils.HandlerError(c, utils.NewBadRequestError("Invalid cursor"))
+		}
 		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch items"))
 	}
 
+	for i := range items.Items {
+		setImageURLs(ic.storage, items.Items[i].Images)
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, no-cache")
+	if utils.NotModified(c, responseETag(items), time.Time{}) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
 	return c.JSON(http.StatusOK, items)
 }
 
+// GetItemByID godoc
+// @Summary Get an item
+// @Description Get a single item. The response carries an ETag header; send it back as If-None-Match to get 304 Not Modified while the item is unchanged. No authentication required.
+// @Tags item
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Item ID"
+// @Param If-None-Match header string false "ETag of the cached copy"
+// @Success 200 {object} dto.GetAllItemResponse
+// @Success 304 "Not Modified"
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/items/{id} [get]
+func (ic *ItemController) GetItemByID(c echo.Context) error {
+	parsedItemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid item ID"))
+	}
+
+	item, err := ic.itemRepo.GetItemDetail(parsedItemID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Item not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch item"))
+	}
+
+	setImageURLs(ic.storage, item.Images)
+
+	// Clients may keep the item but must check it is still current. No
+	// Last-Modified is sent: no single timestamp covers the item together
+	// with its categories, tags and images, which change without touching it
+	// and can be removed altogether.
+	c.Response().Header().Set("Cache-Control", "public, no-cache")
+	if utils.NotModified(c, responseETag(item), time.Time{}) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.JSON(http.StatusOK, item)
+}
+
 // EditItem godoc
 // @Summary Edit an existing item
-// @Description Edit an existing item. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Description Edit an existing item. The SKU, categories and tags are only changed when sku, category_ids or tags are sent. The stock of an item with variants stays the sum of its variants' stock. This endpoint can only be accessed by admin users (isAdmin=true).
 // @Tags item
 // @Accept  json
 // @Produce  json
@@ -114,28 +216,28 @@ func (ic *ItemController) EditItem(c echo.Context) error {
 		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
 	}
 
-	if itemBody.Name == "" {
-		return utils.HandlerError(c, utils.NewBadRequestError("Name is required"))
+	change, apiErr := ic.prepareItem(itemBody)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
 	}
+	item := change.item
 
-	if itemBody.Price == 0 {
-		return utils.HandlerError(c, utils.NewBadRequestError("Price is required"))
-	}
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+	source := stockSource{reason: models.StockMovementAdjustment, userID: &userPayload.UserID, note: "Item edited"}
 
-	if itemBody.Stock == 0 {
-		return utils.HandlerError(c, utils.NewBadRequestError("Quantity is required"))
-	}
-
-	item := &models.Item{
-		Name:  itemBody.Name,
-		Price: itemBody.Price,
-		Stock: itemBody.Stock,
-	}
-
-	if err := ic.itemRepo.EditItem(item, parsedItemID); err != nil {
+	err = ic.txManager.WithinTransaction(func(tx *gorm.DB) error {
+		return ic.updateItem(tx, parsedItemID, change, source)
+	})
+	if err != nil {
+		var apiErr *utils.APIError
+		if errors.As(err, &apiErr) {
+			return utils.HandlerError(c, apiErr)
+		}
 		return utils.HandlerError(c, utils.NewInternalError("Failed to update item"))
 	}
 
+	ic.indexItem(c, item)
+
 	return c.JSON(http.StatusOK, item)
 }
 
@@ -166,7 +268,149 @@ func (ic *ItemController) DeleteItem(c echo.Context) error {
 		return utils.HandlerError(c, utils.NewInternalError("Failed to delete item"))
 	}
 
+	if err := ic.searcher.Remove(c.Request().Context(), parsedItemID); err != nil {
+		c.Logger().Warnf("failed to remove item %s from search: %v", parsedItemID, err)
+	}
+
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "Item success deleted",
 	})
 }
+
+// indexItem updates the search index after the item was saved. The item is
+// already committed, so a failure is logged rather than reported.
+func (ic *ItemController) indexItem(c echo.Context, item *models.Item) {
+	if err := ic.searcher.Index(c.Request().Context(), search.Document{ID: item.ID, Name: item.Name}); err != nil {
+		c.Logger().Warnf("failed to index item %s for search: %v", item.ID, err)
+	}
+}
+
+// setTaxonomyFilter resolves the category and tag query parameters of the
+// item list. A category includes its subcategories.
+func (ic *ItemController) setTaxonomyFilter(c echo.Context, filter *repositories.ItemFilter) *utils.APIError {
+	if category := c.QueryParam("category"); category != "" {
+		var found *models.Category
+		var err error
+		if parsedCategoryID, parseErr := uuid.Parse(category); parseErr == nil {
+			found, err = ic.categoryRepo.GetCategoryByID(parsedCategoryID)
+		} else {
+			found, err = ic.categoryRepo.GetCategoryBySlug(category)
+		}
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Category not found")
+			}
+			return utils.NewInternalError("Failed to fetch category")
+		}
+
+		categoryIDs, err := ic.categoryRepo.GetDescendantIDs(found.ID)
+		if err != nil {
+			return utils.NewInternalError("Failed to fetch categories")
+		}
+		filter.CategoryIDs = categoryIDs
+	}
+
+	if tag := c.QueryParam("tag"); tag != "" {
+		found, err := ic.tagRepo.GetTagBySlug(utils.Slugify(tag))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Tag not found")
+			}
+			return utils.NewInternalError("Failed to fetch tag")
+		}
+		filter.TagID = &found.ID
+	}
+
+	return nil
+}
+
+const (
+	defaultItemPageSize = 20
+	maxItemPageSize     = 100
+)
+
+// parseItemFilter reads the pagination, sort and filter query parameters of
+// the item list.
+func parseItemFilter(c echo.Context) (*repositories.ItemFilter, *utils.APIError) {
+	filter := &repositories.ItemFilter{
+		Limit:    defaultItemPageSize,
+		SortBy:   repositories.ItemSortCreatedAt,
+		SortDesc: true,
+		Search:   strings.TrimSpace(c.QueryParam("q")),
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		parsedLimit, err := strconv.Atoi(limit)
+		if err != nil || parsedLimit <= 0 {
+			return nil, utils.NewBadRequestError("Limit must be a positive number")
+		}
+		filter.Limit = min(parsedLimit, maxItemPageSize)
+	}
+
+	if sort := c.QueryParam("sort"); sort != "" {
+		filter.SortDesc = strings.HasPrefix(sort, "-")
+		filter.SortBy = repositories.ItemSortField(strings.TrimPrefix(sort, "-"))
+		if !filter.SortBy.IsValid() {
+			return nil, utils.NewBadRequestError("sort must be name, price, stock or created_at, optionally prefixed with -")
+		}
+	}
+
+	if page := c.QueryParam("page"); page != "" {
+		parsedPage, err := strconv.Atoi(page)
+		if err != nil || parsedPage <= 0 {
+			return nil, utils.NewBadRequestError("Page must be a positive number")
+		}
+		filter.Page = parsedPage
+	}
+
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		if filter.Page != 0 {
+			return nil, utils.NewBadRequestError("Use either page or cursor, not both")
+		}
+
+		parsedCursor, err := utils.DecodeSortCursor(cursor)
+		if err != nil || parsedCursor.Sort != filter.CursorSort() {
+			return nil, utils.NewBadRequestError("Invalid cursor")
+		}
+		filter.Cursor = parsedCursor
+	}
+
+	if minPrice := c.QueryParam("min_price"); minPrice != "" {
+		parsedMinPrice, err := money.Parse(minPrice)
+		if err != nil || parsedMinPrice.IsNegative() {
+			return nil, utils.NewBadRequestError("Invalid min_price")
+		}
+		filter.MinPrice = &parsedMinPrice
+	}
+
+	if maxPrice := c.QueryParam("max_price"); maxPrice != "" {
+		parsedMaxPrice, err := money.Parse(maxPrice)
+		if err != nil || parsedMaxPrice.IsNegative() {
+			return nil, utils.NewBadRequestError("Invalid max_price")
+		}
+		filter.MaxPrice = &parsedMaxPrice
+	}
+
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return nil, utils.NewBadRequestError("min_price must not be greater than max_price")
+	}
+
+	if inStock := c.QueryParam("in_stock"); inStock != "" {
+		parsedInStock, err := strconv.ParseBool(inStock)
+		if err != nil {
+			return nil, utils.NewBadRequestError("in_stock must be true or false")
+		}
+		filter.InStock = parsedInStock
+	}
+
+	return filter, nil
+}
+
+// responseETag identifies a version of an item or item list response. It
+// hashes the whole response rather than UpdatedAt, because renaming a
+// category or tag changes the response without touching the item.
+func responseETag(response interface{}) string {
+	body, _ := json.Marshal(response)
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"ordent/dto"
+	"ordent/money"
+	"strconv"
+	"strings"
+)
+
+const (
+	itemFileFormatCSV   = "csv"
+	itemFileFormatJSONL = "jsonl"
+
+	// itemFileListSeparator separates category IDs and tag names in one
+	// CSV cell.
+	itemFileListSeparator = "|"
+)
+
+// itemFileColumns are the CSV columns of the import and export files, in
+// export order. Imports may leave columns out or reorder them, but name is
+// required.
+var itemFileColumns = []string{"id", "sku", "name", "price", "stock", "weight_grams", "reorder_threshold", "tax_mode", "tax_rate_id", "category_ids", "tags"}
+
+// parsedItemRow is a row of an import file. err is set when the row could
+// not be read, so it can be reported with the other row errors.
+type parsedItemRow struct {
+	index int
+	row   dto.ItemImportRow
+	err   string
+}
+
+// itemFileFormat picks the file format from the format parameter, or else
+// from the file name.
+func itemFileFormat(format string, filename string) (string, bool) {
+	if format == "" {
+		lower := strings.ToLower(filename)
+		switch {
+		case strings.HasSuffix(lower, ".csv"):
+			format = itemFileFormatCSV
+		case strings.HasSuffix(lower, ".jsonl"), strings.HasSuffix(lower, ".ndjson"):
+			format = itemFileFormatJSONL
+		}
+	}
+
+	switch format {
+	case itemFileFormatCSV, itemFileFormatJSONL:
+		return format, true
+	}
+	return "", false
+}
+
+// parseItemFile reads all rows of an import file. Only problems with the
+// file as a whole, such as a bad CSV header, are returned as errors.
+func parseItemFile(format string, r io.Reader) ([]parsedItemRow, error) {
+	if format == itemFileFormatJSONL {
+		return parseItemJSONL(r)
+	}
+	return parseItemCSV(r)
+}
+
+func parseItemCSV(r io.Reader) ([]parsedItemRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	known := map[string]bool{}
+	for _, column := range itemFileColumns {
+		known[column] = true
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if !known[column] {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+		if _, ok := columns[column]; ok {
+			return nil, fmt.Errorf("column %q appears twice", column)
+		}
+		columns[column] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("the name column is required")
+	}
+
+	var rows []parsedItemRow
+	for index := 0; ; index++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			rows = append(rows, parsedItemRow{index: index, err: "Invalid CSV: " + parseErr.Err.Error()})
+			continue
+		}
+
+		row, rowErr := csvItemRow(columns, record)
+		rows = append(rows, parsedItemRow{index: index, row: row, err: rowErr})
+	}
+}
+
+// csvItemRow turns a CSV record into a row. An empty cell leaves number
+// fields at 0 and the SKU unchanged; an empty category_ids or tags cell
+// removes them all, while a missing column keeps them.
+func csvItemRow(columns map[string]int, record []string) (dto.ItemImportRow, string) {
+	cell := func(column string) (string, bool) {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return "", ok
+		}
+		return strings.TrimSpace(record[i]), true
+	}
+
+	var row dto.ItemImportRow
+	row.ID, _ = cell("id")
+	row.Name, _ = cell("name")
+	row.TaxMode, _ = cell("tax_mode")
+	row.TaxRateID, _ = cell("tax_rate_id")
+
+	if sku, _ := cell("sku"); sku != "" {
+		row.SKU = &sku
+	}
+
+	if price, _ := cell("price"); price != "" {
+		parsedPrice, err := money.Parse(price)
+		if err != nil {
+			return row, "Invalid price"
+		}
+		row.Price = parsedPrice
+	}
+
+	for _, field := range []struct {
+		column string
+		target *int
+	}{
+		{"stock", &row.Stock},
+		{"weight_grams", &row.WeightGrams},
+		{"reorder_threshold", &row.ReorderThreshold},
+	} {
+		if value, _ := cell(field.column); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return row, "Invalid " + field.column
+			}
+			*field.target = parsed
+		}
+	}
+
+	if categoryIDs, ok := cell("category_ids"); ok {
+		row.CategoryIDs = splitItemFileList(categoryIDs)
+	}
+	if tags, ok := cell("tags"); ok {
+		row.Tags = splitItemFileList(tags)
+	}
+
+	return row, ""
+}
+
+func splitItemFileList(value string) []string {
+	values := []string{}
+	for _, part := range strings.Split(value, itemFileListSeparator) {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+// parseItemJSONL reads one JSON object per line. Blank lines are skipped but
+// still counted, so row numbers match line numbers.
+func parseItemJSONL(r io.Reader) ([]parsedItemRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	var rows []parsedItemRow
+	for index := 0; scanner.Scan(); index++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var row dto.ItemImportRow
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			rows = append(rows, parsedItemRow{index: index, err: "Invalid JSON"})
+			continue
+		}
+		rows = append(rows, parsedItemRow{index: index, row: row})
+	}
+
+	return rows, scanner.Err()
+}
+
+// itemFileRow is the export form of an item, which imports back unchanged.
+func itemFileRow(item dto.GetAllItemResponse) dto.ItemImportRow {
+	row := dto.ItemImportRow{
+		ID: item.ID.String(),
+		ItemRequestBody: dto.ItemRequestBody{
+			SKU:              item.SKU,
+			Name:             item.Name,
+			Price:            item.Price,
+			Stock:            item.Stock,
+			WeightGrams:      item.WeightGrams,
+			ReorderThreshold: item.ReorderThreshold,
+			TaxMode:          item.TaxMode,
+			CategoryIDs:      make([]string, 0, len(item.Categories)),
+			Tags:             make([]string, 0, len(item.Tags)),
+		},
+	}
+
+	if item.TaxRateID != nil {
+		row.TaxRateID = item.TaxRateID.String()
+	}
+	for _, category := range item.Categories {
+		row.CategoryIDs = append(row.CategoryIDs, category.ID.String())
+	}
+	for _, tag := range item.Tags {
+		row.Tags = append(row.Tags, tag.Name)
+	}
+
+	return row
+}
+
+// itemCSVRecord lays a row out in itemFileColumns order.
+func itemCSVRecord(row dto.ItemImportRow) []string {
+	sku := ""
+	if row.SKU != nil {
+		sku = *row.SKU
+	}
+
+	return []string{
+		row.ID,
+		sku,
+		row.Name,
+		row.Price.String(),
+		strconv.Itoa(row.Stock),
+		strconv.Itoa(row.WeightGrams),
+		strconv.Itoa(row.ReorderThreshold),
+		row.TaxMode,
+		row.TaxRateID,
+		strings.Join(row.CategoryIDs, itemFileListSeparator),
+		strings.Join(row.Tags, itemFileListSeparator),
+	}
+}
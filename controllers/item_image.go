@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"ordent/dto"
+	"ordent/imaging"
+	"ordent/models"
+	"ordent/repositories"
+	"ordent/storage"
+	"ordent/utils"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// maxImagesPerUpload caps how many files one upload request may carry.
+const maxImagesPerUpload = 10
+
+type ItemImageController struct {
+	itemRepo      repositories.ItemRepository
+	imageRepo     repositories.ItemImageRepository
+	storage       storage.Storage
+	maxBytes      int64
+	thumbnailSize int
+}
+
+func NewItemImageController(itemRepo repositories.ItemRepository, imageRepo repositories.ItemImageRepository, storage storage.Storage, maxBytes int64, thumbnailSize int) *ItemImageController {
+	return &ItemImageController{
+		itemRepo:      itemRepo,
+		imageRepo:     imageRepo,
+		storage:       storage,
+		maxBytes:      maxBytes,
+		thumbnailSize: thumbnailSize,
+	}
+}
+
+// UploadItemImages godoc
+// @Summary Upload images of an item
+// @Description Upload one or more images as repeated multipart "image" fields. The format is detected from the file content; only JPEG, PNG and GIF are accepted. A thumbnail is made of every image. New images are added after the existing ones, and the first image of an item becomes its primary image. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags item
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Item ID"
+// @Param image formData file true "Image file, may be repeated"
+// @Success 201 {array} dto.ItemImageResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 413 {object} utils.APIError "Image too large"
+// @Failure 415 {object} utils.APIError "Unsupported image format"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/items/{id}/images [post]
+func (ic *ItemImageController) UploadItemImages(c echo.Context) error {
+	parsedItemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid item ID"))
+	}
+
+	if _, err := ic.itemRepo.GetItemByID(parsedItemID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Item not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch item"))
+	}
+
+	// Leave room for the multipart headers around the largest allowed files.
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, maxImagesPerUpload*ic.maxBytes+1<<20)
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return utils.HandlerError(c, utils.NewPayloadTooLargeError("Request is too large"))
+		}
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid multipart form"))
+	}
+	defer form.RemoveAll()
+
+	files := form.File["image"]
+	if len(files) == 0 {
+		return utils.HandlerError(c, utils.NewBadRequestError("At least one image is required"))
+	}
+	if len(files) > maxImagesPerUpload {
+		return utils.HandlerError(c, utils.NewBadRequestError(fmt.Sprintf("At most %d images can be uploaded at once", maxImagesPerUpload)))
+	}
+
+	ctx := req.Context()
+	var uploadedKeys []string
+	images := make([]*models.ItemImage, 0, len(files))
+	for _, file := range files {
+		image, apiErr := ic.storeImage(ctx, parsedItemID, file, &uploadedKeys)
+		if apiErr != nil {
+			ic.deleteFiles(c, uploadedKeys)
+			return utils.HandlerError(c, apiErr)
+		}
+		images = append(images, image)
+	}
+
+	if err := ic.imageRepo.AddImages(parsedItemID, images); err != nil {
+		ic.deleteFiles(c, uploadedKeys)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Item not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to save images"))
+	}
+
+	return ic.respondWithImages(c, http.StatusCreated, parsedItemID)
+}
+
+// ReorderItemImages godoc
+// @Summary Reorder the images of an item
+// @Description Set the display order of an item's images. image_ids must list every image of the item exactly once. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags item
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Item ID"
+// @Param order body dto.ItemImageOrderRequestBody true "Image IDs in display order"
+// @Success 200 {array} dto.ItemImageResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/items/{id}/images/order [put]
+func (ic *ItemImageController) ReorderItemImages(c echo.Context) error {
+	parsedItemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid item ID"))
+	}
+
+	var orderBody dto.ItemImageOrderRequestBody
+	if err := c.Bind(&orderBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	imageIDs := make([]uuid.UUID, 0, len(orderBody.ImageIDs))
+	for _, imageID := range orderBody.ImageIDs {
+		parsedImageID, err := uuid.Parse(imageID)
+		if err != nil {
+			return utils.HandlerError(c, utils.NewBadRequestError("Invalid image ID"))
+		}
+		imageIDs = append(imageIDs, parsedImageID)
+	}
+
+	if err := ic.imageRepo.ReorderImages(parsedItemID, imageIDs); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Item not found"))
+		}
+		if errors.Is(err, repositories.ErrImageOrderMismatch) {
+			return utils.HandlerError(c, utils.NewBadRequestError("image_ids must list every image of the item once"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to reorder images"))
+	}
+
+	return ic.respondWithImages(c, http.StatusOK, parsedItemID)
+}
+
+// SetPrimaryItemImage godoc
+// @Summary Set the primary image of an item
+// @Description Make an image the item's primary image, replacing the previous one. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags item
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Item ID"
+// @Param imageId path string true "Image ID"
+// @Success 200 {array} dto.ItemImageResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/items/{id}/images/{imageId}/primary [put]
+func (ic *ItemImageController) SetPrimaryItemImage(c echo.Context) error {
+	parsedItemID, parsedImageID, apiErr := parseItemImageParams(c)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	if err := ic.imageRepo.SetPrimaryImage(parsedItemID, parsedImageID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Image not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to set primary image"))
+	}
+
+	return ic.respondWithImages(c, http.StatusOK, parsedItemID)
+}
+
+// DeleteItemImage godoc
+// @Summary Delete an image of an item
+// @Description Delete an image and its thumbnail. When the primary image is deleted, the first remaining image becomes primary. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags item
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Item ID"
+// @Param imageId path string true "Image ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/items/{id}/images/{imageId} [delete]
+func (ic *ItemImageController) DeleteItemImage(c echo.Context) error {
+	parsedItemID, parsedImageID, apiErr := parseItemImageParams(c)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	image, err := ic.imageRepo.DeleteImage(parsedItemID, parsedImageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Image not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to delete image"))
+	}
+
+	// The row is gone, so a file left behind is only wasted space.
+	ic.deleteFiles(c, []string{image.Key, image.ThumbnailKey})
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Image deleted successfully",
+	})
+}
+
+// storeImage checks one uploaded file and puts it and its thumbnail in
+// storage. The keys of the stored files are appended to uploadedKeys so they
+// can be removed if a later step fails.
+func (ic *ItemImageController) storeImage(ctx context.Context, itemID uuid.UUID, file *multipart.FileHeader, uploadedKeys *[]string) (*models.ItemImage, *utils.APIError) {
+	if file.Size > ic.maxBytes {
+		return nil, utils.NewPayloadTooLargeError(fmt.Sprintf("%s is larger than %d bytes", file.Filename, ic.maxBytes))
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, utils.NewBadRequestError("Failed to read " + file.Filename)
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, ic.maxBytes+1))
+	if err != nil {
+		return nil, utils.NewBadRequestError("Failed to read " + file.Filename)
+	}
+	if int64(len(data)) > ic.maxBytes {
+		return nil, utils.NewPayloadTooLargeError(fmt.Sprintf("%s is larger than %d bytes", file.Filename, ic.maxBytes))
+	}
+
+	info, err := imaging.Inspect(data)
+	if err != nil {
+		if errors.Is(err, imaging.ErrTooManyPixels) {
+			return nil, utils.NewPayloadTooLargeError(file.Filename + " has too many pixels")
+		}
+		return nil, utils.NewUnsupportedMediaTypeError(file.Filename + " is not a JPEG, PNG or GIF image")
+	}
+
+	thumbnail, thumbnailContentType, thumbnailExtension, err := imaging.Thumbnail(data, ic.thumbnailSize)
+	if err != nil {
+		return nil, utils.NewUnsupportedMediaTypeError(file.Filename + " could not be decoded")
+	}
+
+	name := uuid.New().String()
+	image := &models.ItemImage{
+		Key:                  fmt.Sprintf("items/%s/%s%s", itemID, name, info.Extension),
+		ThumbnailKey:         fmt.Sprintf("items/%s/%s_thumb%s", itemID, name, thumbnailExtension),
+		ContentType:          info.ContentType,
+		ThumbnailContentType: thumbnailContentType,
+		SizeBytes:            int64(len(data)),
+		Width:                info.Width,
+		Height:               info.Height,
+	}
+
+	if err := ic.storage.Put(ctx, image.Key, data, image.ContentType); err != nil {
+		return nil, utils.NewInternalError("Failed to store image")
+	}
+	*uploadedKeys = append(*uploadedKeys, image.Key)
+
+	if err := ic.storage.Put(ctx, image.ThumbnailKey, thumbnail, image.ThumbnailContentType); err != nil {
+		return nil, utils.NewInternalError("Failed to store thumbnail")
+	}
+	*uploadedKeys = append(*uploadedKeys, image.ThumbnailKey)
+
+	return image, nil
+}
+
+// deleteFiles removes files from storage, logging the ones that could not be
+// removed. It does not use the request context, so the clean-up still runs
+// when the client has gone away.
+func (ic *ItemImageController) deleteFiles(c echo.Context, keys []string) {
+	for _, key := range keys {
+		if err := ic.storage.Delete(context.Background(), key); err != nil {
+			c.Logger().Warnf("failed to delete stored file %s: %v", key, err)
+		}
+	}
+}
+
+func (ic *ItemImageController) respondWithImages(c echo.Context, status int, itemID uuid.UUID) error {
+	images, err := ic.imageRepo.GetImagesByItemID(itemID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch images"))
+	}
+
+	setImageURLs(ic.storage, images)
+	return c.JSON(status, images)
+}
+
+func parseItemImageParams(c echo.Context) (uuid.UUID, uuid.UUID, *utils.APIError) {
+	parsedItemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, utils.NewBadRequestError("Invalid item ID")
+	}
+
+	parsedImageID, err := uuid.Parse(c.Param("imageId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, utils.NewBadRequestError("Invalid image ID")
+	}
+
+	return parsedItemID, parsedImageID, nil
+}
+
+// setImageURLs turns the storage keys of the images into download URLs.
+func setImageURLs(files storage.Storage, images []dto.ItemImageResponse) {
+	for i := range images {
+		images[i].URL = files.URL(images[i].Key)
+		images[i].ThumbnailURL = files.URL(images[i].ThumbnailKey)
+	}
+}
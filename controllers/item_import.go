@@ -0,0 +1,506 @@
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/repositories"
+	"ordent/search"
+	"ordent/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	// importProgressEvery is how many rows a background import processes
+	// between progress saves.
+	importProgressEvery = 100
+	// maxImportErrors caps the row errors kept for one import.
+	maxImportErrors = 1000
+	// exportBatchSize is how many items the export loads at a time.
+	exportBatchSize = 200
+)
+
+type ItemImportController struct {
+	itemWriter
+	txManager     repositories.TxManager
+	importJobRepo repositories.ImportJobRepository
+	searcher      search.Searcher
+	maxBytes      int64
+	syncMaxRows   int
+}
+
+func NewItemImportController(txManager repositories.TxManager, itemRepo repositories.ItemRepository, variantRepo repositories.ItemVariantRepository, stockMovementRepo repositories.StockMovementRepository, lowStockAlertRepo repositories.LowStockAlertRepository, taxRateRepo repositories.TaxRateRepository, categoryRepo repositories.CategoryRepository, tagRepo repositories.TagRepository, importJobRepo repositories.ImportJobRepository, searcher search.Searcher, maxBytes int64, syncMaxRows int) *ItemImportController {
+	return &ItemImportController{
+		itemWriter: itemWriter{
+			itemRepo:          itemRepo,
+			variantRepo:       variantRepo,
+			stockMovementRepo: stockMovementRepo,
+			lowStockAlertRepo: lowStockAlertRepo,
+			taxRateRepo:       taxRateRepo,
+			categoryRepo:      categoryRepo,
+			tagRepo:           tagRepo,
+		},
+		txManager:     txManager,
+		importJobRepo: importJobRepo,
+		searcher:      searcher,
+		maxBytes:      maxBytes,
+		syncMaxRows:   syncMaxRows,
+	}
+}
+
+// importRun is the state of one import while its rows are processed.
+type importRun struct {
+	logger echo.Logger
+	userID uuid.UUID
+	dryRun bool
+	report *dto.ItemImportReport
+	// pendingSKUs are the SKUs a dry run pretended to create, so later rows
+	// with the same SKU count as updates.
+	pendingSKUs map[string]bool
+}
+
+// importTarget is the item a row updates; a zero value means the row creates
+// a new item. During a dry run an update may have no ID yet, when it targets
+// an item an earlier row would have created.
+type importTarget struct {
+	itemID uuid.UUID
+	update bool
+}
+
+// ImportItems godoc
+// @Summary Import items from a file
+// @Description Create and update items from a CSV file or a JSON-lines file (one item object per line), sent as the multipart "file" field. Every row is checked with the same rules as creating an item. Rows with an id update that item, rows with only a sku update the item with that SKU or create it, and other rows create a new item. CSV files need a header line; the columns are id, sku, name, price, stock, weight_grams, reorder_threshold, tax_mode, tax_rate_id, category_ids and tags, with category IDs and tag names separated by |. Each row is saved on its own, and rows with errors are skipped and reported. With dry_run=true nothing is saved and the report tells what would happen. Files with more rows than the configured limit, or any file when async=true, are imported in the background: the response is 202 with a job_id to poll. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags item
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security BearerAuth
+// @Param file formData file true "CSV or JSON-lines file"
+// @Param format query string false "File format; taken from the file name when left out" Enums(csv, jsonl)
+// @Param dry_run query bool false "Check the file without saving anything"
+// @Param async query bool false "Run in the background even if the file is small"
+// @Success 200 {object} dto.ItemImportReport
+// @Success 202 {object} dto.ItemImportReport
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 413 {object} utils.APIError "File too large"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/items/imports [post]
+func (ic *ItemImportController) ImportItems(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	dryRun, apiErr := parseBoolParam(c, "dry_run")
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+	async, apiErr := parseBoolParam(c, "async")
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	// Leave room for the multipart headers around the largest allowed file.
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, ic.maxBytes+1<<20)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return utils.HandlerError(c, utils.NewPayloadTooLargeError(fmt.Sprintf("File is larger than %d bytes", ic.maxBytes)))
+		}
+		return utils.HandlerError(c, utils.NewBadRequestError("file is required"))
+	}
+	if fileHeader.Size > ic.maxBytes {
+		return utils.HandlerError(c, utils.NewPayloadTooLargeError(fmt.Sprintf("File is larger than %d bytes", ic.maxBytes)))
+	}
+
+	format, ok := itemFileFormat(c.QueryParam("format"), fileHeader.Filename)
+	if !ok {
+		return utils.HandlerError(c, utils.NewBadRequestError("format must be csv or jsonl"))
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Failed to read file"))
+	}
+	defer file.Close()
+
+	rows, err := parseItemFile(format, file)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid file: "+err.Error()))
+	}
+	if len(rows) == 0 {
+		return utils.HandlerError(c, utils.NewBadRequestError("The file has no rows"))
+	}
+
+	run := &importRun{
+		logger: c.Logger(),
+		userID: userPayload.UserID,
+		dryRun: dryRun,
+		report: &dto.ItemImportReport{
+			Status:    string(models.ImportJobStatusRunning),
+			Format:    format,
+			DryRun:    dryRun,
+			TotalRows: len(rows),
+			Errors:    []utils.LineError{},
+		},
+		pendingSKUs: map[string]bool{},
+	}
+
+	if !async && len(rows) <= ic.syncMaxRows {
+		ic.importRows(req.Context(), run, rows, nil)
+		run.report.Status = string(models.ImportJobStatusCompleted)
+		return c.JSON(http.StatusOK, run.report)
+	}
+
+	job := &models.ImportJob{
+		UserID:    userPayload.UserID,
+		Format:    format,
+		DryRun:    dryRun,
+		Status:    models.ImportJobStatusRunning,
+		TotalRows: len(rows),
+		Errors:    []utils.LineError{},
+	}
+	if err := ic.importJobRepo.CreateImportJob(job); err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to start import"))
+	}
+
+	// The report is built before the job starts changing it. The job
+	// outlives the request, so it must not use the request context.
+	report := toImportReport(job)
+	go ic.runImportJob(job, run, rows)
+
+	return c.JSON(http.StatusAccepted, report)
+}
+
+// GetImportJob godoc
+// @Summary Get the progress of an item import
+// @Description Get the progress and row errors of a background item import. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags item
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param jobId path string true "Import job ID"
+// @Success 200 {object} dto.ItemImportReport
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/items/imports/{jobId} [get]
+func (ic *ItemImportController) GetImportJob(c echo.Context) error {
+	parsedJobID, err := uuid.Parse(c.Param("jobId"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid import job ID"))
+	}
+
+	job, err := ic.importJobRepo.GetImportJobByID(parsedJobID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Import job not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch import job"))
+	}
+
+	return c.JSON(http.StatusOK, toImportReport(job))
+}
+
+// ExportItems godoc
+// @Summary Export the catalog
+// @Description Download every item as CSV or JSON lines, in the same layout the import accepts. The file is streamed while it is read from the database. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags item
+// @Produce  text/csv
+// @Produce  application/x-ndjson
+// @Security BearerAuth
+// @Param format query string false "File format (default csv)" Enums(csv, jsonl)
+// @Success 200 {file} file
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/items/export [get]
+func (ic *ItemImportController) ExportItems(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = itemFileFormatCSV
+	}
+	if _, ok := itemFileFormat(format, ""); !ok {
+		return utils.HandlerError(c, utils.NewBadRequestError("format must be csv or jsonl"))
+	}
+
+	// Read the first batch before sending headers, so a failing database
+	// still gets a proper error response.
+	items, err := ic.itemRepo.GetItemsAfter(uuid.Nil, exportBatchSize)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to export items"))
+	}
+
+	contentType, extension := "text/csv; charset=utf-8", "csv"
+	if format == itemFileFormatJSONL {
+		contentType, extension = "application/x-ndjson", "jsonl"
+	}
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, contentType)
+	response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="items-%s.%s"`, time.Now().Format("20060102"), extension))
+	response.WriteHeader(http.StatusOK)
+
+	csvWriter := csv.NewWriter(response)
+	jsonEncoder := json.NewEncoder(response)
+	if format == itemFileFormatCSV {
+		if err := csvWriter.Write(itemFileColumns); err != nil {
+			return err
+		}
+	}
+
+	for len(items) > 0 {
+		for _, item := range items {
+			row := itemFileRow(item)
+			if format == itemFileFormatCSV {
+				err = csvWriter.Write(itemCSVRecord(row))
+			} else {
+				err = jsonEncoder.Encode(row)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+		response.Flush()
+
+		// The status is already sent; a failure from here on can only cut
+		// the file short.
+		items, err = ic.itemRepo.GetItemsAfter(items[len(items)-1].ID, exportBatchSize)
+		if err != nil {
+			c.Logger().Errorf("item export stopped: %v", err)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// runImportJob processes the rows in the background and saves the job's
+// progress as it goes.
+func (ic *ItemImportController) runImportJob(job *models.ImportJob, run *importRun, rows []parsedItemRow) {
+	logger := run.logger
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logger.Errorf("import job %s panicked: %v", job.ID, recovered)
+			ic.finishImportJob(job, run, models.ImportJobStatusFailed, fmt.Sprint(recovered))
+		}
+	}()
+
+	ic.importRows(context.Background(), run, rows, func() {
+		copyImportProgress(job, run.report)
+		if err := ic.importJobRepo.SaveImportJobProgress(job); err != nil {
+			logger.Warnf("failed to save progress of import job %s: %v", job.ID, err)
+		}
+	})
+
+	ic.finishImportJob(job, run, models.ImportJobStatusCompleted, "")
+}
+
+func (ic *ItemImportController) finishImportJob(job *models.ImportJob, run *importRun, status models.ImportJobStatus, lastError string) {
+	now := time.Now()
+	copyImportProgress(job, run.report)
+	job.Status = status
+	job.LastError = lastError
+	job.FinishedAt = &now
+
+	if err := ic.importJobRepo.SaveImportJobProgress(job); err != nil {
+		run.logger.Errorf("failed to finish import job %s: %v", job.ID, err)
+	}
+}
+
+// importRows processes the rows in order. progress, when set, is called
+// every importProgressEvery rows.
+func (ic *ItemImportController) importRows(ctx context.Context, run *importRun, rows []parsedItemRow, progress func()) {
+	report := run.report
+	for i, parsed := range rows {
+		target, apiErr := ic.importRow(ctx, run, parsed)
+		switch {
+		case apiErr != nil:
+			report.FailedRows++
+			if len(report.Errors) < maxImportErrors {
+				report.Errors = append(report.Errors, utils.LineError{
+					Lines:   []int{parsed.index},
+					ItemID:  parsed.row.ID,
+					Message: apiErr.Message,
+				})
+			}
+		case target.update:
+			report.UpdatedRows++
+		default:
+			report.CreatedRows++
+		}
+		report.ProcessedRows++
+
+		if progress != nil && (i+1)%importProgressEvery == 0 {
+			progress()
+		}
+	}
+}
+
+// importRow checks one row and, unless this is a dry run, saves it in its
+// own transaction.
+func (ic *ItemImportController) importRow(ctx context.Context, run *importRun, parsed parsedItemRow) (importTarget, *utils.APIError) {
+	if parsed.err != "" {
+		return importTarget{}, utils.NewBadRequestError(parsed.err)
+	}
+
+	change, apiErr := ic.prepareItem(parsed.row.ItemRequestBody)
+	if apiErr != nil {
+		return importTarget{}, apiErr
+	}
+
+	target, apiErr := ic.findImportTarget(run, parsed.row, change)
+	if apiErr != nil {
+		return importTarget{}, apiErr
+	}
+
+	if run.dryRun {
+		if change.item.SKU != nil {
+			run.pendingSKUs[*change.item.SKU] = true
+		}
+		return target, nil
+	}
+
+	source := stockSource{reason: models.StockMovementImport, userID: &run.userID, note: fmt.Sprintf("Import row %d", parsed.index)}
+	err := ic.txManager.WithinTransaction(func(tx *gorm.DB) error {
+		if target.update {
+			return ic.updateItem(tx, target.itemID, change, source)
+		}
+		return ic.createItem(tx, change, source)
+	})
+	if err != nil {
+		var apiErr *utils.APIError
+		if errors.As(err, &apiErr) {
+			return importTarget{}, apiErr
+		}
+		return importTarget{}, utils.NewInternalError("Failed to save item")
+	}
+
+	// The item is already committed, so a failure is logged rather than
+	// reported against the row.
+	if err := ic.searcher.Index(ctx, search.Document{ID: change.item.ID, Name: change.item.Name}); err != nil {
+		run.logger.Warnf("failed to index item %s for search: %v", change.item.ID, err)
+	}
+
+	return target, nil
+}
+
+// findImportTarget decides which item the row updates, if any. Because a
+// dry run saves nothing, it also checks the SKU is free here; a real run
+// learns that from the unique index.
+func (ic *ItemImportController) findImportTarget(run *importRun, row dto.ItemImportRow, change *itemChange) (importTarget, *utils.APIError) {
+	sku := ""
+	if change.item.SKU != nil {
+		sku = *change.item.SKU
+	}
+
+	if row.ID != "" {
+		parsedItemID, err := uuid.Parse(row.ID)
+		if err != nil {
+			return importTarget{}, utils.NewBadRequestError("Invalid item ID")
+		}
+
+		if _, err := ic.itemRepo.GetItemByID(parsedItemID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return importTarget{}, utils.NewNotFoundError("Item not found")
+			}
+			return importTarget{}, utils.NewInternalError("Failed to fetch item")
+		}
+
+		if run.dryRun && sku != "" {
+			if run.pendingSKUs[sku] {
+				return importTarget{}, utils.NewBadRequestError("SKU already exists")
+			}
+			owner, err := ic.itemRepo.GetItemBySKU(sku)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return importTarget{}, utils.NewInternalError("Failed to fetch item")
+			}
+			if err == nil && owner.ID != parsedItemID {
+				return importTarget{}, utils.NewBadRequestError("SKU already exists")
+			}
+		}
+
+		return importTarget{itemID: parsedItemID, update: true}, nil
+	}
+
+	if sku == "" {
+		return importTarget{}, nil
+	}
+
+	if run.dryRun && run.pendingSKUs[sku] {
+		return importTarget{update: true}, nil
+	}
+
+	owner, err := ic.itemRepo.GetItemBySKU(sku)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return importTarget{}, nil
+		}
+		return importTarget{}, utils.NewInternalError("Failed to fetch item")
+	}
+	return importTarget{itemID: owner.ID, update: true}, nil
+}
+
+func copyImportProgress(job *models.ImportJob, report *dto.ItemImportReport) {
+	job.ProcessedRows = report.ProcessedRows
+	job.CreatedRows = report.CreatedRows
+	job.UpdatedRows = report.UpdatedRows
+	job.FailedRows = report.FailedRows
+	job.Errors = report.Errors
+}
+
+func toImportReport(job *models.ImportJob) dto.ItemImportReport {
+	rowErrors := job.Errors
+	if rowErrors == nil {
+		rowErrors = []utils.LineError{}
+	}
+
+	return dto.ItemImportReport{
+		JobID:         &job.ID,
+		Status:        string(job.Status),
+		Format:        job.Format,
+		DryRun:        job.DryRun,
+		TotalRows:     job.TotalRows,
+		ProcessedRows: job.ProcessedRows,
+		CreatedRows:   job.CreatedRows,
+		UpdatedRows:   job.UpdatedRows,
+		FailedRows:    job.FailedRows,
+		Errors:        rowErrors,
+		LastError:     job.LastError,
+		CreatedAt:     &job.CreatedAt,
+		FinishedAt:    job.FinishedAt,
+	}
+}
+
+func parseBoolParam(c echo.Context, name string) (bool, *utils.APIError) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return false, nil
+	}
+
+	parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return false, utils.NewBadRequestError(name + " must be true or false")
+	}
+	return parsed, nil
+}
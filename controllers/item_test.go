@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"ordent/models"
+	"ordent/money"
+	"ordent/repositories"
+	"ordent/search"
+	"ordent/storage"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestItemETagsCoverRelatedRows(t *testing.T) {
+	db := openTestDB(t)
+	item := createTestItem(t, db, money.MustParse("10.00"), 3)
+	category := createTestCategory(t, db, "etag")
+	if err := db.Create(&models.ItemCategory{ItemID: item.ID, CategoryID: category.ID}).Error; err != nil {
+		t.Fatalf("link category: %v", err)
+	}
+
+	files, err := storage.NewLocalStorage(t.TempDir(), "http://localhost/uploads")
+	if err != nil {
+		t.Fatalf("create storage: %v", err)
+	}
+	ic := NewItemController(
+		repositories.NewTxManager(db),
+		repositories.NewItemRepository(db),
+		repositories.NewItemVariantRepository(db),
+		repositories.NewStockMovementRepository(db),
+		repositories.NewLowStockAlertRepository(db),
+		repositories.NewTaxRateRepository(db),
+		repositories.NewCategoryRepository(db),
+		repositories.NewTagRepository(db),
+		files,
+		search.NewMemoryIndex(),
+	)
+
+	e := echo.New()
+	e.GET("/api/v1/items", ic.GetAllItems)
+	e.GET("/api/v1/items/:id", ic.GetItemByID)
+
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, path := range []string{"/api/v1/items/" + item.ID.String(), "/api/v1/items?category=" + category.ID.String()} {
+		t.Run(path, func(t *testing.T) {
+			first := get(path, nil)
+			if first.Code != http.StatusOK {
+				t.Fatalf("first request returned %d: %s", first.Code, first.Body.String())
+			}
+			etag := first.Header().Get("ETag")
+			if etag == "" {
+				t.Fatal("response has no ETag")
+			}
+			if lastModified := first.Header().Get(echo.HeaderLastModified); lastModified != "" {
+				t.Errorf("response has Last-Modified %q, want none", lastModified)
+			}
+
+			if rec := get(path, http.Header{"If-None-Match": {etag}}); rec.Code != http.StatusNotModified {
+				t.Fatalf("revalidating an unchanged response returned %d, want 304", rec.Code)
+			}
+
+			// Renaming the category changes the response but not the item.
+			if err := db.Model(category).Update("name", "renamed "+time.Now().String()).Error; err != nil {
+				t.Fatalf("rename category: %v", err)
+			}
+
+			future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+			rec := get(path, http.Header{"If-None-Match": {etag}, "If-Modified-Since": {future}})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("revalidating after a category rename returned %d, want 200", rec.Code)
+			}
+			if rec.Header().Get("ETag") == etag {
+				t.Error("ETag did not change after a category rename")
+			}
+
+			if rec := get(path, http.Header{"If-Modified-Since": {future}}); rec.Code != http.StatusOK {
+				t.Errorf("If-Modified-Since alone returned %d, want 200", rec.Code)
+			}
+		})
+	}
+}
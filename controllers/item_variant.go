@@ -0,0 +1,324 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/repositories"
+	"ordent/utils"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type ItemVariantController struct {
+	txManager         repositories.TxManager
+	itemRepo          repositories.ItemRepository
+	variantRepo       repositories.ItemVariantRepository
+	stockMovementRepo repositories.StockMovementRepository
+}
+
+func NewItemVariantController(txManager repositories.TxManager, itemRepo repositories.ItemRepository, variantRepo repositories.ItemVariantRepository, stockMovementRepo repositories.StockMovementRepository) *ItemVariantController {
+	return &ItemVariantController{
+		txManager:         txManager,
+		itemRepo:          itemRepo,
+		variantRepo:       variantRepo,
+		stockMovementRepo: stockMovementRepo,
+	}
+}
+
+// CreateItemVariant godoc
+// @Summary Add a variant to an item
+// @Description Add a variant, such as a size or colour, with its own SKU and stock. Once an item has variants, orders and carts must name the variant, and the item's stock is the sum of its variants' stock. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags item
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Item ID"
+// @Param variant body dto.ItemVariantRequestBody true "Variant details"
+// @Success 201 {object} models.ItemVariant
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/items/{id}/variants [post]
+func (vc *ItemVariantController) CreateItemVariant(c echo.Context) error {
+	parsedItemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid item ID"))
+	}
+
+	var variantBody dto.ItemVariantRequestBody
+	if err := c.Bind(&variantBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	variant, apiErr := variantFromBody(variantBody)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+	variant.ItemID = parsedItemID
+
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+	source := stockSource{reason: models.StockMovementRestock, userID: &userPayload.UserID, note: "Variant created"}
+
+	err = vc.txManager.WithinTransaction(func(tx *gorm.DB) error {
+		variantRepo := vc.variantRepo.WithTx(tx)
+		stockMovementRepo := vc.stockMovementRepo.WithTx(tx)
+
+		if apiErr := vc.lockItem(tx, parsedItemID); apiErr != nil {
+			return apiErr
+		}
+
+		if apiErr := vc.checkVariantOptions(tx, parsedItemID, uuid.Nil, variant); apiErr != nil {
+			return apiErr
+		}
+
+		if err := variantRepo.CreateVariant(variant); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return utils.NewBadRequestError("SKU already exists")
+			}
+			return err
+		}
+
+		if err := stockMovementRepo.RecordMovement(source.movement(parsedItemID, &variant.ID, variant.Stock)); err != nil {
+			return err
+		}
+
+		return syncItemStock(variantRepo, stockMovementRepo, parsedItemID, source)
+	})
+	if err != nil {
+		var apiErr *utils.APIError
+		if errors.As(err, &apiErr) {
+			return utils.HandlerError(c, apiErr)
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to create variant"))
+	}
+
+	return c.JSON(http.StatusCreated, variant)
+}
+
+// EditItemVariant godoc
+// @Summary Edit a variant of an item
+// @Description Replace the SKU, options, price override and stock of a variant. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags item
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Item ID"
+// @Param variantId path string true "Variant ID"
+// @Param variant body dto.ItemVariantRequestBody true "Variant details"
+// @Success 200 {object} models.ItemVariant
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/items/{id}/variants/{variantId} [put]
+func (vc *ItemVariantController) EditItemVariant(c echo.Context) error {
+	parsedItemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid item ID"))
+	}
+
+	parsedVariantID, err := uuid.Parse(c.Param("variantId"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid variant ID"))
+	}
+
+	var variantBody dto.ItemVariantRequestBody
+	if err := c.Bind(&variantBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	variant, apiErr := variantFromBody(variantBody)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+	source := stockSource{reason: models.StockMovementAdjustment, userID: &userPayload.UserID, note: "Variant edited"}
+
+	var updated *models.ItemVariant
+	err = vc.txManager.WithinTransaction(func(tx *gorm.DB) error {
+		variantRepo := vc.variantRepo.WithTx(tx)
+		stockMovementRepo := vc.stockMovementRepo.WithTx(tx)
+
+		// The item is locked before the variant, in the same order as
+		// checkout, so the two cannot deadlock each other.
+		if apiErr := vc.lockItem(tx, parsedItemID); apiErr != nil {
+			return apiErr
+		}
+
+		current, err := variantRepo.GetVariantByIDForUpdate(parsedItemID, parsedVariantID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Variant not found")
+			}
+			return err
+		}
+
+		if apiErr := vc.checkVariantOptions(tx, parsedItemID, parsedVariantID, variant); apiErr != nil {
+			return apiErr
+		}
+
+		if err := variantRepo.EditVariant(variant, parsedVariantID); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return utils.NewBadRequestError("SKU already exists")
+			}
+			return err
+		}
+
+		if err := stockMovementRepo.RecordMovement(source.movement(parsedItemID, &parsedVariantID, variant.Stock-current.Stock)); err != nil {
+			return err
+		}
+
+		if err := syncItemStock(variantRepo, stockMovementRepo, parsedItemID, source); err != nil {
+			return err
+		}
+
+		updated, err = variantRepo.GetVariantByID(parsedItemID, parsedVariantID)
+		return err
+	})
+	if err != nil {
+		var apiErr *utils.APIError
+		if errors.As(err, &apiErr) {
+			return utils.HandlerError(c, apiErr)
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to update variant"))
+	}
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+// DeleteItemVariant godoc
+// @Summary Delete a variant of an item
+// @Description Delete a variant so it can no longer be ordered. Its stock is taken off the item; orders that contain it keep showing it. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags item
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Item ID"
+// @Param variantId path string true "Variant ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/items/{id}/variants/{variantId} [delete]
+func (vc *ItemVariantController) DeleteItemVariant(c echo.Context) error {
+	parsedItemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid item ID"))
+	}
+
+	parsedVariantID, err := uuid.Parse(c.Param("variantId"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid variant ID"))
+	}
+
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+	source := stockSource{reason: models.StockMovementAdjustment, userID: &userPayload.UserID, note: "Variant deleted"}
+
+	err = vc.txManager.WithinTransaction(func(tx *gorm.DB) error {
+		variantRepo := vc.variantRepo.WithTx(tx)
+
+		if apiErr := vc.lockItem(tx, parsedItemID); apiErr != nil {
+			return apiErr
+		}
+
+		if _, err := variantRepo.GetVariantByIDForUpdate(parsedItemID, parsedVariantID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Variant not found")
+			}
+			return err
+		}
+
+		if err := variantRepo.DeleteVariant(parsedVariantID); err != nil {
+			return err
+		}
+
+		return syncItemStock(variantRepo, vc.stockMovementRepo.WithTx(tx), parsedItemID, source)
+	})
+	if err != nil {
+		var apiErr *utils.APIError
+		if errors.As(err, &apiErr) {
+			return utils.HandlerError(c, apiErr)
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to delete variant"))
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Variant success deleted",
+	})
+}
+
+// lockItem locks the item row. Variant changes take it before any variant
+// row, the same order as checkout, and hold it while they compare options
+// and sync the item's stock.
+func (vc *ItemVariantController) lockItem(tx *gorm.DB, itemID uuid.UUID) *utils.APIError {
+	if _, err := vc.itemRepo.WithTx(tx).GetItemByIDForUpdate(itemID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewNotFoundError("Item not found")
+		}
+		return utils.NewInternalError("Failed to fetch item")
+	}
+	return nil
+}
+
+// checkVariantOptions makes sure no other variant of the item has the same
+// options. The item must already be locked with lockItem. exceptID is the
+// variant being edited, or uuid.Nil.
+func (vc *ItemVariantController) checkVariantOptions(tx *gorm.DB, itemID uuid.UUID, exceptID uuid.UUID, variant *models.ItemVariant) *utils.APIError {
+	siblings, err := vc.variantRepo.WithTx(tx).GetVariantsByItemID(itemID)
+	if err != nil {
+		return utils.NewInternalError("Failed to fetch variants")
+	}
+
+	for _, sibling := range siblings {
+		if sibling.ID != exceptID && sibling.Label() == variant.Label() {
+			return utils.NewBadRequestError("The item already has a variant with these options")
+		}
+	}
+	return nil
+}
+
+func variantFromBody(variantBody dto.ItemVariantRequestBody) (*models.ItemVariant, *utils.APIError) {
+	sku := strings.TrimSpace(variantBody.SKU)
+	if sku == "" {
+		return nil, utils.NewBadRequestError("SKU is required")
+	}
+
+	if len(variantBody.Options) == 0 {
+		return nil, utils.NewBadRequestError("At least one option is required")
+	}
+
+	options := make(map[string]string, len(variantBody.Options))
+	for name, value := range variantBody.Options {
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name == "" || value == "" {
+			return nil, utils.NewBadRequestError("Option names and values cannot be empty")
+		}
+		options[name] = value
+	}
+
+	if variantBody.Price != nil && (variantBody.Price.IsNegative() || variantBody.Price.IsZero()) {
+		return nil, utils.NewBadRequestError("Price must be greater than 0")
+	}
+
+	if variantBody.Stock < 0 {
+		return nil, utils.NewBadRequestError("Stock cannot be negative")
+	}
+
+	return &models.ItemVariant{
+		SKU:     sku,
+		Options: options,
+		Price:   variantBody.Price,
+		Stock:   variantBody.Stock,
+	}, nil
+}
@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"errors"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/repositories"
+	"ordent/utils"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// itemWriter holds the rules for saving items, shared by the item endpoints
+// and the bulk import so both accept exactly the same items.
+type itemWriter struct {
+	itemRepo          repositories.ItemRepository
+	variantRepo       repositories.ItemVariantRepository
+	stockMovementRepo repositories.StockMovementRepository
+	lowStockAlertRepo repositories.LowStockAlertRepository
+	taxRateRepo       repositories.TaxRateRepository
+	categoryRepo      repositories.CategoryRepository
+	tagRepo           repositories.TagRepository
+}
+
+// itemChange is a checked item body, ready to be saved.
+type itemChange struct {
+	item       *models.Item
+	categories []models.Category
+	tags       []string
+	// skuSent is false when the body left the SKU out, so an update keeps
+	// the current one.
+	skuSent bool
+}
+
+// prepareItem checks the body against the rules every item must meet and
+// looks up its tax rate and categories.
+func (w *itemWriter) prepareItem(itemBody dto.ItemRequestBody) (*itemChange, *utils.APIError) {
+	if itemBody.Name == "" {
+		return nil, utils.NewBadRequestError("Name is required")
+	}
+
+	if itemBody.Price == 0 {
+		return nil, utils.NewBadRequestError("Price is required")
+	}
+
+	if itemBody.Stock == 0 {
+		return nil, utils.NewBadRequestError("Quantity is required")
+	}
+
+	if itemBody.WeightGrams < 0 {
+		return nil, utils.NewBadRequestError("Weight cannot be negative")
+	}
+
+	if itemBody.ReorderThreshold < 0 {
+		return nil, utils.NewBadRequestError("Reorder threshold cannot be negative")
+	}
+
+	change := &itemChange{
+		item: &models.Item{
+			Name:             itemBody.Name,
+			Price:            itemBody.Price,
+			Stock:            itemBody.Stock,
+			WeightGrams:      itemBody.WeightGrams,
+			ReorderThreshold: itemBody.ReorderThreshold,
+		},
+		tags:    itemBody.Tags,
+		skuSent: itemBody.SKU != nil,
+	}
+
+	if itemBody.SKU != nil {
+		if sku := strings.TrimSpace(*itemBody.SKU); sku != "" {
+			change.item.SKU = &sku
+		}
+	}
+
+	if apiErr := w.setItemTax(change.item, itemBody); apiErr != nil {
+		return nil, apiErr
+	}
+
+	categories, apiErr := w.findItemCategories(itemBody.CategoryIDs)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	change.categories = categories
+
+	return change, nil
+}
+
+// createItem inserts the item, records its opening stock in the ledger and
+// links its categories and tags. tx must be an open transaction.
+func (w *itemWriter) createItem(tx *gorm.DB, change *itemChange, source stockSource) error {
+	if err := w.itemRepo.WithTx(tx).CreateItem(change.item); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return utils.NewBadRequestError("SKU already exists")
+		}
+		return err
+	}
+	movement := source.movement(change.item.ID, nil, change.item.Stock)
+	if err := w.stockMovementRepo.WithTx(tx).RecordMovement(movement); err != nil {
+		return err
+	}
+	// An item created with no stock records no movement, but may already be
+	// at its reorder threshold.
+	if change.item.ReorderThreshold > 0 {
+		if err := w.lowStockAlertRepo.WithTx(tx).TrackLowStock(change.item.ID); err != nil {
+			return err
+		}
+	}
+	return w.linkItemTaxonomy(tx, change.item, change.categories, change.tags)
+}
+
+// updateItem replaces the item with the change, records any stock change in
+// the ledger and re-evaluates the low-stock alert when the threshold
+// changes. The stock of an item with variants stays the sum of its variants'
+// stock. tx must be an open transaction.
+func (w *itemWriter) updateItem(tx *gorm.DB, itemID uuid.UUID, change *itemChange, source stockSource) error {
+	itemRepo := w.itemRepo.WithTx(tx)
+	item := change.item
+
+	// The lock keeps a checkout from changing the stock between this read and
+	// the edit, so the ledger delta below is taken against the real balance.
+	current, err := itemRepo.GetItemByIDForUpdate(itemID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewNotFoundError("Item not found")
+		}
+		return err
+	}
+
+	if !change.skuSent {
+		item.SKU = current.SKU
+	}
+
+	if err := itemRepo.EditItem(item, itemID); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return utils.NewBadRequestError("SKU already exists")
+		}
+		return err
+	}
+
+	variantRepo := w.variantRepo.WithTx(tx)
+	variantCount, err := variantRepo.CountVariants(itemID)
+	if err != nil {
+		return err
+	}
+	if variantCount > 0 {
+		if _, err := variantRepo.SyncItemStock(itemID); err != nil {
+			return err
+		}
+
+		synced, err := itemRepo.GetItemByID(itemID)
+		if err != nil {
+			return err
+		}
+		item.Stock = synced.Stock
+	}
+
+	movement := source.movement(itemID, nil, item.Stock-current.Stock)
+	if err := w.stockMovementRepo.WithTx(tx).RecordMovement(movement); err != nil {
+		return err
+	}
+	// A new threshold can put the item below it, or lift it above it, without
+	// any stock moving.
+	if item.ReorderThreshold != current.ReorderThreshold {
+		if err := w.lowStockAlertRepo.WithTx(tx).TrackLowStock(itemID); err != nil {
+			return err
+		}
+	}
+
+	item.ID = itemID
+	return w.linkItemTaxonomy(tx, item, change.categories, change.tags)
+}
+
+// setItemTax copies the tax settings from the request onto the item. Items
+// are tax-exclusive unless told otherwise, and an item without a tax rate is
+// taxed at the default rate.
+func (w *itemWriter) setItemTax(item *models.Item, itemBody dto.ItemRequestBody) *utils.APIError {
+	item.TaxMode = models.TaxModeExclusive
+	if itemBody.TaxMode != "" {
+		item.TaxMode = models.TaxMode(itemBody.TaxMode)
+	}
+
+	if !item.TaxMode.IsValid() {
+		return utils.NewBadRequestError("Tax mode must be exclusive, inclusive or exempt")
+	}
+
+	if itemBody.TaxRateID == "" {
+		return nil
+	}
+
+	parsedTaxRateID, err := uuid.Parse(itemBody.TaxRateID)
+	if err != nil {
+		return utils.NewBadRequestError("Invalid tax rate ID")
+	}
+
+	if _, err := w.taxRateRepo.GetTaxRateByID(parsedTaxRateID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewNotFoundError("Tax rate not found")
+		}
+		return utils.NewInternalError("Failed to fetch tax rate")
+	}
+
+	item.TaxRateID = &parsedTaxRateID
+	return nil
+}
+
+// findItemCategories looks up the categories an item is filed under. A nil
+// list means the categories were not sent and is returned as nil.
+func (w *itemWriter) findItemCategories(categoryIDs []string) ([]models.Category, *utils.APIError) {
+	if categoryIDs == nil {
+		return nil, nil
+	}
+
+	parsedCategoryIDs := make([]uuid.UUID, 0, len(categoryIDs))
+	seen := map[uuid.UUID]bool{}
+	for _, categoryID := range categoryIDs {
+		parsedCategoryID, err := uuid.Parse(categoryID)
+		if err != nil {
+			return nil, utils.NewBadRequestError("Invalid category ID")
+		}
+		if !seen[parsedCategoryID] {
+			seen[parsedCategoryID] = true
+			parsedCategoryIDs = append(parsedCategoryIDs, parsedCategoryID)
+		}
+	}
+
+	categories, err := w.categoryRepo.GetCategoriesByIDs(parsedCategoryIDs)
+	if err != nil {
+		return nil, utils.NewInternalError("Failed to fetch categories")
+	}
+
+	if len(categories) != len(parsedCategoryIDs) {
+		return nil, utils.NewNotFoundError("Category not found")
+	}
+
+	return categories, nil
+}
+
+// linkItemTaxonomy replaces the categories and tags of the item with the ones
+// sent. A nil list leaves the current links alone.
+func (w *itemWriter) linkItemTaxonomy(tx *gorm.DB, item *models.Item, categories []models.Category, tagNames []string) error {
+	itemRepo := w.itemRepo.WithTx(tx)
+
+	if categories != nil {
+		categoryIDs := make([]uuid.UUID, 0, len(categories))
+		for _, category := range categories {
+			categoryIDs = append(categoryIDs, category.ID)
+		}
+		if err := itemRepo.SetItemCategories(item.ID, categoryIDs); err != nil {
+			return err
+		}
+		item.Categories = categories
+	}
+
+	if tagNames != nil {
+		tags, err := w.tagRepo.WithTx(tx).FindOrCreateTags(tagNames)
+		if err != nil {
+			return err
+		}
+
+		tagIDs := make([]uuid.UUID, 0, len(tags))
+		for _, tag := range tags {
+			tagIDs = append(tagIDs, tag.ID)
+		}
+		if err := itemRepo.SetItemTags(item.ID, tagIDs); err != nil {
+			return err
+		}
+		item.Tags = tags
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"ordent/dto"
+	"ordent/models"
+	"ordent/money"
+	"ordent/repositories"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestUpdateItemTracksLowStockWhenThresholdChanges(t *testing.T) {
+	db := openTestDB(t)
+	item := createTestItem(t, db, money.MustParse("10.00"), 3)
+
+	writer := &itemWriter{
+		itemRepo:          repositories.NewItemRepository(db),
+		variantRepo:       repositories.NewItemVariantRepository(db),
+		stockMovementRepo: repositories.NewStockMovementRepository(db),
+		lowStockAlertRepo: repositories.NewLowStockAlertRepository(db),
+		taxRateRepo:       repositories.NewTaxRateRepository(db),
+		categoryRepo:      repositories.NewCategoryRepository(db),
+		tagRepo:           repositories.NewTagRepository(db),
+	}
+
+	setThreshold := func(threshold int) {
+		t.Helper()
+
+		change, apiErr := writer.prepareItem(dto.ItemRequestBody{
+			Name:             item.Name,
+			Price:            item.Price,
+			Stock:            item.Stock,
+			ReorderThreshold: threshold,
+			TaxMode:          string(models.TaxModeExempt),
+		})
+		if apiErr != nil {
+			t.Fatalf("prepare item: %s", apiErr.Message)
+		}
+
+		source := stockSource{reason: models.StockMovementAdjustment, note: "Item edited"}
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return writer.updateItem(tx, item.ID, change, source)
+		}); err != nil {
+			t.Fatalf("update item: %v", err)
+		}
+	}
+
+	openAlerts := func() int64 {
+		t.Helper()
+
+		var count int64
+		if err := db.Model(&models.LowStockAlert{}).Where("item_id = ? AND resolved_at IS NULL", item.ID).Count(&count).Error; err != nil {
+			t.Fatalf("count alerts: %v", err)
+		}
+		return count
+	}
+
+	setThreshold(5)
+	if got := openAlerts(); got != 1 {
+		t.Fatalf("raising the threshold above the stock left %d open alerts, want 1", got)
+	}
+
+	setThreshold(2)
+	if got := openAlerts(); got != 0 {
+		t.Errorf("lowering the threshold below the stock left %d open alerts, want 0", got)
+	}
+}
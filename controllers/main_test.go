@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"ordent/configs"
+	"ordent/models"
+	"ordent/money"
+	"ordent/notifications"
+	"ordent/payments"
+	"ordent/repositories"
+	"ordent/shipping"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	testDBOnce sync.Once
+	testDB     *gorm.DB
+	testDBErr  error
+)
+
+// openTestDB connects to the MySQL database named by TEST_DATABASE_DSN, e.g.
+// "root:secret@tcp(127.0.0.1:3306)/ordent_test?parseTime=True", and migrates
+// it once per run. Tests that need a database are skipped when it is not
+// set, except under CI, where they fail so that a missing database cannot
+// pass unnoticed. Every test creates rows of its own, so the database can be
+// reused between runs.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		if os.Getenv("CI") != "" {
+			t.Fatal("TEST_DATABASE_DSN must be set when CI is set")
+		}
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	testDBOnce.Do(func() {
+		testDB, testDBErr = gorm.Open(mysql.Open(dsn), &gorm.Config{
+			TranslateError: true,
+			Logger:         logger.Default.LogMode(logger.Silent),
+		})
+		if testDBErr == nil {
+			configs.MigrateDB(testDB)
+		}
+	})
+	if testDBErr != nil {
+		t.Fatalf("connect to test database: %v", testDBErr)
+	}
+
+	return testDB
+}
+
+// createTestBuyer stores a buyer with a default shipping address.
+func createTestBuyer(t *testing.T, db *gorm.DB) *models.User {
+	t.Helper()
+
+	suffix := uuid.NewString()
+	user := &models.User{
+		FullName: "Test Buyer",
+		Email:    "buyer-" + suffix + "@example.com",
+		Username: "buyer-" + suffix,
+		Password: "secret",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("create buyer: %v", err)
+	}
+
+	address := &models.Address{
+		UserID: user.ID,
+		PostalAddress: models.PostalAddress{
+			RecipientName: user.FullName,
+			Line1:         "Jl. Test 1",
+			City:          "Jakarta",
+			PostalCode:    "10110",
+			Country:       "ID",
+		},
+		IsDefault: true,
+	}
+	if err := db.Create(address).Error; err != nil {
+		t.Fatalf("create address: %v", err)
+	}
+
+	return user
+}
+
+// createTestItem stores a tax-exempt item, so its price is what the buyer
+// pays.
+func createTestItem(t *testing.T, db *gorm.DB, price money.Money, stock int) *models.Item {
+	t.Helper()
+
+	item := &models.Item{
+		Name:    "Test item " + uuid.NewString(),
+		Price:   price,
+		Stock:   stock,
+		TaxMode: models.TaxModeExempt,
+	}
+	if err := db.Create(item).Error; err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	return item
+}
+
+// startMockGateway serves a stand-in payment gateway that reports to
+// webhookURL, and returns a provider talking to it.
+func startMockGateway(t *testing.T, webhookURL string, webhookSecret string) (*payments.MockProvider, *httptest.Server) {
+	t.Helper()
+
+	gateway := httptest.NewServer(payments.NewMockServer(webhookURL, webhookSecret))
+	t.Cleanup(gateway.Close)
+
+	return payments.NewMockProvider(gateway.URL, webhookSecret), gateway
+}
+
+func newTestNotifier(t *testing.T, db *gorm.DB) *notifications.Notifier {
+	t.Helper()
+
+	notifier, err := notifications.NewNotifier(notifications.NewMemoryMailer(), repositories.NewEmailMessageRepository(db), repositories.NewUserRepository(db), notifications.Options{
+		From:      "Test Store <no-reply@example.com>",
+		StoreName: "Test Store",
+	})
+	if err != nil {
+		t.Fatalf("create notifier: %v", err)
+	}
+
+	return notifier
+}
+
+// newTestCheckout wires a checkout to db with free shipping.
+func newTestCheckout(t *testing.T, db *gorm.DB, provider payments.Provider) *checkout {
+	t.Helper()
+
+	return newCheckout(
+		repositories.NewTxManager(db),
+		provider,
+		repositories.NewItemRepository(db),
+		repositories.NewItemVariantRepository(db),
+		repositories.NewStockMovementRepository(db),
+		repositories.NewTransactionRepository(db),
+		repositories.NewTransactionDetailRepository(db),
+		repositories.NewCouponRepository(db),
+		repositories.NewTaxRateRepository(db),
+		repositories.NewAddressRepository(db),
+		shipping.NewFlatRateProvider(0),
+		newTestNotifier(t, db),
+	)
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"ordent/models"
+	"ordent/repositories"
+	"time"
+)
+
+const (
+	// orderExpiryInterval is how often the worker looks for stale orders.
+	orderExpiryInterval = time.Minute
+	// orderExpiryBatchSize bounds how many orders one query returns.
+	orderExpiryBatchSize = 50
+)
+
+// OrderExpiryWorker expires orders whose payment has not been confirmed
+// within the TTL, putting their stock back on the shelf and giving back their
+// coupon uses. An order whose payment is confirmed while it is being expired
+// keeps its payment; the status change decides which one wins.
+type OrderExpiryWorker struct {
+	txManager         repositories.TxManager
+	itemRepo          repositories.ItemRepository
+	variantRepo       repositories.ItemVariantRepository
+	stockMovementRepo repositories.StockMovementRepository
+	transactionRepo   repositories.TransactionRepository
+	couponRepo        repositories.CouponRepository
+	ttl               time.Duration
+}
+
+func NewOrderExpiryWorker(txManager repositories.TxManager, itemRepo repositories.ItemRepository, variantRepo repositories.ItemVariantRepository, stockMovementRepo repositories.StockMovementRepository, transactionRepo repositories.TransactionRepository, couponRepo repositories.CouponRepository, ttl time.Duration) *OrderExpiryWorker {
+	return &OrderExpiryWorker{
+		txManager:         txManager,
+		itemRepo:          itemRepo,
+		variantRepo:       variantRepo,
+		stockMovementRepo: stockMovementRepo,
+		transactionRepo:   transactionRepo,
+		couponRepo:        couponRepo,
+		ttl:               ttl,
+	}
+}
+
+// Run expires stale orders until ctx is cancelled. Several instances of the
+// application may run workers against the same table.
+func (w *OrderExpiryWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(orderExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		w.expireDue(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expireDue expires the orders that were still pending a TTL before now and
+// returns how many it expired.
+func (w *OrderExpiryWorker) expireDue(ctx context.Context, now time.Time) int {
+	note := "Payment not received within " + w.ttl.String()
+
+	expired := 0
+	for ctx.Err() == nil {
+		transactionIDs, err := w.transactionRepo.GetStalePendingTransactionIDs(now.Add(-w.ttl), orderExpiryBatchSize)
+		if err != nil {
+			log.Printf("failed to fetch stale pending transactions: %v", err)
+			return expired
+		}
+
+		progressed := false
+		for _, transactionID := range transactionIDs {
+			_, err := releasePendingOrder(w.txManager, w.transactionRepo, w.itemRepo, w.variantRepo, w.stockMovementRepo, w.couponRepo, transactionID, models.TransactionStatusExpired, note, nil)
+			switch {
+			case err == nil:
+				expired++
+				progressed = true
+			case errors.Is(err, repositories.ErrIllegalStatusTransition):
+				// Paid, failed or cancelled since it was listed.
+				progressed = true
+			default:
+				log.Printf("failed to expire transaction %s: %v", transactionID, err)
+			}
+		}
+
+		// A batch that failed entirely would be listed again as is.
+		if len(transactionIDs) < orderExpiryBatchSize || !progressed {
+			return expired
+		}
+	}
+	return expired
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/money"
+	"ordent/payments"
+	"ordent/repositories"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// pendingProvider creates charges at the stand-in gateway but never captures
+// them, like a buyer who leaves the payment page.
+type pendingProvider struct {
+	*payments.MockProvider
+}
+
+func (p *pendingProvider) CaptureCharge(ctx context.Context, chargeID string) (*payments.Charge, error) {
+	return &payments.Charge{ID: chargeID, Status: payments.ChargeStatusAuthorized}, nil
+}
+
+func (app *paymentTestApp) itemStock(t *testing.T, itemID uuid.UUID) int {
+	t.Helper()
+
+	var item models.Item
+	if err := app.db.First(&item, "id = ?", itemID).Error; err != nil {
+		t.Fatalf("reload item: %v", err)
+	}
+	return item.Stock
+}
+
+func TestOrderExpiryWorkerExpiresStalePendingOrders(t *testing.T) {
+	app := startPaymentTestApp(t)
+	item := createTestItem(t, app.db, money.MustParse("25.00"), 5)
+	transaction := app.placeOrder(t, &pendingProvider{app.provider}, item)
+
+	worker := NewOrderExpiryWorker(
+		repositories.NewTxManager(app.db),
+		repositories.NewItemRepository(app.db),
+		repositories.NewItemVariantRepository(app.db),
+		repositories.NewStockMovementRepository(app.db),
+		repositories.NewTransactionRepository(app.db),
+		repositories.NewCouponRepository(app.db),
+		time.Hour,
+	)
+
+	worker.expireDue(context.Background(), time.Now())
+	app.waitForStatus(t, transaction.ID, models.TransactionStatusPending)
+	if stock := app.itemStock(t, item.ID); stock != 4 {
+		t.Fatalf("stock is %d while the order is pending, want 4", stock)
+	}
+
+	if expired := worker.expireDue(context.Background(), time.Now().Add(2*time.Hour)); expired < 1 {
+		t.Fatalf("expired %d orders, want at least 1", expired)
+	}
+	app.waitForStatus(t, transaction.ID, models.TransactionStatusExpired)
+	if stock := app.itemStock(t, item.ID); stock != 5 {
+		t.Errorf("stock is %d after the order expired, want 5", stock)
+	}
+}
+
+func TestCancelTransaction(t *testing.T) {
+	app := startPaymentTestApp(t)
+	item := createTestItem(t, app.db, money.MustParse("25.00"), 5)
+	provider := &pendingProvider{app.provider}
+	transactionController := &TransactionController{
+		checkout:        newTestCheckout(t, app.db, provider),
+		transactionRepo: repositories.NewTransactionRepository(app.db),
+	}
+
+	cancel := func(transactionID uuid.UUID, payload *dto.JWTPayload) (int, *dto.TransactionResponse) {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues(transactionID.String())
+		c.Set("userPayload", payload)
+
+		if err := transactionController.CancelTransaction(c); err != nil {
+			t.Fatalf("cancel: %v", err)
+		}
+
+		var transaction dto.TransactionResponse
+		json.Unmarshal(rec.Body.Bytes(), &transaction)
+		return rec.Code, &transaction
+	}
+
+	t.Run("buyer cancels their own order", func(t *testing.T) {
+		transaction := app.placeOrder(t, provider, item)
+		buyer := &dto.JWTPayload{UserID: transaction.UserID}
+
+		if status, _ := cancel(transaction.ID, &dto.JWTPayload{UserID: uuid.New()}); status != http.StatusNotFound {
+			t.Errorf("another buyer's cancel answered %d, want 404", status)
+		}
+		app.waitForStatus(t, transaction.ID, models.TransactionStatusPending)
+
+		status, cancelled := cancel(transaction.ID, buyer)
+		if status != http.StatusOK || cancelled.Status != string(models.TransactionStatusCancelled) {
+			t.Fatalf("cancel answered %d with status %q, want 200 cancelled", status, cancelled.Status)
+		}
+		if stock := app.itemStock(t, item.ID); stock != 5 {
+			t.Errorf("stock is %d after the order was cancelled, want 5", stock)
+		}
+
+		if status, _ := cancel(transaction.ID, buyer); status != http.StatusBadRequest {
+			t.Errorf("second cancel answered %d, want 400", status)
+		}
+	})
+
+	t.Run("admin cancels any order", func(t *testing.T) {
+		transaction := app.placeOrder(t, provider, item)
+
+		status, cancelled := cancel(transaction.ID, &dto.JWTPayload{UserID: uuid.New(), IsAdmin: true})
+		if status != http.StatusOK || cancelled.Status != string(models.TransactionStatusCancelled) {
+			t.Fatalf("cancel answered %d with status %q, want 200 cancelled", status, cancelled.Status)
+		}
+	})
+
+	t.Run("payment after cancelling is refunded", func(t *testing.T) {
+		transaction := app.placeOrder(t, provider, item)
+		if status, _ := cancel(transaction.ID, &dto.JWTPayload{UserID: transaction.UserID}); status != http.StatusOK {
+			t.Fatalf("cancel answered %d, want 200", status)
+		}
+
+		// The buyer completes the payment page after all.
+		if _, err := app.provider.CaptureCharge(context.Background(), transaction.PaymentChargeID); err != nil {
+			t.Fatalf("capture: %v", err)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			var refund models.PaymentRefund
+			err := app.db.Where("transaction_id = ?", transaction.ID).Take(&refund).Error
+			if err == nil {
+				if refund.Amount != transaction.TotalPrice {
+					t.Errorf("refunded %s, want %s", refund.Amount, transaction.TotalPrice)
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("late payment was not refunded: %v", err)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		app.waitForStatus(t, transaction.ID, models.TransactionStatusCancelled)
+	})
+}
@@ -0,0 +1,380 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/notifications"
+	"ordent/payments"
+	"ordent/repositories"
+	"ordent/utils"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type PaymentController struct {
+	txManager         repositories.TxManager
+	paymentProvider   payments.Provider
+	itemRepo          repositories.ItemRepository
+	variantRepo       repositories.ItemVariantRepository
+	stockMovementRepo repositories.StockMovementRepository
+	transactionRepo   repositories.TransactionRepository
+	paymentEventRepo  repositories.PaymentEventRepository
+	paymentRefundRepo repositories.PaymentRefundRepository
+	couponRepo        repositories.CouponRepository
+	notifier          *notifications.Notifier
+}
+
+func NewPaymentController(txManager repositories.TxManager, paymentProvider payments.Provider, itemRepo repositories.ItemRepository, variantRepo repositories.ItemVariantRepository, stockMovementRepo repositories.StockMovementRepository, transactionRepo repositories.TransactionRepository, paymentEventRepo repositories.PaymentEventRepository, paymentRefundRepo repositories.PaymentRefundRepository, couponRepo repositories.CouponRepository, notifier *notifications.Notifier) *PaymentController {
+	return &PaymentController{
+		txManager:         txManager,
+		paymentProvider:   paymentProvider,
+		itemRepo:          itemRepo,
+		variantRepo:       variantRepo,
+		stockMovementRepo: stockMovementRepo,
+		transactionRepo:   transactionRepo,
+		paymentEventRepo:  paymentEventRepo,
+		paymentRefundRepo: paymentRefundRepo,
+		couponRepo:        couponRepo,
+		notifier:          notifier,
+	}
+}
+
+var (
+	errDuplicatePaymentEvent = errors.New("duplicate payment event")
+	// errLatePayment rolls back the recording of a payment that came in after
+	// its order expired or was cancelled, so the event is redelivered if the
+	// refund that follows fails.
+	errLatePayment = errors.New("payment for a released order")
+)
+
+// HandleWebhook godoc
+// @Summary Payment provider webhook
+// @Description Receives signed payment events from the configured payment provider and moves the referenced transaction to paid, failed or refunded. A payment that comes in after its order expired or was cancelled is refunded in full. Events that were already processed are acknowledged without being applied again.
+// @Tags payment
+// @Accept  json
+// @Produce  json
+// @Param X-Payment-Signature header string true "t=<unix seconds>,v1=<hex HMAC-SHA256 of \"<t>.<body>\">"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Invalid signature"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/payments/webhook [post]
+func (pc *PaymentController) HandleWebhook(c echo.Context) error {
+	event, err := pc.paymentProvider.ParseWebhook(c.Request())
+	if err != nil {
+		if errors.Is(err, payments.ErrInvalidSignature) {
+			return utils.HandlerError(c, utils.NewUnauthorizedError("Invalid signature"))
+		}
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid webhook payload"))
+	}
+
+	transactionID, err := uuid.Parse(event.Reference)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid transaction reference"))
+	}
+
+	// failedTransaction is set when this event failed the order, so the buyer
+	// can be told once the change is committed.
+	var failedTransaction *dto.TransactionResponse
+	err = pc.txManager.WithinTransaction(func(tx *gorm.DB) error {
+		transactionRepo := pc.transactionRepo.WithTx(tx)
+
+		if err := pc.paymentEventRepo.WithTx(tx).CreatePaymentEvent(&models.PaymentEvent{
+			Provider:      pc.paymentProvider.Name(),
+			EventID:       event.ID,
+			Type:          string(event.Type),
+			TransactionID: transactionID,
+		}); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return errDuplicatePaymentEvent
+			}
+			return err
+		}
+
+		transaction, err := transactionRepo.GetTransactionByID(transactionID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Transaction not found")
+			}
+			return err
+		}
+
+		if transaction.PaymentChargeID != "" && transaction.PaymentChargeID != event.ChargeID {
+			return utils.NewBadRequestError("Charge does not belong to this transaction")
+		}
+
+		switch event.Type {
+		case payments.EventChargeSucceeded:
+			// The order was given up before the money came in and its stock
+			// may already be sold again, so the payment is returned.
+			if status := models.TransactionStatus(transaction.Status); status == models.TransactionStatusExpired || status == models.TransactionStatusCancelled {
+				return errLatePayment
+			}
+			err = transactionRepo.UpdateTransactionStatus(transactionID, models.TransactionStatusPaid, "Payment captured by "+pc.paymentProvider.Name())
+		case payments.EventChargeFailed:
+			err = releaseTransaction(transactionRepo, pc.itemRepo.WithTx(tx), pc.variantRepo.WithTx(tx), pc.stockMovementRepo.WithTx(tx), pc.couponRepo.WithTx(tx), transaction, models.TransactionStatusFailed, "Payment failed at "+pc.paymentProvider.Name())
+			if err == nil {
+				failedTransaction = transaction
+			}
+		case payments.EventChargeRefunded:
+			if err := pc.paymentRefundRepo.WithTx(tx).ConfirmPendingRefunds(event.ChargeID, event.RefundedAmount); err != nil {
+				return err
+			}
+
+			status := models.TransactionStatusPartiallyRefunded
+			if event.RefundedAmount >= transaction.TotalPrice {
+				status = models.TransactionStatusRefunded
+			}
+			err = transactionRepo.UpdateTransactionStatus(transactionID, status, "Refund processed by "+pc.paymentProvider.Name())
+		default:
+			c.Logger().Infof("ignoring payment event %s of type %s", event.ID, event.Type)
+			return nil
+		}
+
+		// The provider may report outcomes the transaction has already moved
+		// past (e.g. a failure after a manual cancel). The event is recorded
+		// and acknowledged so it is not redelivered.
+		if errors.Is(err, repositories.ErrIllegalStatusTransition) {
+			c.Logger().Warnf("payment event %s not applied: %v", event.ID, err)
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errDuplicatePaymentEvent) {
+			return c.JSON(http.StatusOK, map[string]string{"message": "Event already processed"})
+		}
+		if errors.Is(err, errLatePayment) {
+			return pc.refundLatePayment(c, event, transactionID)
+		}
+
+		var apiErr *utils.APIError
+		if errors.As(err, &apiErr) {
+			return utils.HandlerError(c, apiErr)
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to process payment event"))
+	}
+
+	if failedTransaction != nil {
+		pc.notifier.PaymentFailed(failedTransaction)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Event processed"})
+}
+
+// refundLatePayment returns the whole payment of an order that expired or was
+// cancelled before it was paid, then records the event and the refund.
+func (pc *PaymentController) refundLatePayment(c echo.Context, event *payments.WebhookEvent, transactionID uuid.UUID) error {
+	refund, err := pc.paymentProvider.RefundCharge(c.Request().Context(), event.ChargeID, event.Amount)
+	if err != nil {
+		c.Logger().Errorf("failed to refund late payment %s of transaction %s: %v", event.ChargeID, transactionID, err)
+		return utils.HandlerError(c, utils.NewInternalError("Failed to refund late payment"))
+	}
+
+	err = pc.txManager.WithinTransaction(func(tx *gorm.DB) error {
+		if err := pc.paymentEventRepo.WithTx(tx).CreatePaymentEvent(&models.PaymentEvent{
+			Provider:      pc.paymentProvider.Name(),
+			EventID:       event.ID,
+			Type:          string(event.Type),
+			TransactionID: transactionID,
+		}); err != nil {
+			return err
+		}
+
+		return pc.paymentRefundRepo.WithTx(tx).CreatePaymentRefund(&models.PaymentRefund{
+			TransactionID: transactionID,
+			Provider:      pc.paymentProvider.Name(),
+			ChargeID:      refund.ChargeID,
+			RefundID:      refund.ID,
+			Amount:        refund.Amount,
+			Status:        models.PaymentRefundStatusSucceeded,
+		})
+	})
+	if err != nil {
+		c.Logger().Errorf("late payment %s of transaction %s was refunded as %s but could not be recorded: %v", event.ChargeID, transactionID, refund.ID, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Event processed"})
+}
+
+// RefundTransaction godoc
+// @Summary Refund a transaction
+// @Description Ask the payment provider to refund all or part of a paid transaction. Refunds of a transaction together cannot exceed its total; a refund the provider rejects does not count. The transaction status changes once the provider confirms the refund through the webhook. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags payment
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Transaction ID"
+// @Param refund body dto.RefundRequestBody true "Refund amount"
+// @Success 202 {object} dto.RefundResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/transactions/{id}/refunds [post]
+func (pc *PaymentController) RefundTransaction(c echo.Context) error {
+	parsedTransactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid transaction ID"))
+	}
+
+	var refundBody dto.RefundRequestBody
+	if err := c.Bind(&refundBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	if refundBody.Amount <= 0 {
+		return utils.HandlerError(c, utils.NewBadRequestError("Amount must be greater than 0"))
+	}
+
+	// The refund is reserved while the transaction is locked, so concurrent
+	// refunds see each other and cannot add up past the total. The provider
+	// is only asked once the lock is released, so a slow gateway does not
+	// hold up webhooks and checkouts of the order.
+	var reserved *models.PaymentRefund
+	err = pc.txManager.WithinTransaction(func(tx *gorm.DB) error {
+		transactionRepo := pc.transactionRepo.WithTx(tx)
+		paymentRefundRepo := pc.paymentRefundRepo.WithTx(tx)
+
+		if err := transactionRepo.LockTransaction(parsedTransactionID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Transaction not found")
+			}
+			return err
+		}
+
+		transaction, err := transactionRepo.GetTransactionByID(parsedTransactionID)
+		if err != nil {
+			return err
+		}
+
+		status := models.TransactionStatus(transaction.Status)
+		if status != models.TransactionStatusPaid && status != models.TransactionStatusPartiallyRefunded {
+			return utils.NewBadRequestError("Only paid transactions can be refunded")
+		}
+
+		if transaction.PaymentChargeID == "" {
+			return utils.NewBadRequestError("Transaction has no payment to refund")
+		}
+
+		refunded, err := paymentRefundRepo.GetRefundedAmount(transaction.PaymentChargeID)
+		if err != nil {
+			return err
+		}
+		if remaining := transaction.TotalPrice.Sub(refunded); refundBody.Amount > remaining {
+			return utils.NewBadRequestError("Amount exceeds the " + remaining.String() + " left to refund")
+		}
+
+		reserved = &models.PaymentRefund{
+			TransactionID: transaction.ID,
+			Provider:      pc.paymentProvider.Name(),
+			ChargeID:      transaction.PaymentChargeID,
+			Amount:        refundBody.Amount,
+			Status:        models.PaymentRefundStatusPending,
+		}
+		return paymentRefundRepo.CreatePaymentRefund(reserved)
+	})
+	if err != nil {
+		var apiErr *utils.APIError
+		if errors.As(err, &apiErr) {
+			return utils.HandlerError(c, apiErr)
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to refund payment"))
+	}
+
+	refund, err := pc.paymentProvider.RefundCharge(c.Request().Context(), reserved.ChargeID, reserved.Amount)
+	if err != nil {
+		c.Logger().Errorf("refund %s of transaction %s failed: %v", reserved.ID, reserved.TransactionID, err)
+		if err := pc.paymentRefundRepo.MarkPaymentRefundFailed(reserved.ID); err != nil {
+			c.Logger().Errorf("failed refund %s of transaction %s could not be released: %v", reserved.ID, reserved.TransactionID, err)
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to refund payment"))
+	}
+
+	// The refund was made either way. If it cannot be marked succeeded here,
+	// it stays pending, still counting against what is left to refund, until
+	// the provider's refund webhook confirms it.
+	if err := pc.paymentRefundRepo.MarkPaymentRefundSucceeded(reserved.ID, refund.ID, refund.Amount); err != nil {
+		c.Logger().Errorf("refund %s of transaction %s was made as %s but could not be recorded: %v", reserved.ID, reserved.TransactionID, refund.ID, err)
+	}
+
+	return c.JSON(http.StatusAccepted, dto.RefundResponse{
+		RefundID: refund.ID,
+		ChargeID: refund.ChargeID,
+		Amount:   refund.Amount,
+	})
+}
+
+// releaseTransaction moves a pending transaction to a final unpaid status,
+// puts the stock it reserved back on the shelf, recording the returns in the
+// stock ledger, and gives back its coupon uses.
+// All repositories must be bound to the same database transaction. When the
+// status change is refused nothing else is touched, so everything is released
+// at most once.
+func releaseTransaction(transactionRepo repositories.TransactionRepository, itemRepo repositories.ItemRepository, variantRepo repositories.ItemVariantRepository, stockMovementRepo repositories.StockMovementRepository, couponRepo repositories.CouponRepository, transaction *dto.TransactionResponse, status models.TransactionStatus, note string) error {
+	if err := transactionRepo.UpdateTransactionStatus(transaction.ID, status, note); err != nil {
+		return err
+	}
+
+	source := stockSource{reason: models.StockMovementReturn, userID: &transaction.UserID, transactionID: &transaction.ID, note: note}
+	for _, detail := range transaction.TransactionDetails {
+		if err := itemRepo.IncrementStock(detail.Item.ID, detail.Quantity); err != nil {
+			return err
+		}
+		if err := stockMovementRepo.RecordMovement(source.movement(detail.Item.ID, nil, detail.Quantity)); err != nil {
+			return err
+		}
+		if detail.Variant != nil {
+			if err := variantRepo.IncrementStock(detail.Variant.ID, detail.Quantity); err != nil {
+				return err
+			}
+			if err := stockMovementRepo.RecordMovement(source.movement(detail.Item.ID, &detail.Variant.ID, detail.Quantity)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return couponRepo.ReleaseRedemptions(transaction.ID)
+}
+
+// releasePendingOrder locks the transaction and releases it with the given
+// final status, returning the transaction as it is afterwards. authorize,
+// when given, checks the locked transaction before anything changes.
+// ErrIllegalStatusTransition is returned when the order is no longer
+// pending, e.g. because its payment came in first.
+func releasePendingOrder(txManager repositories.TxManager, transactionRepo repositories.TransactionRepository, itemRepo repositories.ItemRepository, variantRepo repositories.ItemVariantRepository, stockMovementRepo repositories.StockMovementRepository, couponRepo repositories.CouponRepository, transactionID uuid.UUID, status models.TransactionStatus, note string, authorize func(transaction *dto.TransactionResponse) *utils.APIError) (*dto.TransactionResponse, error) {
+	err := txManager.WithinTransaction(func(tx *gorm.DB) error {
+		transactionRepo := transactionRepo.WithTx(tx)
+
+		if err := transactionRepo.LockTransaction(transactionID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Transaction not found")
+			}
+			return err
+		}
+
+		transaction, err := transactionRepo.GetTransactionByID(transactionID)
+		if err != nil {
+			return err
+		}
+
+		if authorize != nil {
+			if apiErr := authorize(transaction); apiErr != nil {
+				return apiErr
+			}
+		}
+
+		return releaseTransaction(transactionRepo, itemRepo.WithTx(tx), variantRepo.WithTx(tx), stockMovementRepo.WithTx(tx), couponRepo.WithTx(tx), transaction, status, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transactionRepo.GetTransactionByID(transactionID)
+}
@@ -0,0 +1,361 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/money"
+	"ordent/payments"
+	"ordent/repositories"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const testWebhookSecret = "test-webhook-secret"
+
+// paymentTestApp serves the payment webhook and a stand-in gateway that
+// reports to it, the way the application runs with the mock provider.
+type paymentTestApp struct {
+	db         *gorm.DB
+	webhookURL string
+	gatewayURL string
+	provider   *payments.MockProvider
+}
+
+func startPaymentTestApp(t *testing.T) *paymentTestApp {
+	t.Helper()
+
+	db := openTestDB(t)
+
+	e := echo.New()
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+
+	app := &paymentTestApp{db: db, webhookURL: server.URL + "/api/v1/payments/webhook"}
+	var gateway *httptest.Server
+	app.provider, gateway = startMockGateway(t, app.webhookURL, testWebhookSecret)
+	app.gatewayURL = gateway.URL
+
+	e.POST("/api/v1/payments/webhook", app.paymentController(t, app.provider).HandleWebhook)
+
+	return app
+}
+
+// paymentController wires a payment controller to the app's database and
+// provider.
+func (app *paymentTestApp) paymentController(t *testing.T, provider payments.Provider) *PaymentController {
+	t.Helper()
+
+	return NewPaymentController(
+		repositories.NewTxManager(app.db),
+		provider,
+		repositories.NewItemRepository(app.db),
+		repositories.NewItemVariantRepository(app.db),
+		repositories.NewStockMovementRepository(app.db),
+		repositories.NewTransactionRepository(app.db),
+		repositories.NewPaymentEventRepository(app.db),
+		repositories.NewPaymentRefundRepository(app.db),
+		repositories.NewCouponRepository(app.db),
+		newTestNotifier(t, app.db),
+	)
+}
+
+// refund asks for a refund of the transaction through provider and returns
+// the response status.
+func (app *paymentTestApp) refund(t *testing.T, provider payments.Provider, transactionID uuid.UUID, amount money.Money) int {
+	t.Helper()
+
+	body, err := json.Marshal(dto.RefundRequestBody{Amount: amount})
+	if err != nil {
+		t.Fatalf("encode refund: %v", err)
+	}
+
+	e := echo.New()
+	e.POST("/api/v1/transactions/:id/refunds", app.paymentController(t, provider).RefundTransaction)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transactions/"+transactionID.String()+"/refunds", bytes.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	return rec.Code
+}
+
+// placeOrder checks out one unit of the item through provider.
+func (app *paymentTestApp) placeOrder(t *testing.T, provider payments.Provider, item *models.Item) *dto.TransactionResponse {
+	t.Helper()
+
+	buyer := createTestBuyer(t, app.db)
+	transaction, apiErr := newTestCheckout(t, app.db, provider).placeOrder(context.Background(), buyer.ID, dto.TransactionRequestBody{
+		PaidAmount: item.Price,
+		TransactionDetailRequestBody: []dto.TransactionDetailRequestBody{
+			{ItemID: item.ID.String(), Quantity: 1},
+		},
+	}, nil)
+	if apiErr != nil {
+		t.Fatalf("place order: %s", apiErr.Message)
+	}
+
+	return transaction
+}
+
+// waitForStatus polls until the gateway's webhook has moved the transaction
+// to status.
+func (app *paymentTestApp) waitForStatus(t *testing.T, transactionID uuid.UUID, status models.TransactionStatus) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var transaction models.Transaction
+		if err := app.db.First(&transaction, "id = ?", transactionID).Error; err != nil {
+			t.Fatalf("reload transaction: %v", err)
+		}
+		if transaction.Status == status {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("transaction is %s, want %s", transaction.Status, status)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// postEvent sends event to the webhook signed with secret.
+func (app *paymentTestApp) postEvent(t *testing.T, event payments.WebhookEvent, secret string) (int, string) {
+	t.Helper()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("encode event: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, app.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("build webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(payments.SignatureHeader, payments.SignPayload(secret, time.Now(), body))
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post webhook: %v", err)
+	}
+	defer res.Body.Close()
+
+	var response struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(res.Body).Decode(&response)
+
+	return res.StatusCode, response.Message
+}
+
+// decliningProvider declines charges at the stand-in gateway instead of
+// capturing them, like a card that is refused after checkout.
+type decliningProvider struct {
+	*payments.MockProvider
+	gatewayURL string
+}
+
+func (p *decliningProvider) CaptureCharge(ctx context.Context, chargeID string) (*payments.Charge, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.gatewayURL+"/charges/"+chargeID+"/decline", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var charge payments.Charge
+	if err := json.NewDecoder(res.Body).Decode(&charge); err != nil {
+		return nil, err
+	}
+	return &charge, nil
+}
+
+func TestMockGatewayWebhookMarksOrderPaid(t *testing.T) {
+	app := startPaymentTestApp(t)
+	item := createTestItem(t, app.db, money.MustParse("25.00"), 5)
+
+	transaction := app.placeOrder(t, app.provider, item)
+
+	app.waitForStatus(t, transaction.ID, models.TransactionStatusPaid)
+}
+
+func TestMockGatewayWebhookFailsOrderAndReleasesStock(t *testing.T) {
+	app := startPaymentTestApp(t)
+	item := createTestItem(t, app.db, money.MustParse("25.00"), 5)
+
+	transaction := app.placeOrder(t, &decliningProvider{MockProvider: app.provider, gatewayURL: app.gatewayURL}, item)
+
+	app.waitForStatus(t, transaction.ID, models.TransactionStatusFailed)
+
+	var stored models.Item
+	if err := app.db.First(&stored, "id = ?", item.ID).Error; err != nil {
+		t.Fatalf("reload item: %v", err)
+	}
+	if stored.Stock != 5 {
+		t.Errorf("stock is %d after the payment failed, want 5", stored.Stock)
+	}
+}
+
+func TestWebhookRejectsBadSignature(t *testing.T) {
+	app := startPaymentTestApp(t)
+	item := createTestItem(t, app.db, money.MustParse("25.00"), 5)
+	transaction := app.placeOrder(t, app.provider, item)
+	app.waitForStatus(t, transaction.ID, models.TransactionStatusPaid)
+
+	status, _ := app.postEvent(t, payments.WebhookEvent{
+		ID:             "evt_" + uuid.NewString(),
+		Type:           payments.EventChargeRefunded,
+		ChargeID:       transaction.PaymentChargeID,
+		Reference:      transaction.ID.String(),
+		Amount:         transaction.TotalPrice,
+		RefundedAmount: transaction.TotalPrice,
+	}, "wrong-secret")
+	if status != http.StatusUnauthorized {
+		t.Errorf("webhook with a bad signature answered %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	app.waitForStatus(t, transaction.ID, models.TransactionStatusPaid)
+}
+
+func TestWebhookAppliesRedeliveredEventOnce(t *testing.T) {
+	app := startPaymentTestApp(t)
+	item := createTestItem(t, app.db, money.MustParse("25.00"), 5)
+	transaction := app.placeOrder(t, app.provider, item)
+	app.waitForStatus(t, transaction.ID, models.TransactionStatusPaid)
+
+	event := payments.WebhookEvent{
+		ID:             "evt_" + uuid.NewString(),
+		Type:           payments.EventChargeRefunded,
+		ChargeID:       transaction.PaymentChargeID,
+		Reference:      transaction.ID.String(),
+		Amount:         transaction.TotalPrice,
+		RefundedAmount: money.MustParse("5.00"),
+	}
+
+	for _, want := range []string{"Event processed", "Event already processed"} {
+		status, message := app.postEvent(t, event, testWebhookSecret)
+		if status != http.StatusOK || message != want {
+			t.Errorf("webhook answered %d %q, want 200 %q", status, message, want)
+		}
+	}
+
+	app.waitForStatus(t, transaction.ID, models.TransactionStatusPartiallyRefunded)
+
+	var applied int64
+	if err := app.db.Model(&models.TransactionStatusHistory{}).
+		Where("transaction_id = ? AND to_status = ?", transaction.ID, models.TransactionStatusPartiallyRefunded).
+		Count(&applied).Error; err != nil {
+		t.Fatalf("count status changes: %v", err)
+	}
+	if applied != 1 {
+		t.Errorf("refund was applied %d times, want 1", applied)
+	}
+}
+
+// unreachableRefundProvider cannot reach the gateway when refunding.
+type unreachableRefundProvider struct {
+	*payments.MockProvider
+}
+
+func (p *unreachableRefundProvider) RefundCharge(ctx context.Context, chargeID string, amount money.Money) (*payments.Refund, error) {
+	return nil, errors.New("gateway unreachable")
+}
+
+func TestRefundTransaction(t *testing.T) {
+	app := startPaymentTestApp(t)
+	item := createTestItem(t, app.db, money.MustParse("25.00"), 5)
+	transaction := app.placeOrder(t, app.provider, item)
+	app.waitForStatus(t, transaction.ID, models.TransactionStatusPaid)
+
+	refunds := func() []models.PaymentRefund {
+		t.Helper()
+
+		var refunds []models.PaymentRefund
+		if err := app.db.Where("transaction_id = ?", transaction.ID).Order("created_at ASC").Find(&refunds).Error; err != nil {
+			t.Fatalf("fetch refunds: %v", err)
+		}
+		return refunds
+	}
+
+	if status := app.refund(t, &unreachableRefundProvider{app.provider}, transaction.ID, transaction.TotalPrice); status != http.StatusInternalServerError {
+		t.Fatalf("refund through an unreachable gateway answered %d, want 500", status)
+	}
+	if got := refunds(); len(got) != 1 || got[0].Status != models.PaymentRefundStatusFailed {
+		t.Fatalf("refunds after a gateway error are %+v, want one failed", got)
+	}
+
+	// The failed refund does not count against the total.
+	if status := app.refund(t, app.provider, transaction.ID, transaction.TotalPrice); status != http.StatusAccepted {
+		t.Fatalf("refund answered %d, want 202", status)
+	}
+	got := refunds()
+	if len(got) != 2 || got[1].Status != models.PaymentRefundStatusSucceeded || got[1].RefundID == "" || got[1].Amount != transaction.TotalPrice {
+		t.Fatalf("refunds are %+v, want the second one succeeded with a refund ID", got)
+	}
+	app.waitForStatus(t, transaction.ID, models.TransactionStatusRefunded)
+
+	if status := app.refund(t, app.provider, transaction.ID, money.MustParse("0.01")); status != http.StatusBadRequest {
+		t.Errorf("refund past the total answered %d, want 400", status)
+	}
+}
+
+func TestRefundWebhookConfirmsPendingRefunds(t *testing.T) {
+	app := startPaymentTestApp(t)
+	item := createTestItem(t, app.db, money.MustParse("25.00"), 5)
+	transaction := app.placeOrder(t, app.provider, item)
+	app.waitForStatus(t, transaction.ID, models.TransactionStatusPaid)
+
+	// Two refunds the provider made but that were never marked succeeded.
+	pending := make([]*models.PaymentRefund, 2)
+	for i := range pending {
+		pending[i] = &models.PaymentRefund{
+			TransactionID: transaction.ID,
+			Provider:      app.provider.Name(),
+			ChargeID:      transaction.PaymentChargeID,
+			Amount:        money.MustParse("5.00"),
+			Status:        models.PaymentRefundStatusPending,
+		}
+		if err := app.db.Create(pending[i]).Error; err != nil {
+			t.Fatalf("create pending refund: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The provider reports only the first one so far.
+	status, message := app.postEvent(t, payments.WebhookEvent{
+		ID:             "evt_" + uuid.NewString(),
+		Type:           payments.EventChargeRefunded,
+		ChargeID:       transaction.PaymentChargeID,
+		Reference:      transaction.ID.String(),
+		Amount:         transaction.TotalPrice,
+		RefundedAmount: money.MustParse("5.00"),
+	}, testWebhookSecret)
+	if status != http.StatusOK {
+		t.Fatalf("webhook answered %d %q, want 200", status, message)
+	}
+
+	for i, want := range []models.PaymentRefundStatus{models.PaymentRefundStatusSucceeded, models.PaymentRefundStatusPending} {
+		var stored models.PaymentRefund
+		if err := app.db.First(&stored, "id = ?", pending[i].ID).Error; err != nil {
+			t.Fatalf("reload refund: %v", err)
+		}
+		if stored.Status != want {
+			t.Errorf("refund %d is %s, want %s", i, stored.Status, want)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"ordent/dto"
+	"ordent/repositories"
+	"ordent/utils"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultReportLimit = 10
+	maxReportLimit     = 100
+)
+
+type ReportController struct {
+	reportRepo repositories.ReportRepository
+}
+
+func NewReportController(reportRepo repositories.ReportRepository) *ReportController {
+	return &ReportController{reportRepo: reportRepo}
+}
+
+// GetSalesSummary godoc
+// @Summary Sales summary
+// @Description Get the order count, items sold, gross revenue with its net, tax, shipping and discount parts, the amount refunded, the revenue left after refunds, and the average order value based on it. Sales are orders that are paid or partially refunded. Add format=csv to download the report as CSV. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags report
+// @Accept  json
+// @Produce  json,text/csv
+// @Security BearerAuth
+// @Param start_date query string false "Only orders created at or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param end_date query string false "Only orders created on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Param format query string false "Response format" Enums(json, csv)
+// @Success 200 {object} dto.SalesSummaryResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/admin/reports/summary [get]
+func (rc *ReportController) GetSalesSummary(c echo.Context) error {
+	filter, format, apiErr := parseReportFilter(c)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	summary, err := rc.reportRepo.GetSalesSummary(*filter)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to build sales summary"))
+	}
+	summary.ReportRange = reportRange(filter)
+
+	if format == "csv" {
+		return writeCSV(c, "sales-summary.csv",
+			[]string{"order_count", "items_sold", "gross_revenue", "refunded_amount", "revenue", "net_amount", "tax_amount", "shipping_fee", "discount_amount", "average_order_value"},
+			[][]string{{
+				strconv.FormatInt(summary.OrderCount, 10),
+				strconv.FormatInt(summary.ItemsSold, 10),
+				summary.GrossRevenue.String(),
+				summary.RefundedAmount.String(),
+				summary.Revenue.String(),
+				summary.NetAmount.String(),
+				summary.TaxAmount.String(),
+				summary.ShippingFee.String(),
+				summary.DiscountAmount.String(),
+				summary.AverageOrderValue.String(),
+			}})
+	}
+
+	return c.JSON(http.StatusOK, summary)
+}
+
+// GetSalesReport godoc
+// @Summary Revenue and orders over time
+// @Description Get order count, gross revenue, refunds, revenue after refunds and average order value per day, week (starting Monday) or month. Refunds count in the period of their order. Periods without sales are left out. Sales are orders that are paid or partially refunded. Add format=csv to download the report as CSV. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags report
+// @Accept  json
+// @Produce  json,text/csv
+// @Security BearerAuth
+// @Param group_by query string false "Period length (default day)" Enums(day, week, month)
+// @Param start_date query string false "Only orders created at or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param end_date query string false "Only orders created on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Param format query string false "Response format" Enums(json, csv)
+// @Success 200 {object} dto.SalesReportResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/admin/reports/sales [get]
+func (rc *ReportController) GetSalesReport(c echo.Context) error {
+	filter, format, apiErr := parseReportFilter(c)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	groupBy := repositories.ReportGroupByDay
+	if value := c.QueryParam("group_by"); value != "" {
+		groupBy = repositories.ReportGroupBy(value)
+		if !groupBy.IsValid() {
+			return utils.HandlerError(c, utils.NewBadRequestError("group_by must be day, week or month"))
+		}
+	}
+
+	rows, err := rc.reportRepo.GetSalesByPeriod(*filter, groupBy)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to build sales report"))
+	}
+
+	if format == "csv" {
+		records := make([][]string, len(rows))
+		for i, row := range rows {
+			records[i] = []string{
+				row.Period,
+				strconv.FormatInt(row.OrderCount, 10),
+				row.GrossRevenue.String(),
+				row.RefundedAmount.String(),
+				row.Revenue.String(),
+				row.NetAmount.String(),
+				row.TaxAmount.String(),
+				row.ShippingFee.String(),
+				row.DiscountAmount.String(),
+				row.AverageOrderValue.String(),
+			}
+		}
+		return writeCSV(c, "sales-by-"+string(groupBy)+".csv",
+			[]string{"period", "order_count", "gross_revenue", "refunded_amount", "revenue", "net_amount", "tax_amount", "shipping_fee", "discount_amount", "average_order_value"},
+			records)
+	}
+
+	return c.JSON(http.StatusOK, dto.SalesReportResponse{
+		ReportRange: reportRange(filter),
+		GroupBy:     string(groupBy),
+		Rows:        rows,
+	})
+}
+
+// GetTopItemsReport godoc
+// @Summary Best-selling items
+// @Description Get the items that sold the most units or brought in the most revenue. Item revenue is the sum of its order lines after discounts, including tax and excluding shipping. Add format=csv to download the report as CSV. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags report
+// @Accept  json
+// @Produce  json,text/csv
+// @Security BearerAuth
+// @Param sort_by query string false "Ranking (default quantity)" Enums(quantity, revenue)
+// @Param limit query int false "Number of items (default 10, max 100)"
+// @Param start_date query string false "Only orders created at or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param end_date query string false "Only orders created on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Param format query string false "Response format" Enums(json, csv)
+// @Success 200 {object} dto.TopItemsReportResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/admin/reports/top-items [get]
+func (rc *ReportController) GetTopItemsReport(c echo.Context) error {
+	filter, format, apiErr := parseReportFilter(c)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	limit, apiErr := parseReportLimit(c)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	sortBy := repositories.TopItemsByQuantity
+	if value := c.QueryParam("sort_by"); value != "" {
+		sortBy = repositories.TopItemsSort(value)
+		if sortBy != repositories.TopItemsByQuantity && sortBy != repositories.TopItemsByRevenue {
+			return utils.HandlerError(c, utils.NewBadRequestError("sort_by must be quantity or revenue"))
+		}
+	}
+
+	items, err := rc.reportRepo.GetTopItems(*filter, sortBy, limit)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to build top items report"))
+	}
+
+	if format == "csv" {
+		records := make([][]string, len(items))
+		for i, item := range items {
+			records[i] = []string{
+				item.ItemID.String(),
+				item.Name,
+				strconv.FormatInt(item.QuantitySold, 10),
+				strconv.FormatInt(item.OrderCount, 10),
+				item.Revenue.String(),
+			}
+		}
+		return writeCSV(c, "top-items-by-"+string(sortBy)+".csv",
+			[]string{"item_id", "name", "quantity_sold", "order_count", "revenue"},
+			records)
+	}
+
+	return c.JSON(http.StatusOK, dto.TopItemsReportResponse{
+		ReportRange: reportRange(filter),
+		SortBy:      string(sortBy),
+		Items:       items,
+	})
+}
+
+// GetCustomerReport godoc
+// @Summary Top customers
+// @Description Get the customers who spent the most after refunds, with their order count, average order value and first and last order in the range. Add format=csv to download the report as CSV. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags report
+// @Accept  json
+// @Produce  json,text/csv
+// @Security BearerAuth
+// @Param limit query int false "Number of customers (default 10, max 100)"
+// @Param start_date query string false "Only orders created at or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param end_date query string false "Only orders created on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Param format query string false "Response format" Enums(json, csv)
+// @Success 200 {object} dto.CustomerReportResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/admin/reports/customers [get]
+func (rc *ReportController) GetCustomerReport(c echo.Context) error {
+	filter, format, apiErr := parseReportFilter(c)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	limit, apiErr := parseReportLimit(c)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	customers, err := rc.reportRepo.GetCustomerTotals(*filter, limit)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to build customer report"))
+	}
+
+	if format == "csv" {
+		records := make([][]string, len(customers))
+		for i, customer := range customers {
+			records[i] = []string{
+				customer.UserID.String(),
+				customer.FullName,
+				customer.Email,
+				strconv.FormatInt(customer.OrderCount, 10),
+				customer.Revenue.String(),
+				customer.AverageOrderValue.String(),
+				customer.FirstOrderAt.UTC().Format(time.RFC3339),
+				customer.LastOrderAt.UTC().Format(time.RFC3339),
+			}
+		}
+		return writeCSV(c, "top-customers.csv",
+			[]string{"user_id", "full_name", "email", "order_count", "revenue", "average_order_value", "first_order_at", "last_order_at"},
+			records)
+	}
+
+	return c.JSON(http.StatusOK, dto.CustomerReportResponse{
+		ReportRange: reportRange(filter),
+		Customers:   customers,
+	})
+}
+
+// parseReportFilter reads the date range and output format shared by all
+// reports.
+func parseReportFilter(c echo.Context) (*repositories.ReportFilter, string, *utils.APIError) {
+	startDate, endDate, apiErr := parseDateRange(c)
+	if apiErr != nil {
+		return nil, "", apiErr
+	}
+
+	format := c.QueryParam("format")
+	switch format {
+	case "":
+		format = "json"
+	case "json", "csv":
+	default:
+		return nil, "", utils.NewBadRequestError("format must be json or csv")
+	}
+
+	return &repositories.ReportFilter{StartDate: startDate, EndDate: endDate}, format, nil
+}
+
+func parseReportLimit(c echo.Context) (int, *utils.APIError) {
+	value := c.QueryParam("limit")
+	if value == "" {
+		return defaultReportLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 0, utils.NewBadRequestError("Limit must be a positive number")
+	}
+	return min(limit, maxReportLimit), nil
+}
+
+func reportRange(filter *repositories.ReportFilter) dto.ReportRange {
+	return dto.ReportRange{StartDate: filter.StartDate, EndDate: filter.EndDate}
+}
+
+// writeCSV sends a header row and records as a CSV download.
+func writeCSV(c echo.Context, filename string, header []string, records [][]string) error {
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	res.WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(res)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	if err := w.WriteAll(records); err != nil {
+		return err
+	}
+	return w.Error()
+}
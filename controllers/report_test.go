@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"ordent/models"
+	"ordent/money"
+	"ordent/repositories"
+	"testing"
+)
+
+func TestSalesReportsSubtractRefunds(t *testing.T) {
+	app := startPaymentTestApp(t)
+	item := createTestItem(t, app.db, money.MustParse("25.00"), 5)
+	transaction := app.placeOrder(t, app.provider, item)
+	app.waitForStatus(t, transaction.ID, models.TransactionStatusPaid)
+
+	reportRepo := repositories.NewReportRepository(app.db)
+	before, err := reportRepo.GetSalesSummary(repositories.ReportFilter{})
+	if err != nil {
+		t.Fatalf("summary before refund: %v", err)
+	}
+
+	refund := money.MustParse("5.00")
+	if err := app.db.Create(&models.PaymentRefund{
+		TransactionID: transaction.ID,
+		Provider:      "mock",
+		ChargeID:      transaction.PaymentChargeID,
+		RefundID:      "re_report_test",
+		Amount:        refund,
+	}).Error; err != nil {
+		t.Fatalf("record refund: %v", err)
+	}
+	if err := repositories.NewTransactionRepository(app.db).UpdateTransactionStatus(transaction.ID, models.TransactionStatusPartiallyRefunded, "Partial refund"); err != nil {
+		t.Fatalf("mark partially refunded: %v", err)
+	}
+
+	after, err := reportRepo.GetSalesSummary(repositories.ReportFilter{})
+	if err != nil {
+		t.Fatalf("summary after refund: %v", err)
+	}
+
+	if after.OrderCount != before.OrderCount || after.GrossRevenue != before.GrossRevenue {
+		t.Errorf("orders and gross revenue changed from %d %s to %d %s", before.OrderCount, before.GrossRevenue, after.OrderCount, after.GrossRevenue)
+	}
+	if want := before.RefundedAmount.Add(refund); after.RefundedAmount != want {
+		t.Errorf("refunded amount is %s, want %s", after.RefundedAmount, want)
+	}
+	if want := before.Revenue.Sub(refund); after.Revenue != want {
+		t.Errorf("revenue is %s, want %s", after.Revenue, want)
+	}
+
+	customers, err := reportRepo.GetCustomerTotals(repositories.ReportFilter{StartDate: &transaction.CreatedAt}, 1000)
+	if err != nil {
+		t.Fatalf("customer totals: %v", err)
+	}
+	for _, customer := range customers {
+		if customer.UserID != transaction.UserID {
+			continue
+		}
+		if want := transaction.TotalPrice.Sub(refund); customer.Revenue != want {
+			t.Errorf("customer revenue is %s, want %s", customer.Revenue, want)
+		}
+		return
+	}
+	t.Error("buyer is missing from the customer totals")
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"net/http"
+	"ordent/dto"
+	"ordent/repositories"
+	"ordent/search"
+	"ordent/storage"
+	"ordent/utils"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// maxSearchQueryLength bounds the q parameter of a search.
+const maxSearchQueryLength = 200
+
+type SearchController struct {
+	searcher search.Searcher
+	itemRepo repositories.ItemRepository
+	storage  storage.Storage
+}
+
+func NewSearchController(searcher search.Searcher, itemRepo repositories.ItemRepository, storage storage.Storage) *SearchController {
+	return &SearchController{
+		searcher: searcher,
+		itemRepo: itemRepo,
+		storage:  storage,
+	}
+}
+
+// SearchItems godoc
+// @Summary Search the catalog
+// @Description Search item names. Results are ranked by relevance: whole words rank above word prefixes, and prefixes above words with a typo or two. Each result carries the item name with the matched words wrapped in <mark> tags. No authentication required.
+// @Tags search
+// @Accept  json
+// @Produce  json
+// @Param q query string true "Search text"
+// @Param page query int false "Page number, starting at 1 (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {object} dto.SearchResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/search [get]
+func (sc *SearchController) SearchItems(c echo.Context) error {
+	text := strings.TrimSpace(c.QueryParam("q"))
+	if text == "" {
+		return utils.HandlerError(c, utils.NewBadRequestError("q is required"))
+	}
+	if len(text) > maxSearchQueryLength {
+		return utils.HandlerError(c, utils.NewBadRequestError("q is too long"))
+	}
+
+	limit := defaultItemPageSize
+	if value := c.QueryParam("limit"); value != "" {
+		parsedLimit, err := strconv.Atoi(value)
+		if err != nil || parsedLimit <= 0 {
+			return utils.HandlerError(c, utils.NewBadRequestError("Limit must be a positive number"))
+		}
+		limit = min(parsedLimit, maxItemPageSize)
+	}
+
+	page := 1
+	if value := c.QueryParam("page"); value != "" {
+		parsedPage, err := strconv.Atoi(value)
+		if err != nil || parsedPage <= 0 {
+			return utils.HandlerError(c, utils.NewBadRequestError("Page must be a positive number"))
+		}
+		page = parsedPage
+	}
+
+	result, err := sc.searcher.Search(c.Request().Context(), search.Query{
+		Text:   text,
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	})
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to search items"))
+	}
+
+	itemIDs := make([]uuid.UUID, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		itemIDs = append(itemIDs, hit.ID)
+	}
+
+	items, err := sc.itemRepo.GetItemDetails(itemIDs)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch items"))
+	}
+
+	response := dto.SearchResponse{
+		Query:   text,
+		Results: []dto.SearchResult{},
+		Pagination: dto.PaginationMeta{
+			Total:      int64(result.Total),
+			Limit:      limit,
+			Page:       page,
+			TotalPages: (result.Total + limit - 1) / limit,
+		},
+	}
+
+	for _, hit := range result.Hits {
+		// An item deleted after the search ran is skipped.
+		item, ok := items[hit.ID]
+		if !ok {
+			continue
+		}
+		setImageURLs(sc.storage, item.Images)
+		response.Results = append(response.Results, dto.SearchResult{
+			Item:      item,
+			Score:     hit.Score,
+			Highlight: hit.Highlight,
+		})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
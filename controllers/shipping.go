@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/repositories"
+	"ordent/shipping"
+	"ordent/utils"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type ShippingController struct {
+	shippingProvider shipping.RateProvider
+	itemRepo         repositories.ItemRepository
+	addressRepo      repositories.AddressRepository
+}
+
+func NewShippingController(shippingProvider shipping.RateProvider, itemRepo repositories.ItemRepository, addressRepo repositories.AddressRepository) *ShippingController {
+	return &ShippingController{
+		shippingProvider: shippingProvider,
+		itemRepo:         itemRepo,
+		addressRepo:      addressRepo,
+	}
+}
+
+// QuoteShipping godoc
+// @Summary Quote the shipping fee of an order
+// @Description Get the shipping fee that checkout would add for these items and address. Without address_id the default address is used. This endpoint can only be accessed by users with isAdmin=false.
+// @Tags shipping
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param quote body dto.ShippingQuoteRequestBody true "Items and address"
+// @Success 200 {object} dto.ShippingQuoteResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/shipping/quote [post]
+func (sc *ShippingController) QuoteShipping(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	var quoteBody dto.ShippingQuoteRequestBody
+	if err := c.Bind(&quoteBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	if len(quoteBody.TransactionDetailRequestBody) == 0 {
+		return utils.HandlerError(c, utils.NewBadRequestError("Transaction detail is required"))
+	}
+
+	address, apiErr := resolveShippingAddress(sc.addressRepo, userPayload.UserID, quoteBody.AddressID)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	weightGrams := 0
+	for _, detail := range quoteBody.TransactionDetailRequestBody {
+		parsedItemID, err := uuid.Parse(detail.ItemID)
+		if err != nil {
+			return utils.HandlerError(c, utils.NewBadRequestError("Invalid Item ID format"))
+		}
+
+		if detail.Quantity <= 0 {
+			return utils.HandlerError(c, utils.NewBadRequestError("Quantity must be greater than 0"))
+		}
+
+		item, err := sc.itemRepo.GetItemByID(parsedItemID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.HandlerError(c, utils.NewNotFoundError("Item not found"))
+			}
+			return utils.HandlerError(c, utils.NewInternalError("Failed to fetch item"))
+		}
+
+		weightGrams += item.WeightGrams * detail.Quantity
+	}
+
+	quote, apiErr := quoteShipping(c.Request().Context(), sc.shippingProvider, address.PostalAddress, weightGrams)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, dto.ShippingQuoteResponse{
+		Provider:    sc.shippingProvider.Name(),
+		Service:     quote.Service,
+		WeightGrams: weightGrams,
+		Fee:         quote.Fee,
+	})
+}
+
+// resolveShippingAddress finds the buyer's chosen address, or their default
+// address when none was chosen.
+func resolveShippingAddress(addressRepo repositories.AddressRepository, userID uuid.UUID, addressID string) (*models.Address, *utils.APIError) {
+	var address *models.Address
+	var err error
+
+	if addressID == "" {
+		address, err = addressRepo.GetDefaultAddress(userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewBadRequestError("Shipping address is required")
+		}
+	} else {
+		parsedAddressID, parseErr := uuid.Parse(addressID)
+		if parseErr != nil {
+			return nil, utils.NewBadRequestError("Invalid address ID")
+		}
+
+		address, err = addressRepo.GetAddressByID(userID, parsedAddressID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Address not found")
+		}
+	}
+
+	if err != nil {
+		return nil, utils.NewInternalError("Failed to fetch address")
+	}
+
+	return address, nil
+}
+
+func quoteShipping(ctx context.Context, provider shipping.RateProvider, address models.PostalAddress, weightGrams int) (*shipping.Quote, *utils.APIError) {
+	quote, err := provider.Quote(ctx, shipping.Destination{
+		Country:    address.Country,
+		Province:   address.Province,
+		City:       address.City,
+		PostalCode: address.PostalCode,
+	}, shipping.Parcel{WeightGrams: weightGrams})
+	if err != nil {
+		if errors.Is(err, shipping.ErrNoRate) {
+			return nil, utils.NewBadRequestError("This order cannot be shipped to the selected address")
+		}
+		return nil, utils.NewInternalError("Failed to quote shipping")
+	}
+
+	return quote, nil
+}
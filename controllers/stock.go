@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"ordent/models"
+	"ordent/repositories"
+
+	"github.com/google/uuid"
+)
+
+// stockSource tells the stock ledger why, and for whom, stock is changing.
+type stockSource struct {
+	reason        models.StockMovementReason
+	userID        *uuid.UUID
+	transactionID *uuid.UUID
+	note          string
+}
+
+func (s stockSource) movement(itemID uuid.UUID, variantID *uuid.UUID, delta int) *models.StockMovement {
+	return &models.StockMovement{
+		ItemID:        itemID,
+		VariantID:     variantID,
+		Delta:         delta,
+		Reason:        s.reason,
+		TransactionID: s.transactionID,
+		UserID:        s.userID,
+		Note:          s.note,
+	}
+}
+
+// syncItemStock makes the item's stock the sum of its variants' stock and
+// records the change in the ledger. Both repositories must be bound to the
+// same database transaction.
+func syncItemStock(variantRepo repositories.ItemVariantRepository, stockMovementRepo repositories.StockMovementRepository, itemID uuid.UUID, source stockSource) error {
+	delta, err := variantRepo.SyncItemStock(itemID)
+	if err != nil {
+		return err
+	}
+	return stockMovementRepo.RecordMovement(source.movement(itemID, nil, delta))
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/repositories"
+	"ordent/utils"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultStockMovementPageSize = 50
+	maxStockMovementPageSize     = 200
+)
+
+type StockMovementController struct {
+	txManager         repositories.TxManager
+	itemRepo          repositories.ItemRepository
+	variantRepo       repositories.ItemVariantRepository
+	stockMovementRepo repositories.StockMovementRepository
+}
+
+func NewStockMovementController(txManager repositories.TxManager, itemRepo repositories.ItemRepository, variantRepo repositories.ItemVariantRepository, stockMovementRepo repositories.StockMovementRepository) *StockMovementController {
+	return &StockMovementController{
+		txManager:         txManager,
+		itemRepo:          itemRepo,
+		variantRepo:       variantRepo,
+		stockMovementRepo: stockMovementRepo,
+	}
+}
+
+// GetStockMovements godoc
+// @Summary List the stock movements of an item
+// @Description Returns the stock ledger of an item, newest first. Without variant_id the movements of the item and of all its variants are listed; movements without a variant_id explain the item's own stock. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags item
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Item ID"
+// @Param variant_id query string false "Only list the movements of this variant"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param cursor query string false "Cursor from the previous page's next_cursor"
+// @Success 200 {object} dto.StockMovementListResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/items/{id}/stock-movements [get]
+func (sc *StockMovementController) GetStockMovements(c echo.Context) error {
+	parsedItemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid item ID"))
+	}
+
+	filter, apiErr := parseStockMovementFilter(c)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+	filter.ItemID = parsedItemID
+
+	if _, err := sc.itemRepo.GetItemByID(parsedItemID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Item not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch item"))
+	}
+
+	movements, err := sc.stockMovementRepo.GetMovements(*filter)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch stock movements"))
+	}
+
+	return c.JSON(http.StatusOK, movements)
+}
+
+// AdjustStock godoc
+// @Summary Adjust the stock of an item
+// @Description Adds delta (negative to remove) to the stock of an item and records it in the stock ledger with the given reason. Items with variants keep their stock per variant, so variant_id is required for them and the item's stock follows the sum of its variants. Stock cannot go below 0. The response lists the movements that were recorded. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags item
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Item ID"
+// @Param adjustment body dto.StockAdjustmentRequestBody true "Stock adjustment"
+// @Success 201 {array} models.StockMovement
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/items/{id}/stock-movements [post]
+func (sc *StockMovementController) AdjustStock(c echo.Context) error {
+	parsedItemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid item ID"))
+	}
+
+	var adjustmentBody dto.StockAdjustmentRequestBody
+	if err := c.Bind(&adjustmentBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	if adjustmentBody.Delta == 0 {
+		return utils.HandlerError(c, utils.NewBadRequestError("Delta must not be 0"))
+	}
+
+	reason := models.StockMovementReason(adjustmentBody.Reason)
+	if !reason.IsManual() {
+		return utils.HandlerError(c, utils.NewBadRequestError("Reason must be restock, adjustment or return"))
+	}
+
+	var variantID *uuid.UUID
+	if adjustmentBody.VariantID != "" {
+		parsedVariantID, err := uuid.Parse(adjustmentBody.VariantID)
+		if err != nil {
+			return utils.HandlerError(c, utils.NewBadRequestError("Invalid variant ID"))
+		}
+		variantID = &parsedVariantID
+	}
+
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+	source := stockSource{reason: reason, userID: &userPayload.UserID, note: strings.TrimSpace(adjustmentBody.Note)}
+
+	var recorded []*models.StockMovement
+	err = sc.txManager.WithinTransaction(func(tx *gorm.DB) error {
+		itemRepo := sc.itemRepo.WithTx(tx)
+		variantRepo := sc.variantRepo.WithTx(tx)
+		stockMovementRepo := sc.stockMovementRepo.WithTx(tx)
+
+		item, err := itemRepo.GetItemByIDForUpdate(parsedItemID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Item not found")
+			}
+			return err
+		}
+
+		variantCount, err := variantRepo.CountVariants(parsedItemID)
+		if err != nil {
+			return err
+		}
+
+		if variantCount == 0 {
+			if variantID != nil {
+				return utils.NewBadRequestError("Item has no variants")
+			}
+			if item.Stock+adjustmentBody.Delta < 0 {
+				return utils.NewBadRequestError("Stock cannot go below 0")
+			}
+			if err := itemRepo.IncrementStock(parsedItemID, adjustmentBody.Delta); err != nil {
+				return err
+			}
+
+			movement := source.movement(parsedItemID, nil, adjustmentBody.Delta)
+			recorded = append(recorded, movement)
+			return stockMovementRepo.RecordMovement(movement)
+		}
+
+		if variantID == nil {
+			return utils.NewBadRequestError("variant_id is required for items with variants")
+		}
+
+		variant, err := variantRepo.GetVariantByIDForUpdate(parsedItemID, *variantID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Variant not found")
+			}
+			return err
+		}
+		if variant.Stock+adjustmentBody.Delta < 0 {
+			return utils.NewBadRequestError("Stock cannot go below 0")
+		}
+		if err := variantRepo.IncrementStock(variant.ID, adjustmentBody.Delta); err != nil {
+			return err
+		}
+
+		variantMovement := source.movement(parsedItemID, &variant.ID, adjustmentBody.Delta)
+		if err := stockMovementRepo.RecordMovement(variantMovement); err != nil {
+			return err
+		}
+		recorded = append(recorded, variantMovement)
+
+		itemDelta, err := variantRepo.SyncItemStock(parsedItemID)
+		if err != nil {
+			return err
+		}
+		itemMovement := source.movement(parsedItemID, nil, itemDelta)
+		if err := stockMovementRepo.RecordMovement(itemMovement); err != nil {
+			return err
+		}
+		if itemDelta != 0 {
+			recorded = append(recorded, itemMovement)
+		}
+		return nil
+	})
+	if err != nil {
+		var apiErr *utils.APIError
+		if errors.As(err, &apiErr) {
+			return utils.HandlerError(c, apiErr)
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to adjust stock"))
+	}
+
+	return c.JSON(http.StatusCreated, recorded)
+}
+
+// parseStockMovementFilter reads the variant and pagination query parameters
+// of the stock movement list.
+func parseStockMovementFilter(c echo.Context) (*repositories.StockMovementFilter, *utils.APIError) {
+	filter := &repositories.StockMovementFilter{Limit: defaultStockMovementPageSize}
+
+	if variantID := c.QueryParam("variant_id"); variantID != "" {
+		parsedVariantID, err := uuid.Parse(variantID)
+		if err != nil {
+			return nil, utils.NewBadRequestError("Invalid variant ID")
+		}
+		filter.VariantID = &parsedVariantID
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		parsedLimit, err := strconv.Atoi(limit)
+		if err != nil || parsedLimit <= 0 {
+			return nil, utils.NewBadRequestError("Limit must be a positive number")
+		}
+		if parsedLimit > maxStockMovementPageSize {
+			parsedLimit = maxStockMovementPageSize
+		}
+		filter.Limit = parsedLimit
+	}
+
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		parsedCursor, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return nil, utils.NewBadRequestError("Invalid cursor")
+		}
+		filter.Cursor = parsedCursor
+	}
+
+	return filter, nil
+}
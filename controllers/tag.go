@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/repositories"
+	"ordent/utils"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type TagController struct {
+	tagRepo repositories.TagRepository
+}
+
+func NewTagController(tagRepo repositories.TagRepository) *TagController {
+	return &TagController{
+		tagRepo: tagRepo,
+	}
+}
+
+// CreateTag godoc
+// @Summary Create new tag
+// @Description Create a tag. The slug is derived from the name when left out. Tags are also created when an item is saved with a tag that does not exist yet. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags tag
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param tag body dto.TagRequestBody true "Tag details"
+// @Success 201 {object} models.Tag
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/tags [post]
+func (tc *TagController) CreateTag(c echo.Context) error {
+	var tagBody dto.TagRequestBody
+	if err := c.Bind(&tagBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	tag, apiErr := tagFromBody(tagBody)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	if err := tc.tagRepo.CreateTag(tag); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return utils.HandlerError(c, utils.NewBadRequestError("Tag slug already exists"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to create tag"))
+	}
+
+	return c.JSON(http.StatusCreated, tag)
+}
+
+// GetAllTags godoc
+// @Summary Get all tags
+// @Description Get all tags by name with the number of items carrying each. No authentication required.
+// @Tags tag
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} dto.TagResponse
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/tags [get]
+func (tc *TagController) GetAllTags(c echo.Context) error {
+	tags, err := tc.tagRepo.GetAllTags()
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch tags"))
+	}
+
+	return c.JSON(http.StatusOK, tags)
+}
+
+// EditTag godoc
+// @Summary Edit an existing tag
+// @Description Rename a tag or change its slug. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags tag
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tag ID"
+// @Param tag body dto.TagRequestBody true "Tag details"
+// @Success 200 {object} models.Tag
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/tags/{id} [put]
+func (tc *TagController) EditTag(c echo.Context) error {
+	parsedTagID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid tag ID"))
+	}
+
+	var tagBody dto.TagRequestBody
+	if err := c.Bind(&tagBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	tag, apiErr := tagFromBody(tagBody)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	if _, err := tc.tagRepo.GetTagByID(parsedTagID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Tag not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch tag"))
+	}
+
+	if err := tc.tagRepo.EditTag(tag, parsedTagID); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return utils.HandlerError(c, utils.NewBadRequestError("Tag slug already exists"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to update tag"))
+	}
+
+	updated, err := tc.tagRepo.GetTagByID(parsedTagID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch tag"))
+	}
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+// DeleteTag godoc
+// @Summary Delete an existing tag
+// @Description Delete a tag and remove it from every item. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags tag
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tag ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/tags/{id} [delete]
+func (tc *TagController) DeleteTag(c echo.Context) error {
+	parsedTagID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid tag ID"))
+	}
+
+	if err := tc.tagRepo.DeleteTag(parsedTagID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Tag not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to delete tag"))
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Tag success deleted",
+	})
+}
+
+func tagFromBody(tagBody dto.TagRequestBody) (*models.Tag, *utils.APIError) {
+	name := strings.TrimSpace(tagBody.Name)
+	if name == "" {
+		return nil, utils.NewBadRequestError("Name is required")
+	}
+
+	slug, apiErr := slugFromBody(name, tagBody.Slug)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return &models.Tag{Name: name, Slug: slug}, nil
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/repositories"
+	"ordent/utils"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type TaxRateController struct {
+	taxRateRepo repositories.TaxRateRepository
+}
+
+func NewTaxRateController(taxRateRepo repositories.TaxRateRepository) *TaxRateController {
+	return &TaxRateController{
+		taxRateRepo: taxRateRepo,
+	}
+}
+
+// CreateTaxRate godoc
+// @Summary Create new tax rate
+// @Description Create a tax rate in basis points (1100 = 11%). Marking it as default replaces the current default, which applies to every item without its own rate. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags tax
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param taxRate body dto.TaxRateRequestBody true "Tax rate details"
+// @Success 201 {object} models.TaxRate
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/tax-rates [post]
+func (tc *TaxRateController) CreateTaxRate(c echo.Context) error {
+	var taxRateBody dto.TaxRateRequestBody
+	if err := c.Bind(&taxRateBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	taxRate, apiErr := taxRateFromBody(taxRateBody)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	if err := tc.taxRateRepo.CreateTaxRate(taxRate); err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to create tax rate"))
+	}
+
+	return c.JSON(http.StatusCreated, taxRate)
+}
+
+// GetAllTaxRates godoc
+// @Summary Get all tax rates
+// @Description Get a list of all tax rates. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags tax
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} models.TaxRate
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/tax-rates [get]
+func (tc *TaxRateController) GetAllTaxRates(c echo.Context) error {
+	taxRates, err := tc.taxRateRepo.GetAllTaxRates()
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch tax rates"))
+	}
+
+	return c.JSON(http.StatusOK, taxRates)
+}
+
+// EditTaxRate godoc
+// @Summary Edit an existing tax rate
+// @Description Change a tax rate. Orders already placed keep the rate they were charged. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags tax
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tax rate ID"
+// @Param taxRate body dto.TaxRateRequestBody true "Tax rate details"
+// @Success 200 {object} models.TaxRate
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/tax-rates/{id} [put]
+func (tc *TaxRateController) EditTaxRate(c echo.Context) error {
+	parsedTaxRateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid tax rate ID"))
+	}
+
+	var taxRateBody dto.TaxRateRequestBody
+	if err := c.Bind(&taxRateBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	taxRate, apiErr := taxRateFromBody(taxRateBody)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	if _, err := tc.taxRateRepo.GetTaxRateByID(parsedTaxRateID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Tax rate not found"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch tax rate"))
+	}
+
+	if err := tc.taxRateRepo.EditTaxRate(taxRate, parsedTaxRateID); err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to update tax rate"))
+	}
+
+	updated, err := tc.taxRateRepo.GetTaxRateByID(parsedTaxRateID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch tax rate"))
+	}
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+// DeleteTaxRate godoc
+// @Summary Delete an existing tax rate
+// @Description Delete a tax rate that no item uses any more. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags tax
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tax rate ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/tax-rates/{id} [delete]
+func (tc *TaxRateController) DeleteTaxRate(c echo.Context) error {
+	parsedTaxRateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid tax rate ID"))
+	}
+
+	inUse, err := tc.taxRateRepo.CountItemsUsingTaxRate(parsedTaxRateID)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to check tax rate usage"))
+	}
+
+	if inUse > 0 {
+		return utils.HandlerError(c, utils.NewBadRequestError("Tax rate is still used by items"))
+	}
+
+	if err := tc.taxRateRepo.DeleteTaxRate(parsedTaxRateID); err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to delete tax rate"))
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Tax rate success deleted",
+	})
+}
+
+func taxRateFromBody(taxRateBody dto.TaxRateRequestBody) (*models.TaxRate, *utils.APIError) {
+	name := strings.TrimSpace(taxRateBody.Name)
+	if name == "" {
+		return nil, utils.NewBadRequestError("Name is required")
+	}
+
+	if taxRateBody.RateBps < 0 || taxRateBody.RateBps > 10000 {
+		return nil, utils.NewBadRequestError("Rate must be between 0 and 10000 basis points")
+	}
+
+	return &models.TaxRate{
+		Name:      name,
+		RateBps:   taxRateBody.RateBps,
+		IsDefault: taxRateBody.IsDefault,
+	}, nil
+}
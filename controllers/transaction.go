@@ -1,42 +1,59 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"ordent/dto"
 	"ordent/models"
+	"ordent/money"
+	"ordent/notifications"
+	"ordent/payments"
 	"ordent/repositories"
+	"ordent/shipping"
 	"ordent/utils"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 type TransactionController struct {
-	itemRepo              repositories.ItemRepository
-	transactionRepo       repositories.TransactionRepository
-	transactionDetailRepo repositories.TransactionDetailRepository
+	checkout        *checkout
+	transactionRepo repositories.TransactionRepository
+	notifier        *notifications.Notifier
 }
 
-func NewTransactionController(itemRepo repositories.ItemRepository, transactionRepo repositories.TransactionRepository, transactionDetailRepo repositories.TransactionDetailRepository) *TransactionController {
+func NewTransactionController(txManager repositories.TxManager, paymentProvider payments.Provider, itemRepo repositories.ItemRepository, variantRepo repositories.ItemVariantRepository, stockMovementRepo repositories.StockMovementRepository, transactionRepo repositories.TransactionRepository, transactionDetailRepo repositories.TransactionDetailRepository, couponRepo repositories.CouponRepository, taxRateRepo repositories.TaxRateRepository, addressRepo repositories.AddressRepository, shippingProvider shipping.RateProvider, notifier *notifications.Notifier) *TransactionController {
 	return &TransactionController{
-		itemRepo:              itemRepo,
-		transactionRepo:       transactionRepo,
-		transactionDetailRepo: transactionDetailRepo,
+		checkout:        newCheckout(txManager, paymentProvider, itemRepo, variantRepo, stockMovementRepo, transactionRepo, transactionDetailRepo, couponRepo, taxRateRepo, addressRepo, shippingProvider, notifier),
+		transactionRepo: transactionRepo,
+		notifier:        notifier,
 	}
 }
 
 // CreateTransaction godoc
 // @Summary Create a new transaction
 // @Description Create a new transaction. This endpoint can only be accessed by users with isAdmin=false.
+// @Description The order is created as pending and charged through the payment provider; it becomes paid or failed once the provider reports the outcome.
+// @Description Send an Idempotency-Key header to make retries safe: an identical retry replays the first response instead of creating another order.
+// @Description Coupons are applied before tax. paid_amount must equal the total: the discounted amount, plus tax on tax-exclusive items, plus the shipping fee.
+// @Description The order ships to address_id, or to the buyer's default address when it is omitted. POST /api/v1/shipping/quote returns the fee in advance.
+// @Description Lines for the same item are merged and stock is checked against their total. When lines are invalid or cannot be fulfilled, errors lists every problem with the positions of the lines involved and the stock available.
 // @Tags transaction
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
+// @Param Idempotency-Key header string false "Unique key that identifies this checkout attempt"
 // @Param transaction body dto.TransactionRequestBody true "Transaction details"
-// @Success 201 {object} map[string]string "Transaction created successfully"
+// @Success 201 {object} dto.TransactionResponse
 // @Failure 400 {object} utils.APIError "Bad Request"
 // @Failure 401 {object} utils.APIError "Unauthorized"
 // @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 409 {object} utils.APIError "A request with the same Idempotency-Key is in progress"
+// @Failure 422 {object} utils.APIError "Idempotency-Key reused with a different request"
 // @Failure 500 {object} utils.APIError "Internal Server Error"
 // @Router /api/v1/transactions [post]
 func (tc *TransactionController) CreateTransaction(c echo.Context) error {
@@ -47,91 +64,407 @@ func (tc *TransactionController) CreateTransaction(c echo.Context) error {
 		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
 	}
 
-	if transactionBody.PaidAmount < 0 {
-		return utils.HandlerError(c, utils.NewBadRequestError("Paid amount must be greater than or equal to 0"))
+	transaction, apiErr := tc.checkout.placeOrder(c.Request().Context(), userPayload.UserID, transactionBody, nil)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
 	}
 
-	if len(transactionBody.TransactionDetailRequestBody) == 0 {
-		return utils.HandlerError(c, utils.NewBadRequestError("Transaction detail is required"))
-	}
+	return c.JSON(http.StatusCreated, transaction)
+}
 
-	var totalRequiredPrice float64
+// GetTransactionByID godoc
+// @Summary Get a transaction
+// @Description Get a single transaction with its details and its timeline of payment and fulfillment steps. Clients can only see their own transactions, admins can see any.
+// @Tags transaction
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Transaction ID"
+// @Success 200 {object} dto.TransactionResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/transactions/{id} [get]
+func (tc *TransactionController) GetTransactionByID(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
 
-	for _, detail := range transactionBody.TransactionDetailRequestBody {
-		if detail.ItemID == "" {
-			return utils.HandlerError(c, utils.NewBadRequestError("Item ID is required"))
-		}
+	parsedTransactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid transaction ID"))
+	}
 
-		parsedItemID, err := uuid.Parse(detail.ItemID)
-		if err != nil {
-			return utils.HandlerError(c, utils.NewBadRequestError("Invalid Item ID format"))
+	transaction, err := tc.transactionRepo.GetTransactionByID(parsedTransactionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Transaction not found"))
 		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch transaction"))
+	}
+
+	// Someone else's order is reported as missing rather than forbidden so
+	// transaction IDs cannot be probed.
+	if !userPayload.IsAdmin && transaction.UserID != userPayload.UserID {
+		return utils.HandlerError(c, utils.NewNotFoundError("Transaction not found"))
+	}
+
+	return c.JSON(http.StatusOK, transaction)
+}
+
+// GetMyTransactions godoc
+// @Summary Get my order history
+// @Description Get the logged in user's transactions, newest first. Pass next_cursor from the previous page as cursor to get the next one. This endpoint can only be accessed by users with isAdmin=false.
+// @Tags transaction
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param start_date query string false "Only transactions created at or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param end_date query string false "Only transactions created on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Param status query string false "Payment status" Enums(pending, paid, failed, expired, cancelled, refunded, partially_refunded)
+// @Param fulfillment_status query string false "Fulfillment status" Enums(awaiting_fulfillment, packed, shipped, delivered, returned)
+// @Success 200 {object} dto.TransactionListResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/transactions [get]
+func (tc *TransactionController) GetMyTransactions(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	filter, apiErr := parseTransactionFilter(c)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+	filter.UserID = &userPayload.UserID
+
+	transactions, err := tc.transactionRepo.GetTransactions(*filter)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch transactions"))
+	}
+
+	return c.JSON(http.StatusOK, transactions)
+}
 
-		if detail.Quantity == 0 {
-			return utils.HandlerError(c, utils.NewBadRequestError("Quantity must be greater than 0"))
+// GetAllTransactions godoc
+// @Summary List and search all orders
+// @Description Get every customer's transactions with their buyer, newest first, e.g. all paid orders awaiting fulfillment. Filters can be combined; q matches part of the buyer's email, username or full name. Pass next_cursor from the previous page as cursor to get the next one. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags transaction
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param start_date query string false "Only transactions created at or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param end_date query string false "Only transactions created on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Param status query string false "Payment status" Enums(pending, paid, failed, expired, cancelled, refunded, partially_refunded)
+// @Param fulfillment_status query string false "Fulfillment status" Enums(awaiting_fulfillment, packed, shipped, delivered, returned)
+// @Param user_id query string false "Only transactions of this user"
+// @Param item_id query string false "Only transactions containing this item"
+// @Param min_total query number false "Only transactions with a total of at least this amount"
+// @Param max_total query number false "Only transactions with a total of at most this amount"
+// @Param q query string false "Search the buyer's email, username or full name"
+// @Success 200 {object} dto.TransactionListResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/admin/transactions [get]
+func (tc *TransactionController) GetAllTransactions(c echo.Context) error {
+	filter, apiErr := parseTransactionFilter(c)
+	if apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+
+	if apiErr := parseAdminTransactionFilter(c, filter); apiErr != nil {
+		return utils.HandlerError(c, apiErr)
+	}
+	filter.IncludeCustomer = true
+
+	transactions, err := tc.transactionRepo.GetTransactions(*filter)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch transactions"))
+	}
+
+	return c.JSON(http.StatusOK, transactions)
+}
+
+// GetAdminTransaction godoc
+// @Summary Inspect any order
+// @Description Get a single transaction of any customer with its details, timeline and buyer. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags transaction
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Transaction ID"
+// @Success 200 {object} dto.TransactionResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/admin/transactions/{id} [get]
+func (tc *TransactionController) GetAdminTransaction(c echo.Context) error {
+	parsedTransactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid transaction ID"))
+	}
+
+	transaction, err := tc.transactionRepo.GetTransactionWithCustomer(parsedTransactionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Transaction not found"))
 		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch transaction"))
+	}
 
-		item, err := tc.itemRepo.GetItemByID(parsedItemID)
-		if err != nil {
-			return utils.HandlerError(c, utils.NewNotFoundError("Item not found"))
+	return c.JSON(http.StatusOK, transaction)
+}
+
+// CancelTransaction godoc
+// @Summary Cancel a pending order
+// @Description Cancel an order whose payment has not been confirmed yet. Its reserved stock and coupon uses are released. Buyers can cancel their own orders, admins can cancel any. Orders that are no longer pending cannot be cancelled; paid orders are refunded instead. A payment that still comes in for a cancelled order is refunded automatically.
+// @Tags transaction
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Transaction ID"
+// @Success 200 {object} dto.TransactionResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/transactions/{id}/cancel [post]
+func (tc *TransactionController) CancelTransaction(c echo.Context) error {
+	userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+	parsedTransactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid transaction ID"))
+	}
+
+	note := "Cancelled by the buyer"
+	if userPayload.IsAdmin {
+		note = "Cancelled by an admin"
+	}
+
+	co := tc.checkout
+	transaction, err := releasePendingOrder(co.txManager, co.transactionRepo, co.itemRepo, co.variantRepo, co.stockMovementRepo, co.couponRepo, parsedTransactionID, models.TransactionStatusCancelled, note, func(transaction *dto.TransactionResponse) *utils.APIError {
+		// As with reading, someone else's order is reported as missing.
+		if !userPayload.IsAdmin && transaction.UserID != userPayload.UserID {
+			return utils.NewNotFoundError("Transaction not found")
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, repositories.ErrIllegalStatusTransition) {
+			return utils.HandlerError(c, utils.NewBadRequestError("Only pending transactions can be cancelled"))
 		}
 
-		if item.Stock < detail.Quantity {
-			return utils.HandlerError(c, utils.NewBadRequestError("Insufficient stock"))
+		var apiErr *utils.APIError
+		if errors.As(err, &apiErr) {
+			return utils.HandlerError(c, apiErr)
 		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to cancel transaction"))
+	}
+
+	return c.JSON(http.StatusOK, transaction)
+}
 
-		totalRequiredPrice += item.Price * float64(detail.Quantity)
+// UpdateFulfillment godoc
+// @Summary Move an order to its next fulfillment step
+// @Description Record that a paid order was packed, shipped, delivered or returned. Steps must follow that order (awaiting_fulfillment, packed, shipped, delivered) and only paid orders can be packed, shipped or delivered; shipped and delivered orders can be returned. Shipping requires a carrier and tracking number. This endpoint can only be accessed by admin users (isAdmin=true).
+// @Tags transaction
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Transaction ID"
+// @Param fulfillment body dto.FulfillmentUpdateRequestBody true "Next fulfillment step"
+// @Success 200 {object} dto.TransactionResponse
+// @Failure 400 {object} utils.APIError "Bad Request"
+// @Failure 401 {object} utils.APIError "Unauthorized"
+// @Failure 403 {object} utils.APIError "Forbidden"
+// @Failure 404 {object} utils.APIError "Not Found"
+// @Failure 500 {object} utils.APIError "Internal Server Error"
+// @Router /api/v1/admin/transactions/{id}/fulfillment [put]
+func (tc *TransactionController) UpdateFulfillment(c echo.Context) error {
+	parsedTransactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid transaction ID"))
 	}
 
-	if transactionBody.PaidAmount != totalRequiredPrice {
-		return utils.HandlerError(c, utils.NewBadRequestError("Paid amount does not match total price"))
+	var fulfillmentBody dto.FulfillmentUpdateRequestBody
+	if err := c.Bind(&fulfillmentBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
 	}
 
-	transaction := &models.Transaction{
-		UserID:        userPayload.UserID,
-		TotalPrice:    totalRequiredPrice,
-		IsSuccessPaid: true,
+	update := repositories.FulfillmentUpdate{
+		Status:         models.FulfillmentStatus(fulfillmentBody.Status),
+		Carrier:        strings.TrimSpace(fulfillmentBody.Carrier),
+		TrackingNumber: strings.TrimSpace(fulfillmentBody.TrackingNumber),
+		Note:           strings.TrimSpace(fulfillmentBody.Note),
 	}
 
-	transactionID, err := tc.transactionRepo.CreateTransaction(transaction)
-	if err != nil {
-		return utils.HandlerError(c, utils.NewInternalError("Failed to create transaction"))
+	// awaiting_fulfillment is entered automatically once the order is paid.
+	if !update.Status.IsValid() || update.Status == models.FulfillmentStatusAwaiting {
+		return utils.HandlerError(c, utils.NewBadRequestError("Status must be packed, shipped, delivered or returned"))
+	}
+
+	if update.Status == models.FulfillmentStatusShipped && (update.Carrier == "" || update.TrackingNumber == "") {
+		return utils.HandlerError(c, utils.NewBadRequestError("Carrier and tracking number are required to ship an order"))
 	}
 
-	parsedTransactionID, err := uuid.Parse(transactionID)
+	if err := tc.transactionRepo.UpdateFulfillmentStatus(parsedTransactionID, update); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.HandlerError(c, utils.NewNotFoundError("Transaction not found"))
+		}
+		if errors.Is(err, repositories.ErrIllegalFulfillmentTransition) {
+			return utils.HandlerError(c, utils.NewBadRequestError("Cannot update fulfillment: "+err.Error()))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to update fulfillment"))
+	}
+
+	transaction, err := tc.transactionRepo.GetTransactionByID(parsedTransactionID)
 	if err != nil {
-		return utils.HandlerError(c, utils.NewInternalError("Failed to parse transaction ID"))
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch transaction"))
+	}
+
+	if update.Status == models.FulfillmentStatusShipped {
+		tc.notifier.Shipped(transaction)
+	}
+
+	return c.JSON(http.StatusOK, transaction)
+}
+
+const (
+	defaultTransactionPageSize = 20
+	maxTransactionPageSize     = 100
+)
+
+// parseTransactionFilter reads the pagination, date range, status and
+// fulfillment status query parameters shared by the transaction list
+// endpoints.
+func parseTransactionFilter(c echo.Context) (*repositories.TransactionFilter, *utils.APIError) {
+	filter := &repositories.TransactionFilter{Limit: defaultTransactionPageSize}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		parsedLimit, err := strconv.Atoi(limit)
+		if err != nil || parsedLimit <= 0 {
+			return nil, utils.NewBadRequestError("Limit must be a positive number")
+		}
+		if parsedLimit > maxTransactionPageSize {
+			parsedLimit = maxTransactionPageSize
+		}
+		filter.Limit = parsedLimit
 	}
 
-	for _, detail := range transactionBody.TransactionDetailRequestBody {
-		parsedItemID, err := uuid.Parse(detail.ItemID)
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		parsedCursor, err := utils.DecodeCursor(cursor)
 		if err != nil {
-			return utils.HandlerError(c, utils.NewBadRequestError("Invalid Item ID format"))
+			return nil, utils.NewBadRequestError("Invalid cursor")
 		}
+		filter.Cursor = parsedCursor
+	}
 
-		item, err := tc.itemRepo.GetItemByID(parsedItemID)
+	startDate, endDate, apiErr := parseDateRange(c)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	filter.StartDate = startDate
+	filter.EndDate = endDate
+
+	if status := c.QueryParam("status"); status != "" {
+		transactionStatus := models.TransactionStatus(status)
+		if !transactionStatus.IsValid() {
+			return nil, utils.NewBadRequestError("Invalid status")
+		}
+		filter.Status = &transactionStatus
+	}
+
+	if fulfillmentStatus := c.QueryParam("fulfillment_status"); fulfillmentStatus != "" {
+		parsedFulfillmentStatus := models.FulfillmentStatus(fulfillmentStatus)
+		if !parsedFulfillmentStatus.IsValid() {
+			return nil, utils.NewBadRequestError("Invalid fulfillment_status")
+		}
+		filter.FulfillmentStatus = &parsedFulfillmentStatus
+	}
+
+	return filter, nil
+}
+
+// parseAdminTransactionFilter adds the filters only admins may use: another
+// user, an item, a total range and a customer search.
+func parseAdminTransactionFilter(c echo.Context, filter *repositories.TransactionFilter) *utils.APIError {
+	if userID := c.QueryParam("user_id"); userID != "" {
+		parsedUserID, err := uuid.Parse(userID)
 		if err != nil {
-			return utils.HandlerError(c, utils.NewNotFoundError("Item not found"))
+			return utils.NewBadRequestError("Invalid user_id")
 		}
+		filter.UserID = &parsedUserID
+	}
 
-		transactionDetail := &models.TransactionDetail{
-			TransactionID: parsedTransactionID,
-			ItemID:        item.ID,
-			Quantity:      detail.Quantity,
-			PricePerUnit:  item.Price,
-			TotalPrice:    item.Price * float64(detail.Quantity),
+	if itemID := c.QueryParam("item_id"); itemID != "" {
+		parsedItemID, err := uuid.Parse(itemID)
+		if err != nil {
+			return utils.NewBadRequestError("Invalid item_id")
 		}
+		filter.ItemID = &parsedItemID
+	}
+
+	if minTotal := c.QueryParam("min_total"); minTotal != "" {
+		parsedMinTotal, err := money.Parse(minTotal)
+		if err != nil || parsedMinTotal.IsNegative() {
+			return utils.NewBadRequestError("Invalid min_total")
+		}
+		filter.MinTotal = &parsedMinTotal
+	}
+
+	if maxTotal := c.QueryParam("max_total"); maxTotal != "" {
+		parsedMaxTotal, err := money.Parse(maxTotal)
+		if err != nil || parsedMaxTotal.IsNegative() {
+			return utils.NewBadRequestError("Invalid max_total")
+		}
+		filter.MaxTotal = &parsedMaxTotal
+	}
+
+	if filter.MinTotal != nil && filter.MaxTotal != nil && *filter.MinTotal > *filter.MaxTotal {
+		return utils.NewBadRequestError("min_total must not be greater than max_total")
+	}
 
-		if err := tc.transactionDetailRepo.CreateTransactionDetail(transactionDetail); err != nil {
-			return utils.HandlerError(c, utils.NewInternalError("Failed to create transaction detail"))
+	filter.Search = strings.TrimSpace(c.QueryParam("q"))
+
+	return nil
+}
+
+// parseDateRange reads the start_date and end_date query parameters. The
+// returned end is exclusive: a bare end date includes the whole day.
+func parseDateRange(c echo.Context) (startDate *time.Time, endDate *time.Time, apiErr *utils.APIError) {
+	if value := c.QueryParam("start_date"); value != "" {
+		parsedStartDate, _, err := utils.ParseDateParam(value)
+		if err != nil {
+			return nil, nil, utils.NewBadRequestError("Invalid start_date")
 		}
+		startDate = &parsedStartDate
+	}
 
-		item.Stock -= detail.Quantity
-		if err := tc.itemRepo.EditItem(item, parsedItemID); err != nil {
-			return utils.HandlerError(c, utils.NewInternalError("Failed to update item stock"))
+	if value := c.QueryParam("end_date"); value != "" {
+		parsedEndDate, isDateOnly, err := utils.ParseDateParam(value)
+		if err != nil {
+			return nil, nil, utils.NewBadRequestError("Invalid end_date")
+		}
+		if isDateOnly {
+			parsedEndDate = parsedEndDate.AddDate(0, 0, 1)
+		} else {
+			parsedEndDate = parsedEndDate.Add(time.Nanosecond)
 		}
+		endDate = &parsedEndDate
+	}
+
+	if startDate != nil && endDate != nil && !startDate.Before(*endDate) {
+		return nil, nil, utils.NewBadRequestError("start_date must be before end_date")
 	}
 
-	return c.JSON(http.StatusCreated, map[string]string{
-		"message": "Transaction created successfully"})
+	return startDate, endDate, nil
 }
@@ -1,28 +1,45 @@
 package controllers
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"net/http"
+	"net/url"
 	"ordent/dto"
 	"ordent/middlewares"
 	"ordent/models"
+	"ordent/notifications"
 	"ordent/repositories"
 	"ordent/utils"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // UserController handles user-related requests
 // @Description This controller is responsible for user registration, login, and profile fetching
 type UserController struct {
-	userRepo repositories.UserRepository
+	userRepo          repositories.UserRepository
+	passwordResetRepo repositories.PasswordResetRepository
+	notifier          *notifications.Notifier
+	passwordResetURL  string
+	passwordResetTTL  time.Duration
 }
 
 // NewUserController creates a new instance of UserController
 // @Description Create a new UserController with a UserRepository dependency
-func NewUserController(userRepo repositories.UserRepository) *UserController {
+func NewUserController(userRepo repositories.UserRepository, passwordResetRepo repositories.PasswordResetRepository, notifier *notifications.Notifier, passwordResetURL string, passwordResetTTL time.Duration) *UserController {
 	return &UserController{
-		userRepo: userRepo,
+		userRepo:          userRepo,
+		passwordResetRepo: passwordResetRepo,
+		notifier:          notifier,
+		passwordResetURL:  passwordResetURL,
+		passwordResetTTL:  passwordResetTTL,
 	}
 }
 
@@ -73,6 +90,8 @@ func (uc *UserController) RegisterUser(c echo.Context) error {
 		return utils.HandlerError(c, utils.NewInternalError("Failed to create user"))
 	}
 
+	uc.notifier.Welcome(newUser)
+
 	return c.JSON(http.StatusCreated, map[string]string{"message": "User created successfully"})
 }
 
@@ -148,3 +167,107 @@ func (uc *UserController) MyProfile(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, user)
 }
+
+const passwordResetRequestedMessage = "If the email is registered, a password reset link has been sent to it"
+
+// RequestPasswordReset godoc
+// @Summary Request a password reset
+// @Description Email a single-use password reset link to the given address. The response is the same whether or not the email is registered.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body dto.PasswordResetRequestBody true "Account email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} utils.APIError "Invalid request body"
+// @Failure 500 {object} utils.APIError "Internal server error"
+// @Router /api/v1/password/forgot [post]
+func (uc *UserController) RequestPasswordReset(c echo.Context) error {
+	var resetBody dto.PasswordResetRequestBody
+	if err := c.Bind(&resetBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	email := strings.TrimSpace(resetBody.Email)
+	if email == "" {
+		return utils.HandlerError(c, utils.NewBadRequestError("Email is required"))
+	}
+
+	foundUser, err := uc.userRepo.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusAccepted, map[string]string{"message": passwordResetRequestedMessage})
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to fetch user"))
+	}
+
+	rawToken := make([]byte, 32)
+	if _, err := rand.Read(rawToken); err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to create reset token"))
+	}
+	token := hex.EncodeToString(rawToken)
+
+	if err := uc.passwordResetRepo.CreatePasswordResetToken(&models.PasswordResetToken{
+		UserID:    foundUser.ID,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: time.Now().Add(uc.passwordResetTTL),
+	}); err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to create reset token"))
+	}
+
+	resetURL, err := url.Parse(uc.passwordResetURL)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Invalid password reset URL"))
+	}
+	query := resetURL.Query()
+	query.Set("token", token)
+	resetURL.RawQuery = query.Encode()
+
+	uc.notifier.PasswordReset(foundUser.FullName, foundUser.Email, resetURL.String(), uc.passwordResetTTL)
+
+	return c.JSON(http.StatusAccepted, map[string]string{"message": passwordResetRequestedMessage})
+}
+
+// ResetPassword godoc
+// @Summary Reset a password
+// @Description Set a new password with the token from a password reset email. A token works once, and using it also invalidates every other reset link of the account.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body dto.PasswordResetConfirmRequestBody true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.APIError "Invalid or expired token"
+// @Failure 500 {object} utils.APIError "Internal server error"
+// @Router /api/v1/password/reset [post]
+func (uc *UserController) ResetPassword(c echo.Context) error {
+	var confirmBody dto.PasswordResetConfirmRequestBody
+	if err := c.Bind(&confirmBody); err != nil {
+		return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+	}
+
+	if confirmBody.Token == "" {
+		return utils.HandlerError(c, utils.NewBadRequestError("Token is required"))
+	}
+
+	if confirmBody.Password == "" {
+		return utils.HandlerError(c, utils.NewBadRequestError("Password is required"))
+	}
+
+	hashedPassword, err := models.HashPassword(confirmBody.Password)
+	if err != nil {
+		return utils.HandlerError(c, utils.NewInternalError("Failed to hash password"))
+	}
+
+	if err := uc.passwordResetRepo.ResetPassword(hashResetToken(confirmBody.Token), hashedPassword); err != nil {
+		if errors.Is(err, repositories.ErrInvalidPasswordResetToken) {
+			return utils.HandlerError(c, utils.NewBadRequestError("Reset token is invalid or has expired"))
+		}
+		return utils.HandlerError(c, utils.NewInternalError("Failed to reset password"))
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Password has been reset"})
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
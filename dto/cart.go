@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"ordent/money"
+
+	"github.com/google/uuid"
+)
+
+type CartItemRequestBody struct {
+	ItemID string `json:"item_id"`
+	// VariantID is required for items that have variants.
+	VariantID string `json:"variant_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+type UpdateCartItemRequestBody struct {
+	Quantity int `json:"quantity"`
+}
+
+type CartCheckoutRequestBody struct {
+	PaidAmount  money.Money `json:"paid_amount" swaggertype:"number"`
+	CouponCode  string      `json:"coupon_code"`
+	CouponCodes []string    `json:"coupon_codes"`
+	AddressID   string      `json:"address_id"`
+}
+
+type CartItemResponse struct {
+	Item           GetItemDetailTransactionResponse `json:"item"`
+	Variant        *VariantSummaryResponse          `json:"variant,omitempty"`
+	Quantity       int                              `json:"quantity"`
+	PricePerUnit   money.Money                      `json:"price_per_unit" swaggertype:"number"`
+	Subtotal       money.Money                      `json:"subtotal" swaggertype:"number"`
+	AvailableStock int                              `json:"available_stock"`
+	Warning        string                           `json:"warning,omitempty"`
+}
+
+type CartResponse struct {
+	ID        uuid.UUID          `json:"id"`
+	CartItems []CartItemResponse `json:"cart_items"`
+	Total     money.Money        `json:"total" swaggertype:"number"`
+	Currency  string             `json:"currency"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+import "github.com/google/uuid"
+
+type CategoryRequestBody struct {
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	ParentID  string `json:"parent_id"`
+	SortOrder int    `json:"sort_order"`
+}
+
+// CategoryTreeResponse is one node of the category tree. ItemCount counts the
+// items filed directly under the category, TotalItemCount also those in its
+// subcategories, each item once.
+type CategoryTreeResponse struct {
+	ID             uuid.UUID              `json:"id"`
+	Name           string                 `json:"name"`
+	Slug           string                 `json:"slug"`
+	ParentID       *uuid.UUID             `json:"parent_id,omitempty"`
+	SortOrder      int                    `json:"sort_order"`
+	ItemCount      int64                  `json:"item_count"`
+	TotalItemCount int64                  `json:"total_item_count"`
+	Children       []CategoryTreeResponse `json:"children"`
+}
+
+type ItemCategoryResponse struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Slug string    `json:"slug"`
+}
@@ -0,0 +1,24 @@
+package dto
+
+import (
+	"ordent/money"
+	"time"
+)
+
+type CouponRequestBody struct {
+	Code          string      `json:"code"`
+	Description   string      `json:"description"`
+	Type          string      `json:"type" enums:"percentage,fixed"`
+	PercentageBps int         `json:"percentage_bps"`
+	AmountOff     money.Money `json:"amount_off" swaggertype:"number"`
+	MaxDiscount   money.Money `json:"max_discount" swaggertype:"number"`
+	MinSpend      money.Money `json:"min_spend" swaggertype:"number"`
+	Scope         string      `json:"scope" enums:"order,item"`
+	ItemID        string      `json:"item_id"`
+	StartsAt      *time.Time  `json:"starts_at"`
+	EndsAt        *time.Time  `json:"ends_at"`
+	UsageLimit    int         `json:"usage_limit"`
+	PerUserLimit  int         `json:"per_user_limit"`
+	Stackable     bool        `json:"stackable"`
+	IsActive      *bool       `json:"is_active"`
+}
@@ -0,0 +1,23 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type LowStockItemResponse struct {
+	ItemID           uuid.UUID `json:"item_id"`
+	SKU              *string   `json:"sku,omitempty"`
+	Name             string    `json:"name"`
+	Stock            int       `json:"stock"`
+	ReorderThreshold int       `json:"reorder_threshold"`
+	// AlertedAt is when the open alert for the item was raised. It is empty
+	// when the item is low only because its threshold was raised and its
+	// stock has not moved since.
+	AlertedAt *time.Time `json:"alerted_at,omitempty"`
+}
+
+type LowStockListResponse struct {
+	Items []LowStockItemResponse `json:"items"`
+}
@@ -1,18 +1,64 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"ordent/money"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type ItemRequestBody struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
-	Stock int     `json:"stock"`
+	// SKU is left unchanged when it is left out; send an empty string to
+	// remove it.
+	SKU         *string     `json:"sku"`
+	Name        string      `json:"name"`
+	Price       money.Money `json:"price" swaggertype:"number"`
+	Stock       int         `json:"stock"`
+	WeightGrams int         `json:"weight_grams"`
+	// ReorderThreshold is the stock at or below which a low-stock alert is
+	// raised; 0 turns alerts off.
+	ReorderThreshold int    `json:"reorder_threshold"`
+	TaxMode          string `json:"tax_mode" enums:"exclusive,inclusive,exempt"`
+	TaxRateID        string `json:"tax_rate_id"`
+	// CategoryIDs and Tags replace the item's categories and tags. Leave them
+	// out to keep the current ones; send an empty list to remove them all.
+	// Tags are names; tags that do not exist yet are created.
+	CategoryIDs []string `json:"category_ids"`
+	Tags        []string `json:"tags"`
 }
 
 type GetAllItemResponse struct {
-	ID    uuid.UUID `json:"id"`
-	Name  string    `json:"name"`
-	Price float64   `json:"price"`
-	Stock int       `json:"stock"`
+	ID               uuid.UUID              `json:"id"`
+	SKU              *string                `json:"sku,omitempty"`
+	Name             string                 `json:"name"`
+	Price            money.Money            `json:"price" swaggertype:"number"`
+	Stock            int                    `json:"stock"`
+	WeightGrams      int                    `json:"weight_grams"`
+	ReorderThreshold int                    `json:"reorder_threshold"`
+	TaxMode          string                 `json:"tax_mode"`
+	TaxRateID        *uuid.UUID             `json:"tax_rate_id,omitempty"`
+	Categories       []ItemCategoryResponse `json:"categories"`
+	Tags             []ItemTagResponse      `json:"tags"`
+	Variants         []ItemVariantResponse  `json:"variants"`
+	Images           []ItemImageResponse    `json:"images"`
+	CreatedAt        time.Time              `json:"created_at"`
+	UpdatedAt        time.Time              `json:"updated_at"`
+}
+
+// PaginationMeta describes where a page sits in the whole list. Page and
+// TotalPages are only set for page-numbered requests; NextCursor is set
+// whenever another page exists.
+type PaginationMeta struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Page       int    `json:"page,omitempty"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type ItemListResponse struct {
+	Items      []GetAllItemResponse `json:"items"`
+	Pagination PaginationMeta       `json:"pagination"`
 }
 
 type GetItemDetailTransactionResponse struct {
@@ -0,0 +1,22 @@
+package dto
+
+import "github.com/google/uuid"
+
+// ItemImageResponse is an image of an item. Key and ThumbnailKey locate the
+// files in storage and are turned into URL and ThumbnailURL before the
+// response is sent.
+type ItemImageResponse struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Position     int       `json:"position"`
+	IsPrimary    bool      `json:"is_primary"`
+	Key          string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+}
+
+type ItemImageOrderRequestBody struct {
+	ImageIDs []string `json:"image_ids"`
+}
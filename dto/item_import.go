@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"ordent/utils"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ItemImportRow is one row of an import or export file. Rows with an id
+// update that item; rows with only a sku update the item with that SKU, or
+// create it when there is none; other rows create a new item.
+type ItemImportRow struct {
+	ID string `json:"id,omitempty"`
+	ItemRequestBody
+}
+
+// ItemImportReport describes an import. Errors lists the rows that were
+// skipped, numbered from 0 without the CSV header line. For a dry run,
+// created_rows and updated_rows count what would have happened.
+type ItemImportReport struct {
+	JobID         *uuid.UUID        `json:"job_id,omitempty"`
+	Status        string            `json:"status" enums:"running,completed,failed"`
+	Format        string            `json:"format"`
+	DryRun        bool              `json:"dry_run"`
+	TotalRows     int               `json:"total_rows"`
+	ProcessedRows int               `json:"processed_rows"`
+	CreatedRows   int               `json:"created_rows"`
+	UpdatedRows   int               `json:"updated_rows"`
+	FailedRows    int               `json:"failed_rows"`
+	Errors        []utils.LineError `json:"errors"`
+	LastError     string            `json:"last_error,omitempty"`
+	CreatedAt     *time.Time        `json:"created_at,omitempty"`
+	FinishedAt    *time.Time        `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"ordent/money"
+
+	"github.com/google/uuid"
+)
+
+type ItemVariantRequestBody struct {
+	SKU     string            `json:"sku"`
+	Options map[string]string `json:"options"`
+	// Price overrides the item's price. Leave it out to sell the variant at
+	// the item's price.
+	Price *money.Money `json:"price" swaggertype:"number"`
+	Stock int          `json:"stock"`
+}
+
+// ItemVariantResponse is a variant as listed with its item. Price is what the
+// variant sells for; PriceOverride is only set when it differs from the item.
+type ItemVariantResponse struct {
+	ID            uuid.UUID         `json:"id"`
+	SKU           string            `json:"sku"`
+	Options       map[string]string `json:"options"`
+	Price         money.Money       `json:"price" swaggertype:"number"`
+	PriceOverride *money.Money      `json:"price_override,omitempty" swaggertype:"number"`
+	Stock         int               `json:"stock"`
+}
+
+// VariantSummaryResponse names the variant chosen on a cart or order line.
+type VariantSummaryResponse struct {
+	ID      uuid.UUID         `json:"id"`
+	SKU     string            `json:"sku"`
+	Options map[string]string `json:"options"`
+	Label   string            `json:"label"`
+}
@@ -0,0 +1,85 @@
+package dto
+
+import (
+	"ordent/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReportRange echoes the date range a report covers. EndDate is exclusive.
+type ReportRange struct {
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+}
+
+// SalesSummaryResponse totals the sales in a range. GrossRevenue is what the
+// orders were paid, and NetAmount, TaxAmount and ShippingFee are its parts.
+// Revenue is what is left of it after RefundedAmount was paid back.
+type SalesSummaryResponse struct {
+	ReportRange
+	OrderCount        int64       `json:"order_count"`
+	ItemsSold         int64       `json:"items_sold"`
+	GrossRevenue      money.Money `json:"gross_revenue" swaggertype:"number"`
+	RefundedAmount    money.Money `json:"refunded_amount" swaggertype:"number"`
+	Revenue           money.Money `json:"revenue" swaggertype:"number"`
+	NetAmount         money.Money `json:"net_amount" swaggertype:"number"`
+	TaxAmount         money.Money `json:"tax_amount" swaggertype:"number"`
+	ShippingFee       money.Money `json:"shipping_fee" swaggertype:"number"`
+	DiscountAmount    money.Money `json:"discount_amount" swaggertype:"number"`
+	AverageOrderValue money.Money `json:"average_order_value" swaggertype:"number"`
+}
+
+// SalesPeriodRow totals the sales of one period, with the same columns as
+// SalesSummaryResponse.
+type SalesPeriodRow struct {
+	// Period is the first day of the day, week (Monday) or month, as YYYY-MM-DD.
+	Period            string      `json:"period"`
+	OrderCount        int64       `json:"order_count"`
+	GrossRevenue      money.Money `json:"gross_revenue" swaggertype:"number"`
+	RefundedAmount    money.Money `json:"refunded_amount" swaggertype:"number"`
+	Revenue           money.Money `json:"revenue" swaggertype:"number"`
+	NetAmount         money.Money `json:"net_amount" swaggertype:"number"`
+	TaxAmount         money.Money `json:"tax_amount" swaggertype:"number"`
+	ShippingFee       money.Money `json:"shipping_fee" swaggertype:"number"`
+	DiscountAmount    money.Money `json:"discount_amount" swaggertype:"number"`
+	AverageOrderValue money.Money `json:"average_order_value" swaggertype:"number"`
+}
+
+type SalesReportResponse struct {
+	ReportRange
+	GroupBy string           `json:"group_by"`
+	Rows    []SalesPeriodRow `json:"rows"`
+}
+
+type TopItemRow struct {
+	ItemID       uuid.UUID   `json:"item_id"`
+	Name         string      `json:"name"`
+	QuantitySold int64       `json:"quantity_sold"`
+	OrderCount   int64       `json:"order_count"`
+	Revenue      money.Money `json:"revenue" swaggertype:"number"`
+}
+
+type TopItemsReportResponse struct {
+	ReportRange
+	SortBy string       `json:"sort_by"`
+	Items  []TopItemRow `json:"items"`
+}
+
+// CustomerTotalRow is what one customer spent. Revenue is what they paid less
+// what was refunded to them.
+type CustomerTotalRow struct {
+	UserID            uuid.UUID   `json:"user_id"`
+	FullName          string      `json:"full_name"`
+	Email             string      `json:"email"`
+	OrderCount        int64       `json:"order_count"`
+	Revenue           money.Money `json:"revenue" swaggertype:"number"`
+	AverageOrderValue money.Money `json:"average_order_value" swaggertype:"number"`
+	FirstOrderAt      time.Time   `json:"first_order_at"`
+	LastOrderAt       time.Time   `json:"last_order_at"`
+}
+
+type CustomerReportResponse struct {
+	ReportRange
+	Customers []CustomerTotalRow `json:"customers"`
+}
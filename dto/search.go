@@ -0,0 +1,15 @@
+package dto
+
+// SearchResult is an item matching a search. Highlight is the item name,
+// HTML-escaped, with the matched words wrapped in <mark> tags.
+type SearchResult struct {
+	Item      GetAllItemResponse `json:"item"`
+	Score     float64            `json:"score"`
+	Highlight string             `json:"highlight"`
+}
+
+type SearchResponse struct {
+	Query      string         `json:"query"`
+	Results    []SearchResult `json:"results"`
+	Pagination PaginationMeta `json:"pagination"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+import "ordent/money"
+
+type ShippingQuoteRequestBody struct {
+	AddressID                    string                         `json:"address_id"`
+	TransactionDetailRequestBody []TransactionDetailRequestBody `json:"transaction_detail"`
+}
+
+type ShippingQuoteResponse struct {
+	Provider    string      `json:"provider"`
+	Service     string      `json:"service"`
+	WeightGrams int         `json:"weight_grams"`
+	Fee         money.Money `json:"fee" swaggertype:"number"`
+}
@@ -0,0 +1,34 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type StockAdjustmentRequestBody struct {
+	// VariantID is required for items with variants, whose stock is kept
+	// per variant.
+	VariantID string `json:"variant_id"`
+	Delta     int    `json:"delta"`
+	Reason    string `json:"reason" enums:"restock,adjustment,return"`
+	Note      string `json:"note"`
+}
+
+type StockMovementResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	ItemID        uuid.UUID  `json:"item_id"`
+	VariantID     *uuid.UUID `json:"variant_id,omitempty"`
+	Delta         int        `json:"delta"`
+	Balance       int        `json:"balance"`
+	Reason        string     `json:"reason" enums:"sale,restock,adjustment,return,import"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty"`
+	UserID        *uuid.UUID `json:"user_id,omitempty"`
+	Note          string     `json:"note,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type StockMovementListResponse struct {
+	Movements  []StockMovementResponse `json:"movements"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
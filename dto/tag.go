@@ -0,0 +1,21 @@
+package dto
+
+import "github.com/google/uuid"
+
+type TagRequestBody struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type TagResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	ItemCount int64     `json:"item_count"`
+}
+
+type ItemTagResponse struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Slug string    `json:"slug"`
+}
@@ -0,0 +1,7 @@
+package dto
+
+type TaxRateRequestBody struct {
+	Name      string `json:"name"`
+	RateBps   int    `json:"rate_bps"`
+	IsDefault bool   `json:"is_default"`
+}
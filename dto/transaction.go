@@ -1,20 +1,98 @@
 package dto
 
 import (
+	"ordent/money"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type TransactionRequestBody struct {
-	PaidAmount                   float64                        `json:"paid_amount"`
+	PaidAmount                   money.Money                    `json:"paid_amount" swaggertype:"number"`
+	CouponCode                   string                         `json:"coupon_code"`
+	CouponCodes                  []string                       `json:"coupon_codes"`
+	AddressID                    string                         `json:"address_id"`
 	TransactionDetailRequestBody []TransactionDetailRequestBody `json:"transaction_detail"`
 }
 
 type TransactionResponse struct {
-	ID                 uuid.UUID                   `json:"id"`
-	TotalPrice         float64                     `json:"total_price"`
-	IsSuccessPaid      bool                        `json:"is_success_paid"`
-	CreatedAt          time.Time                   `json:"created_at"`
-	TransactionDetails []TransactionDetailResponse `json:"transaction_details"`
+	ID                  uuid.UUID                    `json:"id"`
+	UserID              uuid.UUID                    `json:"user_id"`
+	SubtotalPrice       money.Money                  `json:"subtotal_price" swaggertype:"number"`
+	DiscountAmount      money.Money                  `json:"discount_amount" swaggertype:"number"`
+	NetAmount           money.Money                  `json:"net_amount" swaggertype:"number"`
+	TaxAmount           money.Money                  `json:"tax_amount" swaggertype:"number"`
+	ShippingFee         money.Money                  `json:"shipping_fee" swaggertype:"number"`
+	GrossAmount         money.Money                  `json:"gross_amount" swaggertype:"number"`
+	TotalPrice          money.Money                  `json:"total_price" swaggertype:"number"`
+	Currency            string                       `json:"currency"`
+	Status              string                       `json:"status"`
+	PaymentProvider     string                       `json:"payment_provider,omitempty"`
+	PaymentChargeID     string                       `json:"payment_charge_id,omitempty"`
+	ShippingProvider    string                       `json:"shipping_provider,omitempty"`
+	ShippingService     string                       `json:"shipping_service,omitempty"`
+	ShippingWeightGrams int                          `json:"shipping_weight_grams"`
+	FulfillmentStatus   string                       `json:"fulfillment_status,omitempty"`
+	Carrier             string                       `json:"carrier,omitempty"`
+	TrackingNumber      string                       `json:"tracking_number,omitempty"`
+	ShippingAddress     *PostalAddressResponse       `json:"shipping_address,omitempty"`
+	Customer            *TransactionCustomerResponse `json:"customer,omitempty"`
+	CreatedAt           time.Time                    `json:"created_at"`
+	TransactionDetails  []TransactionDetailResponse  `json:"transaction_details"`
+	StatusHistories     []StatusHistoryResponse      `json:"status_histories"`
+	Timeline            []TimelineEntryResponse      `json:"timeline"`
+	AppliedCoupons      []AppliedCouponResponse      `json:"applied_coupons,omitempty"`
+}
+
+// TransactionCustomerResponse is the buyer of a transaction, shown to admins.
+type TransactionCustomerResponse struct {
+	ID       uuid.UUID `json:"id"`
+	FullName string    `json:"full_name"`
+	Email    string    `json:"email"`
+	Username string    `json:"username"`
+}
+
+type AppliedCouponResponse struct {
+	Code           string      `json:"code"`
+	DiscountAmount money.Money `json:"discount_amount" swaggertype:"number"`
+}
+
+// TimelineEntryResponse is one step of an order as shown to the customer:
+// a payment status change or a fulfillment step.
+type TimelineEntryResponse struct {
+	Type           string    `json:"type" enums:"payment,fulfillment"`
+	Status         string    `json:"status"`
+	Carrier        string    `json:"carrier,omitempty"`
+	TrackingNumber string    `json:"tracking_number,omitempty"`
+	Note           string    `json:"note,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type FulfillmentUpdateRequestBody struct {
+	Status         string `json:"status" enums:"packed,shipped,delivered,returned"`
+	Carrier        string `json:"carrier"`
+	TrackingNumber string `json:"tracking_number"`
+	Note           string `json:"note"`
+}
+
+type StatusHistoryResponse struct {
+	FromStatus string    `json:"from_status,omitempty"`
+	ToStatus   string    `json:"to_status"`
+	Note       string    `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type RefundRequestBody struct {
+	Amount money.Money `json:"amount" swaggertype:"number"`
+}
+
+type RefundResponse struct {
+	RefundID string      `json:"refund_id"`
+	ChargeID string      `json:"charge_id"`
+	Amount   money.Money `json:"amount" swaggertype:"number"`
+}
+
+type TransactionListResponse struct {
+	Transactions []TransactionResponse `json:"transactions"`
+	NextCursor   string                `json:"next_cursor,omitempty"`
 }
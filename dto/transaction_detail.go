@@ -1,13 +1,24 @@
 package dto
 
+import "ordent/money"
+
 type TransactionDetailRequestBody struct {
-	ItemID   string `json:"item_id"`
-	Quantity int    `json:"quantity"`
+	ItemID string `json:"item_id"`
+	// VariantID is required for items that have variants.
+	VariantID string `json:"variant_id"`
+	Quantity  int    `json:"quantity"`
 }
 
 type TransactionDetailResponse struct {
-	Item         GetItemDetailTransactionResponse `json:"item"`
-	Quantity     int                              `json:"quantity"`
-	PricePerUnit float64                          `json:"price_per_unit"`
-	TotalPrice   float64                          `json:"total_price"`
+	Item           GetItemDetailTransactionResponse `json:"item"`
+	Variant        *VariantSummaryResponse          `json:"variant,omitempty"`
+	Quantity       int                              `json:"quantity"`
+	PricePerUnit   money.Money                      `json:"price_per_unit" swaggertype:"number"`
+	DiscountAmount money.Money                      `json:"discount_amount" swaggertype:"number"`
+	TaxMode        string                           `json:"tax_mode"`
+	TaxRateBps     int                              `json:"tax_rate_bps"`
+	NetAmount      money.Money                      `json:"net_amount" swaggertype:"number"`
+	TaxAmount      money.Money                      `json:"tax_amount" swaggertype:"number"`
+	GrossAmount    money.Money                      `json:"gross_amount" swaggertype:"number"`
+	TotalPrice     money.Money                      `json:"total_price" swaggertype:"number"`
 }
@@ -19,6 +19,15 @@ type LoginBodyRequest struct {
 	Password string `json:"password"`
 }
 
+type PasswordResetRequestBody struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetConfirmRequestBody struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
 type LoginResponse struct {
 	Token string `json:"token"`
 }
@@ -0,0 +1,148 @@
+// Package imaging checks uploaded images and makes thumbnails of them using
+// only the standard library decoders.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooManyPixels     = errors.New("image has too many pixels")
+)
+
+// maxPixels bounds the decoded size of an image, so a small file cannot
+// expand into gigabytes of memory.
+const maxPixels = 40_000_000
+
+// Info describes an uploaded image as found by sniffing its content.
+type Info struct {
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+}
+
+// Inspect sniffs the content type from the bytes, ignoring whatever the
+// client claimed, and reads the dimensions without decoding the pixels.
+// Only JPEG, PNG and GIF are accepted.
+func Inspect(data []byte) (*Info, error) {
+	info := &Info{ContentType: http.DetectContentType(data)}
+
+	var decodeConfig func(r *bytes.Reader) (image.Config, error)
+	switch info.ContentType {
+	case "image/jpeg":
+		info.Extension = ".jpg"
+		decodeConfig = func(r *bytes.Reader) (image.Config, error) { return jpeg.DecodeConfig(r) }
+	case "image/png":
+		info.Extension = ".png"
+		decodeConfig = func(r *bytes.Reader) (image.Config, error) { return png.DecodeConfig(r) }
+	case "image/gif":
+		info.Extension = ".gif"
+		decodeConfig = func(r *bytes.Reader) (image.Config, error) { return gif.DecodeConfig(r) }
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	config, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrUnsupportedFormat
+	}
+	if int64(config.Width)*int64(config.Height) > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	info.Width, info.Height = config.Width, config.Height
+	return info, nil
+}
+
+// Thumbnail scales the image down so its longer side is at most maxEdge
+// pixels and returns it with its content type and extension. JPEGs stay
+// JPEG; PNGs and GIFs become PNG to keep transparency. Images that are
+// already small enough are re-encoded at their own size. GIFs keep only
+// their first frame.
+func Thumbnail(data []byte, maxEdge int) ([]byte, string, string, error) {
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", "", ErrUnsupportedFormat
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxEdge || height > maxEdge {
+		if width >= height {
+			width, height = maxEdge, max(1, height*maxEdge/width)
+		} else {
+			width, height = max(1, width*maxEdge/height), maxEdge
+		}
+	}
+
+	thumb := resize(src, width, height)
+
+	var out bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&out, thumb, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", "", err
+		}
+		return out.Bytes(), "image/jpeg", ".jpg", nil
+	}
+
+	if err := png.Encode(&out, thumb); err != nil {
+		return nil, "", "", err
+	}
+	return out.Bytes(), "image/png", ".png", nil
+}
+
+// resize scales src to width x height by averaging the source pixels that
+// fall into each target pixel (a box filter), which is accurate for the
+// downscaling thumbnails need. Colours are averaged premultiplied so
+// transparent pixels do not darken the edges.
+func resize(src image.Image, width int, height int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max(y0+1, (y+1)*srcHeight/height)
+
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max(x0+1, (x+1)*srcWidth/width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				offset := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(rgba.Pix[offset])
+					g += uint64(rgba.Pix[offset+1])
+					b += uint64(rgba.Pix[offset+2])
+					a += uint64(rgba.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,138 @@
+package inventory
+
+import (
+	"context"
+	"log"
+	"ordent/models"
+	"ordent/repositories"
+	"time"
+)
+
+// Options tunes the AlertWorker. Zero values fall back to the defaults below.
+type Options struct {
+	// PollInterval is how often the worker looks for newly raised alerts.
+	PollInterval time.Duration
+	BatchSize    int
+	SendTimeout  time.Duration
+
+	// A failed delivery is retried after RetryBaseDelay, doubling on every
+	// further failure up to RetryMaxDelay, until MaxAttempts is reached.
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+func (o *Options) setDefaults() {
+	if o.PollInterval <= 0 {
+		o.PollInterval = 30 * time.Second
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 20
+	}
+	if o.SendTimeout <= 0 {
+		o.SendTimeout = 30 * time.Second
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 8
+	}
+	if o.RetryBaseDelay <= 0 {
+		o.RetryBaseDelay = time.Minute
+	}
+	if o.RetryMaxDelay <= 0 {
+		o.RetryMaxDelay = 2 * time.Hour
+	}
+}
+
+// AlertWorker delivers low-stock alerts to the sink. Alerts are raised in the
+// database transaction that moved the stock, so an alert is only delivered
+// for a change that was committed; the worker picks them up from there and
+// retries until the sink accepts them.
+type AlertWorker struct {
+	sink      Sink
+	alertRepo repositories.LowStockAlertRepository
+	options   Options
+}
+
+func NewAlertWorker(sink Sink, alertRepo repositories.LowStockAlertRepository, options Options) *AlertWorker {
+	options.setDefaults()
+
+	return &AlertWorker{
+		sink:      sink,
+		alertRepo: alertRepo,
+		options:   options,
+	}
+}
+
+// Run delivers raised alerts until ctx is cancelled. Several instances of the
+// application may run workers against the same table.
+func (w *AlertWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *AlertWorker) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		// The lease outlives a whole batch of sends, so an alert is never
+		// picked up twice while its worker is still busy with it.
+		lease := time.Duration(w.options.BatchSize+1) * w.options.SendTimeout
+
+		alerts, err := w.alertRepo.ClaimDueAlerts(w.options.BatchSize, lease)
+		if err != nil {
+			log.Printf("inventory: failed to fetch low-stock alerts: %v", err)
+			return
+		}
+
+		for _, alert := range alerts {
+			w.deliver(ctx, alert)
+		}
+
+		if len(alerts) < w.options.BatchSize {
+			return
+		}
+	}
+}
+
+func (w *AlertWorker) deliver(ctx context.Context, alert models.LowStockAlert) {
+	sendCtx, cancel := context.WithTimeout(ctx, w.options.SendTimeout)
+	defer cancel()
+
+	err := w.sink.Send(sendCtx, alert)
+	if err == nil {
+		if err := w.alertRepo.MarkAlertSent(alert.ID); err != nil {
+			log.Printf("inventory: low-stock alert %s was sent but could not be marked sent: %v", alert.ID, err)
+		}
+		return
+	}
+
+	var nextAttemptAt *time.Time
+	if alert.Attempts < w.options.MaxAttempts {
+		next := time.Now().Add(w.retryDelay(alert.Attempts))
+		nextAttemptAt = &next
+		log.Printf("inventory: sending low-stock alert %s failed (attempt %d), retrying at %s: %v", alert.ID, alert.Attempts, next.Format(time.RFC3339), err)
+	} else {
+		log.Printf("inventory: giving up on low-stock alert %s after %d attempts: %v", alert.ID, alert.Attempts, err)
+	}
+
+	if err := w.alertRepo.MarkAlertFailed(alert.ID, err.Error(), nextAttemptAt); err != nil {
+		log.Printf("inventory: failed to record delivery failure of low-stock alert %s: %v", alert.ID, err)
+	}
+}
+
+// retryDelay is the wait after the given number of failed attempts.
+func (w *AlertWorker) retryDelay(attempts int) time.Duration {
+	delay := w.options.RetryBaseDelay
+	for i := 1; i < attempts && delay < w.options.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, w.options.RetryMaxDelay)
+}
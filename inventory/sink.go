@@ -0,0 +1,126 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"ordent/models"
+	"ordent/notifications"
+	"ordent/payments"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sink delivers a low-stock alert to whoever restocks the store.
+// Implementations return an error when the alert may not have been
+// delivered, so it can be retried.
+type Sink interface {
+	Send(ctx context.Context, alert models.LowStockAlert) error
+}
+
+// LogSink writes alerts to the application log.
+type LogSink struct{}
+
+func NewLogSink() *LogSink {
+	return &LogSink{}
+}
+
+func (s *LogSink) Send(ctx context.Context, alert models.LowStockAlert) error {
+	log.Printf("inventory: item %s (%s) is low on stock: %d left, reorder threshold %d", alert.ItemID, alert.ItemName, alert.Stock, alert.Threshold)
+	return nil
+}
+
+// EmailSink queues an alert email to every recipient through the notifier,
+// whose outbox takes care of delivering it. The emails are queued together,
+// so a failed attempt queues none of them and the retry mails no one twice.
+type EmailSink struct {
+	notifier   *notifications.Notifier
+	recipients []string
+}
+
+func NewEmailSink(notifier *notifications.Notifier, recipients []string) *EmailSink {
+	return &EmailSink{notifier: notifier, recipients: recipients}
+}
+
+func (s *EmailSink) Send(ctx context.Context, alert models.LowStockAlert) error {
+	return s.notifier.LowStock(s.recipients, &alert)
+}
+
+// WebhookSignatureHeader carries the signature of webhook alerts when a
+// secret is configured, in the same "t=<unix seconds>,v1=<hex hmac>" format
+// as payment webhooks.
+const WebhookSignatureHeader = "X-Inventory-Signature"
+
+// WebhookEventLowStock is the event name of a low-stock alert.
+const WebhookEventLowStock = "item.low_stock"
+
+// WebhookSink posts alerts as JSON to a URL. Any response other than 2xx
+// counts as a failed delivery.
+type WebhookSink struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, secret string) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type webhookAlert struct {
+	ID        uuid.UUID `json:"id"`
+	Event     string    `json:"event"`
+	ItemID    uuid.UUID `json:"item_id"`
+	ItemName  string    `json:"item_name"`
+	SKU       *string   `json:"sku,omitempty"`
+	Stock     int       `json:"stock"`
+	Threshold int       `json:"threshold"`
+	RaisedAt  time.Time `json:"raised_at"`
+}
+
+func (s *WebhookSink) Send(ctx context.Context, alert models.LowStockAlert) error {
+	body, err := json.Marshal(webhookAlert{
+		ID:        alert.ID,
+		Event:     WebhookEventLowStock,
+		ItemID:    alert.ItemID,
+		ItemName:  alert.ItemName,
+		SKU:       alert.SKU,
+		Stock:     alert.Stock,
+		Threshold: alert.Threshold,
+		RaisedAt:  alert.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.secret != "" {
+		req.Header.Set(WebhookSignatureHeader, payments.SignPayload(s.secret, time.Now(), body))
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("alert webhook: %s: %s", res.Status, strings.TrimSpace(string(message)))
+	}
+
+	return nil
+}
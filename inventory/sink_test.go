@@ -0,0 +1,78 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"ordent/models"
+	"ordent/notifications"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// flakyOutbox stores email messages in memory and fails the first failures
+// attempts to queue them.
+type flakyOutbox struct {
+	failures int
+	messages []*models.EmailMessage
+}
+
+func (o *flakyOutbox) CreateEmailMessage(message *models.EmailMessage) error {
+	return o.CreateEmailMessages([]*models.EmailMessage{message})
+}
+
+func (o *flakyOutbox) CreateEmailMessages(messages []*models.EmailMessage) error {
+	if o.failures > 0 {
+		o.failures--
+		return errors.New("database unavailable")
+	}
+	o.messages = append(o.messages, messages...)
+	return nil
+}
+
+func (o *flakyOutbox) ClaimDueEmailMessages(limit int, lease time.Duration) ([]models.EmailMessage, error) {
+	return nil, nil
+}
+
+func (o *flakyOutbox) MarkEmailMessageSent(messageID uuid.UUID) error {
+	return nil
+}
+
+func (o *flakyOutbox) MarkEmailMessageFailed(messageID uuid.UUID, lastError string, nextAttemptAt *time.Time) error {
+	return nil
+}
+
+func TestEmailSinkRetryQueuesEachRecipientOnce(t *testing.T) {
+	outbox := &flakyOutbox{failures: 1}
+	notifier, err := notifications.NewNotifier(notifications.NewMemoryMailer(), outbox, nil, notifications.Options{From: "Test Store <no-reply@example.com>"})
+	if err != nil {
+		t.Fatalf("new notifier: %v", err)
+	}
+
+	recipients := []string{"stock@example.com", "owner@example.com"}
+	sink := NewEmailSink(notifier, recipients)
+	alert := models.LowStockAlert{ItemID: uuid.New(), ItemName: "Mug", Stock: 1, Threshold: 5}
+
+	if err := sink.Send(context.Background(), alert); err == nil {
+		t.Fatal("send succeeded while the outbox was failing")
+	}
+	if len(outbox.messages) != 0 {
+		t.Fatalf("failed send queued %d emails, want 0", len(outbox.messages))
+	}
+
+	if err := sink.Send(context.Background(), alert); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+
+	var got []string
+	for _, message := range outbox.messages {
+		got = append(got, message.To)
+	}
+	sort.Strings(got)
+	want := []string{"<owner@example.com>", "<stock@example.com>"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("queued emails to %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,210 @@
+// Package invoice lays out transaction invoices as PDF documents.
+package invoice
+
+import (
+	"fmt"
+	"ordent/money"
+	"ordent/pdf"
+	"strings"
+	"time"
+)
+
+// Letterhead is the seller information printed at the top of every invoice.
+type Letterhead struct {
+	Name    string
+	Address string
+	Email   string
+	Phone   string
+	TaxID   string
+}
+
+type Party struct {
+	Name  string
+	Email string
+	// Address lines, empty when the order has no shipping address.
+	Address []string
+}
+
+type Line struct {
+	Description string
+	Quantity    int
+	UnitPrice   money.Money
+	Discount    money.Money
+	Tax         money.Money
+	Total       money.Money
+}
+
+// Invoice is everything printed on one invoice. Amounts are in Currency.
+type Invoice struct {
+	Number        string
+	IssuedAt      time.Time
+	TransactionID string
+	OrderedAt     time.Time
+	PaymentStatus string
+	Currency      string
+	Seller        Letterhead
+	Buyer         Party
+	Lines         []Line
+	Subtotal      money.Money
+	Discount      money.Money
+	Tax           money.Money
+	Shipping      money.Money
+	Total         money.Money
+}
+
+const (
+	marginLeft   = 50.0
+	marginRight  = pdf.PageWidth - 50.0
+	marginBottom = pdf.PageHeight - 60.0
+	lineHeight   = 16.0
+)
+
+// Table columns, given by their right edge except for the description.
+var (
+	columnDescription = marginLeft
+	columnQuantity    = 300.0
+	columnUnitPrice   = 370.0
+	columnDiscount    = 430.0
+	columnTax         = 485.0
+	columnTotal       = marginRight
+)
+
+// Render produces the PDF for inv. The same invoice always renders to the
+// same bytes.
+func Render(inv Invoice) []byte {
+	doc := pdf.NewDocument("Invoice " + inv.Number)
+	y := 60.0
+
+	// Letterhead
+	doc.Text(marginLeft, y, pdf.HelveticaBold, 18, inv.Seller.Name)
+	doc.TextRight(marginRight, y, pdf.HelveticaBold, 18, "INVOICE")
+	y += 18
+	for _, line := range nonEmpty(inv.Seller.Address, contact(inv.Seller.Email, inv.Seller.Phone), taxID(inv.Seller.TaxID)) {
+		doc.Text(marginLeft, y, pdf.Helvetica, 9, line)
+		y += 12
+	}
+
+	y += 6
+	doc.Line(marginLeft, y, marginRight, y, 1)
+	y += 22
+
+	// Invoice facts on the right, buyer on the left.
+	facts := [][2]string{
+		{"Invoice number", inv.Number},
+		{"Issued", inv.IssuedAt.Format("2 January 2006")},
+		{"Order", inv.TransactionID},
+		{"Order date", inv.OrderedAt.Format("2 January 2006")},
+		{"Payment status", inv.PaymentStatus},
+	}
+	factsY := y
+	for _, fact := range facts {
+		doc.Text(330, factsY, pdf.Helvetica, 9, fact[0])
+		doc.TextRight(marginRight, factsY, pdf.HelveticaBold, 9, fact[1])
+		factsY += 13
+	}
+
+	doc.Text(marginLeft, y, pdf.HelveticaBold, 10, "Bill to")
+	y += 14
+	for _, line := range nonEmpty(append([]string{inv.Buyer.Name, inv.Buyer.Email}, inv.Buyer.Address...)...) {
+		doc.Text(marginLeft, y, pdf.Helvetica, 9, line)
+		y += 12
+	}
+
+	if factsY > y {
+		y = factsY
+	}
+	y += 20
+
+	// Line items
+	header := func() {
+		doc.Text(columnDescription, y, pdf.HelveticaBold, 9, "Description")
+		doc.TextRight(columnQuantity, y, pdf.HelveticaBold, 9, "Qty")
+		doc.TextRight(columnUnitPrice, y, pdf.HelveticaBold, 9, "Unit price")
+		doc.TextRight(columnDiscount, y, pdf.HelveticaBold, 9, "Discount")
+		doc.TextRight(columnTax, y, pdf.HelveticaBold, 9, "Tax")
+		doc.TextRight(columnTotal, y, pdf.HelveticaBold, 9, "Amount")
+		y += 6
+		doc.Line(marginLeft, y, marginRight, y, 0.5)
+		y += 14
+	}
+	header()
+
+	for _, line := range inv.Lines {
+		if y > marginBottom {
+			doc.AddPage()
+			y = 60
+			header()
+		}
+
+		doc.Text(columnDescription, y, pdf.Helvetica, 9, truncate(line.Description, pdf.Helvetica, 9, columnQuantity-columnDescription-40))
+		doc.TextRight(columnQuantity, y, pdf.Helvetica, 9, fmt.Sprintf("%d", line.Quantity))
+		doc.TextRight(columnUnitPrice, y, pdf.Helvetica, 9, line.UnitPrice.String())
+		doc.TextRight(columnDiscount, y, pdf.Helvetica, 9, line.Discount.String())
+		doc.TextRight(columnTax, y, pdf.Helvetica, 9, line.Tax.String())
+		doc.TextRight(columnTotal, y, pdf.Helvetica, 9, line.Total.String())
+		y += lineHeight
+	}
+
+	doc.Line(marginLeft, y-8, marginRight, y-8, 0.5)
+	y += 8
+
+	// Totals
+	totals := [][2]string{
+		{"Subtotal", inv.Subtotal.String()},
+		{"Discount", "-" + inv.Discount.String()},
+		{"Tax", inv.Tax.String()},
+		{"Shipping", inv.Shipping.String()},
+	}
+	if y+float64(len(totals)+2)*lineHeight > marginBottom {
+		doc.AddPage()
+		y = 60
+	}
+	for _, total := range totals {
+		doc.Text(columnDiscount-60, y, pdf.Helvetica, 9, total[0])
+		doc.TextRight(columnTotal, y, pdf.Helvetica, 9, total[1])
+		y += 14
+	}
+	y += 4
+	doc.Line(columnDiscount-60, y-10, marginRight, y-10, 0.5)
+	doc.Text(columnDiscount-60, y+2, pdf.HelveticaBold, 11, "Total ("+inv.Currency+")")
+	doc.TextRight(columnTotal, y+2, pdf.HelveticaBold, 11, inv.Total.String())
+
+	doc.Gray(0.4)
+	doc.Text(marginLeft, pdf.PageHeight-40, pdf.Helvetica, 8, "Invoice "+inv.Number+" - issued by "+inv.Seller.Name)
+
+	return doc.Bytes()
+}
+
+func contact(email string, phone string) string {
+	return strings.Join(nonEmpty(email, phone), "  |  ")
+}
+
+func taxID(id string) string {
+	if id == "" {
+		return ""
+	}
+	return "Tax ID: " + id
+}
+
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// truncate shortens text with an ellipsis so it fits in width points.
+func truncate(text string, font pdf.Font, size float64, width float64) string {
+	if pdf.TextWidth(font, size, text) <= width {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.TextWidth(font, size, string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
@@ -0,0 +1,111 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"ordent/money"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleInvoice(lines int) Invoice {
+	inv := Invoice{
+		Number:        "INV-2024-000042",
+		IssuedAt:      time.Date(2024, 6, 2, 9, 30, 0, 0, time.UTC),
+		TransactionID: "5f0c1f9e-8d5b-4c1e-9f55-3a1c2b7d9e10",
+		OrderedAt:     time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC),
+		PaymentStatus: "paid",
+		Currency:      "IDR",
+		Seller: Letterhead{
+			Name:    "Toko Café",
+			Address: "Jl. Sudirman 1 (Lt. 2), Jakarta",
+			Email:   "hello@example.com",
+			Phone:   "+62 21 555 0100",
+			TaxID:   "01.234.567.8-901.000",
+		},
+		Buyer: Party{
+			Name:    "Zoë O'Brien",
+			Email:   "zoe@example.com",
+			Address: []string{`Apt. 3\B`, "Bandung 40115"},
+		},
+		Shipping: money.MustParse("15.00"),
+	}
+
+	for i := 0; i < lines; i++ {
+		line := Line{
+			Description: fmt.Sprintf("Crème brûlée set (%d) with a description far too long for its column", i+1),
+			Quantity:    i + 1,
+			UnitPrice:   money.MustParse("12.50"),
+			Discount:    money.MustParse("1.25"),
+			Tax:         money.MustParse("1.24"),
+		}
+		line.Total = line.UnitPrice.Mul(int64(line.Quantity)).Sub(line.Discount).Add(line.Tax)
+
+		inv.Lines = append(inv.Lines, line)
+		inv.Subtotal = inv.Subtotal.Add(line.UnitPrice.Mul(int64(line.Quantity)))
+		inv.Discount = inv.Discount.Add(line.Discount)
+		inv.Tax = inv.Tax.Add(line.Tax)
+	}
+	inv.Total = inv.Subtotal.Sub(inv.Discount).Add(inv.Tax).Add(inv.Shipping)
+
+	return inv
+}
+
+func TestRenderIsDeterministic(t *testing.T) {
+	for _, lines := range []int{1, 60} {
+		t.Run(fmt.Sprintf("%d lines", lines), func(t *testing.T) {
+			first := Render(sampleInvoice(lines))
+			second := Render(sampleInvoice(lines))
+			if !bytes.Equal(first, second) {
+				t.Fatal("two renders of the same invoice differ")
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines int
+		want  []string
+	}{
+		{
+			name:  "one page",
+			lines: 1,
+			want: []string{
+				"/Count 1",
+				`(Toko Caf\351) Tj`,
+				`(Zo\353 O'Brien) Tj`,
+				`(Apt. 3\\B) Tj`,
+				`(Jl. Sudirman 1 \(Lt. 2\), Jakarta) Tj`,
+				"(2 June 2024) Tj",
+				"(Total \\(IDR\\)) Tj",
+				"(27.49) Tj",
+			},
+		},
+		{
+			name:  "line items continue on a second page",
+			lines: 60,
+			want:  []string{"/Count 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := string(Render(sampleInvoice(tt.lines)))
+
+			if !strings.HasPrefix(out, "%PDF-1.4\n") || !strings.HasSuffix(out, "%%EOF\n") {
+				t.Fatal("output is not a complete PDF")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("invoice does not contain %q", want)
+				}
+			}
+			if strings.Contains(out, "far too long for its column") {
+				t.Error("long descriptions are not truncated")
+			}
+		})
+	}
+}
@@ -22,7 +22,15 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
+	configs.InitCurrency()
 	configs.InitDB()
+	configs.InitPayments()
+	configs.InitShipping()
+	configs.InitNotifications()
+	configs.InitStorage()
+	configs.InitSearch()
+	configs.InitImports()
+	configs.InitInventory()
 
 	port := os.Getenv("PORT")
 
@@ -30,7 +38,19 @@ func main() {
 
 	routes.UserRoutes(e)
 	routes.ItemRoutes(e)
+	routes.SearchRoutes(e)
+	routes.CategoryRoutes(e)
+	routes.TagRoutes(e)
 	routes.TransactionRoutes(e)
+	routes.CartRoutes(e)
+	routes.PaymentRoutes(e)
+	routes.CouponRoutes(e)
+	routes.TaxRateRoutes(e)
+	routes.AddressRoutes(e)
+	routes.InvoiceRoutes(e)
+	routes.ReportRoutes(e)
+	routes.InventoryRoutes(e)
+	routes.StorageRoutes(e)
 
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
@@ -0,0 +1,177 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/repositories"
+	"ordent/utils"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 191
+
+	// An in-flight duplicate waits this long for the first request to finish
+	// before it is told the request is still in progress.
+	idempotencyWaitTimeout  = 5 * time.Second
+	idempotencyPollInterval = 100 * time.Millisecond
+
+	// A key still marked in progress after this long belongs to a request
+	// that died without completing, so it may be taken over.
+	idempotencyLockTimeout = time.Minute
+)
+
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Idempotency makes a handler safe to retry. When the request carries an
+// Idempotency-Key header, the first response for that key is stored per user
+// and replayed for identical retries until ttl elapses. Reusing a key with a
+// different request body is rejected with 422, and a retry that arrives while
+// the first request is still running gets 409 once it has waited a moment.
+// Requests without the header pass through untouched. It must run after
+// JWTAuth.
+func Idempotency(repo repositories.IdempotencyKeyRepository, ttl time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				return next(c)
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				return utils.HandlerError(c, utils.NewBadRequestError("Idempotency-Key is too long"))
+			}
+
+			userPayload := c.Get("userPayload").(*dto.JWTPayload)
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return utils.HandlerError(c, utils.NewBadRequestError("Invalid request body"))
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			fingerprint := requestFingerprint(c.Request().Method, c.Path(), body)
+
+			record, existing, err := claimIdempotencyKey(repo, userPayload, key, fingerprint, ttl)
+			if err != nil {
+				return utils.HandlerError(c, utils.NewInternalError("Failed to process Idempotency-Key"))
+			}
+
+			if existing != nil {
+				if existing.Fingerprint != fingerprint {
+					return utils.HandlerError(c, utils.NewUnprocessableEntityError("Idempotency-Key was already used with a different request"))
+				}
+
+				if existing.Status != models.IdempotencyStatusCompleted {
+					return utils.HandlerError(c, utils.NewConflictError("A request with this Idempotency-Key is still in progress"))
+				}
+
+				c.Response().Header().Set(IdempotencyReplayedHeader, "true")
+				return c.Blob(existing.ResponseStatus, existing.ResponseContentType, []byte(existing.ResponseBody))
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			if err := next(c); err != nil {
+				repo.DeleteIdempotencyKey(record.ID)
+				return err
+			}
+
+			// Server errors are not final, so the client must be able to retry
+			// them with the same key.
+			status := c.Response().Status
+			if status >= http.StatusInternalServerError {
+				repo.DeleteIdempotencyKey(record.ID)
+				return nil
+			}
+
+			contentType := c.Response().Header().Get(echo.HeaderContentType)
+			if err := repo.CompleteIdempotencyKey(record.ID, status, contentType, recorder.body.String()); err != nil {
+				c.Logger().Errorf("failed to store idempotent response: %v", err)
+			}
+
+			return nil
+		}
+	}
+}
+
+// claimIdempotencyKey either inserts a fresh in-progress key and returns it as
+// record, or returns the live key that already holds the slot as existing.
+// Expired and abandoned keys are removed and the insert is retried.
+func claimIdempotencyKey(repo repositories.IdempotencyKeyRepository, userPayload *dto.JWTPayload, key string, fingerprint string, ttl time.Duration) (record *models.IdempotencyKey, existing *models.IdempotencyKey, err error) {
+	deadline := time.Now().Add(idempotencyWaitTimeout)
+
+	for {
+		record = &models.IdempotencyKey{
+			UserID:      userPayload.UserID,
+			Key:         key,
+			Fingerprint: fingerprint,
+			Status:      models.IdempotencyStatusInProgress,
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+
+		err = repo.CreateIdempotencyKey(record)
+		if err == nil {
+			return record, nil, nil
+		}
+
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, nil, err
+		}
+
+		existing, err = repo.GetIdempotencyKey(userPayload.UserID, key)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// Deleted between our insert and read; try to claim it again.
+				continue
+			}
+			return nil, nil, err
+		}
+
+		now := time.Now()
+		abandoned := existing.Status == models.IdempotencyStatusInProgress && now.Sub(existing.UpdatedAt) > idempotencyLockTimeout
+		if now.After(existing.ExpiresAt) || abandoned {
+			if err := repo.DeleteIdempotencyKey(existing.ID); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		if existing.Fingerprint != fingerprint || existing.Status == models.IdempotencyStatusCompleted || now.After(deadline) {
+			return nil, existing, nil
+		}
+
+		time.Sleep(idempotencyPollInterval)
+	}
+}
+
+func requestFingerprint(method string, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PostalAddress is where a parcel goes. It is stored on Address for the
+// address book and copied onto Transaction at checkout, so later edits to the
+// address book do not change where past orders were sent.
+type PostalAddress struct {
+	RecipientName string `json:"recipient_name" gorm:"size:100"`
+	Phone         string `json:"phone" gorm:"size:32"`
+	Line1         string `json:"line1"`
+	Line2         string `json:"line2"`
+	City          string `json:"city" gorm:"size:100"`
+	Province      string `json:"province" gorm:"size:100"`
+	PostalCode    string `json:"postal_code" gorm:"size:20"`
+	// Country is an ISO 3166-1 alpha-2 code such as ID.
+	Country string `json:"country" gorm:"size:2"`
+}
+
+type Address struct {
+	Basemodel
+	UserID        uuid.UUID `json:"user_id" gorm:"not null;size:191;index"`
+	Label         string    `json:"label" gorm:"size:50"`
+	PostalAddress `gorm:"embedded"`
+	IsDefault     bool `json:"is_default" gorm:"not null;default:false"`
+}
+
+func (a *Address) BeforeCreate(tx *gorm.DB) (err error) {
+	a.ID = uuid.New()
+	a.CreatedAt = time.Now()
+
+	return
+}
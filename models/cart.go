@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Cart struct {
+	Basemodel
+	UserID    uuid.UUID  `json:"user_id" gorm:"not null;size:191;uniqueIndex"`
+	CartItems []CartItem `json:"cart_items" gorm:"foreignKey:CartID"`
+}
+
+func (c *Cart) BeforeCreate(tx *gorm.DB) (err error) {
+	c.ID = uuid.New()
+	c.CreatedAt = time.Now()
+
+	return
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CartItem is one line of a cart. A cart holds at most one line per item and
+// variant; adding the same item again increases the quantity of the existing
+// line.
+type CartItem struct {
+	Basemodel
+	CartID uuid.UUID `json:"cart_id" gorm:"not null;size:191;uniqueIndex:idx_cart_item_variant"`
+	ItemID uuid.UUID `json:"item_id" gorm:"not null;size:191;uniqueIndex:idx_cart_item_variant"`
+	Item   Item      `json:"item"`
+	// VariantID is uuid.Nil for items without variants. It is not nullable
+	// because the unique index would not match NULLs, and so it has no
+	// foreign key either.
+	VariantID uuid.UUID `json:"variant_id" gorm:"not null;size:191;default:'00000000-0000-0000-0000-000000000000';uniqueIndex:idx_cart_item_variant"`
+	Quantity  int       `json:"quantity" gorm:"not null"`
+}
+
+func (ci *CartItem) BeforeCreate(tx *gorm.DB) (err error) {
+	ci.ID = uuid.New()
+	ci.CreatedAt = time.Now()
+
+	return
+}
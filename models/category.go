@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Category groups items for browsing. Categories form a tree through
+// ParentID; siblings are listed by SortOrder and then by name.
+type Category struct {
+	Basemodel
+	Name      string     `json:"name" gorm:"not null;size:191"`
+	Slug      string     `json:"slug" gorm:"not null;size:191;uniqueIndex"`
+	ParentID  *uuid.UUID `json:"parent_id" gorm:"size:191;index"`
+	SortOrder int        `json:"sort_order" gorm:"not null;default:0"`
+}
+
+func (c *Category) BeforeCreate(tx *gorm.DB) (err error) {
+	c.ID = uuid.New()
+	c.CreatedAt = time.Now()
+
+	return
+}
+
+// ItemCategory links an item to one of its categories.
+type ItemCategory struct {
+	ItemID     uuid.UUID `gorm:"primaryKey;size:191"`
+	CategoryID uuid.UUID `gorm:"primaryKey;size:191;index"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"ordent/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CouponType string
+
+const (
+	CouponTypePercentage CouponType = "percentage"
+	CouponTypeFixed      CouponType = "fixed"
+)
+
+type CouponScope string
+
+const (
+	// CouponScopeOrder discounts the whole order.
+	CouponScopeOrder CouponScope = "order"
+	// CouponScopeItem discounts only the lines of Coupon.ItemID.
+	CouponScopeItem CouponScope = "item"
+)
+
+// Coupon is an admin-managed discount code. Percentage coupons take
+// PercentageBps basis points (1000 = 10%) off, optionally capped at
+// MaxDiscount; fixed coupons take AmountOff off. A zero limit means
+// unlimited. Only Stackable coupons can be combined with other coupons.
+type Coupon struct {
+	Basemodel
+	Code          string      `json:"code" gorm:"not null;size:64;uniqueIndex"`
+	Description   string      `json:"description"`
+	Type          CouponType  `json:"type" gorm:"not null;size:20"`
+	PercentageBps int         `json:"percentage_bps"`
+	AmountOff     money.Money `json:"amount_off" swaggertype:"number"`
+	MaxDiscount   money.Money `json:"max_discount" swaggertype:"number"`
+	MinSpend      money.Money `json:"min_spend" swaggertype:"number"`
+	Scope         CouponScope `json:"scope" gorm:"not null;size:20;default:order"`
+	ItemID        *uuid.UUID  `json:"item_id" gorm:"size:191"`
+	StartsAt      *time.Time  `json:"starts_at"`
+	EndsAt        *time.Time  `json:"ends_at"`
+	UsageLimit    int         `json:"usage_limit"`
+	PerUserLimit  int         `json:"per_user_limit"`
+	UsedCount     int         `json:"used_count" gorm:"not null;default:0"`
+	Stackable     bool        `json:"stackable" gorm:"default:false"`
+	IsActive      bool        `json:"is_active"`
+}
+
+func (c *Coupon) BeforeCreate(tx *gorm.DB) (err error) {
+	c.ID = uuid.New()
+	c.CreatedAt = time.Now()
+
+	return
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"ordent/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CouponRedemption records that a coupon was used on a transaction and how
+// much it took off. ReleasedAt is set when the transaction failed and the use
+// was given back.
+type CouponRedemption struct {
+	Basemodel
+	CouponID       uuid.UUID   `json:"coupon_id" gorm:"not null;size:191;index:idx_coupon_redemption_user"`
+	Coupon         Coupon      `json:"coupon"`
+	UserID         uuid.UUID   `json:"user_id" gorm:"not null;size:191;index:idx_coupon_redemption_user"`
+	TransactionID  uuid.UUID   `json:"transaction_id" gorm:"not null;size:191;index"`
+	DiscountAmount money.Money `json:"discount_amount" gorm:"not null" swaggertype:"number"`
+	ReleasedAt     *time.Time  `json:"released_at"`
+}
+
+func (cr *CouponRedemption) BeforeCreate(tx *gorm.DB) (err error) {
+	cr.ID = uuid.New()
+	cr.CreatedAt = time.Now()
+
+	return
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EmailMessageStatus string
+
+const (
+	EmailMessageStatusPending EmailMessageStatus = "pending"
+	EmailMessageStatusSent    EmailMessageStatus = "sent"
+	EmailMessageStatusFailed  EmailMessageStatus = "failed"
+)
+
+// EmailMessage is an outgoing email in the outbox. Messages are rendered when
+// they are queued and delivered by the notification worker, which retries
+// failed deliveries with backoff until it gives up and marks them failed.
+type EmailMessage struct {
+	Basemodel
+	Template      string             `json:"template" gorm:"not null;size:64"`
+	From          string             `json:"from" gorm:"not null;size:255"`
+	To            string             `json:"to" gorm:"not null;size:255"`
+	Subject       string             `json:"subject" gorm:"not null;size:255"`
+	TextBody      string             `json:"text_body" gorm:"type:text"`
+	HTMLBody      string             `json:"html_body" gorm:"type:mediumtext"`
+	Status        EmailMessageStatus `json:"status" gorm:"not null;size:16;default:pending;index:idx_email_messages_due,priority:1"`
+	NextAttemptAt time.Time          `json:"next_attempt_at" gorm:"not null;index:idx_email_messages_due,priority:2"`
+	Attempts      int                `json:"attempts" gorm:"not null;default:0"`
+	LastError     string             `json:"last_error" gorm:"type:text"`
+	SentAt        *time.Time         `json:"sent_at"`
+}
+
+func (m *EmailMessage) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	m.CreatedAt = time.Now()
+
+	return
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FulfillmentStatus tracks the physical side of an order. It is empty until
+// the order is paid and then starts at awaiting_fulfillment.
+type FulfillmentStatus string
+
+const (
+	FulfillmentStatusAwaiting  FulfillmentStatus = "awaiting_fulfillment"
+	FulfillmentStatusPacked    FulfillmentStatus = "packed"
+	FulfillmentStatusShipped   FulfillmentStatus = "shipped"
+	FulfillmentStatusDelivered FulfillmentStatus = "delivered"
+	FulfillmentStatusReturned  FulfillmentStatus = "returned"
+)
+
+// fulfillmentStatusTransitions lists, for every fulfillment status, the
+// statuses an order may move to next. Statuses without an entry are final.
+var fulfillmentStatusTransitions = map[FulfillmentStatus][]FulfillmentStatus{
+	"": {
+		FulfillmentStatusAwaiting,
+	},
+	FulfillmentStatusAwaiting: {
+		FulfillmentStatusPacked,
+	},
+	FulfillmentStatusPacked: {
+		FulfillmentStatusShipped,
+	},
+	FulfillmentStatusShipped: {
+		FulfillmentStatusDelivered,
+		FulfillmentStatusReturned,
+	},
+	FulfillmentStatusDelivered: {
+		FulfillmentStatusReturned,
+	},
+}
+
+func (s FulfillmentStatus) IsValid() bool {
+	switch s {
+	case FulfillmentStatusAwaiting, FulfillmentStatusPacked, FulfillmentStatusShipped,
+		FulfillmentStatusDelivered, FulfillmentStatusReturned:
+		return true
+	}
+	return false
+}
+
+func (s FulfillmentStatus) CanTransitionTo(next FulfillmentStatus) bool {
+	for _, allowed := range fulfillmentStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresPayment reports whether an order must be paid for before it can
+// move to s. Only returns can be recorded for refunded orders.
+func (s FulfillmentStatus) RequiresPayment() bool {
+	return s != FulfillmentStatusReturned
+}
+
+// FulfillmentEvent records one fulfillment step of a transaction. Together
+// with the status history it forms the timeline shown to the customer.
+type FulfillmentEvent struct {
+	Basemodel
+	TransactionID  uuid.UUID         `json:"transaction_id" gorm:"not null;size:191;index"`
+	FromStatus     FulfillmentStatus `json:"from_status" gorm:"size:32"`
+	ToStatus       FulfillmentStatus `json:"to_status" gorm:"not null;size:32"`
+	Carrier        string            `json:"carrier" gorm:"size:64"`
+	TrackingNumber string            `json:"tracking_number" gorm:"size:128"`
+	Note           string            `json:"note"`
+}
+
+func (e *FulfillmentEvent) BeforeCreate(tx *gorm.DB) (err error) {
+	e.ID = uuid.New()
+	e.CreatedAt = time.Now()
+
+	return
+}
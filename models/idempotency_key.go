@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	IdempotencyStatusInProgress = "in_progress"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+type IdempotencyKey struct {
+	Basemodel
+	UserID              uuid.UUID `json:"user_id" gorm:"not null;size:191;uniqueIndex:idx_idempotency_user_key"`
+	Key                 string    `json:"key" gorm:"column:idempotency_key;not null;size:191;uniqueIndex:idx_idempotency_user_key"`
+	Fingerprint         string    `json:"fingerprint" gorm:"not null;size:64"`
+	Status              string    `json:"status" gorm:"not null;size:20"`
+	ResponseStatus      int       `json:"response_status"`
+	ResponseContentType string    `json:"response_content_type"`
+	ResponseBody        string    `json:"response_body" gorm:"type:longtext"`
+	ExpiresAt           time.Time `json:"expires_at" gorm:"not null;index"`
+}
+
+func (k *IdempotencyKey) BeforeCreate(tx *gorm.DB) (err error) {
+	k.ID = uuid.New()
+	k.CreatedAt = time.Now()
+
+	return
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"ordent/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ImportJobStatus string
+
+const (
+	ImportJobStatusRunning   ImportJobStatus = "running"
+	ImportJobStatusCompleted ImportJobStatus = "completed"
+	ImportJobStatusFailed    ImportJobStatus = "failed"
+)
+
+// ImportJob tracks a bulk item import that runs in the background. Progress
+// is saved as rows are processed so admins can poll it. Errors keeps the
+// first rows that could not be imported; FailedRows counts all of them.
+type ImportJob struct {
+	Basemodel
+	UserID        uuid.UUID         `json:"user_id" gorm:"not null;size:191;index"`
+	Format        string            `json:"format" gorm:"not null;size:10"`
+	DryRun        bool              `json:"dry_run" gorm:"not null;default:false"`
+	Status        ImportJobStatus   `json:"status" gorm:"not null;size:16;index"`
+	TotalRows     int               `json:"total_rows" gorm:"not null;default:0"`
+	ProcessedRows int               `json:"processed_rows" gorm:"not null;default:0"`
+	CreatedRows   int               `json:"created_rows" gorm:"not null;default:0"`
+	UpdatedRows   int               `json:"updated_rows" gorm:"not null;default:0"`
+	FailedRows    int               `json:"failed_rows" gorm:"not null;default:0"`
+	Errors        []utils.LineError `json:"errors" gorm:"type:json;serializer:json"`
+	LastError     string            `json:"last_error" gorm:"type:text"`
+	FinishedAt    *time.Time        `json:"finished_at"`
+}
+
+func (j *ImportJob) BeforeCreate(tx *gorm.DB) (err error) {
+	j.ID = uuid.New()
+	j.CreatedAt = time.Now()
+
+	return
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Invoice is the issued invoice of a transaction. The PDF is rendered once
+// when the invoice is issued and served unchanged from then on.
+type Invoice struct {
+	Basemodel
+	TransactionID uuid.UUID `json:"transaction_id" gorm:"not null;size:191;uniqueIndex"`
+	Number        string    `json:"number" gorm:"not null;size:32;uniqueIndex"`
+	IssuedAt      time.Time `json:"issued_at" gorm:"not null"`
+	PDF           []byte    `json:"-" gorm:"type:mediumblob;not null"`
+	// Checksum is the hex SHA-256 of PDF, served as the ETag.
+	Checksum string `json:"checksum" gorm:"not null;size:64"`
+}
+
+func (i *Invoice) BeforeCreate(tx *gorm.DB) (err error) {
+	i.ID = uuid.New()
+	i.CreatedAt = time.Now()
+
+	return
+}
+
+// InvoiceSequence hands out invoice numbers without gaps, one counter per
+// calendar year.
+type InvoiceSequence struct {
+	Year       int `gorm:"primaryKey;autoIncrement:false"`
+	LastNumber int `gorm:"not null;default:0"`
+}
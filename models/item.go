@@ -1,18 +1,56 @@
 package models
 
 import (
+	"ordent/money"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// TaxMode tells how an item's price relates to tax.
+type TaxMode string
+
+const (
+	// TaxModeExclusive prices are before tax; tax is added at checkout.
+	TaxModeExclusive TaxMode = "exclusive"
+	// TaxModeInclusive prices already contain the tax.
+	TaxModeInclusive TaxMode = "inclusive"
+	// TaxModeExempt items are not taxed.
+	TaxModeExempt TaxMode = "exempt"
+)
+
+func (m TaxMode) IsValid() bool {
+	switch m {
+	case TaxModeExclusive, TaxModeInclusive, TaxModeExempt:
+		return true
+	}
+	return false
+}
+
 type Item struct {
 	Basemodel
-	Name               string              `json:"name" gorm:"not null"`
-	Price              float64             `json:"price" gorm:"not null"`
-	Stock              int                 `json:"stock" gorm:"not null"`
+	Name string `json:"name" gorm:"not null;size:191;index"`
+	// SKU is optional; items without one are stored as NULL so the unique
+	// index does not clash.
+	SKU         *string     `json:"sku,omitempty" gorm:"size:100;uniqueIndex"`
+	Price       money.Money `json:"price" gorm:"not null;index" swaggertype:"number"`
+	Stock       int         `json:"stock" gorm:"not null;index"`
+	WeightGrams int         `json:"weight_grams" gorm:"not null;default:0"`
+	// ReorderThreshold raises a low-stock alert when stock falls to it or
+	// below; 0 turns alerts off.
+	ReorderThreshold   int                 `json:"reorder_threshold" gorm:"not null;default:0"`
+	TaxMode            TaxMode             `json:"tax_mode" gorm:"not null;size:20;default:exclusive"`
+	TaxRateID          *uuid.UUID          `json:"tax_rate_id" gorm:"size:191"`
 	TransactionDetails []TransactionDetail `json:"transaction_details" gorm:"foreignKey:ItemID"`
+	// Categories and Tags are read-only associations; links are written
+	// through ItemCategory and ItemTag.
+	Categories []Category `json:"categories,omitempty" gorm:"many2many:item_categories"`
+	Tags       []Tag      `json:"tags,omitempty" gorm:"many2many:item_tags"`
+	// Variants are read-only here; they are written through ItemVariant.
+	Variants []ItemVariant `json:"variants,omitempty" gorm:"foreignKey:ItemID"`
+	// Images are read-only here; they are written through ItemImage.
+	Images []ItemImage `json:"images,omitempty" gorm:"foreignKey:ItemID"`
 }
 
 func (i *Item) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ItemImage is a picture of an item. The file and its thumbnail live in the
+// configured storage under Key and ThumbnailKey. Images are shown by Position;
+// at most one image of an item is primary.
+type ItemImage struct {
+	Basemodel
+	ItemID               uuid.UUID `json:"item_id" gorm:"not null;size:191;index"`
+	Key                  string    `json:"key" gorm:"not null;size:191"`
+	ThumbnailKey         string    `json:"thumbnail_key" gorm:"not null;size:191"`
+	ContentType          string    `json:"content_type" gorm:"not null;size:50"`
+	ThumbnailContentType string    `json:"thumbnail_content_type" gorm:"not null;size:50"`
+	SizeBytes            int64     `json:"size_bytes" gorm:"not null"`
+	Width                int       `json:"width" gorm:"not null"`
+	Height               int       `json:"height" gorm:"not null"`
+	Position             int       `json:"position" gorm:"not null;default:0"`
+	IsPrimary            bool      `json:"is_primary" gorm:"not null;default:false"`
+}
+
+func (ii *ItemImage) BeforeCreate(tx *gorm.DB) (err error) {
+	ii.ID = uuid.New()
+	ii.CreatedAt = time.Now()
+
+	return
+}
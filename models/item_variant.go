@@ -0,0 +1,54 @@
+package models
+
+import (
+	"ordent/money"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ItemVariant is one sellable version of an item, such as a size and colour.
+// A variant has its own SKU and stock and may override the item's price. The
+// stock of an item with variants is the sum of its variants' stock.
+type ItemVariant struct {
+	Basemodel
+	ItemID  uuid.UUID         `json:"item_id" gorm:"not null;size:191;index"`
+	SKU     string            `json:"sku" gorm:"not null;size:191;uniqueIndex"`
+	Options map[string]string `json:"options" gorm:"type:json;serializer:json"`
+	// Price overrides the item's price when set.
+	Price *money.Money `json:"price" swaggertype:"number"`
+	Stock int          `json:"stock" gorm:"not null"`
+}
+
+func (iv *ItemVariant) BeforeCreate(tx *gorm.DB) (err error) {
+	iv.ID = uuid.New()
+	iv.CreatedAt = time.Now()
+
+	return
+}
+
+// UnitPrice is the price the variant sells for.
+func (iv *ItemVariant) UnitPrice(item *Item) money.Money {
+	if iv.Price != nil {
+		return *iv.Price
+	}
+	return item.Price
+}
+
+// Label describes the variant by its options, e.g. "colour: red, size: M".
+func (iv *ItemVariant) Label() string {
+	names := make([]string, 0, len(iv.Options))
+	for name := range iv.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+": "+iv.Options[name])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type LowStockAlertStatus string
+
+const (
+	LowStockAlertStatusPending LowStockAlertStatus = "pending"
+	LowStockAlertStatusSent    LowStockAlertStatus = "sent"
+	LowStockAlertStatusFailed  LowStockAlertStatus = "failed"
+)
+
+// LowStockAlert is raised when an item's stock falls to or below its reorder
+// threshold. An item has at most one open alert, the one without ResolvedAt;
+// it is resolved once the item is restocked above the threshold, so the next
+// drop raises a new alert. Alerts are delivered by the inventory alert
+// worker, which retries failed deliveries with backoff like the email outbox.
+type LowStockAlert struct {
+	Basemodel
+	ItemID     uuid.UUID  `json:"item_id" gorm:"not null;size:191;index"`
+	ItemName   string     `json:"item_name" gorm:"not null;size:191"`
+	SKU        *string    `json:"sku,omitempty" gorm:"size:100"`
+	Stock      int        `json:"stock" gorm:"not null"`
+	Threshold  int        `json:"threshold" gorm:"not null"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+
+	Status        LowStockAlertStatus `json:"status" gorm:"not null;size:16;default:pending;index:idx_low_stock_alerts_due,priority:1"`
+	NextAttemptAt time.Time           `json:"next_attempt_at" gorm:"not null;index:idx_low_stock_alerts_due,priority:2"`
+	Attempts      int                 `json:"attempts" gorm:"not null;default:0"`
+	LastError     string              `json:"last_error" gorm:"type:text"`
+	SentAt        *time.Time          `json:"sent_at"`
+}
+
+func (a *LowStockAlert) BeforeCreate(tx *gorm.DB) (err error) {
+	a.ID = uuid.New()
+	a.CreatedAt = time.Now()
+
+	return
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordResetToken is a single-use token that lets a user choose a new
+// password. Only the SHA-256 of the token is stored; the token itself is only
+// ever sent to the user's email address.
+type PasswordResetToken struct {
+	Basemodel
+	UserID    uuid.UUID  `json:"user_id" gorm:"not null;size:191;index"`
+	TokenHash string     `json:"-" gorm:"not null;size:64;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+func (t *PasswordResetToken) BeforeCreate(tx *gorm.DB) (err error) {
+	t.ID = uuid.New()
+	t.CreatedAt = time.Now()
+
+	return
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PaymentEvent remembers every processed webhook event so that redelivered
+// events are acknowledged without being applied twice.
+type PaymentEvent struct {
+	Basemodel
+	Provider      string    `json:"provider" gorm:"not null;size:64;uniqueIndex:idx_payment_event_provider_event"`
+	EventID       string    `json:"event_id" gorm:"not null;size:191;uniqueIndex:idx_payment_event_provider_event"`
+	Type          string    `json:"type" gorm:"not null;size:64"`
+	TransactionID uuid.UUID `json:"transaction_id" gorm:"not null;size:191;index"`
+}
+
+func (e *PaymentEvent) BeforeCreate(tx *gorm.DB) (err error) {
+	e.ID = uuid.New()
+	e.CreatedAt = time.Now()
+
+	return
+}
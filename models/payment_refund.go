@@ -0,0 +1,40 @@
+package models
+
+import (
+	"ordent/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PaymentRefundStatus string
+
+const (
+	// PaymentRefundStatusPending is a refund that was reserved but not yet
+	// accepted by the payment provider.
+	PaymentRefundStatusPending   PaymentRefundStatus = "pending"
+	PaymentRefundStatusSucceeded PaymentRefundStatus = "succeeded"
+	PaymentRefundStatusFailed    PaymentRefundStatus = "failed"
+)
+
+// PaymentRefund is a refund of a charge. Pending and succeeded refunds are
+// summed to tell how much of a charge is left to refund, so a refund is
+// recorded as pending before the provider is asked for it and settled once
+// the provider answers. RefundID is empty until then.
+type PaymentRefund struct {
+	Basemodel
+	TransactionID uuid.UUID           `json:"transaction_id" gorm:"not null;size:191;index"`
+	Provider      string              `json:"provider" gorm:"not null;size:64"`
+	ChargeID      string              `json:"charge_id" gorm:"not null;size:191;index"`
+	RefundID      string              `json:"refund_id" gorm:"not null;size:191"`
+	Amount        money.Money         `json:"amount" gorm:"not null" swaggertype:"number"`
+	Status        PaymentRefundStatus `json:"status" gorm:"not null;size:16;default:succeeded"`
+}
+
+func (r *PaymentRefund) BeforeCreate(tx *gorm.DB) (err error) {
+	r.ID = uuid.New()
+	r.CreatedAt = time.Now()
+
+	return
+}
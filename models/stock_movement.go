@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StockMovementReason tells why stock changed.
+type StockMovementReason string
+
+const (
+	StockMovementSale       StockMovementReason = "sale"
+	StockMovementRestock    StockMovementReason = "restock"
+	StockMovementAdjustment StockMovementReason = "adjustment"
+	StockMovementReturn     StockMovementReason = "return"
+	StockMovementImport     StockMovementReason = "import"
+)
+
+func (r StockMovementReason) IsValid() bool {
+	switch r {
+	case StockMovementSale, StockMovementRestock, StockMovementAdjustment, StockMovementReturn, StockMovementImport:
+		return true
+	}
+	return false
+}
+
+// IsManual tells whether admins may post movements with this reason
+// themselves. Sales and imports are only recorded by checkout and the
+// importer.
+func (r StockMovementReason) IsManual() bool {
+	switch r {
+	case StockMovementRestock, StockMovementAdjustment, StockMovementReturn:
+		return true
+	}
+	return false
+}
+
+// StockMovement is an entry in the append-only stock ledger. Movements with a
+// VariantID explain the variant's stock; the others explain the item's own
+// stock, which for an item with variants is the sum of theirs. Balance is
+// the stock right after the movement.
+type StockMovement struct {
+	Basemodel
+	ItemID        uuid.UUID           `json:"item_id" gorm:"not null;size:191;index"`
+	VariantID     *uuid.UUID          `json:"variant_id" gorm:"size:191;index"`
+	Delta         int                 `json:"delta" gorm:"not null"`
+	Balance       int                 `json:"balance" gorm:"not null"`
+	Reason        StockMovementReason `json:"reason" gorm:"not null;size:20"`
+	TransactionID *uuid.UUID          `json:"transaction_id" gorm:"size:191;index"`
+	UserID        *uuid.UUID          `json:"user_id" gorm:"size:191"`
+	Note          string              `json:"note" gorm:"size:255"`
+}
+
+func (m *StockMovement) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	m.CreatedAt = time.Now()
+
+	return
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Tag is a free-form label on items, identified by its slug.
+type Tag struct {
+	Basemodel
+	Name string `json:"name" gorm:"not null;size:191"`
+	Slug string `json:"slug" gorm:"not null;size:191;uniqueIndex"`
+}
+
+func (t *Tag) BeforeCreate(tx *gorm.DB) (err error) {
+	t.ID = uuid.New()
+	t.CreatedAt = time.Now()
+
+	return
+}
+
+// ItemTag links an item to one of its tags.
+type ItemTag struct {
+	ItemID uuid.UUID `gorm:"primaryKey;size:191"`
+	TagID  uuid.UUID `gorm:"primaryKey;size:191;index"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TaxRate is an admin-defined tax such as VAT/PPN, in basis points
+// (1100 = 11%). Items without their own rate are taxed at the default rate;
+// at most one rate is the default.
+type TaxRate struct {
+	Basemodel
+	Name      string `json:"name" gorm:"not null"`
+	RateBps   int    `json:"rate_bps" gorm:"not null"`
+	IsDefault bool   `json:"is_default" gorm:"not null;default:false"`
+}
+
+func (t *TaxRate) BeforeCreate(tx *gorm.DB) (err error) {
+	t.ID = uuid.New()
+	t.CreatedAt = time.Now()
+
+	return
+}
@@ -1,18 +1,86 @@
 package models
 
 import (
+	"ordent/money"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+type TransactionStatus string
+
+const (
+	TransactionStatusPending           TransactionStatus = "pending"
+	TransactionStatusPaid              TransactionStatus = "paid"
+	TransactionStatusFailed            TransactionStatus = "failed"
+	TransactionStatusExpired           TransactionStatus = "expired"
+	TransactionStatusCancelled         TransactionStatus = "cancelled"
+	TransactionStatusRefunded          TransactionStatus = "refunded"
+	TransactionStatusPartiallyRefunded TransactionStatus = "partially_refunded"
+)
+
+// transactionStatusTransitions lists, for every status, the statuses a
+// transaction may move to next. Statuses without an entry are final.
+var transactionStatusTransitions = map[TransactionStatus][]TransactionStatus{
+	TransactionStatusPending: {
+		TransactionStatusPaid,
+		TransactionStatusFailed,
+		TransactionStatusExpired,
+		TransactionStatusCancelled,
+	},
+	TransactionStatusPaid: {
+		TransactionStatusPartiallyRefunded,
+		TransactionStatusRefunded,
+	},
+	TransactionStatusPartiallyRefunded: {
+		TransactionStatusPartiallyRefunded,
+		TransactionStatusRefunded,
+	},
+}
+
+func (s TransactionStatus) IsValid() bool {
+	switch s {
+	case TransactionStatusPending, TransactionStatusPaid, TransactionStatusFailed, TransactionStatusExpired,
+		TransactionStatusCancelled, TransactionStatusRefunded, TransactionStatusPartiallyRefunded:
+		return true
+	}
+	return false
+}
+
+func (s TransactionStatus) CanTransitionTo(next TransactionStatus) bool {
+	for _, allowed := range transactionStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 type Transaction struct {
 	Basemodel
-	TotalPrice         float64             `json:"total_price" gorm:"not null"`
-	IsSuccessPaid      bool                `json:"is_success_paid" gorm:"default:false"`
-	UserID             uuid.UUID           `json:"user_id" gorm:"not null;size:191"`
-	TransactionDetails []TransactionDetail `json:"transaction_details" gorm:"foreignKey:TransactionID"`
+	SubtotalPrice       money.Money                `json:"subtotal_price" gorm:"not null;default:0" swaggertype:"number"`
+	DiscountAmount      money.Money                `json:"discount_amount" gorm:"not null;default:0" swaggertype:"number"`
+	NetAmount           money.Money                `json:"net_amount" gorm:"not null;default:0" swaggertype:"number"`
+	TaxAmount           money.Money                `json:"tax_amount" gorm:"not null;default:0" swaggertype:"number"`
+	ShippingFee         money.Money                `json:"shipping_fee" gorm:"not null;default:0" swaggertype:"number"`
+	TotalPrice          money.Money                `json:"total_price" gorm:"not null" swaggertype:"number"`
+	Currency            string                     `json:"currency" gorm:"size:3"`
+	Status              TransactionStatus          `json:"status" gorm:"not null;size:32;default:pending;index"`
+	PaymentProvider     string                     `json:"payment_provider" gorm:"size:64"`
+	PaymentChargeID     string                     `json:"payment_charge_id" gorm:"size:191;index"`
+	ShippingProvider    string                     `json:"shipping_provider" gorm:"size:64"`
+	ShippingService     string                     `json:"shipping_service" gorm:"size:64"`
+	ShippingWeightGrams int                        `json:"shipping_weight_grams" gorm:"not null;default:0"`
+	FulfillmentStatus   FulfillmentStatus          `json:"fulfillment_status" gorm:"size:32;index"`
+	Carrier             string                     `json:"carrier" gorm:"size:64"`
+	TrackingNumber      string                     `json:"tracking_number" gorm:"size:128"`
+	ShippingAddress     PostalAddress              `json:"shipping_address" gorm:"embedded;embeddedPrefix:shipping_"`
+	UserID              uuid.UUID                  `json:"user_id" gorm:"not null;size:191"`
+	TransactionDetails  []TransactionDetail        `json:"transaction_details" gorm:"foreignKey:TransactionID"`
+	StatusHistories     []TransactionStatusHistory `json:"status_histories" gorm:"foreignKey:TransactionID"`
+	FulfillmentEvents   []FulfillmentEvent         `json:"fulfillment_events" gorm:"foreignKey:TransactionID"`
+	CouponRedemptions   []CouponRedemption         `json:"coupon_redemptions" gorm:"foreignKey:TransactionID"`
 }
 
 func (t *Transaction) BeforeCreate(tx *gorm.DB) (err error) {
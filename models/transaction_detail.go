@@ -1,6 +1,7 @@
 package models
 
 import (
+	"ordent/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,9 +13,18 @@ type TransactionDetail struct {
 	TransactionID uuid.UUID `json:"transaction_id" gorm:"not null;size:191"`
 	ItemID        uuid.UUID `json:"item_id" gorm:"not null;size:191"`
 	Item          Item      `json:"item"`
-	Quantity      int       `json:"quantity" gorm:"not null"`
-	PricePerUnit  float64   `json:"price_per_unit" gorm:"not null"`
-	TotalPrice    float64   `json:"total_price" gorm:"not null"`
+	// VariantID is the variant that was ordered, when the item has variants.
+	VariantID      *uuid.UUID   `json:"variant_id" gorm:"size:191;index"`
+	Variant        *ItemVariant `json:"variant,omitempty"`
+	Quantity       int          `json:"quantity" gorm:"not null"`
+	PricePerUnit   money.Money  `json:"price_per_unit" gorm:"not null" swaggertype:"number"`
+	DiscountAmount money.Money  `json:"discount_amount" gorm:"not null;default:0" swaggertype:"number"`
+	TaxMode        TaxMode      `json:"tax_mode" gorm:"not null;size:20;default:exclusive"`
+	TaxRateBps     int          `json:"tax_rate_bps" gorm:"not null;default:0"`
+	NetAmount      money.Money  `json:"net_amount" gorm:"not null;default:0" swaggertype:"number"`
+	TaxAmount      money.Money  `json:"tax_amount" gorm:"not null;default:0" swaggertype:"number"`
+	// TotalPrice is the gross line amount: after discounts, including tax.
+	TotalPrice money.Money `json:"total_price" gorm:"not null" swaggertype:"number"`
 }
 
 func (td *TransactionDetail) BeforeCreate(tx *gorm.DB) (err error) {
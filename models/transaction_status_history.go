@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TransactionStatusHistory records one status change of a transaction. The
+// first entry of every transaction has an empty FromStatus.
+type TransactionStatusHistory struct {
+	Basemodel
+	TransactionID uuid.UUID         `json:"transaction_id" gorm:"not null;size:191;index"`
+	FromStatus    TransactionStatus `json:"from_status" gorm:"size:32"`
+	ToStatus      TransactionStatus `json:"to_status" gorm:"not null;size:32"`
+	Note          string            `json:"note"`
+}
+
+func (h *TransactionStatusHistory) BeforeCreate(tx *gorm.DB) (err error) {
+	h.ID = uuid.New()
+	h.CreatedAt = time.Now()
+
+	return
+}
@@ -22,8 +22,15 @@ func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 	u.ID = uuid.New()
 	u.CreatedAt = time.Now()
 
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(u.Password), 14)
-
-	u.Password = string(hashedPassword)
+	u.Password, err = HashPassword(u.Password)
 	return
 }
+
+// HashPassword returns the bcrypt hash stored in User.Password.
+func HashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
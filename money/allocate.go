@@ -0,0 +1,56 @@
+package money
+
+import (
+	"math/big"
+	"sort"
+)
+
+// Allocate splits total into parts proportional to weights. The parts always
+// add up to total exactly; minor units left over after rounding down go to the
+// parts with the largest remainders. When all weights are zero the whole
+// amount goes to the first part.
+func Allocate(total Money, weights []Money) []Money {
+	parts := make([]Money, len(weights))
+	if len(weights) == 0 {
+		return parts
+	}
+
+	sum := new(big.Int)
+	for _, weight := range weights {
+		sum.Add(sum, big.NewInt(int64(weight)))
+	}
+
+	if sum.Sign() == 0 {
+		parts[0] = total
+		return parts
+	}
+
+	remainders := make([]*big.Int, len(weights))
+	allocated := Money(0)
+	for i, weight := range weights {
+		product := new(big.Int).Mul(big.NewInt(int64(total)), big.NewInt(int64(weight)))
+		quotient, remainder := new(big.Int).QuoRem(product, sum, new(big.Int))
+		parts[i] = Money(quotient.Int64())
+		remainders[i] = remainder.Abs(remainder)
+		allocated += parts[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]].Cmp(remainders[order[b]]) > 0
+	})
+
+	step := Money(1)
+	if total < 0 {
+		step = -1
+	}
+	for i := 0; allocated != total; i++ {
+		parts[order[i%len(order)]] += step
+		allocated += step
+	}
+
+	return parts
+}
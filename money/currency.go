@@ -0,0 +1,69 @@
+package money
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds ties away from zero.
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds ties to the nearest even multiple (banker's
+	// rounding).
+	RoundHalfEven
+)
+
+// Currency describes how amounts of one currency are stored and rounded.
+// Exponent is the number of decimal places of the minor unit and
+// RoundingIncrement the smallest multiple of minor units a computed amount is
+// rounded to.
+type Currency struct {
+	Code              string
+	Exponent          int
+	RoundingIncrement int64
+	Rounding          RoundingMode
+}
+
+var currencies = map[string]Currency{
+	"IDR": {Code: "IDR", Exponent: 2, RoundingIncrement: 1, Rounding: RoundHalfUp},
+	"USD": {Code: "USD", Exponent: 2, RoundingIncrement: 1, Rounding: RoundHalfUp},
+	"EUR": {Code: "EUR", Exponent: 2, RoundingIncrement: 1, Rounding: RoundHalfEven},
+	"SGD": {Code: "SGD", Exponent: 2, RoundingIncrement: 1, Rounding: RoundHalfUp},
+	"CHF": {Code: "CHF", Exponent: 2, RoundingIncrement: 5, Rounding: RoundHalfUp},
+	"JPY": {Code: "JPY", Exponent: 0, RoundingIncrement: 1, Rounding: RoundHalfUp},
+	"KWD": {Code: "KWD", Exponent: 3, RoundingIncrement: 1, Rounding: RoundHalfUp},
+}
+
+var defaultCurrency atomic.Value
+
+func init() {
+	defaultCurrency.Store(currencies["IDR"])
+}
+
+func LookupCurrency(code string) (Currency, error) {
+	currency, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, fmt.Errorf("unsupported currency %q", code)
+	}
+	return currency, nil
+}
+
+// DefaultCurrency is the store currency every Money amount is expressed in.
+func DefaultCurrency() Currency {
+	return defaultCurrency.Load().(Currency)
+}
+
+// SetDefaultCurrency must be called before any amount is parsed or stored,
+// since changing it reinterprets existing minor unit values.
+func SetDefaultCurrency(code string) error {
+	currency, err := LookupCurrency(code)
+	if err != nil {
+		return err
+	}
+
+	defaultCurrency.Store(currency)
+	return nil
+}
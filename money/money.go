@@ -0,0 +1,275 @@
+// Package money represents amounts of the store currency exactly, as an
+// integer number of minor units (e.g. cents), instead of float64.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount = errors.New("invalid money amount")
+	ErrTooPrecise    = errors.New("money amount has more decimal places than the currency allows")
+	ErrOverflow      = errors.New("money amount out of range")
+)
+
+// Money is an amount in minor units of the configured currency. Its JSON form
+// is a decimal number with exactly the currency's number of decimal places,
+// and it is stored as BIGINT.
+type Money int64
+
+func FromMinor(minor int64) Money {
+	return Money(minor)
+}
+
+// Parse reads a decimal string such as "12", "-3.5" or "0.30" exactly. More
+// decimal places than the currency has are rejected unless they are zeros.
+func Parse(value string) (Money, error) {
+	currency := DefaultCurrency()
+
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" && fraction == "" {
+		return 0, ErrInvalidAmount
+	}
+	if !isDigits(whole) || !isDigits(fraction) {
+		return 0, ErrInvalidAmount
+	}
+
+	if len(fraction) > currency.Exponent {
+		if strings.Trim(fraction[currency.Exponent:], "0") != "" {
+			return 0, ErrTooPrecise
+		}
+		fraction = fraction[:currency.Exponent]
+	}
+	fraction += strings.Repeat("0", currency.Exponent-len(fraction))
+
+	digits := strings.TrimLeft(whole+fraction, "0")
+	if digits == "" {
+		return 0, nil
+	}
+
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, ErrOverflow
+	}
+
+	if negative {
+		minor = -minor
+	}
+	return Money(minor), nil
+}
+
+func MustParse(value string) Money {
+	m, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) Minor() int64 {
+	return int64(m)
+}
+
+func (m Money) IsZero() bool {
+	return m == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m < 0
+}
+
+func (m Money) Add(other Money) Money {
+	return m + other
+}
+
+func (m Money) Sub(other Money) Money {
+	return m - other
+}
+
+// Mul multiplies by a whole quantity, which never needs rounding.
+func (m Money) Mul(quantity int64) Money {
+	return m * Money(quantity)
+}
+
+// MulRatio returns m * numerator / denominator rounded with the currency's
+// rounding rules. It is meant for percentages, e.g. MulRatio(1100, 10000) for
+// 11%.
+func (m Money) MulRatio(numerator int64, denominator int64) Money {
+	product := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(numerator))
+	return roundQuotient(product, big.NewInt(denominator), DefaultCurrency())
+}
+
+// Round applies the currency's rounding increment, e.g. cash rounding to
+// 5 minor units. Amounts that are already multiples are returned unchanged.
+func (m Money) Round() Money {
+	return roundQuotient(big.NewInt(int64(m)), big.NewInt(1), DefaultCurrency())
+}
+
+// roundQuotient rounds numerator/denominator to a multiple of the currency's
+// rounding increment.
+func roundQuotient(numerator *big.Int, denominator *big.Int, currency Currency) Money {
+	increment := currency.RoundingIncrement
+	if increment <= 0 {
+		increment = 1
+	}
+	denominator = new(big.Int).Mul(denominator, big.NewInt(increment))
+	if denominator.Sign() < 0 {
+		denominator.Neg(denominator)
+		numerator = new(big.Int).Neg(numerator)
+	}
+
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+
+	// Compare twice the remainder with the denominator to decide between the
+	// two neighbouring multiples.
+	twiceRemainder := new(big.Int).Abs(remainder)
+	twiceRemainder.Lsh(twiceRemainder, 1)
+	cmp := twiceRemainder.Cmp(denominator)
+
+	roundAway := false
+	switch currency.Rounding {
+	case RoundHalfEven:
+		roundAway = cmp > 0 || (cmp == 0 && quotient.Bit(0) == 1)
+	default:
+		roundAway = cmp >= 0
+	}
+
+	if roundAway && remainder.Sign() != 0 {
+		if numerator.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	quotient.Mul(quotient, big.NewInt(increment))
+	if !quotient.IsInt64() {
+		panic(ErrOverflow)
+	}
+	return Money(quotient.Int64())
+}
+
+// String formats the amount with the currency's decimal places, e.g. "12.50".
+func (m Money) String() string {
+	exponent := DefaultCurrency().Exponent
+
+	minor := int64(m)
+	sign := ""
+	var abs uint64
+	if minor < 0 {
+		sign = "-"
+		abs = uint64(-(minor + 1)) + 1
+	} else {
+		abs = uint64(minor)
+	}
+
+	digits := strconv.FormatUint(abs, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	point := len(digits) - exponent
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a string holding one. The digits are
+// parsed directly, so 0.1 is exactly ten cents rather than a float
+// approximation.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	value := strings.TrimSpace(string(data))
+	if value == "null" {
+		return nil
+	}
+
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+
+	if strings.ContainsAny(value, "eE") {
+		return fmt.Errorf("%w: exponent notation is not supported", ErrInvalidAmount)
+	}
+
+	parsed, err := Parse(value)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+func (m *Money) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v)
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case float64:
+		// Only reached for columns that were never converted to minor units.
+		if math.Trunc(v) != v {
+			return fmt.Errorf("%w: %v is not a whole number of minor units", ErrInvalidAmount, v)
+		}
+		*m = Money(int64(v))
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidAmount, value)
+	}
+	return nil
+}
+
+func (m *Money) scanString(value string) error {
+	minor, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidAmount, value)
+	}
+
+	*m = Money(minor)
+	return nil
+}
+
+func (Money) GormDataType() string {
+	return "bigint"
+}
@@ -0,0 +1,298 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// useCurrency switches the default currency for the rest of the test.
+func useCurrency(t *testing.T, code string) {
+	t.Helper()
+
+	previous := DefaultCurrency().Code
+	if err := SetDefaultCurrency(code); err != nil {
+		t.Fatalf("set currency %s: %v", code, err)
+	}
+	t.Cleanup(func() {
+		if err := SetDefaultCurrency(previous); err != nil {
+			t.Fatalf("restore currency %s: %v", previous, err)
+		}
+	})
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		value    string
+		want     Money
+		wantErr  error
+	}{
+		{"whole", "IDR", "12", 1200, nil},
+		{"one decimal", "IDR", "-3.5", -350, nil},
+		{"two decimals", "IDR", "0.30", 30, nil},
+		{"plus sign", "IDR", "+1.05", 105, nil},
+		{"no whole part", "IDR", ".5", 50, nil},
+		{"no fraction", "IDR", "5.", 500, nil},
+		{"surrounding space", "IDR", " 7 ", 700, nil},
+		{"extra zeros", "IDR", "1.500", 150, nil},
+		{"negative zero", "IDR", "-0.00", 0, nil},
+		{"too precise", "IDR", "1.005", 0, ErrTooPrecise},
+		{"empty", "IDR", "", 0, ErrInvalidAmount},
+		{"only a sign", "IDR", "-", 0, ErrInvalidAmount},
+		{"decimal comma", "IDR", "1,5", 0, ErrInvalidAmount},
+		{"letters", "IDR", "abc", 0, ErrInvalidAmount},
+		{"exponent", "IDR", "1e3", 0, ErrInvalidAmount},
+		{"overflow", "IDR", "99999999999999999999", 0, ErrOverflow},
+		{"zero exponent whole", "JPY", "150", 150, nil},
+		{"zero exponent negative", "JPY", "-3", -3, nil},
+		{"zero exponent zero fraction", "JPY", "150.0", 150, nil},
+		{"zero exponent fraction", "JPY", "150.5", 0, ErrTooPrecise},
+		{"three decimals", "KWD", "1.234", 1234, nil},
+		{"three decimals padded", "KWD", "-0.5", -500, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCurrency(t, tt.currency)
+
+			got, err := Parse(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse(%q) error = %v, want %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		amount   Money
+		want     string
+	}{
+		{"zero", "IDR", 0, "0.00"},
+		{"minor units only", "IDR", 5, "0.05"},
+		{"negative minor units only", "IDR", -5, "-0.05"},
+		{"negative", "IDR", -1205, "-12.05"},
+		{"smallest", "IDR", math.MinInt64, "-92233720368547758.08"},
+		{"zero exponent", "JPY", 150, "150"},
+		{"zero exponent negative", "JPY", -150, "-150"},
+		{"three decimals", "KWD", 1, "0.001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCurrency(t, tt.currency)
+
+			if got := tt.amount.String(); got != tt.want {
+				t.Errorf("Money(%d).String() = %q, want %q", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddIsExact(t *testing.T) {
+	sum := MustParse("0.1").Add(MustParse("0.2"))
+	if sum != MustParse("0.3") {
+		t.Errorf("0.1 + 0.2 = %s, want 0.30", sum)
+	}
+
+	var a, b, c Money
+	for value, target := range map[string]*Money{"0.1": &a, "0.2": &b, "0.3": &c} {
+		if err := json.Unmarshal([]byte(value), target); err != nil {
+			t.Fatalf("unmarshal %s: %v", value, err)
+		}
+	}
+	if a.Add(b) != c {
+		t.Errorf("JSON 0.1 + 0.2 = %s, want %s", a.Add(b), c)
+	}
+}
+
+func TestMulRatio(t *testing.T) {
+	tests := []struct {
+		name        string
+		currency    string
+		amount      Money
+		numerator   int64
+		denominator int64
+		want        Money
+	}{
+		{"exact percentage", "IDR", 1000, 1100, 10000, 110},
+		{"rounds down below half", "IDR", 7, 1, 3, 2},
+		{"half up rounds ties away from zero", "IDR", 5, 1, 2, 3},
+		{"half up rounds negative ties away from zero", "IDR", -5, 1, 2, -3},
+		{"negative denominator", "IDR", 5, 1, -2, -3},
+		{"half even rounds ties down to even", "EUR", 5, 1, 2, 2},
+		{"half even rounds ties up to even", "EUR", 7, 1, 2, 4},
+		{"half even negative tie", "EUR", -5, 1, 2, -2},
+		{"half even above half", "EUR", 7, 2, 3, 5},
+		{"zero exponent", "JPY", 999, 1100, 10000, 110},
+		{"rounding increment", "CHF", 1000, 1100, 10000, 110},
+		{"rounding increment rounds to a multiple", "CHF", 1003, 1, 1, 1005},
+		{"rounding increment tie", "CHF", 1005, 1, 2, 505},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCurrency(t, tt.currency)
+
+			if got := tt.amount.MulRatio(tt.numerator, tt.denominator); got != tt.want {
+				t.Errorf("Money(%d).MulRatio(%d, %d) = %d, want %d", tt.amount, tt.numerator, tt.denominator, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		amount   Money
+		want     Money
+	}{
+		{"increment of one is unchanged", "IDR", 1003, 1003},
+		{"already a multiple", "CHF", 1005, 1005},
+		{"rounds down", "CHF", 1002, 1000},
+		{"rounds up", "CHF", 1003, 1005},
+		{"negative rounds away from zero", "CHF", -1003, -1005},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCurrency(t, tt.currency)
+
+			if got := tt.amount.Round(); got != tt.want {
+				t.Errorf("Money(%d).Round() = %d, want %d", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   Money
+		weights []Money
+		want    []Money
+	}{
+		{"no weights", 100, nil, []Money{}},
+		{"exact split", 10, []Money{3, 7}, []Money{3, 7}},
+		{"equal remainders go to the first parts", 100, []Money{1, 1, 1}, []Money{34, 33, 33}},
+		{"largest remainder gets the extra unit", 100, []Money{1, 2, 4}, []Money{14, 29, 57}},
+		{"negative total", -100, []Money{1, 1, 1}, []Money{-34, -33, -33}},
+		{"zero weight gets nothing", 100, []Money{0, 1, 1}, []Money{0, 50, 50}},
+		{"all weights zero", 1000, []Money{0, 0}, []Money{1000, 0}},
+		{"zero total", 0, []Money{5, 5}, []Money{0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Allocate(tt.total, tt.weights)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Allocate(%d, %v) = %v, want %v", tt.total, tt.weights, got, tt.want)
+			}
+
+			sum := Money(0)
+			for _, part := range got {
+				sum += part
+			}
+			if len(got) > 0 && sum != tt.total {
+				t.Errorf("parts add up to %d, want %d", sum, tt.total)
+			}
+		})
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		value    interface{}
+		want     string
+	}{
+		{"amount", "IDR", Money(1250), `12.50`},
+		{"negative", "IDR", Money(-1), `-0.01`},
+		{"zero exponent", "JPY", Money(150), `150`},
+		{"field", "IDR", struct {
+			Price Money `json:"price"`
+		}{1250}, `{"price":12.50}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCurrency(t, tt.currency)
+
+			got, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("marshal = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		data     string
+		want     Money
+		wantErr  error
+	}{
+		{"number", "IDR", `12.5`, 1250, nil},
+		{"string", "IDR", `"12.50"`, 1250, nil},
+		{"negative", "IDR", `-0.01`, -1, nil},
+		{"null keeps the value", "IDR", `null`, 42, nil},
+		{"exponent", "IDR", `1e3`, 42, ErrInvalidAmount},
+		{"too precise", "IDR", `12.505`, 42, ErrTooPrecise},
+		{"zero exponent", "JPY", `150`, 150, nil},
+		{"zero exponent fraction", "JPY", `150.5`, 42, ErrTooPrecise},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCurrency(t, tt.currency)
+
+			got := Money(42)
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("unmarshal %s error = %v, want %v", tt.data, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("unmarshal %s = %d, want %d", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, code := range []string{"IDR", "JPY", "KWD"} {
+		t.Run(code, func(t *testing.T) {
+			useCurrency(t, code)
+
+			for _, amount := range []Money{0, 1, -1, 12345, -98765, math.MaxInt64, math.MinInt64 + 1} {
+				data, err := json.Marshal(amount)
+				if err != nil {
+					t.Fatalf("marshal %d: %v", amount, err)
+				}
+
+				var got Money
+				if err := json.Unmarshal(data, &got); err != nil {
+					t.Fatalf("unmarshal %s: %v", data, err)
+				}
+				if got != amount {
+					t.Errorf("round trip of %d via %s = %d", amount, data, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer writes every message as an .eml file into a directory instead of
+// sending it. It is meant for local development: the files open in any mail
+// client.
+type FileMailer struct {
+	dir     string
+	counter atomic.Uint64
+}
+
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, message Message) error {
+	raw, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+
+	recipient := message.To
+	if address, err := mail.ParseAddress(message.To); err == nil {
+		recipient = address.Address
+	}
+	recipient = strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, recipient)
+
+	name := fmt.Sprintf("%s-%04d-%s.eml", time.Now().Format("20060102T150405"), m.counter.Add(1)%10000, recipient)
+
+	return os.WriteFile(filepath.Join(m.dir, name), raw, 0o644)
+}
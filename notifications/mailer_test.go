@@ -0,0 +1,190 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestMessageBytes(t *testing.T) {
+	longLine := strings.Repeat("x", 100) + " = done"
+
+	tests := []struct {
+		name      string
+		message   Message
+		wantTo    string
+		wantParts map[string]string
+	}{
+		{
+			name: "plain text",
+			message: Message{
+				From:    "Toko Café <no-reply@example.com>",
+				To:      `"Zoë" <zoe@example.com>`,
+				Subject: "Pesanan Anda – diterima",
+				Text:    "Hi Zoë,\n" + longLine + "\n",
+			},
+			wantTo:    `=?utf-8?q?Zo=C3=AB?= <zoe@example.com>`,
+			wantParts: map[string]string{"text/plain": "Hi Zoë,\n" + longLine + "\n"},
+		},
+		{
+			name: "text with an HTML alternative",
+			message: Message{
+				From:    "no-reply@example.com",
+				To:      "ana@example.com",
+				Subject: "Welcome",
+				Text:    "Hi Ana,\n",
+				HTML:    `<p style="margin:0">Hi Ana,</p>`,
+			},
+			wantTo: "<ana@example.com>",
+			wantParts: map[string]string{
+				"text/plain": "Hi Ana,\n",
+				"text/html":  `<p style="margin:0">Hi Ana,</p>`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := tt.message.Bytes()
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			for i, line := range bytes.Split(raw, []byte("\r\n")) {
+				if len(line) > 998 {
+					t.Errorf("line %d is %d characters long", i, len(line))
+				}
+			}
+			if bytes.Contains(raw, []byte(longLine)) {
+				t.Error("long body line was not wrapped")
+			}
+
+			parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			if got := parsed.Header.Get("To"); got != tt.wantTo {
+				t.Errorf("To = %q, want %q", got, tt.wantTo)
+			}
+			subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+			if err != nil || subject != tt.message.Subject {
+				t.Errorf("Subject decodes to %q (%v), want %q", subject, err, tt.message.Subject)
+			}
+			for _, header := range []string{"Date", "Message-ID", "MIME-Version"} {
+				if parsed.Header.Get(header) == "" {
+					t.Errorf("%s header is missing", header)
+				}
+			}
+			if !strings.HasSuffix(parsed.Header.Get("Message-ID"), "@example.com>") {
+				t.Errorf("Message-ID %q is not on the sender's domain", parsed.Header.Get("Message-ID"))
+			}
+
+			parts := readParts(t, parsed)
+			if len(parts) != len(tt.wantParts) {
+				t.Fatalf("message has parts %v, want %v", parts, tt.wantParts)
+			}
+			for contentType, want := range tt.wantParts {
+				if got := parts[contentType]; got != want {
+					t.Errorf("%s body = %q, want %q", contentType, got, want)
+				}
+			}
+		})
+	}
+}
+
+// readParts decodes the bodies of a message by content type.
+func readParts(t *testing.T, message *mail.Message) map[string]string {
+	t.Helper()
+
+	decode := func(encoding string, body io.Reader) string {
+		if encoding != "quoted-printable" {
+			t.Errorf("Content-Transfer-Encoding = %q, want quoted-printable", encoding)
+		}
+		decoded, err := io.ReadAll(quotedprintable.NewReader(body))
+		if err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		// Line ends are sent as CRLF.
+		return strings.ReplaceAll(string(decoded), "\r\n", "\n")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("parse Content-Type: %v", err)
+	}
+
+	parts := map[string]string{}
+	if mediaType != "multipart/alternative" {
+		if params["charset"] != "utf-8" {
+			t.Errorf("charset = %q, want utf-8", params["charset"])
+		}
+		parts[mediaType] = decode(message.Header.Get("Content-Transfer-Encoding"), message.Body)
+		return parts
+	}
+
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatalf("read part: %v", err)
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[partType] = decode(part.Header.Get("Content-Transfer-Encoding"), part)
+	}
+}
+
+func TestMessageBytesKeepsSubjectOnOneLine(t *testing.T) {
+	message := Message{
+		From:    "no-reply@example.com",
+		To:      "ana@example.com",
+		Subject: "Hello\r\nBcc: everyone@example.com",
+		Text:    "Hi\n",
+	}
+
+	raw, err := message.Bytes()
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if bcc := parsed.Header.Get("Bcc"); bcc != "" {
+		t.Errorf("subject injected a Bcc header %q", bcc)
+	}
+	if got := parsed.Header.Get("Subject"); got != "Hello Bcc: everyone@example.com" {
+		t.Errorf("Subject = %q", got)
+	}
+}
+
+func TestMessageBytesRejectsInvalidAddresses(t *testing.T) {
+	tests := []struct {
+		name    string
+		message Message
+	}{
+		{"sender", Message{From: "not an address", To: "ana@example.com"}},
+		{"recipient", Message{From: "no-reply@example.com", To: "ana@"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.message.Bytes(); err == nil {
+				t.Error("encoding succeeded")
+			}
+			if err := NewMemoryMailer().Send(context.Background(), tt.message); err == nil {
+				t.Error("memory mailer accepted the message")
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/mail"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/repositories"
+	"time"
+)
+
+// Options tunes the Notifier. Zero values fall back to the defaults below.
+type Options struct {
+	// From is the sender of every message, e.g. "Ordent <no-reply@example.com>".
+	From      string
+	StoreName string
+
+	// PollInterval is how often the worker looks for due messages when it is
+	// not woken up by a newly queued one.
+	PollInterval time.Duration
+	BatchSize    int
+	SendTimeout  time.Duration
+
+	// A failed delivery is retried after RetryBaseDelay, doubling on every
+	// further failure up to RetryMaxDelay, until MaxAttempts is reached.
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+func (o *Options) setDefaults() {
+	if o.PollInterval <= 0 {
+		o.PollInterval = 10 * time.Second
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 20
+	}
+	if o.SendTimeout <= 0 {
+		o.SendTimeout = 30 * time.Second
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 8
+	}
+	if o.RetryBaseDelay <= 0 {
+		o.RetryBaseDelay = time.Minute
+	}
+	if o.RetryMaxDelay <= 0 {
+		o.RetryMaxDelay = 2 * time.Hour
+	}
+}
+
+// Notifier renders emails into the outbox and delivers them in the
+// background. Queuing only writes to the database, so a mail outage never
+// fails the request that triggered the email; the worker started by Run keeps
+// retrying until the mailer accepts the message.
+type Notifier struct {
+	mailer    Mailer
+	templates *Templates
+	emailRepo repositories.EmailMessageRepository
+	userRepo  repositories.UserRepository
+	options   Options
+	wake      chan struct{}
+}
+
+func NewNotifier(mailer Mailer, emailRepo repositories.EmailMessageRepository, userRepo repositories.UserRepository, options Options) (*Notifier, error) {
+	if _, err := mail.ParseAddress(options.From); err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", options.From, err)
+	}
+
+	templates, err := LoadTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	options.setDefaults()
+
+	return &Notifier{
+		mailer:    mailer,
+		templates: templates,
+		emailRepo: emailRepo,
+		userRepo:  userRepo,
+		options:   options,
+		wake:      make(chan struct{}, 1),
+	}, nil
+}
+
+// Enqueue renders a template for one recipient and stores it in the outbox.
+func (n *Notifier) Enqueue(template string, name string, email string, data TemplateData) error {
+	message, err := n.render(template, name, email, data)
+	if err != nil {
+		return err
+	}
+
+	return n.queue([]*models.EmailMessage{message})
+}
+
+// render builds the outbox message of a template for one recipient.
+func (n *Notifier) render(template string, name string, email string, data TemplateData) (*models.EmailMessage, error) {
+	data.StoreName = n.options.StoreName
+	data.Name = name
+
+	message, err := n.templates.Render(template, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.EmailMessage{
+		Template: template,
+		From:     n.options.From,
+		To:       (&mail.Address{Name: name, Address: email}).String(),
+		Subject:  message.Subject,
+		TextBody: message.Text,
+		HTMLBody: message.HTML,
+	}, nil
+}
+
+// queue stores the messages in the outbox, all or none, and wakes the worker.
+func (n *Notifier) queue(messages []*models.EmailMessage) error {
+	if err := n.emailRepo.CreateEmailMessages(messages); err != nil {
+		return err
+	}
+
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Welcome greets a newly registered user.
+func (n *Notifier) Welcome(user *models.User) {
+	n.logFailure(TemplateWelcome, n.Enqueue(TemplateWelcome, user.FullName, user.Email, TemplateData{}))
+}
+
+// OrderConfirmation tells the buyer their order was placed.
+func (n *Notifier) OrderConfirmation(transaction *dto.TransactionResponse) {
+	n.notifyBuyer(TemplateOrderConfirmation, transaction)
+}
+
+// PaymentFailed tells the buyer their order was given up because the payment
+// did not go through.
+func (n *Notifier) PaymentFailed(transaction *dto.TransactionResponse) {
+	n.notifyBuyer(TemplatePaymentFailed, transaction)
+}
+
+// Shipped sends the buyer the carrier and tracking number of their order.
+func (n *Notifier) Shipped(transaction *dto.TransactionResponse) {
+	n.notifyBuyer(TemplateShipped, transaction)
+}
+
+// PasswordReset sends a password reset link that is valid for ttl.
+func (n *Notifier) PasswordReset(name string, email string, resetURL string, ttl time.Duration) {
+	n.logFailure(TemplatePasswordReset, n.Enqueue(TemplatePasswordReset, name, email, TemplateData{
+		ResetURL:  resetURL,
+		ExpiresIn: humanDuration(ttl),
+	}))
+}
+
+// LowStock tells the store admins that an item needs restocking. The emails
+// to all of them are queued together, so a retry after a failure to queue
+// never sends an admin the same alert twice. Unlike the buyer emails it
+// reports that failure, so the caller can retry.
+func (n *Notifier) LowStock(emails []string, alert *models.LowStockAlert) error {
+	messages := make([]*models.EmailMessage, 0, len(emails))
+	for _, email := range emails {
+		message, err := n.render(TemplateLowStock, "", email, TemplateData{Alert: alert})
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+
+	return n.queue(messages)
+}
+
+func (n *Notifier) notifyBuyer(template string, transaction *dto.TransactionResponse) {
+	user, err := n.userRepo.GetUserByID(transaction.UserID)
+	if err != nil {
+		n.logFailure(template, fmt.Errorf("fetch buyer of transaction %s: %w", transaction.ID, err))
+		return
+	}
+
+	n.logFailure(template, n.Enqueue(template, user.FullName, user.Email, TemplateData{Order: transaction}))
+}
+
+func (n *Notifier) logFailure(template string, err error) {
+	if err != nil {
+		log.Printf("notifications: failed to queue %s email: %v", template, err)
+	}
+}
+
+// Run delivers queued messages until ctx is cancelled. Several instances of
+// the application may run workers against the same outbox.
+func (n *Notifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		n.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-n.wake:
+		}
+	}
+}
+
+func (n *Notifier) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		// The lease outlives a whole batch of sends, so a message is never
+		// picked up twice while its worker is still busy with it.
+		lease := time.Duration(n.options.BatchSize+1) * n.options.SendTimeout
+
+		messages, err := n.emailRepo.ClaimDueEmailMessages(n.options.BatchSize, lease)
+		if err != nil {
+			log.Printf("notifications: failed to fetch queued emails: %v", err)
+			return
+		}
+
+		for _, message := range messages {
+			n.deliver(ctx, message)
+		}
+
+		if len(messages) < n.options.BatchSize {
+			return
+		}
+	}
+}
+
+func (n *Notifier) deliver(ctx context.Context, message models.EmailMessage) {
+	sendCtx, cancel := context.WithTimeout(ctx, n.options.SendTimeout)
+	defer cancel()
+
+	err := n.mailer.Send(sendCtx, Message{
+		From:    message.From,
+		To:      message.To,
+		Subject: message.Subject,
+		Text:    message.TextBody,
+		HTML:    message.HTMLBody,
+	})
+	if err == nil {
+		if err := n.emailRepo.MarkEmailMessageSent(message.ID); err != nil {
+			log.Printf("notifications: email %s was sent but could not be marked sent: %v", message.ID, err)
+		}
+		return
+	}
+
+	var nextAttemptAt *time.Time
+	if message.Attempts < n.options.MaxAttempts {
+		next := time.Now().Add(n.retryDelay(message.Attempts))
+		nextAttemptAt = &next
+		log.Printf("notifications: sending email %s failed (attempt %d), retrying at %s: %v", message.ID, message.Attempts, next.Format(time.RFC3339), err)
+	} else {
+		log.Printf("notifications: giving up on email %s after %d attempts: %v", message.ID, message.Attempts, err)
+	}
+
+	if err := n.emailRepo.MarkEmailMessageFailed(message.ID, err.Error(), nextAttemptAt); err != nil {
+		log.Printf("notifications: failed to record delivery failure of email %s: %v", message.ID, err)
+	}
+}
+
+// retryDelay is the wait after the given number of failed attempts.
+func (n *Notifier) retryDelay(attempts int) time.Duration {
+	delay := n.options.RetryBaseDelay
+	for i := 1; i < attempts && delay < n.options.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, n.options.RetryMaxDelay)
+}
+
+func humanDuration(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		if d == time.Hour {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	case d >= time.Minute:
+		minutes := int(d.Round(time.Minute) / time.Minute)
+		if minutes == 1 {
+			return "1 minute"
+		}
+		return fmt.Sprintf("%d minutes", minutes)
+	}
+	return d.String()
+}
@@ -0,0 +1,325 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"ordent/models"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// memoryOutbox is an EmailMessageRepository that keeps the outbox in memory
+// and claims messages the way the database does.
+type memoryOutbox struct {
+	mu       sync.Mutex
+	messages map[uuid.UUID]*models.EmailMessage
+}
+
+func newMemoryOutbox() *memoryOutbox {
+	return &memoryOutbox{messages: map[uuid.UUID]*models.EmailMessage{}}
+}
+
+func (o *memoryOutbox) CreateEmailMessage(message *models.EmailMessage) error {
+	return o.CreateEmailMessages([]*models.EmailMessage{message})
+}
+
+func (o *memoryOutbox) CreateEmailMessages(messages []*models.EmailMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	for _, message := range messages {
+		message.ID = uuid.New()
+		message.CreatedAt = now
+		message.NextAttemptAt = now
+		message.Status = models.EmailMessageStatusPending
+
+		stored := *message
+		o.messages[message.ID] = &stored
+	}
+	return nil
+}
+
+func (o *memoryOutbox) ClaimDueEmailMessages(limit int, lease time.Duration) ([]models.EmailMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	var due []*models.EmailMessage
+	for _, message := range o.messages {
+		if message.Status == models.EmailMessageStatusPending && !message.NextAttemptAt.After(now) {
+			due = append(due, message)
+		}
+	}
+	sort.Slice(due, func(a, b int) bool {
+		return due[a].NextAttemptAt.Before(due[b].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]models.EmailMessage, len(due))
+	for i, message := range due {
+		message.NextAttemptAt = now.Add(lease)
+		message.Attempts++
+		claimed[i] = *message
+	}
+	return claimed, nil
+}
+
+func (o *memoryOutbox) MarkEmailMessageSent(messageID uuid.UUID) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	message := o.messages[messageID]
+	message.Status = models.EmailMessageStatusSent
+	message.SentAt = &now
+	message.LastError = ""
+	return nil
+}
+
+func (o *memoryOutbox) MarkEmailMessageFailed(messageID uuid.UUID, lastError string, nextAttemptAt *time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	message := o.messages[messageID]
+	message.LastError = lastError
+	if nextAttemptAt != nil {
+		message.NextAttemptAt = *nextAttemptAt
+	} else {
+		message.Status = models.EmailMessageStatusFailed
+	}
+	return nil
+}
+
+// only returns the single message in the outbox.
+func (o *memoryOutbox) only(t *testing.T) models.EmailMessage {
+	t.Helper()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.messages) != 1 {
+		t.Fatalf("outbox holds %d messages, want 1", len(o.messages))
+	}
+	for _, message := range o.messages {
+		return *message
+	}
+	return models.EmailMessage{}
+}
+
+// makeDue moves every retry into the past, as if the backoff had passed.
+func (o *memoryOutbox) makeDue() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, message := range o.messages {
+		message.NextAttemptAt = time.Now().Add(-time.Second)
+	}
+}
+
+// flakyMailer fails the first failures sends and delivers the rest to a
+// MemoryMailer.
+type flakyMailer struct {
+	*MemoryMailer
+
+	mu       sync.Mutex
+	failures int
+	attempts int
+}
+
+func (m *flakyMailer) Send(ctx context.Context, message Message) error {
+	m.mu.Lock()
+	m.attempts++
+	fail := m.failures > 0
+	if fail {
+		m.failures--
+	}
+	m.mu.Unlock()
+
+	if fail {
+		return errors.New("connection refused")
+	}
+	return m.MemoryMailer.Send(ctx, message)
+}
+
+func newTestNotifier(t *testing.T, mailer Mailer, outbox *memoryOutbox, options Options) *Notifier {
+	t.Helper()
+
+	options.From = "Test Store <no-reply@example.com>"
+	options.StoreName = "Test Store"
+	notifier, err := NewNotifier(mailer, outbox, nil, options)
+	if err != nil {
+		t.Fatalf("new notifier: %v", err)
+	}
+	return notifier
+}
+
+func TestNotifierDeliversQueuedEmail(t *testing.T) {
+	mailer := NewMemoryMailer()
+	outbox := newMemoryOutbox()
+	notifier := newTestNotifier(t, mailer, outbox, Options{PollInterval: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		notifier.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	notifier.Welcome(&models.User{FullName: "Ana", Email: "ana@example.com"})
+
+	// Queuing wakes the worker, so this does not wait for the poll interval.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(mailer.Messages()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("queued email was not delivered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sent := mailer.Messages()[0]
+	if sent.To != `"Ana" <ana@example.com>` || sent.Subject != "Welcome to Test Store" {
+		t.Errorf("sent %q to %q, want %q to %q", sent.Subject, sent.To, "Welcome to Test Store", `"Ana" <ana@example.com>`)
+	}
+
+	deadline = time.Now().Add(5 * time.Second)
+	for outbox.only(t).Status != models.EmailMessageStatusSent {
+		if time.Now().After(deadline) {
+			t.Fatal("delivered email was not marked sent")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if message := outbox.only(t); message.Attempts != 1 || message.SentAt == nil {
+		t.Errorf("sent message has %d attempts and sent_at %v, want 1 and a time", message.Attempts, message.SentAt)
+	}
+}
+
+func TestNotifierRetriesFailedDelivery(t *testing.T) {
+	mailer := &flakyMailer{MemoryMailer: NewMemoryMailer(), failures: 1}
+	outbox := newMemoryOutbox()
+	notifier := newTestNotifier(t, mailer, outbox, Options{RetryBaseDelay: time.Minute, MaxAttempts: 3})
+	ctx := context.Background()
+
+	if err := notifier.Enqueue(TemplateWelcome, "Ana", "ana@example.com", TemplateData{}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	before := time.Now()
+	notifier.deliverDue(ctx)
+
+	message := outbox.only(t)
+	if message.Status != models.EmailMessageStatusPending || message.Attempts != 1 || message.LastError != "connection refused" {
+		t.Fatalf("after a failed send the message is %s with %d attempts and error %q, want pending, 1 and the send error", message.Status, message.Attempts, message.LastError)
+	}
+	if wait := message.NextAttemptAt.Sub(before); wait < time.Minute || wait > time.Minute+5*time.Second {
+		t.Errorf("retry scheduled after %s, want about 1m", wait)
+	}
+
+	// Nothing is due before the backoff has passed.
+	notifier.deliverDue(ctx)
+	if mailer.attempts != 1 {
+		t.Fatalf("mailer was called %d times before the retry was due, want 1", mailer.attempts)
+	}
+
+	outbox.makeDue()
+	notifier.deliverDue(ctx)
+
+	message = outbox.only(t)
+	if message.Status != models.EmailMessageStatusSent || message.Attempts != 2 || message.LastError != "" {
+		t.Errorf("after the retry the message is %s with %d attempts and error %q, want sent, 2 and none", message.Status, message.Attempts, message.LastError)
+	}
+	if got := len(mailer.Messages()); got != 1 {
+		t.Errorf("delivered %d messages, want 1", got)
+	}
+}
+
+func TestNotifierGivesUpAfterMaxAttempts(t *testing.T) {
+	mailer := &flakyMailer{MemoryMailer: NewMemoryMailer(), failures: 100}
+	outbox := newMemoryOutbox()
+	notifier := newTestNotifier(t, mailer, outbox, Options{MaxAttempts: 3})
+	ctx := context.Background()
+
+	if err := notifier.Enqueue(TemplateWelcome, "Ana", "ana@example.com", TemplateData{}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		notifier.deliverDue(ctx)
+
+		message := outbox.only(t)
+		want := models.EmailMessageStatusPending
+		if attempt == 3 {
+			want = models.EmailMessageStatusFailed
+		}
+		if message.Status != want || message.Attempts != attempt {
+			t.Fatalf("after attempt %d the message is %s with %d attempts, want %s", attempt, message.Status, message.Attempts, want)
+		}
+
+		outbox.makeDue()
+	}
+
+	notifier.deliverDue(ctx)
+	if mailer.attempts != 3 {
+		t.Errorf("mailer was called %d times, want 3", mailer.attempts)
+	}
+	if got := len(mailer.Messages()); got != 0 {
+		t.Errorf("delivered %d messages, want 0", got)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	notifier := &Notifier{options: Options{RetryBaseDelay: time.Minute, RetryMaxDelay: 10 * time.Minute}}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 8 * time.Minute},
+		{5, 10 * time.Minute},
+		{1000, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := notifier.retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+
+	notifier.options.RetryBaseDelay = time.Hour
+	if got := notifier.retryDelay(1); got != 10*time.Minute {
+		t.Errorf("retryDelay with a base above the maximum = %s, want 10m", got)
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{time.Hour, "1 hour"},
+		{3 * time.Hour, "3 hours"},
+		{90 * time.Minute, "90 minutes"},
+		{time.Minute, "1 minute"},
+		{30 * time.Second, "30s"},
+	}
+
+	for _, tt := range tests {
+		if got := humanDuration(tt.d); got != tt.want {
+			t.Errorf("humanDuration(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
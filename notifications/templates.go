@@ -0,0 +1,102 @@
+package notifications
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"ordent/dto"
+	"ordent/models"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Template names. Every template file defines a "subject", a "text" and an
+// "html" part and may use the helpers from layout.tmpl.
+const (
+	TemplateWelcome           = "welcome"
+	TemplateOrderConfirmation = "order_confirmation"
+	TemplatePaymentFailed     = "payment_failed"
+	TemplateShipped           = "shipped"
+	TemplatePasswordReset     = "password_reset"
+	TemplateLowStock          = "low_stock"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// TemplateData is what templates are executed with. Fields that do not apply
+// to a template are left empty.
+type TemplateData struct {
+	StoreName string
+	Name      string
+	Order     *dto.TransactionResponse
+	ResetURL  string
+	ExpiresIn string
+	Alert     *models.LowStockAlert
+}
+
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Templates renders the embedded email templates.
+type Templates struct {
+	templates map[string]emailTemplate
+}
+
+// LoadTemplates parses every embedded template. It fails when a template is
+// missing one of its parts.
+func LoadTemplates() (*Templates, error) {
+	names := []string{TemplateWelcome, TemplateOrderConfirmation, TemplatePaymentFailed, TemplateShipped, TemplatePasswordReset, TemplateLowStock}
+
+	t := &Templates{templates: map[string]emailTemplate{}}
+	for _, name := range names {
+		files := []string{"templates/layout.tmpl", "templates/" + name + ".tmpl"}
+
+		text, err := texttemplate.New(name).ParseFS(templateFS, files...)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", name, err)
+		}
+		html, err := htmltemplate.New(name).ParseFS(templateFS, files...)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", name, err)
+		}
+
+		for _, part := range []string{"subject", "text", "html"} {
+			if text.Lookup(part) == nil {
+				return nil, fmt.Errorf("template %s has no %q part", name, part)
+			}
+		}
+
+		t.templates[name] = emailTemplate{text: text, html: html}
+	}
+
+	return t, nil
+}
+
+// Render executes the named template. From and To are left for the caller.
+func (t *Templates) Render(name string, data TemplateData) (Message, error) {
+	tmpl, ok := t.templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return Message{}, fmt.Errorf("render %s text: %w", name, err)
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "html", data); err != nil {
+		return Message{}, fmt.Errorf("render %s html: %w", name, err)
+	}
+
+	return Message{
+		Subject: singleLine(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,89 @@
+package notifications
+
+import (
+	"ordent/dto"
+	"ordent/models"
+	"ordent/money"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestTemplatesRender(t *testing.T) {
+	templates, err := LoadTemplates()
+	if err != nil {
+		t.Fatalf("load templates: %v", err)
+	}
+
+	sku := "MUG-01"
+	order := &dto.TransactionResponse{
+		ID:             uuid.MustParse("5f0c1f9e-8d5b-4c1e-9f55-3a1c2b7d9e10"),
+		SubtotalPrice:  money.MustParse("50.00"),
+		DiscountAmount: money.MustParse("5.00"),
+		TaxAmount:      money.MustParse("4.50"),
+		ShippingFee:    money.MustParse("10.00"),
+		TotalPrice:     money.MustParse("59.50"),
+		Currency:       "IDR",
+		Carrier:        "JNE",
+		TrackingNumber: "JNE123",
+		TransactionDetails: []dto.TransactionDetailResponse{{
+			Item:        dto.GetItemDetailTransactionResponse{Name: "Mug"},
+			Variant:     &dto.VariantSummaryResponse{Label: "Red"},
+			Quantity:    2,
+			GrossAmount: money.MustParse("49.50"),
+		}},
+	}
+
+	tests := []struct {
+		template    string
+		data        TemplateData
+		wantSubject string
+		wantText    []string
+	}{
+		{TemplateWelcome, TemplateData{}, "Welcome to Test Store", []string{"Hi Ana & <Bo>,"}},
+		{TemplateOrderConfirmation, TemplateData{Order: order}, "We received your order " + order.ID.String(), []string{"- Mug (Red) x 2: 49.50", "Discount: -5.00", "Total: 59.50 IDR"}},
+		{TemplatePaymentFailed, TemplateData{Order: order}, "Payment for order " + order.ID.String() + " failed", nil},
+		{TemplateShipped, TemplateData{Order: order}, "Your order " + order.ID.String() + " has shipped", []string{"Carrier: JNE", "Tracking number: JNE123"}},
+		{TemplatePasswordReset, TemplateData{ResetURL: "https://example.com/reset?token=a&b", ExpiresIn: "1 hour"}, "Reset your Test Store password", []string{"https://example.com/reset?token=a&b", "expires in 1 hour"}},
+		{TemplateLowStock, TemplateData{Alert: &models.LowStockAlert{ItemName: "Mug", SKU: &sku, Stock: 1, Threshold: 5}}, "Low stock: Mug", []string{"Mug (SKU MUG-01) is running low: 1 left", "threshold of 5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tt.data.StoreName = "Test Store"
+			tt.data.Name = "Ana & <Bo>"
+
+			message, err := templates.Render(tt.template, tt.data)
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+
+			if message.Subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", message.Subject, tt.wantSubject)
+			}
+			for _, want := range tt.wantText {
+				if !strings.Contains(message.Text, want) {
+					t.Errorf("text does not contain %q:\n%s", want, message.Text)
+				}
+			}
+
+			// Only the HTML part escapes what it is given.
+			if tt.template != TemplateLowStock {
+				if !strings.Contains(message.Text, "Ana & <Bo>") {
+					t.Errorf("text does not contain the unescaped name:\n%s", message.Text)
+				}
+				if !strings.Contains(message.HTML, "Ana &amp; &lt;Bo&gt;") || strings.Contains(message.HTML, "<Bo>") {
+					t.Errorf("html does not escape the name:\n%s", message.HTML)
+				}
+			}
+			if !strings.Contains(message.HTML, "This email was sent by Test Store.") {
+				t.Error("html has no footer")
+			}
+		})
+	}
+
+	if _, err := templates.Render("unknown", TemplateData{}); err == nil {
+		t.Error("rendering an unknown template succeeded")
+	}
+}
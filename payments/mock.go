@@ -0,0 +1,127 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"ordent/money"
+	"strings"
+	"time"
+)
+
+// MockProvider talks to the stand-in gateway served by MockServer. It behaves
+// like a real provider over HTTP, so the whole payment flow can run locally
+// without network access.
+type MockProvider struct {
+	baseURL       string
+	webhookSecret string
+	client        *http.Client
+}
+
+func NewMockProvider(baseURL string, webhookSecret string) *MockProvider {
+	return &MockProvider{
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockProvider) CreateCharge(ctx context.Context, request ChargeRequest) (*Charge, error) {
+	var charge Charge
+	err := p.do(ctx, http.MethodPost, "/charges", mockChargeRequest{
+		Reference:   request.Reference,
+		Amount:      request.Amount,
+		Currency:    request.Currency,
+		Description: request.Description,
+	}, &charge)
+	if err != nil {
+		return nil, err
+	}
+	return &charge, nil
+}
+
+func (p *MockProvider) CaptureCharge(ctx context.Context, chargeID string) (*Charge, error) {
+	var charge Charge
+	if err := p.do(ctx, http.MethodPost, "/charges/"+chargeID+"/capture", nil, &charge); err != nil {
+		return nil, err
+	}
+	return &charge, nil
+}
+
+func (p *MockProvider) RefundCharge(ctx context.Context, chargeID string, amount money.Money) (*Refund, error) {
+	var refund Refund
+	if err := p.do(ctx, http.MethodPost, "/charges/"+chargeID+"/refunds", mockRefundRequest{Amount: amount}, &refund); err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (p *MockProvider) ParseWebhook(r *http.Request) (*WebhookEvent, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := VerifySignature(p.webhookSecret, r.Header.Get(SignatureHeader), body, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("decode webhook event: %w", err)
+	}
+
+	return &event, nil
+}
+
+func (p *MockProvider) do(ctx context.Context, method string, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return ErrChargeNotFound
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		message, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("mock gateway %s %s: %s: %s", method, path, res.Status, strings.TrimSpace(string(message)))
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+type mockChargeRequest struct {
+	Reference   string      `json:"reference"`
+	Amount      money.Money `json:"amount"`
+	Currency    string      `json:"currency"`
+	Description string      `json:"description"`
+}
+
+type mockRefundRequest struct {
+	Amount money.Money `json:"amount"`
+}
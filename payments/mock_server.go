@@ -0,0 +1,219 @@
+package payments
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MockServer is a local stand-in for a payment gateway. It keeps charges in
+// memory and reports every state change to WebhookURL with a signed callback,
+// the same way a real provider would.
+//
+// Besides the endpoints MockProvider uses, it exposes
+// POST /charges/{id}/decline to simulate a payment that fails after checkout.
+type MockServer struct {
+	webhookURL    string
+	webhookSecret string
+	client        *http.Client
+	mux           *http.ServeMux
+
+	mu      sync.Mutex
+	charges map[string]*Charge
+}
+
+func NewMockServer(webhookURL string, webhookSecret string) *MockServer {
+	s := &MockServer{
+		webhookURL:    webhookURL,
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		mux:           http.NewServeMux(),
+		charges:       map[string]*Charge{},
+	}
+
+	s.mux.HandleFunc("POST /charges", s.createCharge)
+	s.mux.HandleFunc("GET /charges/{id}", s.getCharge)
+	s.mux.HandleFunc("POST /charges/{id}/capture", s.captureCharge)
+	s.mux.HandleFunc("POST /charges/{id}/decline", s.declineCharge)
+	s.mux.HandleFunc("POST /charges/{id}/refunds", s.refundCharge)
+
+	return s
+}
+
+func (s *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *MockServer) createCharge(w http.ResponseWriter, r *http.Request) {
+	var body mockChargeRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Reference == "" || body.Amount < 0 {
+		http.Error(w, "invalid charge request", http.StatusBadRequest)
+		return
+	}
+
+	charge := &Charge{
+		ID:        "ch_" + uuid.NewString(),
+		Reference: body.Reference,
+		Amount:    body.Amount,
+		Currency:  body.Currency,
+		Status:    ChargeStatusAuthorized,
+	}
+
+	s.mu.Lock()
+	s.charges[charge.ID] = charge
+	snapshot := *charge
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, snapshot)
+}
+
+func (s *MockServer) getCharge(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	charge, ok := s.charges[r.PathValue("id")]
+	var snapshot Charge
+	if ok {
+		snapshot = *charge
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "charge not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+func (s *MockServer) captureCharge(w http.ResponseWriter, r *http.Request) {
+	s.settleCharge(w, r.PathValue("id"), ChargeStatusSucceeded, EventChargeSucceeded)
+}
+
+func (s *MockServer) declineCharge(w http.ResponseWriter, r *http.Request) {
+	s.settleCharge(w, r.PathValue("id"), ChargeStatusFailed, EventChargeFailed)
+}
+
+func (s *MockServer) settleCharge(w http.ResponseWriter, chargeID string, status ChargeStatus, eventType EventType) {
+	s.mu.Lock()
+	charge, ok := s.charges[chargeID]
+	if !ok {
+		s.mu.Unlock()
+		http.Error(w, "charge not found", http.StatusNotFound)
+		return
+	}
+
+	if charge.Status != ChargeStatusAuthorized {
+		s.mu.Unlock()
+		http.Error(w, "charge is already "+string(charge.Status), http.StatusConflict)
+		return
+	}
+
+	charge.Status = status
+	snapshot := *charge
+	s.mu.Unlock()
+
+	s.sendWebhook(eventType, snapshot)
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+func (s *MockServer) refundCharge(w http.ResponseWriter, r *http.Request) {
+	var body mockRefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Amount <= 0 {
+		http.Error(w, "invalid refund request", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	charge, ok := s.charges[r.PathValue("id")]
+	if !ok {
+		s.mu.Unlock()
+		http.Error(w, "charge not found", http.StatusNotFound)
+		return
+	}
+
+	if charge.Status != ChargeStatusSucceeded && charge.Status != ChargeStatusRefunded {
+		s.mu.Unlock()
+		http.Error(w, "charge is not captured", http.StatusConflict)
+		return
+	}
+
+	if charge.RefundedAmount+body.Amount > charge.Amount {
+		s.mu.Unlock()
+		http.Error(w, "refund exceeds captured amount", http.StatusBadRequest)
+		return
+	}
+
+	charge.RefundedAmount += body.Amount
+	if charge.RefundedAmount == charge.Amount {
+		charge.Status = ChargeStatusRefunded
+	}
+	snapshot := *charge
+	s.mu.Unlock()
+
+	refund := Refund{
+		ID:       "re_" + uuid.NewString(),
+		ChargeID: snapshot.ID,
+		Amount:   body.Amount,
+	}
+
+	s.sendWebhook(EventChargeRefunded, snapshot)
+	writeJSON(w, http.StatusCreated, refund)
+}
+
+// sendWebhook delivers the event in the background, retrying a few times the
+// way real gateways do when the receiver is unavailable.
+func (s *MockServer) sendWebhook(eventType EventType, charge Charge) {
+	if s.webhookURL == "" {
+		return
+	}
+
+	event := WebhookEvent{
+		ID:             "evt_" + uuid.NewString(),
+		Type:           eventType,
+		ChargeID:       charge.ID,
+		Reference:      charge.Reference,
+		Amount:         charge.Amount,
+		RefundedAmount: charge.RefundedAmount,
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("mock gateway: encode webhook: %v", err)
+		return
+	}
+
+	go func() {
+		backoff := 200 * time.Millisecond
+		for attempt := 1; attempt <= 5; attempt++ {
+			req, err := http.NewRequest(http.MethodPost, s.webhookURL, bytes.NewReader(payload))
+			if err != nil {
+				log.Printf("mock gateway: build webhook request: %v", err)
+				return
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(SignatureHeader, SignPayload(s.webhookSecret, time.Now(), payload))
+
+			res, err := s.client.Do(req)
+			if err == nil {
+				res.Body.Close()
+				if res.StatusCode < http.StatusInternalServerError {
+					return
+				}
+			}
+
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		log.Printf("mock gateway: giving up on webhook %s for charge %s", event.ID, charge.ID)
+	}()
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,78 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"ordent/money"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrChargeNotFound   = errors.New("charge not found")
+)
+
+type ChargeStatus string
+
+const (
+	ChargeStatusAuthorized ChargeStatus = "authorized"
+	ChargeStatusSucceeded  ChargeStatus = "succeeded"
+	ChargeStatusFailed     ChargeStatus = "failed"
+	ChargeStatusRefunded   ChargeStatus = "refunded"
+)
+
+type EventType string
+
+const (
+	EventChargeSucceeded EventType = "charge.succeeded"
+	EventChargeFailed    EventType = "charge.failed"
+	EventChargeRefunded  EventType = "charge.refunded"
+)
+
+type ChargeRequest struct {
+	// Reference is our transaction ID. Providers echo it back in webhooks.
+	Reference   string
+	Amount      money.Money
+	Currency    string
+	Description string
+}
+
+type Charge struct {
+	ID             string       `json:"id"`
+	Reference      string       `json:"reference"`
+	Amount         money.Money  `json:"amount"`
+	Currency       string       `json:"currency"`
+	Status         ChargeStatus `json:"status"`
+	RefundedAmount money.Money  `json:"refunded_amount"`
+}
+
+type Refund struct {
+	ID       string      `json:"id"`
+	ChargeID string      `json:"charge_id"`
+	Amount   money.Money `json:"amount"`
+}
+
+// WebhookEvent is a provider callback after its signature has been verified.
+// RefundedAmount is the total refunded so far, not just this refund.
+type WebhookEvent struct {
+	ID             string      `json:"id"`
+	Type           EventType   `json:"type"`
+	ChargeID       string      `json:"charge_id"`
+	Reference      string      `json:"reference"`
+	Amount         money.Money `json:"amount"`
+	RefundedAmount money.Money `json:"refunded_amount"`
+}
+
+// Provider is a payment gateway. Charges are created in the authorized state
+// and captured afterwards; the final outcome of a charge is reported
+// asynchronously through webhooks.
+type Provider interface {
+	Name() string
+	CreateCharge(ctx context.Context, request ChargeRequest) (*Charge, error)
+	CaptureCharge(ctx context.Context, chargeID string) (*Charge, error)
+	RefundCharge(ctx context.Context, chargeID string, amount money.Money) (*Refund, error)
+	// ParseWebhook verifies the request signature and decodes the event. It
+	// returns ErrInvalidSignature when the request was not signed by the
+	// provider.
+	ParseWebhook(r *http.Request) (*WebhookEvent, error)
+}
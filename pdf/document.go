@@ -0,0 +1,143 @@
+// Package pdf writes simple text-and-line PDF documents without external
+// dependencies. It supports the standard Helvetica fonts, text placed at
+// absolute positions, straight lines and multiple pages, which is all the
+// invoices need.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 page size in points.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Document collects pages and serializes them with Bytes. Coordinates are in
+// points with the origin at the top-left corner of the page.
+type Document struct {
+	title   string
+	pages   []*bytes.Buffer
+	current *bytes.Buffer
+}
+
+func NewDocument(title string) *Document {
+	d := &Document{title: title}
+	d.AddPage()
+	return d
+}
+
+func (d *Document) AddPage() {
+	d.current = &bytes.Buffer{}
+	d.pages = append(d.pages, d.current)
+}
+
+// Text draws text with its baseline starting at (x, y).
+func (d *Document) Text(x, y float64, font Font, size float64, text string) {
+	fmt.Fprintf(d.current, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+		fontResource(font), size, x, PageHeight-y, escape(text))
+}
+
+// TextRight draws text so that it ends at x.
+func (d *Document) TextRight(x, y float64, font Font, size float64, text string) {
+	d.Text(x-TextWidth(font, size, text), y, font, size, text)
+}
+
+// Line draws a straight line of the given width in points.
+func (d *Document) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(d.current, "%.2f w %.2f %.2f m %.2f %.2f l S\n",
+		width, x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// Gray sets the fill and stroke color for what is drawn next, from 0
+// (black) to 1 (white).
+func (d *Document) Gray(level float64) {
+	fmt.Fprintf(d.current, "%.2f g %.2f G\n", level, level)
+}
+
+// Bytes serializes the document. The output only depends on what was drawn,
+// so the same content always gives the same bytes.
+func (d *Document) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	writeObject := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-4 are fixed; pages follow as (page, content) pairs.
+	const firstPageObject = 5
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObject+2*i)
+	}
+
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	writeObject(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", Helvetica))
+	writeObject(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", HelveticaBold))
+
+	for i, page := range d.pages {
+		contentObject := firstPageObject + 2*i + 1
+		writeObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, contentObject))
+		writeObject(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	infoObject := len(offsets) + 1
+	writeObject(fmt.Sprintf("<< /Title (%s) /Producer (ordent) >>", escape(d.title)))
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, infoObject, xref)
+
+	return out.Bytes()
+}
+
+func fontResource(font Font) string {
+	if font == HelveticaBold {
+		return "F2"
+	}
+	return "F1"
+}
+
+// winAnsiPunctuation maps the typographic characters that WinAnsiEncoding
+// places in 0x80-0x9F.
+var winAnsiPunctuation = map[rune]byte{
+	'€': 0x80, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// escape makes text safe inside a PDF string literal. Characters that
+// WinAnsiEncoding cannot show with the standard fonts become '?'.
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		code, isPunctuation := winAnsiPunctuation[r]
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r <= 126:
+			b.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case isPunctuation:
+			fmt.Fprintf(&b, "\\%03o", code)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,145 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "Invoice 42", "Invoice 42"},
+		{"parentheses", "Shirt (red)", `Shirt \(red\)`},
+		{"unbalanced parenthesis", "a)", `a\)`},
+		{"backslash", `C:\path`, `C:\\path`},
+		{"latin-1", "Café Crème", `Caf\351 Cr\350me`},
+		{"no-break space", "10\u00a0kg", `10\240kg`},
+		{"euro sign", "€5", `\2005`},
+		{"dashes and quotes", "–—“”", `\226\227\223\224`},
+		{"outside WinAnsiEncoding", "日本", "??"},
+		{"control characters", "a\nb\tc", "a?b?c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escape(tt.text); got != tt.want {
+				t.Errorf("escape(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func sampleDocument() *Document {
+	doc := NewDocument(`Invoice (draft) \ Café`)
+	doc.Text(50, 60, HelveticaBold, 18, "Toko Café (Jakarta)")
+	doc.TextRight(545, 60, Helvetica, 9, `back\slash – 日本`)
+	doc.Line(50, 70, 545, 70, 0.5)
+	doc.AddPage()
+	doc.Gray(0.4)
+	doc.Text(50, 60, Helvetica, 9, "Page two")
+	return doc
+}
+
+var (
+	startxrefPattern = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	xrefEntryPattern = regexp.MustCompile(`^(\d{10}) (\d{5}) ([nf]) \n$`)
+	streamPattern    = regexp.MustCompile(`(?s)<< /Length (\d+) >>\nstream\n(.*?)endstream`)
+)
+
+func TestBytesXref(t *testing.T) {
+	out := sampleDocument().Bytes()
+
+	match := startxrefPattern.FindSubmatch(out)
+	if match == nil {
+		t.Fatalf("no startxref at the end of the document:\n%s", out)
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	table := out[xref+len("xref\n"):]
+	header, rest, _ := bytes.Cut(table, []byte("\n"))
+	var first, count int
+	if _, err := fmt.Sscanf(string(header), "%d %d", &first, &count); err != nil || first != 0 {
+		t.Fatalf("xref subsection header %q", header)
+	}
+	if !bytes.Contains(out, []byte(fmt.Sprintf("/Size %d", count))) {
+		t.Errorf("trailer does not give /Size %d", count)
+	}
+
+	// Entries are exactly 20 bytes each, including the two-byte line end.
+	for i := 0; i < count; i++ {
+		entry := string(rest[i*20 : (i+1)*20])
+		fields := xrefEntryPattern.FindStringSubmatch(entry)
+		if fields == nil {
+			t.Fatalf("xref entry %d is malformed: %q", i, entry)
+		}
+
+		if i == 0 {
+			if fields[3] != "f" || fields[2] != "65535" {
+				t.Errorf("xref entry 0 = %q, want the free list head", entry)
+			}
+			continue
+		}
+
+		offset, _ := strconv.Atoi(fields[1])
+		want := fmt.Sprintf("%d 0 obj\n", i)
+		if !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q, want %q", i, out[offset:offset+len(want)], want)
+		}
+	}
+	if !bytes.HasPrefix(rest[count*20:], []byte("trailer\n")) {
+		t.Errorf("xref table does not end after %d entries", count)
+	}
+}
+
+func TestBytesStreamLengths(t *testing.T) {
+	out := sampleDocument().Bytes()
+
+	streams := streamPattern.FindAllSubmatch(out, -1)
+	if len(streams) != 2 {
+		t.Fatalf("found %d content streams, want 2", len(streams))
+	}
+	for i, stream := range streams {
+		length, _ := strconv.Atoi(string(stream[1]))
+		if length != len(stream[2]) {
+			t.Errorf("stream %d has /Length %d but %d bytes", i, length, len(stream[2]))
+		}
+	}
+}
+
+func TestBytesEscapesText(t *testing.T) {
+	out := string(sampleDocument().Bytes())
+
+	for _, want := range []string{
+		`(Toko Caf\351 \(Jakarta\)) Tj`,
+		`(back\\slash \226 ??) Tj`,
+		`/Title (Invoice \(draft\) \\ Caf\351)`,
+		"/Count 2",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("document does not contain %q", want)
+		}
+	}
+}
+
+func TestBytesIsDeterministic(t *testing.T) {
+	first := sampleDocument().Bytes()
+	second := sampleDocument().Bytes()
+	if !bytes.Equal(first, second) {
+		t.Fatal("two renders of the same document differ")
+	}
+
+	doc := sampleDocument()
+	if !bytes.Equal(doc.Bytes(), doc.Bytes()) {
+		t.Fatal("serializing a document twice gives different bytes")
+	}
+}
@@ -0,0 +1,149 @@
+package pricing
+
+import (
+	"fmt"
+	"ordent/models"
+	"ordent/money"
+	"sort"
+	"time"
+)
+
+// CouponError explains why a coupon cannot be used. Its message is meant for
+// the buyer.
+type CouponError struct {
+	Code    string
+	Message string
+}
+
+func (e *CouponError) Error() string {
+	return fmt.Sprintf("coupon %s: %s", e.Code, e.Message)
+}
+
+// CouponUsage is a coupon together with how often the buyer already used it.
+type CouponUsage struct {
+	Coupon          models.Coupon
+	UserRedemptions int
+}
+
+type AppliedCoupon struct {
+	Coupon   models.Coupon
+	Discount money.Money
+}
+
+// ApplyCoupons checks every coupon against the order and adds their discounts
+// to the lines. Item coupons are applied before order coupons, and each
+// coupon works on what is left after the coupons before it, so the discount
+// can never exceed the order. Minimum spend is compared with the subtotal
+// before any discount.
+func ApplyCoupons(lines []Line, usages []CouponUsage, now time.Time) ([]AppliedCoupon, error) {
+	if len(usages) == 0 {
+		return nil, nil
+	}
+
+	if len(usages) > 1 {
+		for _, usage := range usages {
+			if !usage.Coupon.Stackable {
+				return nil, &CouponError{Code: usage.Coupon.Code, Message: "cannot be combined with other coupons"}
+			}
+		}
+	}
+
+	subtotal := Subtotal(lines)
+	for _, usage := range usages {
+		if err := validateCoupon(usage, subtotal, now); err != nil {
+			return nil, err
+		}
+	}
+
+	ordered := make([]CouponUsage, len(usages))
+	copy(ordered, usages)
+	sort.SliceStable(ordered, func(a, b int) bool {
+		return ordered[a].Coupon.Scope == models.CouponScopeItem && ordered[b].Coupon.Scope != models.CouponScopeItem
+	})
+
+	applied := make([]AppliedCoupon, 0, len(ordered))
+	for _, usage := range ordered {
+		coupon := usage.Coupon
+
+		var eligible []int
+		for i, line := range lines {
+			if coupon.Scope == models.CouponScopeItem && (coupon.ItemID == nil || line.ItemID != *coupon.ItemID) {
+				continue
+			}
+			eligible = append(eligible, i)
+		}
+
+		if len(eligible) == 0 {
+			return nil, &CouponError{Code: coupon.Code, Message: "does not apply to any item in this order"}
+		}
+
+		weights := make([]money.Money, len(eligible))
+		var base money.Money
+		for j, i := range eligible {
+			weights[j] = lines[i].Discounted()
+			base = base.Add(weights[j])
+		}
+
+		discount := couponDiscount(coupon, base)
+		for j, share := range money.Allocate(discount, weights) {
+			lines[eligible[j]].Discount = lines[eligible[j]].Discount.Add(share)
+		}
+
+		applied = append(applied, AppliedCoupon{Coupon: coupon, Discount: discount})
+	}
+
+	return applied, nil
+}
+
+func validateCoupon(usage CouponUsage, subtotal money.Money, now time.Time) error {
+	coupon := usage.Coupon
+
+	if !coupon.IsActive {
+		return &CouponError{Code: coupon.Code, Message: "is not active"}
+	}
+
+	if coupon.StartsAt != nil && now.Before(*coupon.StartsAt) {
+		return &CouponError{Code: coupon.Code, Message: "is not valid yet"}
+	}
+
+	if coupon.EndsAt != nil && !now.Before(*coupon.EndsAt) {
+		return &CouponError{Code: coupon.Code, Message: "has expired"}
+	}
+
+	if coupon.UsageLimit > 0 && coupon.UsedCount >= coupon.UsageLimit {
+		return &CouponError{Code: coupon.Code, Message: "has reached its usage limit"}
+	}
+
+	if coupon.PerUserLimit > 0 && usage.UserRedemptions >= coupon.PerUserLimit {
+		return &CouponError{Code: coupon.Code, Message: "has already been used the maximum number of times"}
+	}
+
+	if subtotal < coupon.MinSpend {
+		return &CouponError{Code: coupon.Code, Message: "requires a minimum spend of " + coupon.MinSpend.String()}
+	}
+
+	return nil
+}
+
+// couponDiscount is the discount the coupon gives on base, never more than
+// base itself.
+func couponDiscount(coupon models.Coupon, base money.Money) money.Money {
+	var discount money.Money
+	switch coupon.Type {
+	case models.CouponTypePercentage:
+		discount = base.MulRatio(int64(coupon.PercentageBps), 10000)
+		if coupon.MaxDiscount > 0 && discount > coupon.MaxDiscount {
+			discount = coupon.MaxDiscount
+		}
+	case models.CouponTypeFixed:
+		discount = coupon.AmountOff
+	}
+
+	if discount > base {
+		discount = base
+	}
+	if discount < 0 {
+		discount = 0
+	}
+	return discount
+}
@@ -0,0 +1,271 @@
+package pricing
+
+import (
+	"errors"
+	"ordent/models"
+	"ordent/money"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	itemA = uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	itemB = uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+	itemC = uuid.MustParse("00000000-0000-0000-0000-00000000000c")
+)
+
+func percentageCoupon(code string, bps int) models.Coupon {
+	return models.Coupon{Code: code, Type: models.CouponTypePercentage, PercentageBps: bps, Scope: models.CouponScopeOrder, Stackable: true, IsActive: true}
+}
+
+func fixedCoupon(code string, amountOff string) models.Coupon {
+	return models.Coupon{Code: code, Type: models.CouponTypeFixed, AmountOff: money.MustParse(amountOff), Scope: models.CouponScopeOrder, Stackable: true, IsActive: true}
+}
+
+func forItem(coupon models.Coupon, itemID uuid.UUID) models.Coupon {
+	coupon.Scope = models.CouponScopeItem
+	coupon.ItemID = &itemID
+	return coupon
+}
+
+func TestApplyCoupons(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	// 100.00 of item A and 2 x 25.00 of item B.
+	order := func() []Line {
+		return []Line{
+			{ItemID: itemA, Quantity: 1, UnitPrice: money.MustParse("100.00")},
+			{ItemID: itemB, Quantity: 2, UnitPrice: money.MustParse("25.00")},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		lines         []Line
+		coupons       []models.Coupon
+		redemptions   int
+		wantDiscounts []string
+		wantApplied   []string
+		wantErr       string
+	}{
+		{
+			name:          "percentage is allocated by line amount",
+			lines:         order(),
+			coupons:       []models.Coupon{percentageCoupon("TEN", 1000)},
+			wantDiscounts: []string{"10.00", "5.00"},
+			wantApplied:   []string{"15.00"},
+		},
+		{
+			name:  "percentage cap with the remainder on the largest share",
+			lines: order(),
+			coupons: func() []models.Coupon {
+				coupon := percentageCoupon("HALF", 5000)
+				coupon.MaxDiscount = money.MustParse("20.00")
+				return []models.Coupon{coupon}
+			}(),
+			wantDiscounts: []string{"13.33", "6.67"},
+			wantApplied:   []string{"20.00"},
+		},
+		{
+			name:          "fixed amount never exceeds the order",
+			lines:         order(),
+			coupons:       []models.Coupon{fixedCoupon("BIG", "500.00")},
+			wantDiscounts: []string{"100.00", "50.00"},
+			wantApplied:   []string{"150.00"},
+		},
+		{
+			name: "fixed amount split over equal lines",
+			lines: []Line{
+				{ItemID: itemA, Quantity: 1, UnitPrice: money.MustParse("10.00")},
+				{ItemID: itemB, Quantity: 1, UnitPrice: money.MustParse("10.00")},
+				{ItemID: itemC, Quantity: 1, UnitPrice: money.MustParse("10.00")},
+			},
+			coupons:       []models.Coupon{fixedCoupon("TENOFF", "10.00")},
+			wantDiscounts: []string{"3.34", "3.33", "3.33"},
+			wantApplied:   []string{"10.00"},
+		},
+		{
+			name:          "item coupon only discounts its item",
+			lines:         order(),
+			coupons:       []models.Coupon{forItem(fixedCoupon("A20", "20.00"), itemA)},
+			wantDiscounts: []string{"20.00", "0.00"},
+			wantApplied:   []string{"20.00"},
+		},
+		{
+			name:          "item coupons apply before order coupons",
+			lines:         order(),
+			coupons:       []models.Coupon{percentageCoupon("TEN", 1000), forItem(fixedCoupon("A20", "20.00"), itemA)},
+			wantDiscounts: []string{"28.00", "5.00"},
+			wantApplied:   []string{"20.00", "13.00"},
+		},
+		{
+			name:  "minimum spend is checked before discounts",
+			lines: order(),
+			coupons: func() []models.Coupon {
+				coupon := percentageCoupon("TEN", 1000)
+				coupon.MinSpend = money.MustParse("150.00")
+				return []models.Coupon{forItem(fixedCoupon("AFREE", "100.00"), itemA), coupon}
+			}(),
+			wantDiscounts: []string{"100.00", "5.00"},
+			wantApplied:   []string{"100.00", "5.00"},
+		},
+		{
+			name:  "minimum spend not reached",
+			lines: order(),
+			coupons: func() []models.Coupon {
+				coupon := percentageCoupon("TEN", 1000)
+				coupon.MinSpend = money.MustParse("200.00")
+				return []models.Coupon{coupon}
+			}(),
+			wantErr: "coupon TEN: requires a minimum spend of 200.00",
+		},
+		{
+			name:  "coupon that does not stack",
+			lines: order(),
+			coupons: func() []models.Coupon {
+				coupon := percentageCoupon("SOLO", 1000)
+				coupon.Stackable = false
+				return []models.Coupon{fixedCoupon("FIVE", "5.00"), coupon}
+			}(),
+			wantErr: "coupon SOLO: cannot be combined with other coupons",
+		},
+		{
+			name:  "coupon that does not stack used alone",
+			lines: order(),
+			coupons: func() []models.Coupon {
+				coupon := fixedCoupon("SOLO", "5.00")
+				coupon.Stackable = false
+				return []models.Coupon{coupon}
+			}(),
+			wantDiscounts: []string{"3.33", "1.67"},
+			wantApplied:   []string{"5.00"},
+		},
+		{
+			name:    "item coupon for an item not in the order",
+			lines:   order(),
+			coupons: []models.Coupon{forItem(fixedCoupon("C20", "20.00"), itemC)},
+			wantErr: "coupon C20: does not apply to any item in this order",
+		},
+		{
+			name:  "inactive",
+			lines: order(),
+			coupons: func() []models.Coupon {
+				coupon := fixedCoupon("OFF", "5.00")
+				coupon.IsActive = false
+				return []models.Coupon{coupon}
+			}(),
+			wantErr: "coupon OFF: is not active",
+		},
+		{
+			name:  "not started",
+			lines: order(),
+			coupons: func() []models.Coupon {
+				coupon := fixedCoupon("SOON", "5.00")
+				coupon.StartsAt = &future
+				return []models.Coupon{coupon}
+			}(),
+			wantErr: "coupon SOON: is not valid yet",
+		},
+		{
+			name:  "expired",
+			lines: order(),
+			coupons: func() []models.Coupon {
+				coupon := fixedCoupon("OLD", "5.00")
+				coupon.EndsAt = &past
+				return []models.Coupon{coupon}
+			}(),
+			wantErr: "coupon OLD: has expired",
+		},
+		{
+			name:  "usage limit reached",
+			lines: order(),
+			coupons: func() []models.Coupon {
+				coupon := fixedCoupon("GONE", "5.00")
+				coupon.UsageLimit = 3
+				coupon.UsedCount = 3
+				return []models.Coupon{coupon}
+			}(),
+			wantErr: "coupon GONE: has reached its usage limit",
+		},
+		{
+			name:  "per user limit reached",
+			lines: order(),
+			coupons: func() []models.Coupon {
+				coupon := fixedCoupon("ONCE", "5.00")
+				coupon.PerUserLimit = 1
+				return []models.Coupon{coupon}
+			}(),
+			redemptions: 1,
+			wantErr:     "coupon ONCE: has already been used the maximum number of times",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usages := make([]CouponUsage, len(tt.coupons))
+			for i, coupon := range tt.coupons {
+				usages[i] = CouponUsage{Coupon: coupon, UserRedemptions: tt.redemptions}
+			}
+
+			applied, err := ApplyCoupons(tt.lines, usages, now)
+			if tt.wantErr != "" {
+				var couponErr *CouponError
+				if !errors.As(err, &couponErr) || err.Error() != tt.wantErr {
+					t.Fatalf("ApplyCoupons error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyCoupons: %v", err)
+			}
+
+			discounts := make([]string, len(tt.lines))
+			for i, line := range tt.lines {
+				discounts[i] = line.Discount.String()
+			}
+			if !reflect.DeepEqual(discounts, tt.wantDiscounts) {
+				t.Errorf("line discounts = %v, want %v", discounts, tt.wantDiscounts)
+			}
+
+			appliedDiscounts := make([]string, len(applied))
+			for i, coupon := range applied {
+				appliedDiscounts[i] = coupon.Discount.String()
+			}
+			if !reflect.DeepEqual(appliedDiscounts, tt.wantApplied) {
+				t.Errorf("coupon discounts = %v, want %v", appliedDiscounts, tt.wantApplied)
+			}
+
+			if got, want := TotalDiscount(tt.lines).String(), sumOf(t, tt.wantApplied); got != want {
+				t.Errorf("total discount = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyCouponsWithoutCoupons(t *testing.T) {
+	lines := []Line{{ItemID: itemA, Quantity: 1, UnitPrice: money.MustParse("10.00")}}
+
+	applied, err := ApplyCoupons(lines, nil, time.Now())
+	if err != nil || applied != nil {
+		t.Fatalf("ApplyCoupons without coupons = %v, %v; want nil, nil", applied, err)
+	}
+	if !lines[0].Discount.IsZero() {
+		t.Errorf("discount = %s, want 0.00", lines[0].Discount)
+	}
+}
+
+// sumOf adds up decimal amounts.
+func sumOf(t *testing.T, amounts []string) string {
+	t.Helper()
+
+	var sum money.Money
+	for _, amount := range amounts {
+		sum = sum.Add(money.MustParse(amount))
+	}
+	return sum.String()
+}
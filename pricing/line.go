@@ -0,0 +1,90 @@
+// Package pricing computes order amounts from the catalog prices of the
+// ordered items: coupon discounts and taxes, with every amount kept per line
+// so history, invoices and refunds can be traced back to individual items.
+package pricing
+
+import (
+	"ordent/money"
+
+	"github.com/google/uuid"
+)
+
+// Line is one order line being priced. Discount is filled in by ApplyCoupons
+// and Tax by ApplyTax.
+type Line struct {
+	ItemID       uuid.UUID
+	Quantity     int
+	UnitPrice    money.Money
+	Discount     money.Money
+	TaxRateBps   int
+	TaxInclusive bool
+	Tax          money.Money
+}
+
+// Subtotal is the line amount at catalog price, before discounts.
+func (l Line) Subtotal() money.Money {
+	return l.UnitPrice.Mul(int64(l.Quantity))
+}
+
+// Discounted is the line amount after discounts, still at the catalog's
+// tax treatment: it includes tax for tax-inclusive items and excludes it
+// otherwise.
+func (l Line) Discounted() money.Money {
+	return l.Subtotal().Sub(l.Discount)
+}
+
+// Net is the discounted line amount without tax.
+func (l Line) Net() money.Money {
+	if l.TaxInclusive {
+		return l.Discounted().Sub(l.Tax)
+	}
+	return l.Discounted()
+}
+
+// Gross is the discounted line amount with tax, i.e. what the buyer pays.
+func (l Line) Gross() money.Money {
+	if l.TaxInclusive {
+		return l.Discounted()
+	}
+	return l.Discounted().Add(l.Tax)
+}
+
+func Subtotal(lines []Line) money.Money {
+	var subtotal money.Money
+	for _, line := range lines {
+		subtotal = subtotal.Add(line.Subtotal())
+	}
+	return subtotal
+}
+
+func TotalDiscount(lines []Line) money.Money {
+	var discount money.Money
+	for _, line := range lines {
+		discount = discount.Add(line.Discount)
+	}
+	return discount
+}
+
+func TotalNet(lines []Line) money.Money {
+	var net money.Money
+	for _, line := range lines {
+		net = net.Add(line.Net())
+	}
+	return net
+}
+
+func TotalTax(lines []Line) money.Money {
+	var tax money.Money
+	for _, line := range lines {
+		tax = tax.Add(line.Tax)
+	}
+	return tax
+}
+
+func TotalGross(lines []Line) money.Money {
+	var gross money.Money
+	for _, line := range lines {
+		gross = gross.Add(line.Gross())
+	}
+	return gross
+}
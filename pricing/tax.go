@@ -0,0 +1,21 @@
+package pricing
+
+// ApplyTax fills in the tax of every line from its rate and the discounted
+// amount, so coupons lower the tax along with the price. Exclusive lines get
+// the tax added on top; inclusive lines have it carved out of their amount.
+// Tax is rounded per line and the order tax is the sum of the lines.
+func ApplyTax(lines []Line) {
+	for i := range lines {
+		line := &lines[i]
+		if line.TaxRateBps <= 0 {
+			line.Tax = 0
+			continue
+		}
+
+		if line.TaxInclusive {
+			line.Tax = line.Discounted().MulRatio(int64(line.TaxRateBps), int64(10000+line.TaxRateBps))
+		} else {
+			line.Tax = line.Discounted().MulRatio(int64(line.TaxRateBps), 10000)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package pricing
+
+import (
+	"ordent/money"
+	"testing"
+	"time"
+)
+
+func TestApplyTax(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      Line
+		wantTax   string
+		wantNet   string
+		wantGross string
+	}{
+		{
+			name:      "exclusive",
+			line:      Line{Quantity: 1, UnitPrice: money.MustParse("100.00"), TaxRateBps: 1100},
+			wantTax:   "11.00",
+			wantNet:   "100.00",
+			wantGross: "111.00",
+		},
+		{
+			name:      "inclusive",
+			line:      Line{Quantity: 1, UnitPrice: money.MustParse("111.00"), TaxRateBps: 1100, TaxInclusive: true},
+			wantTax:   "11.00",
+			wantNet:   "100.00",
+			wantGross: "111.00",
+		},
+		{
+			name:      "exempt",
+			line:      Line{Quantity: 1, UnitPrice: money.MustParse("100.00"), Tax: money.MustParse("5.00")},
+			wantTax:   "0.00",
+			wantNet:   "100.00",
+			wantGross: "100.00",
+		},
+		{
+			name:      "exclusive rounds half up",
+			line:      Line{Quantity: 1, UnitPrice: money.MustParse("10.05"), TaxRateBps: 1100},
+			wantTax:   "1.11",
+			wantNet:   "10.05",
+			wantGross: "11.16",
+		},
+		{
+			name:      "inclusive rounds down",
+			line:      Line{Quantity: 1, UnitPrice: money.MustParse("10.00"), TaxRateBps: 1100, TaxInclusive: true},
+			wantTax:   "0.99",
+			wantNet:   "9.01",
+			wantGross: "10.00",
+		},
+		{
+			name:      "rounded per line, not per unit",
+			line:      Line{Quantity: 3, UnitPrice: money.MustParse("3.33"), TaxRateBps: 1000},
+			wantTax:   "1.00",
+			wantNet:   "9.99",
+			wantGross: "10.99",
+		},
+		{
+			name:      "exclusive after a discount",
+			line:      Line{Quantity: 1, UnitPrice: money.MustParse("100.00"), Discount: money.MustParse("10.00"), TaxRateBps: 1100},
+			wantTax:   "9.90",
+			wantNet:   "90.00",
+			wantGross: "99.90",
+		},
+		{
+			name:      "inclusive after a discount",
+			line:      Line{Quantity: 1, UnitPrice: money.MustParse("111.00"), Discount: money.MustParse("11.10"), TaxRateBps: 1100, TaxInclusive: true},
+			wantTax:   "9.90",
+			wantNet:   "90.00",
+			wantGross: "99.90",
+		},
+		{
+			name:      "fully discounted",
+			line:      Line{Quantity: 1, UnitPrice: money.MustParse("111.00"), Discount: money.MustParse("111.00"), TaxRateBps: 1100, TaxInclusive: true},
+			wantTax:   "0.00",
+			wantNet:   "0.00",
+			wantGross: "0.00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := []Line{tt.line}
+			ApplyTax(lines)
+
+			line := lines[0]
+			if got := line.Tax.String(); got != tt.wantTax {
+				t.Errorf("tax = %s, want %s", got, tt.wantTax)
+			}
+			if got := line.Net().String(); got != tt.wantNet {
+				t.Errorf("net = %s, want %s", got, tt.wantNet)
+			}
+			if got := line.Gross().String(); got != tt.wantGross {
+				t.Errorf("gross = %s, want %s", got, tt.wantGross)
+			}
+		})
+	}
+}
+
+func TestApplyTaxSumsRoundedLines(t *testing.T) {
+	// Each line's 0.005 of tax rounds up on its own, so the order tax is 0.02
+	// rather than the 0.01 of rounding the order once.
+	lines := []Line{
+		{ItemID: itemA, Quantity: 1, UnitPrice: money.MustParse("0.05"), TaxRateBps: 1000},
+		{ItemID: itemB, Quantity: 1, UnitPrice: money.MustParse("0.05"), TaxRateBps: 1000},
+	}
+	ApplyTax(lines)
+
+	if got := TotalTax(lines).String(); got != "0.02" {
+		t.Errorf("total tax = %s, want 0.02", got)
+	}
+}
+
+func TestApplyTaxAfterCoupons(t *testing.T) {
+	// A tax-inclusive item at 11% and a tax-exclusive item at 10%, with 10%
+	// off the order.
+	lines := []Line{
+		{ItemID: itemA, Quantity: 1, UnitPrice: money.MustParse("111.00"), TaxRateBps: 1100, TaxInclusive: true},
+		{ItemID: itemB, Quantity: 2, UnitPrice: money.MustParse("25.00"), TaxRateBps: 1000},
+	}
+
+	if _, err := ApplyCoupons(lines, []CouponUsage{{Coupon: percentageCoupon("TEN", 1000)}}, time.Now()); err != nil {
+		t.Fatalf("ApplyCoupons: %v", err)
+	}
+	ApplyTax(lines)
+
+	totals := []struct {
+		name string
+		got  money.Money
+		want string
+	}{
+		{"subtotal", Subtotal(lines), "161.00"},
+		{"discount", TotalDiscount(lines), "16.10"},
+		{"net", TotalNet(lines), "135.00"},
+		{"tax", TotalTax(lines), "14.40"},
+		{"gross", TotalGross(lines), "149.40"},
+	}
+	for _, total := range totals {
+		if got := total.got.String(); got != total.want {
+			t.Errorf("%s = %s, want %s", total.name, got, total.want)
+		}
+	}
+
+	// The inclusive line's tax comes out of the discounted amount.
+	if got := lines[0].Tax.String(); got != "9.90" {
+		t.Errorf("inclusive line tax = %s, want 9.90", got)
+	}
+}
@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"errors"
+	"ordent/dto"
+	"ordent/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AddressRepository interface {
+	CreateAddress(address *models.Address) error
+	GetAddressesByUserID(userID uuid.UUID) ([]dto.AddressResponse, error)
+	GetAddressByID(userID uuid.UUID, addressID uuid.UUID) (*models.Address, error)
+	GetAddressDetail(userID uuid.UUID, addressID uuid.UUID) (*dto.AddressResponse, error)
+	GetDefaultAddress(userID uuid.UUID) (*models.Address, error)
+	EditAddress(address *models.Address, userID uuid.UUID, addressID uuid.UUID) error
+	DeleteAddress(userID uuid.UUID, addressID uuid.UUID) error
+}
+
+type addressRepository struct {
+	db *gorm.DB
+}
+
+func NewAddressRepository(db *gorm.DB) AddressRepository {
+	return &addressRepository{db: db}
+}
+
+// CreateAddress saves the address. A user's first address becomes their
+// default, and a new default replaces the old one.
+func (ar *addressRepository) CreateAddress(address *models.Address) error {
+	return ar.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Address{}).Where("user_id = ?", address.UserID).Count(&count).Error; err != nil {
+			return err
+		}
+
+		if count == 0 {
+			address.IsDefault = true
+		}
+
+		if address.IsDefault {
+			if err := clearDefaultAddress(tx, address.UserID); err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(address).Error
+	})
+}
+
+func (ar *addressRepository) GetAddressesByUserID(userID uuid.UUID) ([]dto.AddressResponse, error) {
+	var addresses []models.Address
+	if err := ar.db.Where("user_id = ?", userID).Order("is_default DESC, created_at DESC").Find(&addresses).Error; err != nil {
+		return nil, err
+	}
+
+	addressResponses := []dto.AddressResponse{}
+	for _, address := range addresses {
+		addressResponses = append(addressResponses, toAddressResponse(address))
+	}
+
+	return addressResponses, nil
+}
+
+// GetAddressByID only finds addresses of userID; anybody else's address is
+// reported as gorm.ErrRecordNotFound.
+func (ar *addressRepository) GetAddressByID(userID uuid.UUID, addressID uuid.UUID) (*models.Address, error) {
+	var address models.Address
+	if err := ar.db.Where("id = ? AND user_id = ?", addressID, userID).First(&address).Error; err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+func (ar *addressRepository) GetAddressDetail(userID uuid.UUID, addressID uuid.UUID) (*dto.AddressResponse, error) {
+	address, err := ar.GetAddressByID(userID, addressID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := toAddressResponse(*address)
+	return &response, nil
+}
+
+func (ar *addressRepository) GetDefaultAddress(userID uuid.UUID) (*models.Address, error) {
+	var address models.Address
+	if err := ar.db.Where("user_id = ? AND is_default = ?", userID, true).First(&address).Error; err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+func (ar *addressRepository) EditAddress(address *models.Address, userID uuid.UUID, addressID uuid.UUID) error {
+	return ar.db.Transaction(func(tx *gorm.DB) error {
+		if address.IsDefault {
+			if err := clearDefaultAddress(tx, userID); err != nil {
+				return err
+			}
+		}
+
+		result := tx.Model(&models.Address{}).Where("id = ? AND user_id = ?", addressID, userID).
+			Select("*").Omit("id", "user_id", "created_at", "deleted_at").
+			Updates(address)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// DeleteAddress removes the address from the book. Orders keep their own
+// copy of it. When the default address is deleted, the newest remaining
+// address becomes the default.
+func (ar *addressRepository) DeleteAddress(userID uuid.UUID, addressID uuid.UUID) error {
+	return ar.db.Transaction(func(tx *gorm.DB) error {
+		var address models.Address
+		if err := tx.Where("id = ? AND user_id = ?", addressID, userID).First(&address).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&address).Error; err != nil {
+			return err
+		}
+
+		if !address.IsDefault {
+			return nil
+		}
+
+		var next models.Address
+		err := tx.Where("user_id = ?", userID).Order("created_at DESC").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&next).Update("is_default", true).Error
+	})
+}
+
+func toAddressResponse(address models.Address) dto.AddressResponse {
+	return dto.AddressResponse{
+		ID:    address.ID,
+		Label: address.Label,
+		PostalAddressResponse: dto.PostalAddressResponse{
+			RecipientName: address.RecipientName,
+			Phone:         address.Phone,
+			Line1:         address.Line1,
+			Line2:         address.Line2,
+			City:          address.City,
+			Province:      address.Province,
+			PostalCode:    address.PostalCode,
+			Country:       address.Country,
+		},
+		IsDefault: address.IsDefault,
+		CreatedAt: address.CreatedAt,
+	}
+}
+
+func clearDefaultAddress(tx *gorm.DB, userID uuid.UUID) error {
+	return tx.Model(&models.Address{}).Where("user_id = ? AND is_default = ?", userID, true).Update("is_default", false).Error
+}
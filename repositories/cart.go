@@ -0,0 +1,246 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"ordent/dto"
+	"ordent/models"
+	"ordent/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrCartChanged is returned when the lines of a cart changed between reading
+// them for checkout and removing them.
+var ErrCartChanged = errors.New("cart changed during checkout")
+
+type CartRepository interface {
+	WithTx(tx *gorm.DB) CartRepository
+	GetOrCreateCart(userID uuid.UUID) (*models.Cart, error)
+	GetCartDetail(userID uuid.UUID) (*dto.CartResponse, error)
+	AddCartItem(cartID uuid.UUID, itemID uuid.UUID, variantID uuid.UUID, quantity int) error
+	UpdateCartItem(cartID uuid.UUID, itemID uuid.UUID, variantID uuid.UUID, quantity int) error
+	RemoveCartItem(cartID uuid.UUID, itemID uuid.UUID, variantID uuid.UUID) error
+	ClearCart(cartID uuid.UUID) error
+	RemoveCheckedOutItems(cartID uuid.UUID, checkedOut []models.CartItem) error
+}
+
+type cartRepository struct {
+	db *gorm.DB
+}
+
+func NewCartRepository(db *gorm.DB) CartRepository {
+	return &cartRepository{db: db}
+}
+
+func (cr *cartRepository) WithTx(tx *gorm.DB) CartRepository {
+	return &cartRepository{db: tx}
+}
+
+// GetOrCreateCart returns the user's cart with its lines and their items,
+// creating an empty cart on first use. Lines whose item or variant has been
+// deleted are dropped before the cart is returned.
+func (cr *cartRepository) GetOrCreateCart(userID uuid.UUID) (*models.Cart, error) {
+	var cart models.Cart
+	err := cr.db.Where("user_id = ?", userID).First(&cart).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		cart = models.Cart{UserID: userID}
+		err = cr.db.Create(&cart).Error
+		// Another request created the cart first; use that one.
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			err = cr.db.Where("user_id = ?", userID).First(&cart).Error
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cr.removeDeletedItems(cart.ID); err != nil {
+		return nil, err
+	}
+
+	if err := cr.db.Preload("Item").Where("cart_id = ?", cart.ID).Order("created_at ASC").Find(&cart.CartItems).Error; err != nil {
+		return nil, err
+	}
+
+	return &cart, nil
+}
+
+func (cr *cartRepository) GetCartDetail(userID uuid.UUID) (*dto.CartResponse, error) {
+	cart, err := cr.GetOrCreateCart(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := cr.getLineVariants(cart.CartItems)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.CartResponse{
+		ID:        cart.ID,
+		CartItems: []dto.CartItemResponse{},
+		Currency:  money.DefaultCurrency().Code,
+	}
+
+	for _, cartItem := range cart.CartItems {
+		price, stock := cartItem.Item.Price, cartItem.Item.Stock
+		variant := variants[cartItem.VariantID]
+		if variant != nil {
+			price, stock = variant.UnitPrice(&cartItem.Item), variant.Stock
+		}
+		subtotal := price.Mul(int64(cartItem.Quantity))
+
+		var warning string
+		switch {
+		case stock == 0:
+			warning = "Out of stock"
+		case stock < cartItem.Quantity:
+			warning = fmt.Sprintf("Only %d left in stock", stock)
+		}
+
+		response.CartItems = append(response.CartItems, dto.CartItemResponse{
+			Item: dto.GetItemDetailTransactionResponse{
+				ID:   cartItem.Item.ID,
+				Name: cartItem.Item.Name,
+			},
+			Variant:        toVariantSummary(variant),
+			Quantity:       cartItem.Quantity,
+			PricePerUnit:   price,
+			Subtotal:       subtotal,
+			AvailableStock: stock,
+			Warning:        warning,
+		})
+		response.Total = response.Total.Add(subtotal)
+	}
+
+	return response, nil
+}
+
+// getLineVariants loads the variants of the cart lines that have one, keyed
+// by ID.
+func (cr *cartRepository) getLineVariants(cartItems []models.CartItem) (map[uuid.UUID]*models.ItemVariant, error) {
+	var variantIDs []uuid.UUID
+	for _, cartItem := range cartItems {
+		if cartItem.VariantID != uuid.Nil {
+			variantIDs = append(variantIDs, cartItem.VariantID)
+		}
+	}
+
+	variants := map[uuid.UUID]*models.ItemVariant{}
+	if len(variantIDs) == 0 {
+		return variants, nil
+	}
+
+	var found []models.ItemVariant
+	if err := cr.db.Where("id IN ?", variantIDs).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for i := range found {
+		variants[found[i].ID] = &found[i]
+	}
+	return variants, nil
+}
+
+// AddCartItem adds quantity to the cart line for the item and variant,
+// creating the line when it is not in the cart yet. variantID is uuid.Nil for
+// items without variants.
+func (cr *cartRepository) AddCartItem(cartID uuid.UUID, itemID uuid.UUID, variantID uuid.UUID, quantity int) error {
+	cartItem := &models.CartItem{
+		CartID:    cartID,
+		ItemID:    itemID,
+		VariantID: variantID,
+		Quantity:  quantity,
+	}
+
+	if err := cr.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cart_id"}, {Name: "item_id"}, {Name: "variant_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"quantity": gorm.Expr("quantity + ?", quantity)}),
+	}).Create(cartItem).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateCartItem sets the quantity of an existing line. It returns
+// gorm.ErrRecordNotFound when the item and variant are not in the cart.
+func (cr *cartRepository) UpdateCartItem(cartID uuid.UUID, itemID uuid.UUID, variantID uuid.UUID, quantity int) error {
+	result := cr.db.Model(&models.CartItem{}).Where("cart_id = ? AND item_id = ? AND variant_id = ?", cartID, itemID, variantID).Update("quantity", quantity)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RemoveCartItem deletes the line for good rather than soft-deleting it, so the
+// unique (cart_id, item_id, variant_id) index never collides with an old line.
+func (cr *cartRepository) RemoveCartItem(cartID uuid.UUID, itemID uuid.UUID, variantID uuid.UUID) error {
+	result := cr.db.Unscoped().Where("cart_id = ? AND item_id = ? AND variant_id = ?", cartID, itemID, variantID).Delete(&models.CartItem{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (cr *cartRepository) ClearCart(cartID uuid.UUID) error {
+	if err := cr.db.Unscoped().Where("cart_id = ?", cartID).Delete(&models.CartItem{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// RemoveCheckedOutItems deletes the lines an order was placed for. It locks
+// the lines of the cart first, which also keeps new lines out until the
+// transaction ends, and returns ErrCartChanged when they are no longer exactly
+// the checked out lines, so a line added or changed in the meantime is never
+// dropped without being ordered.
+func (cr *cartRepository) RemoveCheckedOutItems(cartID uuid.UUID, checkedOut []models.CartItem) error {
+	var current []models.CartItem
+	if err := cr.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("cart_id = ?", cartID).Find(&current).Error; err != nil {
+		return err
+	}
+
+	quantities := make(map[uuid.UUID]int, len(checkedOut))
+	for _, cartItem := range checkedOut {
+		quantities[cartItem.ID] = cartItem.Quantity
+	}
+	if len(current) != len(quantities) {
+		return ErrCartChanged
+	}
+
+	cartItemIDs := make([]uuid.UUID, len(current))
+	for i, cartItem := range current {
+		if quantity, ok := quantities[cartItem.ID]; !ok || quantity != cartItem.Quantity {
+			return ErrCartChanged
+		}
+		cartItemIDs[i] = cartItem.ID
+	}
+
+	if len(cartItemIDs) == 0 {
+		return nil
+	}
+	return cr.db.Unscoped().Where("id IN ?", cartItemIDs).Delete(&models.CartItem{}).Error
+}
+
+func (cr *cartRepository) removeDeletedItems(cartID uuid.UUID) error {
+	deletedItems := cr.db.Unscoped().Model(&models.Item{}).Select("id").Where("deleted_at IS NOT NULL")
+	if err := cr.db.Unscoped().Where("cart_id = ? AND item_id IN (?)", cartID, deletedItems).Delete(&models.CartItem{}).Error; err != nil {
+		return err
+	}
+
+	deletedVariants := cr.db.Unscoped().Model(&models.ItemVariant{}).Select("id").Where("deleted_at IS NOT NULL")
+	if err := cr.db.Unscoped().Where("cart_id = ? AND variant_id IN (?)", cartID, deletedVariants).Delete(&models.CartItem{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
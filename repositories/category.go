@@ -0,0 +1,290 @@
+package repositories
+
+import (
+	"errors"
+	"ordent/dto"
+	"ordent/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCategoryCycle       = errors.New("category cannot be moved under itself or one of its subcategories")
+	ErrCategoryHasChildren = errors.New("category has subcategories")
+)
+
+// categorySubtreeCTE lists, for every category, itself and all of its
+// descendants as (root_id, category_id) pairs.
+const categorySubtreeCTE = `WITH RECURSIVE subtree (root_id, category_id) AS (
+	SELECT id, id FROM categories WHERE deleted_at IS NULL
+	UNION ALL
+	SELECT subtree.root_id, categories.id FROM subtree
+	JOIN categories ON categories.parent_id = subtree.category_id AND categories.deleted_at IS NULL
+)`
+
+type CategoryRepository interface {
+	WithTx(tx *gorm.DB) CategoryRepository
+	CreateCategory(category *models.Category) error
+	GetCategoryByID(categoryID uuid.UUID) (*models.Category, error)
+	GetCategoryBySlug(slug string) (*models.Category, error)
+	GetCategoriesByIDs(categoryIDs []uuid.UUID) ([]models.Category, error)
+	GetCategoryTree() ([]dto.CategoryTreeResponse, error)
+	GetDescendantIDs(categoryID uuid.UUID) ([]uuid.UUID, error)
+	EditCategory(category *models.Category, categoryID uuid.UUID) error
+	DeleteCategory(categoryID uuid.UUID) error
+}
+
+type categoryRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryRepository(db *gorm.DB) CategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+func (cr *categoryRepository) WithTx(tx *gorm.DB) CategoryRepository {
+	return &categoryRepository{db: tx}
+}
+
+// CreateCategory locks the parent, if any, so it cannot be deleted while
+// the category is added below it. A parent that is gone returns
+// gorm.ErrRecordNotFound.
+func (cr *categoryRepository) CreateCategory(category *models.Category) error {
+	return cr.db.Transaction(func(tx *gorm.DB) error {
+		if category.ParentID != nil {
+			if err := lockCategory(tx, *category.ParentID); err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(category).Error
+	})
+}
+
+func (cr *categoryRepository) GetCategoryByID(categoryID uuid.UUID) (*models.Category, error) {
+	var category models.Category
+	if err := cr.db.Where("id = ?", categoryID).First(&category).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (cr *categoryRepository) GetCategoryBySlug(slug string) (*models.Category, error) {
+	var category models.Category
+	if err := cr.db.Where("slug = ?", slug).First(&category).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (cr *categoryRepository) GetCategoriesByIDs(categoryIDs []uuid.UUID) ([]models.Category, error) {
+	categories := []models.Category{}
+	if len(categoryIDs) == 0 {
+		return categories, nil
+	}
+
+	if err := cr.db.Where("id IN ?", categoryIDs).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// GetCategoryTree returns the root categories with their subcategories nested
+// below them, each with its item counts. Deleted items are not counted.
+func (cr *categoryRepository) GetCategoryTree() ([]dto.CategoryTreeResponse, error) {
+	var categories []models.Category
+	if err := cr.db.Order("sort_order ASC").Order("name ASC").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	type categoryCount struct {
+		CategoryID uuid.UUID
+		Count      int64
+	}
+
+	var directCounts []categoryCount
+	if err := cr.db.Raw(`SELECT item_categories.category_id AS category_id, COUNT(*) AS count
+		FROM item_categories
+		JOIN items ON items.id = item_categories.item_id AND items.deleted_at IS NULL
+		GROUP BY item_categories.category_id`).Scan(&directCounts).Error; err != nil {
+		return nil, err
+	}
+
+	var totalCounts []categoryCount
+	if err := cr.db.Raw(categorySubtreeCTE + `
+		SELECT subtree.root_id AS category_id, COUNT(DISTINCT item_categories.item_id) AS count
+		FROM subtree
+		JOIN item_categories ON item_categories.category_id = subtree.category_id
+		JOIN items ON items.id = item_categories.item_id AND items.deleted_at IS NULL
+		GROUP BY subtree.root_id`).Scan(&totalCounts).Error; err != nil {
+		return nil, err
+	}
+
+	itemCounts := map[uuid.UUID]int64{}
+	for _, count := range directCounts {
+		itemCounts[count.CategoryID] = count.Count
+	}
+	totalItemCounts := map[uuid.UUID]int64{}
+	for _, count := range totalCounts {
+		totalItemCounts[count.CategoryID] = count.Count
+	}
+
+	children := map[uuid.UUID][]models.Category{}
+	var roots []models.Category
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+			continue
+		}
+		children[*category.ParentID] = append(children[*category.ParentID], category)
+	}
+
+	var build func(categories []models.Category) []dto.CategoryTreeResponse
+	build = func(categories []models.Category) []dto.CategoryTreeResponse {
+		nodes := make([]dto.CategoryTreeResponse, 0, len(categories))
+		for _, category := range categories {
+			nodes = append(nodes, dto.CategoryTreeResponse{
+				ID:             category.ID,
+				Name:           category.Name,
+				Slug:           category.Slug,
+				ParentID:       category.ParentID,
+				SortOrder:      category.SortOrder,
+				ItemCount:      itemCounts[category.ID],
+				TotalItemCount: totalItemCounts[category.ID],
+				Children:       build(children[category.ID]),
+			})
+		}
+		return nodes
+	}
+
+	return build(roots), nil
+}
+
+// GetDescendantIDs returns the category and all categories below it.
+func (cr *categoryRepository) GetDescendantIDs(categoryID uuid.UUID) ([]uuid.UUID, error) {
+	var categoryIDs []uuid.UUID
+	if err := cr.db.Raw(`WITH RECURSIVE subtree (category_id) AS (
+		SELECT id FROM categories WHERE id = ? AND deleted_at IS NULL
+		UNION ALL
+		SELECT categories.id FROM subtree
+		JOIN categories ON categories.parent_id = subtree.category_id AND categories.deleted_at IS NULL
+	)
+	SELECT category_id FROM subtree`, categoryID).Scan(&categoryIDs).Error; err != nil {
+		return nil, err
+	}
+	return categoryIDs, nil
+}
+
+// EditCategory writes every field. Moving a category below itself or one of
+// its descendants returns ErrCategoryCycle.
+func (cr *categoryRepository) EditCategory(category *models.Category, categoryID uuid.UUID) error {
+	return cr.db.Transaction(func(tx *gorm.DB) error {
+		if category.ParentID != nil {
+			if err := lockCategoryMove(tx, categoryID, *category.ParentID); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&models.Category{}).Where("id = ?", categoryID).
+			Select("*").Omit("id", "created_at", "deleted_at").
+			Updates(category).Error
+	})
+}
+
+// lockCategoryMove locks the category and every category from its new parent
+// up to the root with SELECT ... FOR UPDATE, and returns ErrCategoryCycle
+// when the category is among them. Two moves that would together form a
+// cycle, such as A under B and B under A, need the same rows, so one of them
+// waits for the other to commit and then finds the cycle.
+//
+// The rows are found with a plain read and locked in id order, which keeps
+// concurrent moves from deadlocking. The chain is then walked again with
+// locking reads, which see the latest committed parents rather than the
+// transaction's snapshot, so a parent that changed before the lock was taken
+// is still caught.
+func lockCategoryMove(tx *gorm.DB, categoryID uuid.UUID, parentID uuid.UUID) error {
+	var chainIDs []uuid.UUID
+	if err := tx.Raw(`WITH RECURSIVE ancestors (category_id, parent_id) AS (
+		SELECT id, parent_id FROM categories WHERE id = ? AND deleted_at IS NULL
+		UNION ALL
+		SELECT categories.id, categories.parent_id FROM ancestors
+		JOIN categories ON categories.id = ancestors.parent_id AND categories.deleted_at IS NULL
+		WHERE ancestors.category_id <> ?
+	)
+	SELECT category_id FROM ancestors`, parentID, categoryID).Scan(&chainIDs).Error; err != nil {
+		return err
+	}
+
+	var locked []models.Category
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+		Where("id IN ?", append(chainIDs, categoryID)).Order("id").
+		Find(&locked).Error; err != nil {
+		return err
+	}
+	found := false
+	for _, category := range locked {
+		found = found || category.ID == categoryID
+	}
+	if !found {
+		return gorm.ErrRecordNotFound
+	}
+
+	seen := map[uuid.UUID]bool{}
+	for ancestorID := &parentID; ancestorID != nil; {
+		if *ancestorID == categoryID || seen[*ancestorID] {
+			return ErrCategoryCycle
+		}
+		seen[*ancestorID] = true
+
+		var ancestor models.Category
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "parent_id").Where("id = ?", *ancestorID).Take(&ancestor).Error; err != nil {
+			return err
+		}
+		ancestorID = ancestor.ParentID
+	}
+	return nil
+}
+
+// lockCategory locks a category with SELECT ... FOR UPDATE. Creating or
+// moving a category locks its new parent, so once a category is locked no
+// subcategory can be added below it until the transaction ends.
+func lockCategory(tx *gorm.DB, categoryID uuid.UUID) error {
+	var category models.Category
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+		Where("id = ?", categoryID).Take(&category).Error
+}
+
+// DeleteCategory removes a category without subcategories and takes its items
+// out of it. The items themselves are kept. The category is locked before
+// its subcategories are counted, so none can be created or moved below it
+// in the meantime.
+func (cr *categoryRepository) DeleteCategory(categoryID uuid.UUID) error {
+	return cr.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockCategory(tx, categoryID); err != nil {
+			return err
+		}
+
+		var children int64
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", categoryID).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return ErrCategoryHasChildren
+		}
+
+		if err := tx.Where("category_id = ?", categoryID).Delete(&models.ItemCategory{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Where("id = ?", categoryID).Delete(&models.Category{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
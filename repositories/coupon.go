@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"errors"
+	"ordent/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrCouponUsageLimitReached = errors.New("coupon usage limit reached")
+
+type CouponRepository interface {
+	WithTx(tx *gorm.DB) CouponRepository
+	CreateCoupon(coupon *models.Coupon) error
+	GetAllCoupons() ([]models.Coupon, error)
+	GetCouponByID(couponID uuid.UUID) (*models.Coupon, error)
+	GetCouponsByCodesForUpdate(codes []string) ([]models.Coupon, error)
+	EditCoupon(coupon *models.Coupon, couponID uuid.UUID) error
+	DeleteCoupon(couponID uuid.UUID) error
+	CountUserRedemptions(couponID uuid.UUID, userID uuid.UUID) (int64, error)
+	RedeemCoupon(redemption *models.CouponRedemption) error
+	ReleaseRedemptions(transactionID uuid.UUID) error
+}
+
+type couponRepository struct {
+	db *gorm.DB
+}
+
+func NewCouponRepository(db *gorm.DB) CouponRepository {
+	return &couponRepository{db: db}
+}
+
+func (cr *couponRepository) WithTx(tx *gorm.DB) CouponRepository {
+	return &couponRepository{db: tx}
+}
+
+func (cr *couponRepository) CreateCoupon(coupon *models.Coupon) error {
+	if err := cr.db.Create(coupon).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cr *couponRepository) GetAllCoupons() ([]models.Coupon, error) {
+	coupons := []models.Coupon{}
+	if err := cr.db.Order("created_at DESC").Find(&coupons).Error; err != nil {
+		return nil, err
+	}
+	return coupons, nil
+}
+
+func (cr *couponRepository) GetCouponByID(couponID uuid.UUID) (*models.Coupon, error) {
+	var coupon models.Coupon
+	if err := cr.db.Where("id = ?", couponID).First(&coupon).Error; err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// GetCouponsByCodesForUpdate locks the coupons in id order, which keeps
+// concurrent checkouts from deadlocking and makes the usage counts read here
+// stable until the transaction ends.
+func (cr *couponRepository) GetCouponsByCodesForUpdate(codes []string) ([]models.Coupon, error) {
+	var coupons []models.Coupon
+	if err := cr.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code IN ?", codes).Order("id").Find(&coupons).Error; err != nil {
+		return nil, err
+	}
+	return coupons, nil
+}
+
+// EditCoupon writes every field, including zero values such as a disabled
+// coupon or a removed limit. The usage counter is left alone.
+func (cr *couponRepository) EditCoupon(coupon *models.Coupon, couponID uuid.UUID) error {
+	if err := cr.db.Model(&models.Coupon{}).Where("id = ?", couponID).
+		Select("*").Omit("id", "created_at", "deleted_at", "used_count").
+		Updates(coupon).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cr *couponRepository) DeleteCoupon(couponID uuid.UUID) error {
+	if err := cr.db.Delete(&models.Coupon{}, couponID).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cr *couponRepository) CountUserRedemptions(couponID uuid.UUID, userID uuid.UUID) (int64, error) {
+	var count int64
+	if err := cr.db.Model(&models.CouponRedemption{}).Where("coupon_id = ? AND user_id = ? AND released_at IS NULL", couponID, userID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// RedeemCoupon counts one use of the coupon and records the redemption. The
+// counter only moves while it is below the global limit, so the limit holds
+// even without the row lock; ErrCouponUsageLimitReached is returned otherwise.
+func (cr *couponRepository) RedeemCoupon(redemption *models.CouponRedemption) error {
+	result := cr.db.Model(&models.Coupon{}).
+		Where("id = ? AND (usage_limit = 0 OR used_count < usage_limit)", redemption.CouponID).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrCouponUsageLimitReached
+	}
+
+	if err := cr.db.Create(redemption).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReleaseRedemptions gives back the coupon uses of a transaction that did not
+// go through so they can be used again. The redemptions stay as history but
+// are marked released and no longer count against any limit.
+func (cr *couponRepository) ReleaseRedemptions(transactionID uuid.UUID) error {
+	var redemptions []models.CouponRedemption
+	if err := cr.db.Where("transaction_id = ? AND released_at IS NULL", transactionID).Find(&redemptions).Error; err != nil {
+		return err
+	}
+
+	for _, redemption := range redemptions {
+		if err := cr.db.Unscoped().Model(&models.Coupon{}).Where("id = ? AND used_count > 0", redemption.CouponID).
+			Update("used_count", gorm.Expr("used_count - 1")).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := cr.db.Model(&models.CouponRedemption{}).Where("transaction_id = ? AND released_at IS NULL", transactionID).
+		Update("released_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"ordent/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EmailMessageRepository interface {
+	CreateEmailMessage(message *models.EmailMessage) error
+	CreateEmailMessages(messages []*models.EmailMessage) error
+	ClaimDueEmailMessages(limit int, lease time.Duration) ([]models.EmailMessage, error)
+	MarkEmailMessageSent(messageID uuid.UUID) error
+	MarkEmailMessageFailed(messageID uuid.UUID, lastError string, nextAttemptAt *time.Time) error
+}
+
+type emailMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailMessageRepository(db *gorm.DB) EmailMessageRepository {
+	return &emailMessageRepository{db: db}
+}
+
+func (er *emailMessageRepository) CreateEmailMessage(message *models.EmailMessage) error {
+	return er.CreateEmailMessages([]*models.EmailMessage{message})
+}
+
+// CreateEmailMessages stores the messages in one statement, so either all of
+// them are queued or none are.
+func (er *emailMessageRepository) CreateEmailMessages(messages []*models.EmailMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for _, message := range messages {
+		if message.NextAttemptAt.IsZero() {
+			message.NextAttemptAt = now
+		}
+		message.Status = models.EmailMessageStatusPending
+	}
+
+	return er.db.Create(messages).Error
+}
+
+// ClaimDueEmailMessages returns up to limit pending messages that are due and
+// pushes their next attempt lease into the future, so other workers skip them
+// while they are being sent. A message whose worker dies mid-send becomes due
+// again once the lease runs out.
+func (er *emailMessageRepository) ClaimDueEmailMessages(limit int, lease time.Duration) ([]models.EmailMessage, error) {
+	var messages []models.EmailMessage
+
+	err := er.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.EmailMessageStatusPending, now).
+			Order("next_attempt_at ASC").
+			Limit(limit).
+			Find(&messages).Error; err != nil {
+			return err
+		}
+
+		if len(messages) == 0 {
+			return nil
+		}
+
+		messageIDs := make([]uuid.UUID, len(messages))
+		for i, message := range messages {
+			messageIDs[i] = message.ID
+		}
+
+		return tx.Model(&models.EmailMessage{}).Where("id IN ?", messageIDs).Updates(map[string]interface{}{
+			"next_attempt_at": now.Add(lease),
+			"attempts":        gorm.Expr("attempts + 1"),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range messages {
+		messages[i].Attempts++
+	}
+
+	return messages, nil
+}
+
+func (er *emailMessageRepository) MarkEmailMessageSent(messageID uuid.UUID) error {
+	return er.db.Model(&models.EmailMessage{}).Where("id = ?", messageID).Updates(map[string]interface{}{
+		"status":     models.EmailMessageStatusSent,
+		"sent_at":    time.Now(),
+		"last_error": "",
+	}).Error
+}
+
+// MarkEmailMessageFailed records a failed delivery. The message is retried at
+// nextAttemptAt, or marked failed for good when nextAttemptAt is nil.
+func (er *emailMessageRepository) MarkEmailMessageFailed(messageID uuid.UUID, lastError string, nextAttemptAt *time.Time) error {
+	updates := map[string]interface{}{
+		"last_error": lastError,
+	}
+	if nextAttemptAt != nil {
+		updates["next_attempt_at"] = *nextAttemptAt
+	} else {
+		updates["status"] = models.EmailMessageStatusFailed
+	}
+
+	return er.db.Model(&models.EmailMessage{}).Where("id = ?", messageID).Updates(updates).Error
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"ordent/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type IdempotencyKeyRepository interface {
+	CreateIdempotencyKey(idempotencyKey *models.IdempotencyKey) error
+	GetIdempotencyKey(userID uuid.UUID, key string) (*models.IdempotencyKey, error)
+	CompleteIdempotencyKey(idempotencyKeyID uuid.UUID, status int, contentType string, body string) error
+	DeleteIdempotencyKey(idempotencyKeyID uuid.UUID) error
+}
+
+type idempotencyKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyKeyRepository(db *gorm.DB) IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{db: db}
+}
+
+// CreateIdempotencyKey inserts a new key. Because (user_id, idempotency_key) is
+// unique, a concurrent or earlier request with the same key makes this return
+// gorm.ErrDuplicatedKey.
+func (ir *idempotencyKeyRepository) CreateIdempotencyKey(idempotencyKey *models.IdempotencyKey) error {
+	if err := ir.db.Create(idempotencyKey).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ir *idempotencyKeyRepository) GetIdempotencyKey(userID uuid.UUID, key string) (*models.IdempotencyKey, error) {
+	var idempotencyKey models.IdempotencyKey
+	if err := ir.db.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&idempotencyKey).Error; err != nil {
+		return nil, err
+	}
+	return &idempotencyKey, nil
+}
+
+func (ir *idempotencyKeyRepository) CompleteIdempotencyKey(idempotencyKeyID uuid.UUID, status int, contentType string, body string) error {
+	if err := ir.db.Model(&models.IdempotencyKey{}).Where("id = ?", idempotencyKeyID).Updates(map[string]interface{}{
+		"status":                models.IdempotencyStatusCompleted,
+		"response_status":       status,
+		"response_content_type": contentType,
+		"response_body":         body,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteIdempotencyKey removes the row for good so that the unique index frees
+// the key for a new request.
+func (ir *idempotencyKeyRepository) DeleteIdempotencyKey(idempotencyKeyID uuid.UUID) error {
+	if err := ir.db.Unscoped().Delete(&models.IdempotencyKey{}, idempotencyKeyID).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"ordent/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ImportJobRepository interface {
+	CreateImportJob(job *models.ImportJob) error
+	GetImportJobByID(jobID uuid.UUID) (*models.ImportJob, error)
+	SaveImportJobProgress(job *models.ImportJob) error
+	FailInterruptedImportJobs() (int64, error)
+}
+
+type importJobRepository struct {
+	db *gorm.DB
+}
+
+func NewImportJobRepository(db *gorm.DB) ImportJobRepository {
+	return &importJobRepository{db: db}
+}
+
+func (jr *importJobRepository) CreateImportJob(job *models.ImportJob) error {
+	return jr.db.Create(job).Error
+}
+
+func (jr *importJobRepository) GetImportJobByID(jobID uuid.UUID) (*models.ImportJob, error) {
+	var job models.ImportJob
+	if err := jr.db.Where("id = ?", jobID).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// SaveImportJobProgress writes the counters, errors and status of the job.
+func (jr *importJobRepository) SaveImportJobProgress(job *models.ImportJob) error {
+	return jr.db.Model(&models.ImportJob{}).Where("id = ?", job.ID).
+		Select("status", "processed_rows", "created_rows", "updated_rows", "failed_rows", "errors", "last_error", "finished_at").
+		Updates(job).Error
+}
+
+// FailInterruptedImportJobs marks jobs that were still running when the
+// application stopped as failed. Jobs run inside the process that accepted
+// them, so after a restart nothing will finish them.
+func (jr *importJobRepository) FailInterruptedImportJobs() (int64, error) {
+	result := jr.db.Model(&models.ImportJob{}).Where("status = ?", models.ImportJobStatusRunning).
+		Updates(map[string]interface{}{
+			"status":      models.ImportJobStatusFailed,
+			"last_error":  "Interrupted by a restart",
+			"finished_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"ordent/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RenderInvoiceFunc renders the PDF of an invoice once its number and issue
+// time are known.
+type RenderInvoiceFunc func(number string, issuedAt time.Time) ([]byte, error)
+
+type InvoiceRepository interface {
+	GetInvoiceByTransactionID(transactionID uuid.UUID) (*models.Invoice, error)
+	IssueInvoice(transactionID uuid.UUID, render RenderInvoiceFunc) (*models.Invoice, error)
+}
+
+type invoiceRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) InvoiceRepository {
+	return &invoiceRepository{db: db}
+}
+
+func (ir *invoiceRepository) GetInvoiceByTransactionID(transactionID uuid.UUID) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := ir.db.Where("transaction_id = ?", transactionID).First(&invoice).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+var errInvoiceAlreadyIssued = errors.New("invoice already issued")
+
+// IssueInvoice takes the next number of the current year, renders the PDF and
+// stores it. The year's counter stays locked until the invoice is saved, so
+// numbers are sequential without gaps. When another request issued the
+// invoice first, its invoice is returned and the number is not used.
+func (ir *invoiceRepository) IssueInvoice(transactionID uuid.UUID, render RenderInvoiceFunc) (*models.Invoice, error) {
+	var invoice *models.Invoice
+
+	err := ir.db.Transaction(func(tx *gorm.DB) error {
+		issuedAt := time.Now()
+		year := issuedAt.Year()
+
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.InvoiceSequence{Year: year}).Error; err != nil {
+			return err
+		}
+
+		var sequence models.InvoiceSequence
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("year = ?", year).First(&sequence).Error; err != nil {
+			return err
+		}
+
+		// Checked under the lock so a concurrent issue is seen.
+		var existing int64
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&models.Invoice{}).Where("transaction_id = ?", transactionID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return errInvoiceAlreadyIssued
+		}
+
+		number := fmt.Sprintf("INV-%d-%06d", year, sequence.LastNumber+1)
+
+		pdf, err := render(number, issuedAt)
+		if err != nil {
+			return err
+		}
+
+		checksum := sha256.Sum256(pdf)
+		invoice = &models.Invoice{
+			TransactionID: transactionID,
+			Number:        number,
+			IssuedAt:      issuedAt,
+			PDF:           pdf,
+			Checksum:      hex.EncodeToString(checksum[:]),
+		}
+
+		if err := tx.Create(invoice).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return errInvoiceAlreadyIssued
+			}
+			return err
+		}
+
+		return tx.Model(&models.InvoiceSequence{}).Where("year = ?", year).Update("last_number", sequence.LastNumber+1).Error
+	})
+	if errors.Is(err, errInvoiceAlreadyIssued) {
+		return ir.GetInvoiceByTransactionID(transactionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return invoice, nil
+}
@@ -1,18 +1,81 @@
 package repositories
 
 import (
+	"errors"
 	"ordent/dto"
 	"ordent/models"
+	"ordent/money"
+	"ordent/utils"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// ItemSortField is a column the item list can be sorted by.
+type ItemSortField string
+
+const (
+	ItemSortName      ItemSortField = "name"
+	ItemSortPrice     ItemSortField = "price"
+	ItemSortStock     ItemSortField = "stock"
+	ItemSortCreatedAt ItemSortField = "created_at"
+)
+
+func (f ItemSortField) IsValid() bool {
+	switch f {
+	case ItemSortName, ItemSortPrice, ItemSortStock, ItemSortCreatedAt:
+		return true
+	}
+	return false
+}
+
+// ItemFilter narrows and orders GetItems. Nil and zero fields are not
+// applied. Pages are chosen either by Cursor or by Page; Cursor wins when
+// both are set.
+type ItemFilter struct {
+	Search   string
+	MinPrice *money.Money
+	MaxPrice *money.Money
+	InStock  bool
+	// CategoryIDs keeps items in any of the categories; callers expand a
+	// category to its descendants before filtering.
+	CategoryIDs []uuid.UUID
+	TagID       *uuid.UUID
+	SortBy      ItemSortField
+	SortDesc    bool
+	Page        int
+	Limit       int
+	Cursor      *utils.SortCursor
+}
+
+// CursorSort identifies the sort a cursor was made for, e.g. "-price".
+func (f ItemFilter) CursorSort() string {
+	if f.SortDesc {
+		return "-" + string(f.SortBy)
+	}
+	return string(f.SortBy)
+}
+
 type ItemRepository interface {
+	WithTx(tx *gorm.DB) ItemRepository
 	CreateItem(item *models.Item) error
-	GetAllItems() ([]dto.GetAllItemResponse, error)
+	GetItems(filter ItemFilter) (*dto.ItemListResponse, error)
 	GetItemByID(itemID uuid.UUID) (*models.Item, error)
+	GetItemBySKU(sku string) (*models.Item, error)
+	GetItemDetail(itemID uuid.UUID) (*dto.GetAllItemResponse, error)
+	GetItemDetails(itemIDs []uuid.UUID) (map[uuid.UUID]dto.GetAllItemResponse, error)
+	GetItemsAfter(afterID uuid.UUID, limit int) ([]dto.GetAllItemResponse, error)
+	GetItemByIDForUpdate(itemID uuid.UUID) (*models.Item, error)
 	EditItem(item *models.Item, itemID uuid.UUID) error
+	SetItemCategories(itemID uuid.UUID, categoryIDs []uuid.UUID) error
+	SetItemTags(itemID uuid.UUID, tagIDs []uuid.UUID) error
+	DecrementStock(itemID uuid.UUID, quantity int) error
+	IncrementStock(itemID uuid.UUID, quantity int) error
 	DeleteItem(itemID uuid.UUID) error
 }
 
@@ -24,8 +87,14 @@ func NewItemRepository(db *gorm.DB) ItemRepository {
 	return &itemRepository{db: db}
 }
 
+func (ir *itemRepository) WithTx(tx *gorm.DB) ItemRepository {
+	return &itemRepository{db: tx}
+}
+
+// CreateItem inserts the item only; its categories and tags are linked with
+// SetItemCategories and SetItemTags.
 func (ir *itemRepository) CreateItem(item *models.Item) error {
-	if err := ir.db.Create(item).Error; err != nil {
+	if err := ir.db.Omit(clause.Associations).Create(item).Error; err != nil {
 		return err
 	}
 	return nil
@@ -39,26 +108,316 @@ func (ir *itemRepository) GetItemByID(itemID uuid.UUID) (*models.Item, error) {
 	return &item, nil
 }
 
-func (ir *itemRepository) GetAllItems() ([]dto.GetAllItemResponse, error) {
+func (ir *itemRepository) GetItemBySKU(sku string) (*models.Item, error) {
+	var item models.Item
+	if err := ir.db.Where("sku = ?", sku).First(&item).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (ir *itemRepository) GetItemDetail(itemID uuid.UUID) (*dto.GetAllItemResponse, error) {
+	var item models.Item
+	if err := preloadItemRelations(ir.db).Where("id = ?", itemID).First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	response := toItemResponse(item)
+	return &response, nil
+}
+
+// GetItemDetails loads several items keyed by ID. Items that do not exist
+// are left out.
+func (ir *itemRepository) GetItemDetails(itemIDs []uuid.UUID) (map[uuid.UUID]dto.GetAllItemResponse, error) {
+	responses := map[uuid.UUID]dto.GetAllItemResponse{}
+	if len(itemIDs) == 0 {
+		return responses, nil
+	}
+
+	var items []models.Item
+	if err := preloadItemRelations(ir.db).Where("id IN ?", itemIDs).Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		responses[item.ID] = toItemResponse(item)
+	}
+	return responses, nil
+}
+
+// GetItemsAfter returns up to limit items with an ID greater than afterID,
+// ordered by ID, so the whole catalog can be walked in batches. Pass
+// uuid.Nil to start at the beginning.
+func (ir *itemRepository) GetItemsAfter(afterID uuid.UUID, limit int) ([]dto.GetAllItemResponse, error) {
 	var items []models.Item
-	if err := ir.db.Find(&items).Error; err != nil {
+	if err := preloadItemRelations(ir.db).Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&items).Error; err != nil {
 		return nil, err
 	}
 
-	var itemResponses []dto.GetAllItemResponse
+	responses := make([]dto.GetAllItemResponse, 0, len(items))
 	for _, item := range items {
-		itemResponses = append(itemResponses, dto.GetAllItemResponse{
-			ID:    item.ID,
-			Name:  item.Name,
-			Price: item.Price,
+		responses = append(responses, toItemResponse(item))
+	}
+	return responses, nil
+}
+
+// GetItemByIDForUpdate reads the item with SELECT ... FOR UPDATE. It is only
+// meaningful on a repository bound to a transaction via WithTx.
+func (ir *itemRepository) GetItemByIDForUpdate(itemID uuid.UUID) (*models.Item, error) {
+	var item models.Item
+	if err := ir.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", itemID).First(&item).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetItems returns one page of items. Rows are ordered by the sort column and
+// then by id, which keeps keyset pagination stable when sort values repeat.
+func (ir *itemRepository) GetItems(filter ItemFilter) (*dto.ItemListResponse, error) {
+	query := ir.db.Model(&models.Item{})
+
+	if filter.Search != "" {
+		query = query.Where("name LIKE ?", "%"+escapeLike(filter.Search)+"%")
+	}
+
+	if filter.MinPrice != nil {
+		query = query.Where("price >= ?", *filter.MinPrice)
+	}
+
+	if filter.MaxPrice != nil {
+		query = query.Where("price <= ?", *filter.MaxPrice)
+	}
+
+	if filter.InStock {
+		query = query.Where("stock > 0")
+	}
+
+	if len(filter.CategoryIDs) > 0 {
+		query = query.Where("id IN (?)", ir.db.Model(&models.ItemCategory{}).Select("item_id").Where("category_id IN ?", filter.CategoryIDs))
+	}
+
+	if filter.TagID != nil {
+		query = query.Where("id IN (?)", ir.db.Model(&models.ItemTag{}).Select("item_id").Where("tag_id = ?", *filter.TagID))
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	direction := "ASC"
+	comparison := ">"
+	if filter.SortDesc {
+		direction = "DESC"
+		comparison = "<"
+	}
+
+	sortColumn := string(filter.SortBy)
+	pageQuery := query.Order(sortColumn + " " + direction).Order("id " + direction).Limit(filter.Limit + 1)
+
+	if filter.Cursor != nil {
+		value, err := parseItemSortValue(filter.SortBy, filter.Cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		pageQuery = pageQuery.Where("("+sortColumn+" "+comparison+" ?) OR ("+sortColumn+" = ? AND id "+comparison+" ?)", value, value, filter.Cursor.ID)
+	} else if filter.Page > 1 {
+		pageQuery = pageQuery.Offset((filter.Page - 1) * filter.Limit)
+	}
+
+	var items []models.Item
+	if err := preloadItemRelations(pageQuery).Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	response := &dto.ItemListResponse{
+		Items: []dto.GetAllItemResponse{},
+		Pagination: dto.PaginationMeta{
+			Total: total,
+			Limit: filter.Limit,
+		},
+	}
+
+	if filter.Cursor == nil {
+		response.Pagination.Page = max(filter.Page, 1)
+		response.Pagination.TotalPages = int((total + int64(filter.Limit) - 1) / int64(filter.Limit))
+	}
+
+	if len(items) > filter.Limit {
+		items = items[:filter.Limit]
+		last := items[len(items)-1]
+		response.Pagination.NextCursor = utils.EncodeSortCursor(utils.SortCursor{
+			Sort:  filter.CursorSort(),
+			Value: itemSortValue(last, filter.SortBy),
+			ID:    last.ID,
 		})
 	}
 
-	return itemResponses, nil
+	for _, item := range items {
+		response.Items = append(response.Items, toItemResponse(item))
+	}
+
+	return response, nil
+}
+
+// preloadItemRelations loads the categories and tags of the queried items,
+// each ordered by name, their variants in the order they were added and
+// their images by position.
+func preloadItemRelations(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Categories", func(db *gorm.DB) *gorm.DB { return db.Order("categories.name ASC") }).
+		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("tags.name ASC") }).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Images", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") })
+}
+
+func toItemResponse(item models.Item) dto.GetAllItemResponse {
+	categories := make([]dto.ItemCategoryResponse, 0, len(item.Categories))
+	for _, category := range item.Categories {
+		categories = append(categories, dto.ItemCategoryResponse{ID: category.ID, Name: category.Name, Slug: category.Slug})
+	}
+
+	tags := make([]dto.ItemTagResponse, 0, len(item.Tags))
+	for _, tag := range item.Tags {
+		tags = append(tags, dto.ItemTagResponse{ID: tag.ID, Name: tag.Name, Slug: tag.Slug})
+	}
+
+	variants := make([]dto.ItemVariantResponse, 0, len(item.Variants))
+	for _, variant := range item.Variants {
+		variants = append(variants, toItemVariantResponse(item, variant))
+	}
+
+	images := make([]dto.ItemImageResponse, 0, len(item.Images))
+	for _, image := range item.Images {
+		images = append(images, toItemImageResponse(image))
+	}
+
+	return dto.GetAllItemResponse{
+		ID:               item.ID,
+		SKU:              item.SKU,
+		Name:             item.Name,
+		Price:            item.Price,
+		Stock:            item.Stock,
+		WeightGrams:      item.WeightGrams,
+		ReorderThreshold: item.ReorderThreshold,
+		TaxMode:          string(item.TaxMode),
+		TaxRateID:        item.TaxRateID,
+		Categories:       categories,
+		Tags:             tags,
+		Variants:         variants,
+		Images:           images,
+		CreatedAt:        item.CreatedAt,
+		UpdatedAt:        item.UpdatedAt,
+	}
+}
+
+// itemSortValue formats the item's sort key for a cursor.
+func itemSortValue(item models.Item, sortBy ItemSortField) string {
+	switch sortBy {
+	case ItemSortName:
+		return item.Name
+	case ItemSortPrice:
+		return strconv.FormatInt(item.Price.Minor(), 10)
+	case ItemSortStock:
+		return strconv.Itoa(item.Stock)
+	default:
+		return item.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
 }
 
+// parseItemSortValue reads back a sort key written by itemSortValue.
+func parseItemSortValue(sortBy ItemSortField, value string) (interface{}, error) {
+	switch sortBy {
+	case ItemSortName:
+		return value, nil
+	case ItemSortPrice:
+		minor, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, utils.ErrInvalidCursor
+		}
+		return money.FromMinor(minor), nil
+	case ItemSortStock:
+		stock, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, utils.ErrInvalidCursor
+		}
+		return stock, nil
+	default:
+		createdAt, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, utils.ErrInvalidCursor
+		}
+		return createdAt, nil
+	}
+}
+
+// EditItem writes every field, so a tax rate can be removed from an item.
 func (ir *itemRepository) EditItem(item *models.Item, itemID uuid.UUID) error {
-	if err := ir.db.Model(&models.Item{}).Where("id = ?", itemID).Updates(item).Error; err != nil {
+	if err := ir.db.Model(&models.Item{}).Where("id = ?", itemID).
+		Select("*").Omit("id", "created_at", "deleted_at", clause.Associations).
+		Updates(item).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetItemCategories replaces the categories of the item.
+func (ir *itemRepository) SetItemCategories(itemID uuid.UUID, categoryIDs []uuid.UUID) error {
+	if err := ir.db.Where("item_id = ?", itemID).Delete(&models.ItemCategory{}).Error; err != nil {
+		return err
+	}
+
+	if len(categoryIDs) == 0 {
+		return nil
+	}
+
+	links := make([]models.ItemCategory, 0, len(categoryIDs))
+	for _, categoryID := range categoryIDs {
+		links = append(links, models.ItemCategory{ItemID: itemID, CategoryID: categoryID})
+	}
+	return ir.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error
+}
+
+// SetItemTags replaces the tags of the item.
+func (ir *itemRepository) SetItemTags(itemID uuid.UUID, tagIDs []uuid.UUID) error {
+	if err := ir.db.Where("item_id = ?", itemID).Delete(&models.ItemTag{}).Error; err != nil {
+		return err
+	}
+
+	if len(tagIDs) == 0 {
+		return nil
+	}
+
+	links := make([]models.ItemTag, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		links = append(links, models.ItemTag{ItemID: itemID, TagID: tagID})
+	}
+	return ir.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error
+}
+
+// DecrementStock subtracts quantity from the item's stock only when enough is
+// left, so the check and the write happen in one statement. It returns
+// ErrInsufficientStock when no row was updated.
+func (ir *itemRepository) DecrementStock(itemID uuid.UUID, quantity int) error {
+	result := ir.db.Model(&models.Item{}).
+		Where("id = ? AND stock >= ?", itemID, quantity).
+		Update("stock", gorm.Expr("stock - ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+// IncrementStock puts quantity back on the item, e.g. when an order that
+// reserved it fails. Soft-deleted items are restocked as well. A negative
+// quantity takes stock away without checking it stays positive; callers
+// check that under a row lock.
+func (ir *itemRepository) IncrementStock(itemID uuid.UUID, quantity int) error {
+	if err := ir.db.Unscoped().Model(&models.Item{}).Where("id = ?", itemID).Update("stock", gorm.Expr("stock + ?", quantity)).Error; err != nil {
 		return err
 	}
 	return nil
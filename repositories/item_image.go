@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"errors"
+	"ordent/dto"
+	"ordent/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrImageOrderMismatch = errors.New("image order must list every image of the item once")
+
+type ItemImageRepository interface {
+	WithTx(tx *gorm.DB) ItemImageRepository
+	AddImages(itemID uuid.UUID, images []*models.ItemImage) error
+	GetImagesByItemID(itemID uuid.UUID) ([]dto.ItemImageResponse, error)
+	ReorderImages(itemID uuid.UUID, imageIDs []uuid.UUID) error
+	SetPrimaryImage(itemID uuid.UUID, imageID uuid.UUID) error
+	DeleteImage(itemID uuid.UUID, imageID uuid.UUID) (*models.ItemImage, error)
+}
+
+type itemImageRepository struct {
+	db *gorm.DB
+}
+
+func NewItemImageRepository(db *gorm.DB) ItemImageRepository {
+	return &itemImageRepository{db: db}
+}
+
+func (ir *itemImageRepository) WithTx(tx *gorm.DB) ItemImageRepository {
+	return &itemImageRepository{db: tx}
+}
+
+// lockItem serialises changes to the images of one item. It returns
+// gorm.ErrRecordNotFound when the item does not exist.
+func lockItem(tx *gorm.DB, itemID uuid.UUID) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", itemID).First(&models.Item{}).Error
+}
+
+// AddImages appends the images after the item's current ones. The first
+// image an item gets becomes its primary image.
+func (ir *itemImageRepository) AddImages(itemID uuid.UUID, images []*models.ItemImage) error {
+	return ir.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockItem(tx, itemID); err != nil {
+			return err
+		}
+
+		var current struct {
+			Count       int64
+			MaxPosition int
+			Primaries   int64
+		}
+		if err := tx.Model(&models.ItemImage{}).
+			Select("COUNT(*) AS count, COALESCE(MAX(position), -1) AS max_position, COALESCE(SUM(is_primary), 0) AS primaries").
+			Where("item_id = ?", itemID).
+			Scan(&current).Error; err != nil {
+			return err
+		}
+
+		for i, image := range images {
+			image.ItemID = itemID
+			image.Position = current.MaxPosition + 1 + i
+			image.IsPrimary = current.Primaries == 0 && i == 0
+		}
+
+		return tx.Create(images).Error
+	})
+}
+
+func (ir *itemImageRepository) GetImagesByItemID(itemID uuid.UUID) ([]dto.ItemImageResponse, error) {
+	var images []models.ItemImage
+	if err := ir.db.Where("item_id = ?", itemID).Order("position ASC").Find(&images).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.ItemImageResponse, 0, len(images))
+	for _, image := range images {
+		responses = append(responses, toItemImageResponse(image))
+	}
+	return responses, nil
+}
+
+// ReorderImages puts the item's images in the given order. imageIDs must
+// list every image of the item exactly once.
+func (ir *itemImageRepository) ReorderImages(itemID uuid.UUID, imageIDs []uuid.UUID) error {
+	return ir.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockItem(tx, itemID); err != nil {
+			return err
+		}
+
+		var currentIDs []uuid.UUID
+		if err := tx.Model(&models.ItemImage{}).Where("item_id = ?", itemID).Pluck("id", &currentIDs).Error; err != nil {
+			return err
+		}
+
+		if len(currentIDs) != len(imageIDs) {
+			return ErrImageOrderMismatch
+		}
+		remaining := map[uuid.UUID]bool{}
+		for _, id := range currentIDs {
+			remaining[id] = true
+		}
+		for _, id := range imageIDs {
+			if !remaining[id] {
+				return ErrImageOrderMismatch
+			}
+			delete(remaining, id)
+		}
+
+		for position, id := range imageIDs {
+			if err := tx.Model(&models.ItemImage{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetPrimaryImage makes the image the item's only primary image. It returns
+// gorm.ErrRecordNotFound when the image does not belong to the item.
+func (ir *itemImageRepository) SetPrimaryImage(itemID uuid.UUID, imageID uuid.UUID) error {
+	return ir.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockItem(tx, itemID); err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ? AND item_id = ?", imageID, itemID).First(&models.ItemImage{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.ItemImage{}).Where("item_id = ?", itemID).
+			Update("is_primary", gorm.Expr("id = ?", imageID)).Error
+	})
+}
+
+// DeleteImage removes the image for good and returns it so its files can be
+// deleted. When it was the primary image, the first remaining image takes
+// its place.
+func (ir *itemImageRepository) DeleteImage(itemID uuid.UUID, imageID uuid.UUID) (*models.ItemImage, error) {
+	var image models.ItemImage
+	err := ir.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockItem(tx, itemID); err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ? AND item_id = ?", imageID, itemID).First(&image).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Delete(&models.ItemImage{}, "id = ?", imageID).Error; err != nil {
+			return err
+		}
+
+		if !image.IsPrimary {
+			return nil
+		}
+
+		var next models.ItemImage
+		err := tx.Where("item_id = ?", itemID).Order("position ASC").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.ItemImage{}).Where("id = ?", next.ID).Update("is_primary", true).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// toItemImageResponse maps an image without its URLs; the controller fills
+// them in from the storage keys.
+func toItemImageResponse(image models.ItemImage) dto.ItemImageResponse {
+	return dto.ItemImageResponse{
+		ID:           image.ID,
+		Width:        image.Width,
+		Height:       image.Height,
+		Position:     image.Position,
+		IsPrimary:    image.IsPrimary,
+		Key:          image.Key,
+		ThumbnailKey: image.ThumbnailKey,
+	}
+}
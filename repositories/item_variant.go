@@ -0,0 +1,182 @@
+package repositories
+
+import (
+	"ordent/dto"
+	"ordent/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ItemVariantRepository interface {
+	WithTx(tx *gorm.DB) ItemVariantRepository
+	CreateVariant(variant *models.ItemVariant) error
+	GetVariantsByItemID(itemID uuid.UUID) ([]models.ItemVariant, error)
+	GetVariantsByIDs(variantIDs []uuid.UUID) ([]models.ItemVariant, error)
+	GetVariantByID(itemID uuid.UUID, variantID uuid.UUID) (*models.ItemVariant, error)
+	GetVariantByIDForUpdate(itemID uuid.UUID, variantID uuid.UUID) (*models.ItemVariant, error)
+	CountVariants(itemID uuid.UUID) (int64, error)
+	EditVariant(variant *models.ItemVariant, variantID uuid.UUID) error
+	DeleteVariant(variantID uuid.UUID) error
+	DecrementStock(variantID uuid.UUID, quantity int) error
+	IncrementStock(variantID uuid.UUID, quantity int) error
+	SyncItemStock(itemID uuid.UUID) (int, error)
+}
+
+type itemVariantRepository struct {
+	db *gorm.DB
+}
+
+func NewItemVariantRepository(db *gorm.DB) ItemVariantRepository {
+	return &itemVariantRepository{db: db}
+}
+
+func (vr *itemVariantRepository) WithTx(tx *gorm.DB) ItemVariantRepository {
+	return &itemVariantRepository{db: tx}
+}
+
+func (vr *itemVariantRepository) CreateVariant(variant *models.ItemVariant) error {
+	return vr.db.Create(variant).Error
+}
+
+func (vr *itemVariantRepository) GetVariantsByItemID(itemID uuid.UUID) ([]models.ItemVariant, error) {
+	variants := []models.ItemVariant{}
+	if err := vr.db.Where("item_id = ?", itemID).Order("created_at ASC").Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+// GetVariantsByIDs returns the variants that still exist among variantIDs.
+func (vr *itemVariantRepository) GetVariantsByIDs(variantIDs []uuid.UUID) ([]models.ItemVariant, error) {
+	variants := []models.ItemVariant{}
+	if len(variantIDs) == 0 {
+		return variants, nil
+	}
+
+	if err := vr.db.Where("id IN ?", variantIDs).Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+// GetVariantByID returns the variant only when it belongs to the item.
+func (vr *itemVariantRepository) GetVariantByID(itemID uuid.UUID, variantID uuid.UUID) (*models.ItemVariant, error) {
+	var variant models.ItemVariant
+	if err := vr.db.Where("id = ? AND item_id = ?", variantID, itemID).First(&variant).Error; err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// GetVariantByIDForUpdate reads the variant with SELECT ... FOR UPDATE. It is
+// only meaningful on a repository bound to a transaction via WithTx.
+func (vr *itemVariantRepository) GetVariantByIDForUpdate(itemID uuid.UUID, variantID uuid.UUID) (*models.ItemVariant, error) {
+	var variant models.ItemVariant
+	if err := vr.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND item_id = ?", variantID, itemID).First(&variant).Error; err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (vr *itemVariantRepository) CountVariants(itemID uuid.UUID) (int64, error) {
+	var count int64
+	if err := vr.db.Model(&models.ItemVariant{}).Where("item_id = ?", itemID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// EditVariant writes every field, so a price override can be removed.
+func (vr *itemVariantRepository) EditVariant(variant *models.ItemVariant, variantID uuid.UUID) error {
+	return vr.db.Model(&models.ItemVariant{}).Where("id = ?", variantID).
+		Select("*").Omit("id", "item_id", "created_at", "deleted_at").
+		Updates(variant).Error
+}
+
+// DeleteVariant soft-deletes the variant so orders keep showing it. Its SKU
+// stays taken.
+func (vr *itemVariantRepository) DeleteVariant(variantID uuid.UUID) error {
+	result := vr.db.Where("id = ?", variantID).Delete(&models.ItemVariant{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DecrementStock subtracts quantity from the variant's stock only when enough
+// is left. It returns ErrInsufficientStock when no row was updated.
+func (vr *itemVariantRepository) DecrementStock(variantID uuid.UUID, quantity int) error {
+	result := vr.db.Model(&models.ItemVariant{}).
+		Where("id = ? AND stock >= ?", variantID, quantity).
+		Update("stock", gorm.Expr("stock - ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+// IncrementStock puts quantity back on the variant. Soft-deleted variants are
+// restocked as well. A negative quantity takes stock away without checking
+// it stays positive; callers check that under a row lock.
+func (vr *itemVariantRepository) IncrementStock(variantID uuid.UUID, quantity int) error {
+	return vr.db.Unscoped().Model(&models.ItemVariant{}).Where("id = ?", variantID).
+		Update("stock", gorm.Expr("stock + ?", quantity)).Error
+}
+
+// SyncItemStock sets the item's stock to the sum of its variants' stock and
+// returns how much it changed, for the stock ledger. It is called whenever
+// variants are added, changed or removed.
+func (vr *itemVariantRepository) SyncItemStock(itemID uuid.UUID) (int, error) {
+	var item models.Item
+	if err := vr.db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "stock").Where("id = ?", itemID).First(&item).Error; err != nil {
+		return 0, err
+	}
+
+	var variantStock int
+	if err := vr.db.Model(&models.ItemVariant{}).Select("COALESCE(SUM(stock), 0)").Where("item_id = ?", itemID).Scan(&variantStock).Error; err != nil {
+		return 0, err
+	}
+
+	if variantStock == item.Stock {
+		return 0, nil
+	}
+
+	if err := vr.db.Model(&models.Item{}).Where("id = ?", itemID).Update("stock", variantStock).Error; err != nil {
+		return 0, err
+	}
+	return variantStock - item.Stock, nil
+}
+
+func toItemVariantResponse(item models.Item, variant models.ItemVariant) dto.ItemVariantResponse {
+	return dto.ItemVariantResponse{
+		ID:            variant.ID,
+		SKU:           variant.SKU,
+		Options:       variant.Options,
+		Price:         variant.UnitPrice(&item),
+		PriceOverride: variant.Price,
+		Stock:         variant.Stock,
+	}
+}
+
+// toVariantSummary describes the variant of a cart or order line, or returns
+// nil for lines without one.
+func toVariantSummary(variant *models.ItemVariant) *dto.VariantSummaryResponse {
+	if variant == nil {
+		return nil
+	}
+	return &dto.VariantSummaryResponse{
+		ID:      variant.ID,
+		SKU:     variant.SKU,
+		Options: variant.Options,
+		Label:   variant.Label(),
+	}
+}
@@ -0,0 +1,189 @@
+package repositories
+
+import (
+	"errors"
+	"ordent/dto"
+	"ordent/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LowStockAlertRepository interface {
+	WithTx(tx *gorm.DB) LowStockAlertRepository
+	TrackLowStock(itemID uuid.UUID) error
+	GetLowStockItems() ([]dto.LowStockItemResponse, error)
+	ClaimDueAlerts(limit int, lease time.Duration) ([]models.LowStockAlert, error)
+	MarkAlertSent(alertID uuid.UUID) error
+	MarkAlertFailed(alertID uuid.UUID, lastError string, nextAttemptAt *time.Time) error
+}
+
+type lowStockAlertRepository struct {
+	db *gorm.DB
+}
+
+func NewLowStockAlertRepository(db *gorm.DB) LowStockAlertRepository {
+	return &lowStockAlertRepository{db: db}
+}
+
+func (lr *lowStockAlertRepository) WithTx(tx *gorm.DB) LowStockAlertRepository {
+	return &lowStockAlertRepository{db: tx}
+}
+
+// TrackLowStock re-evaluates the item's alert after a change that did not
+// move its stock, such as a new reorder threshold. It must run in the
+// transaction that holds the lock on the item row.
+func (lr *lowStockAlertRepository) TrackLowStock(itemID uuid.UUID) error {
+	return trackLowStock(lr.db, itemID)
+}
+
+// trackLowStock raises an alert when the item's stock is at or below its
+// reorder threshold and no alert is open yet, and resolves the open alert
+// once the stock is above the threshold again. It must run in the
+// transaction that changed the stock, whose lock on the item row keeps two
+// alerts from being raised at once.
+func trackLowStock(tx *gorm.DB, itemID uuid.UUID) error {
+	var item models.Item
+	if err := tx.Select("id", "name", "sku", "stock", "reorder_threshold").Where("id = ?", itemID).Take(&item).Error; err != nil {
+		// Deleted items do not raise alerts.
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	var open int64
+	if err := tx.Model(&models.LowStockAlert{}).Where("item_id = ? AND resolved_at IS NULL", itemID).Count(&open).Error; err != nil {
+		return err
+	}
+
+	low := item.ReorderThreshold > 0 && item.Stock <= item.ReorderThreshold
+	switch {
+	case low && open == 0:
+		return tx.Create(&models.LowStockAlert{
+			ItemID:        item.ID,
+			ItemName:      item.Name,
+			SKU:           item.SKU,
+			Stock:         item.Stock,
+			Threshold:     item.ReorderThreshold,
+			Status:        models.LowStockAlertStatusPending,
+			NextAttemptAt: time.Now(),
+		}).Error
+	case !low && open > 0:
+		return tx.Model(&models.LowStockAlert{}).Where("item_id = ? AND resolved_at IS NULL", itemID).Update("resolved_at", time.Now()).Error
+	}
+	return nil
+}
+
+// GetLowStockItems lists the items whose stock is at or below their reorder
+// threshold, emptiest first.
+func (lr *lowStockAlertRepository) GetLowStockItems() ([]dto.LowStockItemResponse, error) {
+	var items []models.Item
+	if err := lr.db.Where("reorder_threshold > 0 AND stock <= reorder_threshold").
+		Order("stock ASC").Order("name ASC").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.LowStockItemResponse, 0, len(items))
+	if len(items) == 0 {
+		return responses, nil
+	}
+
+	itemIDs := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		itemIDs[i] = item.ID
+	}
+
+	var alerts []models.LowStockAlert
+	if err := lr.db.Select("item_id", "created_at").Where("item_id IN ? AND resolved_at IS NULL", itemIDs).Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+	alertedAt := map[uuid.UUID]time.Time{}
+	for _, alert := range alerts {
+		alertedAt[alert.ItemID] = alert.CreatedAt
+	}
+
+	for _, item := range items {
+		response := dto.LowStockItemResponse{
+			ItemID:           item.ID,
+			SKU:              item.SKU,
+			Name:             item.Name,
+			Stock:            item.Stock,
+			ReorderThreshold: item.ReorderThreshold,
+		}
+		if createdAt, ok := alertedAt[item.ID]; ok {
+			response.AlertedAt = &createdAt
+		}
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+// ClaimDueAlerts returns up to limit pending alerts that are due and pushes
+// their next attempt lease into the future, so other workers skip them while
+// they are being delivered.
+func (lr *lowStockAlertRepository) ClaimDueAlerts(limit int, lease time.Duration) ([]models.LowStockAlert, error) {
+	var alerts []models.LowStockAlert
+
+	err := lr.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.LowStockAlertStatusPending, now).
+			Order("next_attempt_at ASC").
+			Limit(limit).
+			Find(&alerts).Error; err != nil {
+			return err
+		}
+
+		if len(alerts) == 0 {
+			return nil
+		}
+
+		alertIDs := make([]uuid.UUID, len(alerts))
+		for i, alert := range alerts {
+			alertIDs[i] = alert.ID
+		}
+
+		return tx.Model(&models.LowStockAlert{}).Where("id IN ?", alertIDs).Updates(map[string]interface{}{
+			"next_attempt_at": now.Add(lease),
+			"attempts":        gorm.Expr("attempts + 1"),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range alerts {
+		alerts[i].Attempts++
+	}
+
+	return alerts, nil
+}
+
+func (lr *lowStockAlertRepository) MarkAlertSent(alertID uuid.UUID) error {
+	return lr.db.Model(&models.LowStockAlert{}).Where("id = ?", alertID).Updates(map[string]interface{}{
+		"status":     models.LowStockAlertStatusSent,
+		"sent_at":    time.Now(),
+		"last_error": "",
+	}).Error
+}
+
+// MarkAlertFailed records a failed delivery. The alert is retried at
+// nextAttemptAt, or marked failed for good when nextAttemptAt is nil.
+func (lr *lowStockAlertRepository) MarkAlertFailed(alertID uuid.UUID, lastError string, nextAttemptAt *time.Time) error {
+	updates := map[string]interface{}{
+		"last_error": lastError,
+	}
+	if nextAttemptAt != nil {
+		updates["next_attempt_at"] = *nextAttemptAt
+	} else {
+		updates["status"] = models.LowStockAlertStatusFailed
+	}
+
+	return lr.db.Model(&models.LowStockAlert{}).Where("id = ?", alertID).Updates(updates).Error
+}
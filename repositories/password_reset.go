@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"errors"
+	"ordent/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidPasswordResetToken = errors.New("password reset token is invalid or expired")
+
+type PasswordResetRepository interface {
+	CreatePasswordResetToken(token *models.PasswordResetToken) error
+	ResetPassword(tokenHash string, hashedPassword string) error
+}
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+func (pr *passwordResetRepository) CreatePasswordResetToken(token *models.PasswordResetToken) error {
+	return pr.db.Create(token).Error
+}
+
+// ResetPassword sets the password of the user the token was issued to and
+// uses up the token together with every other outstanding token of that user.
+// It returns ErrInvalidPasswordResetToken for unknown, used or expired tokens.
+func (pr *passwordResetRepository) ResetPassword(tokenHash string, hashedPassword string) error {
+	return pr.db.Transaction(func(tx *gorm.DB) error {
+		var token models.PasswordResetToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidPasswordResetToken
+			}
+			return err
+		}
+
+		now := time.Now()
+		if token.UsedAt != nil || now.After(token.ExpiresAt) {
+			return ErrInvalidPasswordResetToken
+		}
+
+		result := tx.Model(&models.User{}).Where("id = ?", token.UserID).Update("password", hashedPassword)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidPasswordResetToken
+		}
+
+		return tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", now).Error
+	})
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"ordent/models"
+
+	"gorm.io/gorm"
+)
+
+type PaymentEventRepository interface {
+	WithTx(tx *gorm.DB) PaymentEventRepository
+	CreatePaymentEvent(paymentEvent *models.PaymentEvent) error
+}
+
+type paymentEventRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentEventRepository(db *gorm.DB) PaymentEventRepository {
+	return &paymentEventRepository{db: db}
+}
+
+func (pr *paymentEventRepository) WithTx(tx *gorm.DB) PaymentEventRepository {
+	return &paymentEventRepository{db: tx}
+}
+
+// CreatePaymentEvent returns gorm.ErrDuplicatedKey when the provider already
+// delivered an event with the same ID.
+func (pr *paymentEventRepository) CreatePaymentEvent(paymentEvent *models.PaymentEvent) error {
+	if err := pr.db.Create(paymentEvent).Error; err != nil {
+		return err
+	}
+	return nil
+}
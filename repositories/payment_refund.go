@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"ordent/models"
+	"ordent/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentRefundRepository interface {
+	WithTx(tx *gorm.DB) PaymentRefundRepository
+	CreatePaymentRefund(refund *models.PaymentRefund) error
+	GetRefundedAmount(chargeID string) (money.Money, error)
+	MarkPaymentRefundSucceeded(id uuid.UUID, refundID string, amount money.Money) error
+	MarkPaymentRefundFailed(id uuid.UUID) error
+	ConfirmPendingRefunds(chargeID string, refundedAmount money.Money) error
+}
+
+type paymentRefundRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRefundRepository(db *gorm.DB) PaymentRefundRepository {
+	return &paymentRefundRepository{db: db}
+}
+
+func (pr *paymentRefundRepository) WithTx(tx *gorm.DB) PaymentRefundRepository {
+	return &paymentRefundRepository{db: tx}
+}
+
+func (pr *paymentRefundRepository) CreatePaymentRefund(refund *models.PaymentRefund) error {
+	return pr.db.Create(refund).Error
+}
+
+// GetRefundedAmount sums the refunds of the charge that succeeded or may
+// still succeed.
+func (pr *paymentRefundRepository) GetRefundedAmount(chargeID string) (money.Money, error) {
+	var refunded money.Money
+	if err := pr.db.Model(&models.PaymentRefund{}).Where("charge_id = ? AND status <> ?", chargeID, models.PaymentRefundStatusFailed).
+		Select("COALESCE(SUM(amount), 0)").Scan(&refunded).Error; err != nil {
+		return 0, err
+	}
+	return refunded, nil
+}
+
+// MarkPaymentRefundSucceeded records the provider's refund ID and amount for
+// a refund the provider accepted.
+func (pr *paymentRefundRepository) MarkPaymentRefundSucceeded(id uuid.UUID, refundID string, amount money.Money) error {
+	return pr.db.Model(&models.PaymentRefund{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":    models.PaymentRefundStatusSucceeded,
+		"refund_id": refundID,
+		"amount":    amount,
+	}).Error
+}
+
+// MarkPaymentRefundFailed gives up a pending refund, so its amount can be
+// refunded again. Refunds the provider webhook already confirmed are left
+// alone.
+func (pr *paymentRefundRepository) MarkPaymentRefundFailed(id uuid.UUID) error {
+	return pr.db.Model(&models.PaymentRefund{}).Where("id = ? AND status = ?", id, models.PaymentRefundStatusPending).
+		Update("status", models.PaymentRefundStatusFailed).Error
+}
+
+// ConfirmPendingRefunds marks pending refunds of the charge succeeded, oldest
+// first, as far as refundedAmount, the total the provider reports refunded,
+// covers them. It settles refunds whose outcome could not be recorded when
+// they were made.
+func (pr *paymentRefundRepository) ConfirmPendingRefunds(chargeID string, refundedAmount money.Money) error {
+	var refunds []models.PaymentRefund
+	if err := pr.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("charge_id = ? AND status <> ?", chargeID, models.PaymentRefundStatusFailed).
+		Order("created_at ASC").Find(&refunds).Error; err != nil {
+		return err
+	}
+
+	var confirmed money.Money
+	for _, refund := range refunds {
+		if refund.Status == models.PaymentRefundStatusSucceeded {
+			confirmed = confirmed.Add(refund.Amount)
+		}
+	}
+
+	var refundIDs []uuid.UUID
+	for _, refund := range refunds {
+		if refund.Status != models.PaymentRefundStatusPending {
+			continue
+		}
+		if confirmed.Add(refund.Amount) > refundedAmount {
+			break
+		}
+		confirmed = confirmed.Add(refund.Amount)
+		refundIDs = append(refundIDs, refund.ID)
+	}
+
+	if len(refundIDs) == 0 {
+		return nil
+	}
+	return pr.db.Model(&models.PaymentRefund{}).Where("id IN ?", refundIDs).
+		Update("status", models.PaymentRefundStatusSucceeded).Error
+}
@@ -0,0 +1,196 @@
+package repositories
+
+import (
+	"ordent/dto"
+	"ordent/models"
+	"ordent/money"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReportGroupBy is the period sales are bucketed into.
+type ReportGroupBy string
+
+const (
+	ReportGroupByDay   ReportGroupBy = "day"
+	ReportGroupByWeek  ReportGroupBy = "week"
+	ReportGroupByMonth ReportGroupBy = "month"
+)
+
+// reportPeriodExpressions gives, for every grouping, the SQL that maps
+// created_at to the first day of its period. Weeks start on Monday.
+var reportPeriodExpressions = map[ReportGroupBy]string{
+	ReportGroupByDay:   "DATE_FORMAT(transactions.created_at, '%Y-%m-%d')",
+	ReportGroupByWeek:  "DATE_FORMAT(DATE_SUB(DATE(transactions.created_at), INTERVAL WEEKDAY(transactions.created_at) DAY), '%Y-%m-%d')",
+	ReportGroupByMonth: "DATE_FORMAT(transactions.created_at, '%Y-%m-01')",
+}
+
+func (g ReportGroupBy) IsValid() bool {
+	_, ok := reportPeriodExpressions[g]
+	return ok
+}
+
+type TopItemsSort string
+
+const (
+	TopItemsByQuantity TopItemsSort = "quantity"
+	TopItemsByRevenue  TopItemsSort = "revenue"
+)
+
+// ReportFilter limits a report to transactions created in [StartDate,
+// EndDate). Nil bounds are open.
+type ReportFilter struct {
+	StartDate *time.Time
+	EndDate   *time.Time
+}
+
+// revenueStatuses are the statuses whose orders count as sales. Fully
+// refunded orders are left out; partially refunded orders count with their
+// recorded refunds subtracted.
+var revenueStatuses = []models.TransactionStatus{
+	models.TransactionStatusPaid,
+	models.TransactionStatusPartiallyRefunded,
+}
+
+type ReportRepository interface {
+	GetSalesSummary(filter ReportFilter) (*dto.SalesSummaryResponse, error)
+	GetSalesByPeriod(filter ReportFilter, groupBy ReportGroupBy) ([]dto.SalesPeriodRow, error)
+	GetTopItems(filter ReportFilter, sortBy TopItemsSort, limit int) ([]dto.TopItemRow, error)
+	GetCustomerTotals(filter ReportFilter, limit int) ([]dto.CustomerTotalRow, error)
+}
+
+type reportRepository struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) ReportRepository {
+	return &reportRepository{db: db}
+}
+
+// salesTransactions selects the transactions that count as sales in the
+// filter's range.
+func (rr *reportRepository) salesTransactions(filter ReportFilter) *gorm.DB {
+	query := rr.db.Model(&models.Transaction{}).Where("transactions.status IN ?", revenueStatuses)
+
+	if filter.StartDate != nil {
+		query = query.Where("transactions.created_at >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("transactions.created_at < ?", *filter.EndDate)
+	}
+
+	return query
+}
+
+// refundTotalsJoin adds the succeeded refunds of each transaction as
+// refunds.amount, which is NULL for transactions without refunds.
+const refundTotalsJoin = "LEFT JOIN (SELECT transaction_id, SUM(amount) AS amount FROM payment_refunds " +
+	"WHERE deleted_at IS NULL AND status = ? GROUP BY transaction_id) AS refunds ON refunds.transaction_id = transactions.id"
+
+// salesTotalsColumns needs refundTotalsJoin. The net, tax, shipping and
+// discount parts add up to the gross revenue; revenue is what is left of it
+// after refunds.
+const salesTotalsColumns = "COUNT(*) AS order_count, " +
+	"COALESCE(SUM(transactions.total_price), 0) AS gross_revenue, " +
+	"COALESCE(SUM(refunds.amount), 0) AS refunded_amount, " +
+	"COALESCE(SUM(transactions.total_price - COALESCE(refunds.amount, 0)), 0) AS revenue, " +
+	"COALESCE(SUM(transactions.net_amount), 0) AS net_amount, " +
+	"COALESCE(SUM(transactions.tax_amount), 0) AS tax_amount, " +
+	"COALESCE(SUM(transactions.shipping_fee), 0) AS shipping_fee, " +
+	"COALESCE(SUM(transactions.discount_amount), 0) AS discount_amount"
+
+func (rr *reportRepository) GetSalesSummary(filter ReportFilter) (*dto.SalesSummaryResponse, error) {
+	summary := &dto.SalesSummaryResponse{}
+	if err := rr.salesTransactions(filter).Joins(refundTotalsJoin, models.PaymentRefundStatusSucceeded).Select(salesTotalsColumns).Scan(summary).Error; err != nil {
+		return nil, err
+	}
+
+	if err := rr.db.Model(&models.TransactionDetail{}).
+		Where("transaction_id IN (?)", rr.salesTransactions(filter).Select("transactions.id")).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&summary.ItemsSold).Error; err != nil {
+		return nil, err
+	}
+
+	summary.AverageOrderValue = averageOrderValue(summary.Revenue, summary.OrderCount)
+	return summary, nil
+}
+
+func (rr *reportRepository) GetSalesByPeriod(filter ReportFilter, groupBy ReportGroupBy) ([]dto.SalesPeriodRow, error) {
+	period := reportPeriodExpressions[groupBy]
+
+	rows := []dto.SalesPeriodRow{}
+	if err := rr.salesTransactions(filter).
+		Joins(refundTotalsJoin, models.PaymentRefundStatusSucceeded).
+		Select(period + " AS period, " + salesTotalsColumns).
+		Group("period").
+		Order("period ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		rows[i].AverageOrderValue = averageOrderValue(rows[i].Revenue, rows[i].OrderCount)
+	}
+	return rows, nil
+}
+
+// GetTopItems ranks items by units sold or by the revenue of their lines,
+// which includes tax but not shipping. Refunds are made on whole orders and
+// are not taken off items. Deleted items are still reported.
+func (rr *reportRepository) GetTopItems(filter ReportFilter, sortBy TopItemsSort, limit int) ([]dto.TopItemRow, error) {
+	order := "quantity_sold DESC, revenue DESC"
+	if sortBy == TopItemsByRevenue {
+		order = "revenue DESC, quantity_sold DESC"
+	}
+
+	rows := []dto.TopItemRow{}
+	if err := rr.salesTransactions(filter).
+		Joins("JOIN transaction_details ON transaction_details.transaction_id = transactions.id AND transaction_details.deleted_at IS NULL").
+		Joins("JOIN items ON items.id = transaction_details.item_id").
+		Select("items.id AS item_id, items.name AS name, " +
+			"SUM(transaction_details.quantity) AS quantity_sold, " +
+			"COUNT(DISTINCT transactions.id) AS order_count, " +
+			"SUM(transaction_details.total_price) AS revenue").
+		Group("items.id, items.name").
+		Order(order).
+		Limit(limit).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// GetCustomerTotals returns the customers who spent the most after refunds,
+// with their order count and first and last order in the range.
+func (rr *reportRepository) GetCustomerTotals(filter ReportFilter, limit int) ([]dto.CustomerTotalRow, error) {
+	rows := []dto.CustomerTotalRow{}
+	if err := rr.salesTransactions(filter).
+		Joins("JOIN users ON users.id = transactions.user_id").
+		Joins(refundTotalsJoin, models.PaymentRefundStatusSucceeded).
+		Select("users.id AS user_id, users.full_name AS full_name, users.email AS email, " +
+			"COUNT(*) AS order_count, " +
+			"SUM(transactions.total_price - COALESCE(refunds.amount, 0)) AS revenue, " +
+			"MIN(transactions.created_at) AS first_order_at, " +
+			"MAX(transactions.created_at) AS last_order_at").
+		Group("users.id, users.full_name, users.email").
+		Order("revenue DESC, order_count DESC").
+		Limit(limit).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		rows[i].AverageOrderValue = averageOrderValue(rows[i].Revenue, rows[i].OrderCount)
+	}
+	return rows, nil
+}
+
+func averageOrderValue(revenue money.Money, orderCount int64) money.Money {
+	if orderCount == 0 {
+		return 0
+	}
+	return revenue.MulRatio(1, orderCount)
+}
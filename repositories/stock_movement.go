@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"ordent/dto"
+	"ordent/models"
+	"ordent/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StockMovementFilter selects one page of an item's movements, newest first.
+// A nil VariantID returns the movements of the item and all its variants.
+type StockMovementFilter struct {
+	ItemID    uuid.UUID
+	VariantID *uuid.UUID
+	Limit     int
+	Cursor    *utils.Cursor
+}
+
+type StockMovementRepository interface {
+	WithTx(tx *gorm.DB) StockMovementRepository
+	RecordMovement(movement *models.StockMovement) error
+	GetMovements(filter StockMovementFilter) (*dto.StockMovementListResponse, error)
+}
+
+type stockMovementRepository struct {
+	db *gorm.DB
+}
+
+func NewStockMovementRepository(db *gorm.DB) StockMovementRepository {
+	return &stockMovementRepository{db: db}
+}
+
+func (sr *stockMovementRepository) WithTx(tx *gorm.DB) StockMovementRepository {
+	return &stockMovementRepository{db: tx}
+}
+
+// RecordMovement appends the movement to the ledger, reading its balance
+// from the item or variant row. It must run in the transaction that changed
+// the stock, right after the change, whose row lock keeps the balance
+// exact. Movements with a zero delta are not recorded. Movements of an
+// item's own stock also raise or resolve its low-stock alert.
+func (sr *stockMovementRepository) RecordMovement(movement *models.StockMovement) error {
+	if movement.Delta == 0 {
+		return nil
+	}
+
+	// Stock returned to deleted items and variants is still recorded.
+	query := sr.db.Unscoped().Model(&models.Item{}).Where("id = ?", movement.ItemID)
+	if movement.VariantID != nil {
+		query = sr.db.Unscoped().Model(&models.ItemVariant{}).Where("id = ?", *movement.VariantID)
+	}
+
+	var balances []int
+	if err := query.Pluck("stock", &balances).Error; err != nil {
+		return err
+	}
+	if len(balances) == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	movement.Balance = balances[0]
+
+	if err := sr.db.Create(movement).Error; err != nil {
+		return err
+	}
+
+	if movement.VariantID != nil {
+		return nil
+	}
+	return trackLowStock(sr.db, movement.ItemID)
+}
+
+func (sr *stockMovementRepository) GetMovements(filter StockMovementFilter) (*dto.StockMovementListResponse, error) {
+	query := sr.db.Where("item_id = ?", filter.ItemID)
+
+	if filter.VariantID != nil {
+		query = query.Where("variant_id = ?", *filter.VariantID)
+	}
+
+	if filter.Cursor != nil {
+		query = query.Where("(created_at < ?) OR (created_at = ? AND id < ?)", filter.Cursor.CreatedAt, filter.Cursor.CreatedAt, filter.Cursor.ID)
+	}
+
+	var movements []models.StockMovement
+	if err := query.Order("created_at DESC").Order("id DESC").Limit(filter.Limit + 1).Find(&movements).Error; err != nil {
+		return nil, err
+	}
+
+	response := &dto.StockMovementListResponse{
+		Movements: []dto.StockMovementResponse{},
+	}
+
+	if len(movements) > filter.Limit {
+		movements = movements[:filter.Limit]
+		last := movements[len(movements)-1]
+		response.NextCursor = utils.EncodeCursor(utils.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	for _, movement := range movements {
+		response.Movements = append(response.Movements, toStockMovementResponse(movement))
+	}
+
+	return response, nil
+}
+
+func toStockMovementResponse(movement models.StockMovement) dto.StockMovementResponse {
+	return dto.StockMovementResponse{
+		ID:            movement.ID,
+		ItemID:        movement.ItemID,
+		VariantID:     movement.VariantID,
+		Delta:         movement.Delta,
+		Balance:       movement.Balance,
+		Reason:        string(movement.Reason),
+		TransactionID: movement.TransactionID,
+		UserID:        movement.UserID,
+		Note:          movement.Note,
+		CreatedAt:     movement.CreatedAt,
+	}
+}
@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"ordent/dto"
+	"ordent/models"
+	"ordent/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TagRepository interface {
+	WithTx(tx *gorm.DB) TagRepository
+	CreateTag(tag *models.Tag) error
+	GetAllTags() ([]dto.TagResponse, error)
+	GetTagByID(tagID uuid.UUID) (*models.Tag, error)
+	GetTagBySlug(slug string) (*models.Tag, error)
+	FindOrCreateTags(names []string) ([]models.Tag, error)
+	EditTag(tag *models.Tag, tagID uuid.UUID) error
+	DeleteTag(tagID uuid.UUID) error
+}
+
+type tagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func (tr *tagRepository) WithTx(tx *gorm.DB) TagRepository {
+	return &tagRepository{db: tx}
+}
+
+func (tr *tagRepository) CreateTag(tag *models.Tag) error {
+	return tr.db.Create(tag).Error
+}
+
+// GetAllTags returns every tag by name with the number of items carrying it.
+// Deleted items are not counted.
+func (tr *tagRepository) GetAllTags() ([]dto.TagResponse, error) {
+	tags := []dto.TagResponse{}
+	if err := tr.db.Model(&models.Tag{}).
+		Select("tags.id AS id, tags.name AS name, tags.slug AS slug, COUNT(items.id) AS item_count").
+		Joins("LEFT JOIN item_tags ON item_tags.tag_id = tags.id").
+		Joins("LEFT JOIN items ON items.id = item_tags.item_id AND items.deleted_at IS NULL").
+		Group("tags.id, tags.name, tags.slug").
+		Order("tags.name ASC").
+		Scan(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (tr *tagRepository) GetTagByID(tagID uuid.UUID) (*models.Tag, error) {
+	var tag models.Tag
+	if err := tr.db.Where("id = ?", tagID).First(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (tr *tagRepository) GetTagBySlug(slug string) (*models.Tag, error) {
+	var tag models.Tag
+	if err := tr.db.Where("slug = ?", slug).First(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// FindOrCreateTags returns the tags with the given names, matched by slug,
+// and creates the ones that do not exist yet. Names without any letter or
+// digit are skipped.
+func (tr *tagRepository) FindOrCreateTags(names []string) ([]models.Tag, error) {
+	tags := []models.Tag{}
+
+	var slugs []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		slug := utils.Slugify(name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		slugs = append(slugs, slug)
+
+		if err := tr.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Tag{Name: name, Slug: slug}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if len(slugs) == 0 {
+		return tags, nil
+	}
+
+	if err := tr.db.Where("slug IN ?", slugs).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (tr *tagRepository) EditTag(tag *models.Tag, tagID uuid.UUID) error {
+	return tr.db.Model(&models.Tag{}).Where("id = ?", tagID).
+		Select("name", "slug").
+		Updates(tag).Error
+}
+
+// DeleteTag removes the tag from every item and deletes it.
+func (tr *tagRepository) DeleteTag(tagID uuid.UUID) error {
+	return tr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", tagID).Delete(&models.ItemTag{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Where("id = ?", tagID).Delete(&models.Tag{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"errors"
+	"ordent/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TaxRateRepository interface {
+	WithTx(tx *gorm.DB) TaxRateRepository
+	CreateTaxRate(taxRate *models.TaxRate) error
+	GetAllTaxRates() ([]models.TaxRate, error)
+	GetTaxRateByID(taxRateID uuid.UUID) (*models.TaxRate, error)
+	GetTaxRatesByIDs(taxRateIDs []uuid.UUID) ([]models.TaxRate, error)
+	GetDefaultTaxRate() (*models.TaxRate, error)
+	EditTaxRate(taxRate *models.TaxRate, taxRateID uuid.UUID) error
+	DeleteTaxRate(taxRateID uuid.UUID) error
+	CountItemsUsingTaxRate(taxRateID uuid.UUID) (int64, error)
+}
+
+type taxRateRepository struct {
+	db *gorm.DB
+}
+
+func NewTaxRateRepository(db *gorm.DB) TaxRateRepository {
+	return &taxRateRepository{db: db}
+}
+
+func (tr *taxRateRepository) WithTx(tx *gorm.DB) TaxRateRepository {
+	return &taxRateRepository{db: tx}
+}
+
+// CreateTaxRate saves the rate; a new default rate takes over from the old
+// one in the same database transaction.
+func (tr *taxRateRepository) CreateTaxRate(taxRate *models.TaxRate) error {
+	return tr.db.Transaction(func(tx *gorm.DB) error {
+		if taxRate.IsDefault {
+			if err := clearDefaultTaxRate(tx); err != nil {
+				return err
+			}
+		}
+		return tx.Create(taxRate).Error
+	})
+}
+
+func (tr *taxRateRepository) GetAllTaxRates() ([]models.TaxRate, error) {
+	taxRates := []models.TaxRate{}
+	if err := tr.db.Order("name ASC").Find(&taxRates).Error; err != nil {
+		return nil, err
+	}
+	return taxRates, nil
+}
+
+func (tr *taxRateRepository) GetTaxRateByID(taxRateID uuid.UUID) (*models.TaxRate, error) {
+	var taxRate models.TaxRate
+	if err := tr.db.Where("id = ?", taxRateID).First(&taxRate).Error; err != nil {
+		return nil, err
+	}
+	return &taxRate, nil
+}
+
+func (tr *taxRateRepository) GetTaxRatesByIDs(taxRateIDs []uuid.UUID) ([]models.TaxRate, error) {
+	var taxRates []models.TaxRate
+	if len(taxRateIDs) == 0 {
+		return taxRates, nil
+	}
+
+	if err := tr.db.Where("id IN ?", taxRateIDs).Find(&taxRates).Error; err != nil {
+		return nil, err
+	}
+	return taxRates, nil
+}
+
+// GetDefaultTaxRate returns nil without an error when no default rate is set,
+// in which case items without their own rate are not taxed.
+func (tr *taxRateRepository) GetDefaultTaxRate() (*models.TaxRate, error) {
+	var taxRate models.TaxRate
+	err := tr.db.Where("is_default = ?", true).First(&taxRate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &taxRate, nil
+}
+
+func (tr *taxRateRepository) EditTaxRate(taxRate *models.TaxRate, taxRateID uuid.UUID) error {
+	return tr.db.Transaction(func(tx *gorm.DB) error {
+		if taxRate.IsDefault {
+			if err := clearDefaultTaxRate(tx); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.TaxRate{}).Where("id = ?", taxRateID).
+			Select("name", "rate_bps", "is_default").
+			Updates(taxRate).Error
+	})
+}
+
+func (tr *taxRateRepository) DeleteTaxRate(taxRateID uuid.UUID) error {
+	if err := tr.db.Delete(&models.TaxRate{}, taxRateID).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tr *taxRateRepository) CountItemsUsingTaxRate(taxRateID uuid.UUID) (int64, error) {
+	var count int64
+	if err := tr.db.Model(&models.Item{}).Where("tax_rate_id = ?", taxRateID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func clearDefaultTaxRate(tx *gorm.DB) error {
+	return tx.Model(&models.TaxRate{}).Where("is_default = ?", true).Update("is_default", false).Error
+}
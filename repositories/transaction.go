@@ -1,13 +1,67 @@
 package repositories
 
 import (
+	"errors"
+	"fmt"
+	"ordent/dto"
 	"ordent/models"
+	"ordent/money"
+	"ordent/utils"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	ErrIllegalStatusTransition      = errors.New("illegal transaction status transition")
+	ErrIllegalFulfillmentTransition = errors.New("illegal fulfillment status transition")
+)
+
+// TransactionFilter narrows GetTransactions. Nil fields are not applied.
+// StartDate is inclusive and EndDate is exclusive.
+type TransactionFilter struct {
+	UserID            *uuid.UUID
+	StartDate         *time.Time
+	EndDate           *time.Time
+	Status            *models.TransactionStatus
+	Cursor            *utils.Cursor
+	Limit             int
+	FulfillmentStatus *models.FulfillmentStatus
+	// MinTotal and MaxTotal bound TotalPrice, both inclusive.
+	MinTotal *money.Money
+	MaxTotal *money.Money
+	// ItemID keeps transactions with at least one line of that item.
+	ItemID *uuid.UUID
+	// Search matches part of the buyer's email, username or full name.
+	Search string
+	// IncludeCustomer fills in the buyer of every transaction.
+	IncludeCustomer bool
+}
+
+// FulfillmentUpdate is one fulfillment step. Carrier and TrackingNumber are
+// kept on the transaction when set and left alone when empty.
+type FulfillmentUpdate struct {
+	Status         models.FulfillmentStatus
+	Carrier        string
+	TrackingNumber string
+	Note           string
+}
+
 type TransactionRepository interface {
+	WithTx(tx *gorm.DB) TransactionRepository
 	CreateTransaction(transaction *models.Transaction) (transactionID string, err error)
+	GetTransactionByID(transactionID uuid.UUID) (*dto.TransactionResponse, error)
+	LockTransaction(transactionID uuid.UUID) error
+	GetStalePendingTransactionIDs(createdBefore time.Time, limit int) ([]uuid.UUID, error)
+	GetTransactionWithCustomer(transactionID uuid.UUID) (*dto.TransactionResponse, error)
+	GetTransactions(filter TransactionFilter) (*dto.TransactionListResponse, error)
+	UpdateTransactionStatus(transactionID uuid.UUID, status models.TransactionStatus, note string) error
+	UpdateFulfillmentStatus(transactionID uuid.UUID, update FulfillmentUpdate) error
+	SetPaymentCharge(transactionID uuid.UUID, provider string, chargeID string) error
 }
 
 type transactionRepository struct {
@@ -18,10 +72,414 @@ func NewTransactionRepository(db *gorm.DB) TransactionRepository {
 	return &transactionRepository{db: db}
 }
 
+func (tr *transactionRepository) WithTx(tx *gorm.DB) TransactionRepository {
+	return &transactionRepository{db: tx}
+}
+
+// CreateTransaction stores the transaction in its initial status (pending when
+// unset) together with the first status history entry.
 func (tr *transactionRepository) CreateTransaction(transaction *models.Transaction) (transactionID string, err error) {
-	if err := tr.db.Create(transaction).Error; err != nil {
+	if transaction.Status == "" {
+		transaction.Status = models.TransactionStatusPending
+	}
+
+	err = tr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(transaction).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.TransactionStatusHistory{
+			TransactionID: transaction.ID,
+			ToStatus:      transaction.Status,
+		}).Error
+	})
+	if err != nil {
 		return "", err
 	}
 
 	return transaction.ID.String(), nil
 }
+
+// UpdateTransactionStatus moves the transaction to status and appends a
+// history entry. The row is locked while the transition is checked, and
+// ErrIllegalStatusTransition is returned when the move is not allowed. A
+// transaction that becomes paid enters the fulfillment queue.
+func (tr *transactionRepository) UpdateTransactionStatus(transactionID uuid.UUID, status models.TransactionStatus, note string) error {
+	return tr.db.Transaction(func(tx *gorm.DB) error {
+		var transaction models.Transaction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", transactionID).First(&transaction).Error; err != nil {
+			return err
+		}
+
+		if !transaction.Status.CanTransitionTo(status) {
+			return fmt.Errorf("%w: %s to %s", ErrIllegalStatusTransition, transaction.Status, status)
+		}
+
+		if err := tx.Model(&models.Transaction{}).Where("id = ?", transactionID).Update("status", status).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&models.TransactionStatusHistory{
+			TransactionID: transactionID,
+			FromStatus:    transaction.Status,
+			ToStatus:      status,
+			Note:          note,
+		}).Error; err != nil {
+			return err
+		}
+
+		if status != models.TransactionStatusPaid || transaction.FulfillmentStatus != "" {
+			return nil
+		}
+
+		return setFulfillmentStatus(tx, &transaction, FulfillmentUpdate{Status: models.FulfillmentStatusAwaiting})
+	})
+}
+
+// UpdateFulfillmentStatus moves the transaction to the next fulfillment step
+// and appends it to the timeline. Orders that are not paid for cannot be
+// packed, shipped or delivered; ErrIllegalFulfillmentTransition is returned
+// for those and for steps out of order.
+func (tr *transactionRepository) UpdateFulfillmentStatus(transactionID uuid.UUID, update FulfillmentUpdate) error {
+	return tr.db.Transaction(func(tx *gorm.DB) error {
+		var transaction models.Transaction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", transactionID).First(&transaction).Error; err != nil {
+			return err
+		}
+
+		if update.Status.RequiresPayment() && transaction.Status != models.TransactionStatusPaid && transaction.Status != models.TransactionStatusPartiallyRefunded {
+			return fmt.Errorf("%w: transaction is %s", ErrIllegalFulfillmentTransition, transaction.Status)
+		}
+
+		if !transaction.FulfillmentStatus.CanTransitionTo(update.Status) {
+			from := string(transaction.FulfillmentStatus)
+			if from == "" {
+				from = "unfulfilled"
+			}
+			return fmt.Errorf("%w: %s to %s", ErrIllegalFulfillmentTransition, from, update.Status)
+		}
+
+		return setFulfillmentStatus(tx, &transaction, update)
+	})
+}
+
+func setFulfillmentStatus(tx *gorm.DB, transaction *models.Transaction, update FulfillmentUpdate) error {
+	changes := map[string]interface{}{"fulfillment_status": update.Status}
+	if update.Carrier != "" {
+		changes["carrier"] = update.Carrier
+	}
+	if update.TrackingNumber != "" {
+		changes["tracking_number"] = update.TrackingNumber
+	}
+
+	if err := tx.Model(&models.Transaction{}).Where("id = ?", transaction.ID).Updates(changes).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&models.FulfillmentEvent{
+		TransactionID:  transaction.ID,
+		FromStatus:     transaction.FulfillmentStatus,
+		ToStatus:       update.Status,
+		Carrier:        update.Carrier,
+		TrackingNumber: update.TrackingNumber,
+		Note:           update.Note,
+	}).Error
+}
+
+func (tr *transactionRepository) SetPaymentCharge(transactionID uuid.UUID, provider string, chargeID string) error {
+	if err := tr.db.Model(&models.Transaction{}).Where("id = ?", transactionID).Updates(map[string]interface{}{
+		"payment_provider":  provider,
+		"payment_charge_id": chargeID,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// LockTransaction takes the transaction row with SELECT ... FOR UPDATE. It is
+// only meaningful on a repository bound to a transaction via WithTx.
+func (tr *transactionRepository) LockTransaction(transactionID uuid.UUID) error {
+	var transaction models.Transaction
+	return tr.db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", transactionID).First(&transaction).Error
+}
+
+// GetStalePendingTransactionIDs returns up to limit transactions that were
+// created before createdBefore and are still waiting for their payment,
+// oldest first.
+func (tr *transactionRepository) GetStalePendingTransactionIDs(createdBefore time.Time, limit int) ([]uuid.UUID, error) {
+	var transactionIDs []uuid.UUID
+	if err := tr.db.Model(&models.Transaction{}).
+		Where("status = ? AND created_at < ?", models.TransactionStatusPending, createdBefore).
+		Order("created_at ASC").Limit(limit).
+		Pluck("id", &transactionIDs).Error; err != nil {
+		return nil, err
+	}
+	return transactionIDs, nil
+}
+
+func (tr *transactionRepository) GetTransactionByID(transactionID uuid.UUID) (*dto.TransactionResponse, error) {
+	var transaction models.Transaction
+	if err := tr.preloadDetails(tr.db).Where("id = ?", transactionID).First(&transaction).Error; err != nil {
+		return nil, err
+	}
+
+	response := toTransactionResponse(transaction)
+	return &response, nil
+}
+
+// GetTransactionWithCustomer is GetTransactionByID with the buyer filled in.
+func (tr *transactionRepository) GetTransactionWithCustomer(transactionID uuid.UUID) (*dto.TransactionResponse, error) {
+	response, err := tr.GetTransactionByID(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []dto.TransactionResponse{*response}
+	if err := tr.attachCustomers(responses); err != nil {
+		return nil, err
+	}
+
+	return &responses[0], nil
+}
+
+// GetTransactions returns transactions newest first using keyset pagination
+// on (created_at, id). NextCursor is set only when another page exists.
+func (tr *transactionRepository) GetTransactions(filter TransactionFilter) (*dto.TransactionListResponse, error) {
+	query := tr.preloadDetails(tr.db).Model(&models.Transaction{})
+
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+
+	if filter.StartDate != nil {
+		query = query.Where("created_at >= ?", *filter.StartDate)
+	}
+
+	if filter.EndDate != nil {
+		query = query.Where("created_at < ?", *filter.EndDate)
+	}
+
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+
+	if filter.FulfillmentStatus != nil {
+		query = query.Where("fulfillment_status = ?", *filter.FulfillmentStatus)
+	}
+
+	if filter.MinTotal != nil {
+		query = query.Where("total_price >= ?", *filter.MinTotal)
+	}
+
+	if filter.MaxTotal != nil {
+		query = query.Where("total_price <= ?", *filter.MaxTotal)
+	}
+
+	if filter.ItemID != nil {
+		query = query.Where("EXISTS (SELECT 1 FROM transaction_details WHERE transaction_details.transaction_id = transactions.id AND transaction_details.item_id = ?)", *filter.ItemID)
+	}
+
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(filter.Search) + "%"
+		query = query.Where("user_id IN (?)", tr.db.Model(&models.User{}).Select("id").
+			Where("email LIKE ? OR username LIKE ? OR full_name LIKE ?", pattern, pattern, pattern))
+	}
+
+	if filter.Cursor != nil {
+		query = query.Where("(created_at < ?) OR (created_at = ? AND id < ?)", filter.Cursor.CreatedAt, filter.Cursor.CreatedAt, filter.Cursor.ID)
+	}
+
+	var transactions []models.Transaction
+	if err := query.Order("created_at DESC").Order("id DESC").Limit(filter.Limit + 1).Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	response := &dto.TransactionListResponse{
+		Transactions: []dto.TransactionResponse{},
+	}
+
+	if len(transactions) > filter.Limit {
+		transactions = transactions[:filter.Limit]
+		last := transactions[len(transactions)-1]
+		response.NextCursor = utils.EncodeCursor(utils.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	for _, trx := range transactions {
+		response.Transactions = append(response.Transactions, toTransactionResponse(trx))
+	}
+
+	if filter.IncludeCustomer {
+		if err := tr.attachCustomers(response.Transactions); err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// attachCustomers sets Customer on every transaction with one query for all
+// buyers.
+func (tr *transactionRepository) attachCustomers(transactions []dto.TransactionResponse) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(transactions))
+	for _, trx := range transactions {
+		userIDs = append(userIDs, trx.UserID)
+	}
+
+	var users []models.User
+	if err := tr.db.Unscoped().Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return err
+	}
+
+	customers := map[uuid.UUID]*dto.TransactionCustomerResponse{}
+	for _, user := range users {
+		customers[user.ID] = &dto.TransactionCustomerResponse{
+			ID:       user.ID,
+			FullName: user.FullName,
+			Email:    user.Email,
+			Username: user.Username,
+		}
+	}
+
+	for i := range transactions {
+		transactions[i].Customer = customers[transactions[i].UserID]
+	}
+
+	return nil
+}
+
+// escapeLike escapes the LIKE wildcards in user input so it is matched
+// literally.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// preloadDetails loads line items together with their items, including items
+// that were deleted after the purchase, the status and fulfillment history
+// and the coupons used.
+func (tr *transactionRepository) preloadDetails(db *gorm.DB) *gorm.DB {
+	return db.Preload("TransactionDetails").Preload("TransactionDetails.Item", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Preload("TransactionDetails.Variant", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Preload("StatusHistories", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Preload("FulfillmentEvents", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Preload("CouponRedemptions.Coupon", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	})
+}
+
+func toTransactionResponse(trx models.Transaction) dto.TransactionResponse {
+	var trxDetails []dto.TransactionDetailResponse
+	for _, detail := range trx.TransactionDetails {
+		trxDetails = append(trxDetails, dto.TransactionDetailResponse{
+			Item: dto.GetItemDetailTransactionResponse{
+				ID:   detail.Item.ID,
+				Name: detail.Item.Name,
+			},
+			Variant:        toVariantSummary(detail.Variant),
+			Quantity:       detail.Quantity,
+			PricePerUnit:   detail.PricePerUnit,
+			DiscountAmount: detail.DiscountAmount,
+			TaxMode:        string(detail.TaxMode),
+			TaxRateBps:     detail.TaxRateBps,
+			NetAmount:      detail.NetAmount,
+			TaxAmount:      detail.TaxAmount,
+			GrossAmount:    detail.TotalPrice,
+			TotalPrice:     detail.TotalPrice,
+		})
+	}
+
+	var statusHistories []dto.StatusHistoryResponse
+	for _, history := range trx.StatusHistories {
+		statusHistories = append(statusHistories, dto.StatusHistoryResponse{
+			FromStatus: string(history.FromStatus),
+			ToStatus:   string(history.ToStatus),
+			Note:       history.Note,
+			CreatedAt:  history.CreatedAt,
+		})
+	}
+
+	var timeline []dto.TimelineEntryResponse
+	for _, history := range trx.StatusHistories {
+		timeline = append(timeline, dto.TimelineEntryResponse{
+			Type:      "payment",
+			Status:    string(history.ToStatus),
+			Note:      history.Note,
+			CreatedAt: history.CreatedAt,
+		})
+	}
+	for _, event := range trx.FulfillmentEvents {
+		timeline = append(timeline, dto.TimelineEntryResponse{
+			Type:           "fulfillment",
+			Status:         string(event.ToStatus),
+			Carrier:        event.Carrier,
+			TrackingNumber: event.TrackingNumber,
+			Note:           event.Note,
+			CreatedAt:      event.CreatedAt,
+		})
+	}
+	sort.SliceStable(timeline, func(a, b int) bool {
+		return timeline[a].CreatedAt.Before(timeline[b].CreatedAt)
+	})
+
+	var appliedCoupons []dto.AppliedCouponResponse
+	for _, redemption := range trx.CouponRedemptions {
+		appliedCoupons = append(appliedCoupons, dto.AppliedCouponResponse{
+			Code:           redemption.Coupon.Code,
+			DiscountAmount: redemption.DiscountAmount,
+		})
+	}
+
+	return dto.TransactionResponse{
+		ID:                  trx.ID,
+		UserID:              trx.UserID,
+		SubtotalPrice:       trx.SubtotalPrice,
+		DiscountAmount:      trx.DiscountAmount,
+		NetAmount:           trx.NetAmount,
+		TaxAmount:           trx.TaxAmount,
+		ShippingFee:         trx.ShippingFee,
+		GrossAmount:         trx.NetAmount.Add(trx.TaxAmount),
+		TotalPrice:          trx.TotalPrice,
+		Currency:            trx.Currency,
+		Status:              string(trx.Status),
+		PaymentProvider:     trx.PaymentProvider,
+		PaymentChargeID:     trx.PaymentChargeID,
+		ShippingProvider:    trx.ShippingProvider,
+		ShippingService:     trx.ShippingService,
+		ShippingWeightGrams: trx.ShippingWeightGrams,
+		FulfillmentStatus:   string(trx.FulfillmentStatus),
+		Carrier:             trx.Carrier,
+		TrackingNumber:      trx.TrackingNumber,
+		ShippingAddress:     toPostalAddressResponse(trx.ShippingAddress),
+		CreatedAt:           trx.CreatedAt,
+		TransactionDetails:  trxDetails,
+		StatusHistories:     statusHistories,
+		Timeline:            timeline,
+		AppliedCoupons:      appliedCoupons,
+	}
+}
+
+// toPostalAddressResponse returns nil for orders placed before addresses
+// were recorded.
+func toPostalAddressResponse(address models.PostalAddress) *dto.PostalAddressResponse {
+	if address == (models.PostalAddress{}) {
+		return nil
+	}
+
+	return &dto.PostalAddressResponse{
+		RecipientName: address.RecipientName,
+		Phone:         address.Phone,
+		Line1:         address.Line1,
+		Line2:         address.Line2,
+		City:          address.City,
+		Province:      address.Province,
+		PostalCode:    address.PostalCode,
+		Country:       address.Country,
+	}
+}
@@ -7,6 +7,7 @@ import (
 )
 
 type TransactionDetailRepository interface {
+	WithTx(tx *gorm.DB) TransactionDetailRepository
 	CreateTransactionDetail(transactionDetail *models.TransactionDetail) error
 }
 
@@ -18,6 +19,10 @@ func NewTransactionDetailRepository(db *gorm.DB) TransactionDetailRepository {
 	return &transactionDetailRepository{db: db}
 }
 
+func (tr *transactionDetailRepository) WithTx(tx *gorm.DB) TransactionDetailRepository {
+	return &transactionDetailRepository{db: tx}
+}
+
 func (tr *transactionDetailRepository) CreateTransactionDetail(transactionDetail *models.TransactionDetail) error {
 	if err := tr.db.Create(transactionDetail).Error; err != nil {
 		return err
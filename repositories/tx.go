@@ -0,0 +1,23 @@
+package repositories
+
+import "gorm.io/gorm"
+
+// TxManager runs a unit of work inside a single database transaction so that
+// several repositories can participate in it through their WithTx methods.
+type TxManager interface {
+	WithinTransaction(fn func(tx *gorm.DB) error) error
+}
+
+type txManager struct {
+	db *gorm.DB
+}
+
+func NewTxManager(db *gorm.DB) TxManager {
+	return &txManager{db: db}
+}
+
+// WithinTransaction commits when fn returns nil and rolls back otherwise.
+// The error returned by fn is passed through unchanged.
+func (tm *txManager) WithinTransaction(fn func(tx *gorm.DB) error) error {
+	return tm.db.Transaction(fn)
+}
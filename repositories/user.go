@@ -12,6 +12,7 @@ import (
 type UserRepository interface {
 	CreateUser(user *models.User) error
 	GetUserByEmail(email string) (*dto.GetUserByEmailResponse, error)
+	GetUserByID(userID uuid.UUID) (*models.User, error)
 	GetUserDetail(userID uuid.UUID) (*dto.GetUserDetailResponse, error)
 }
 
@@ -46,34 +47,31 @@ func (ur *userRepository) GetUserByEmail(email string) (*dto.GetUserByEmailRespo
 	}, nil
 }
 
+func (ur *userRepository) GetUserByID(userID uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := ur.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (ur *userRepository) GetUserDetail(userID uuid.UUID) (*dto.GetUserDetailResponse, error) {
 	var user models.User
-	if err := ur.db.Preload("Transactions.TransactionDetails.Item").Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := ur.db.Preload("Transactions.TransactionDetails.Item").Preload("Transactions.TransactionDetails.Variant", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Preload("Transactions.StatusHistories", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Preload("Transactions.FulfillmentEvents", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Preload("Transactions.CouponRedemptions.Coupon", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Where("id = ?", userID).First(&user).Error; err != nil {
 		return nil, err
 	}
 
 	var transactions []dto.TransactionResponse
 	for _, trx := range user.Transactions {
-		var trxDetails []dto.TransactionDetailResponse
-		for _, detail := range trx.TransactionDetails {
-			trxDetails = append(trxDetails, dto.TransactionDetailResponse{
-				Item: dto.GetItemDetailTransactionResponse{
-					ID:   detail.Item.ID,
-					Name: detail.Item.Name,
-				},
-				Quantity:     detail.Quantity,
-				PricePerUnit: detail.PricePerUnit,
-				TotalPrice:   detail.TotalPrice,
-			})
-		}
-
-		transactions = append(transactions, dto.TransactionResponse{
-			ID:                 trx.ID,
-			TotalPrice:         trx.TotalPrice,
-			IsSuccessPaid:      trx.IsSuccessPaid,
-			CreatedAt:          trx.CreatedAt,
-			TransactionDetails: trxDetails,
-		})
+		transactions = append(transactions, toTransactionResponse(trx))
 	}
 
 	fmt.Println(transactions)
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"ordent/configs"
+	"ordent/controllers"
+	"ordent/middlewares"
+	"ordent/repositories"
+
+	"github.com/labstack/echo/v4"
+)
+
+func AddressRoutes(e *echo.Echo) {
+	addressRepo := repositories.NewAddressRepository(configs.DB)
+	itemRepo := repositories.NewItemRepository(configs.DB)
+
+	addressController := controllers.NewAddressController(addressRepo)
+	shippingController := controllers.NewShippingController(configs.ShippingRateProvider, itemRepo, addressRepo)
+
+	e.POST("/api/v1/addresses", addressController.CreateAddress, middlewares.JWTAuth, middlewares.ClientAuthz)
+	e.GET("/api/v1/addresses", addressController.GetMyAddresses, middlewares.JWTAuth, middlewares.ClientAuthz)
+	e.GET("/api/v1/addresses/:id", addressController.GetAddressByID, middlewares.JWTAuth, middlewares.ClientAuthz)
+	e.PUT("/api/v1/addresses/:id", addressController.EditAddress, middlewares.JWTAuth, middlewares.ClientAuthz)
+	e.DELETE("/api/v1/addresses/:id", addressController.DeleteAddress, middlewares.JWTAuth, middlewares.ClientAuthz)
+	e.POST("/api/v1/shipping/quote", shippingController.QuoteShipping, middlewares.JWTAuth, middlewares.ClientAuthz)
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"ordent/configs"
+	"ordent/controllers"
+	"ordent/middlewares"
+	"ordent/repositories"
+
+	"github.com/labstack/echo/v4"
+)
+
+func CartRoutes(e *echo.Echo) {
+	txManager := repositories.NewTxManager(configs.DB)
+	cartRepo := repositories.NewCartRepository(configs.DB)
+	itemRepo := repositories.NewItemRepository(configs.DB)
+	variantRepo := repositories.NewItemVariantRepository(configs.DB)
+	stockMovementRepo := repositories.NewStockMovementRepository(configs.DB)
+	transactionRepo := repositories.NewTransactionRepository(configs.DB)
+	transactionDetailRepo := repositories.NewTransactionDetailRepository(configs.DB)
+	couponRepo := repositories.NewCouponRepository(configs.DB)
+	taxRateRepo := repositories.NewTaxRateRepository(configs.DB)
+	addressRepo := repositories.NewAddressRepository(configs.DB)
+	idempotencyKeyRepo := repositories.NewIdempotencyKeyRepository(configs.DB)
+
+	cartController := controllers.NewCartController(txManager, configs.PaymentProvider, cartRepo, itemRepo, variantRepo, stockMovementRepo, transactionRepo, transactionDetailRepo, couponRepo, taxRateRepo, addressRepo, configs.ShippingRateProvider, configs.Notifier)
+
+	e.GET("/api/v1/cart", cartController.GetCart, middlewares.JWTAuth, middlewares.ClientAuthz)
+	e.DELETE("/api/v1/cart", cartController.ClearCart, middlewares.JWTAuth, middlewares.ClientAuthz)
+	e.POST("/api/v1/cart/items", cartController.AddCartItem, middlewares.JWTAuth, middlewares.ClientAuthz)
+	e.PUT("/api/v1/cart/items/:itemId", cartController.UpdateCartItem, middlewares.JWTAuth, middlewares.ClientAuthz)
+	e.DELETE("/api/v1/cart/items/:itemId", cartController.RemoveCartItem, middlewares.JWTAuth, middlewares.ClientAuthz)
+	e.POST("/api/v1/cart/checkout", cartController.Checkout, middlewares.JWTAuth, middlewares.ClientAuthz, middlewares.Idempotency(idempotencyKeyRepo, configs.IdempotencyKeyTTL()))
+}
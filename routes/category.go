@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"ordent/configs"
+	"ordent/controllers"
+	"ordent/middlewares"
+	"ordent/repositories"
+
+	"github.com/labstack/echo/v4"
+)
+
+func CategoryRoutes(e *echo.Echo) {
+	categoryRepo := repositories.NewCategoryRepository(configs.DB)
+
+	categoryController := controllers.NewCategoryController(categoryRepo)
+
+	e.POST("/api/v1/categories", categoryController.CreateCategory, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.GET("/api/v1/categories", categoryController.GetCategoryTree)
+	e.PUT("/api/v1/categories/:id", categoryController.EditCategory, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.DELETE("/api/v1/categories/:id", categoryController.DeleteCategory, middlewares.JWTAuth, middlewares.AdminAuthz)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"ordent/configs"
+	"ordent/controllers"
+	"ordent/middlewares"
+	"ordent/repositories"
+
+	"github.com/labstack/echo/v4"
+)
+
+func CouponRoutes(e *echo.Echo) {
+	couponRepo := repositories.NewCouponRepository(configs.DB)
+	itemRepo := repositories.NewItemRepository(configs.DB)
+
+	couponController := controllers.NewCouponController(couponRepo, itemRepo)
+
+	e.POST("/api/v1/coupons", couponController.CreateCoupon, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.GET("/api/v1/coupons", couponController.GetAllCoupons, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.GET("/api/v1/coupons/:id", couponController.GetCouponByID, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.PUT("/api/v1/coupons/:id", couponController.EditCoupon, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.DELETE("/api/v1/coupons/:id", couponController.DeleteCoupon, middlewares.JWTAuth, middlewares.AdminAuthz)
+}
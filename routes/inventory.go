@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"ordent/configs"
+	"ordent/controllers"
+	"ordent/middlewares"
+	"ordent/repositories"
+
+	"github.com/labstack/echo/v4"
+)
+
+func InventoryRoutes(e *echo.Echo) {
+	lowStockAlertRepo := repositories.NewLowStockAlertRepository(configs.DB)
+
+	inventoryController := controllers.NewInventoryController(lowStockAlertRepo)
+
+	e.GET("/api/v1/admin/inventory/low-stock", inventoryController.GetLowStockItems, middlewares.JWTAuth, middlewares.AdminAuthz)
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"ordent/configs"
+	"ordent/controllers"
+	"ordent/middlewares"
+	"ordent/repositories"
+
+	"github.com/labstack/echo/v4"
+)
+
+func InvoiceRoutes(e *echo.Echo) {
+	transactionRepo := repositories.NewTransactionRepository(configs.DB)
+	invoiceRepo := repositories.NewInvoiceRepository(configs.DB)
+	userRepo := repositories.NewUserRepository(configs.DB)
+
+	invoiceController := controllers.NewInvoiceController(transactionRepo, invoiceRepo, userRepo, configs.StoreLetterhead())
+
+	e.GET("/api/v1/transactions/:id/invoice.pdf", invoiceController.GetInvoicePDF, middlewares.JWTAuth)
+}
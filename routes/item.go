@@ -10,12 +10,42 @@ import (
 )
 
 func ItemRoutes(e *echo.Echo) {
+	txManager := repositories.NewTxManager(configs.DB)
 	itemRepo := repositories.NewItemRepository(configs.DB)
+	taxRateRepo := repositories.NewTaxRateRepository(configs.DB)
+	categoryRepo := repositories.NewCategoryRepository(configs.DB)
+	tagRepo := repositories.NewTagRepository(configs.DB)
+	variantRepo := repositories.NewItemVariantRepository(configs.DB)
+	stockMovementRepo := repositories.NewStockMovementRepository(configs.DB)
+	lowStockAlertRepo := repositories.NewLowStockAlertRepository(configs.DB)
+	imageRepo := repositories.NewItemImageRepository(configs.DB)
+	importJobRepo := repositories.NewImportJobRepository(configs.DB)
 
-	itemController := controllers.NewItemController(itemRepo)
+	itemController := controllers.NewItemController(txManager, itemRepo, variantRepo, stockMovementRepo, lowStockAlertRepo, taxRateRepo, categoryRepo, tagRepo, configs.Storage, configs.Searcher)
+	itemVariantController := controllers.NewItemVariantController(txManager, itemRepo, variantRepo, stockMovementRepo)
+	itemImportController := controllers.NewItemImportController(txManager, itemRepo, variantRepo, stockMovementRepo, lowStockAlertRepo, taxRateRepo, categoryRepo, tagRepo, importJobRepo, configs.Searcher, configs.ImportMaxBytes(), configs.ImportSyncMaxRows())
+	stockMovementController := controllers.NewStockMovementController(txManager, itemRepo, variantRepo, stockMovementRepo)
+	itemImageController := controllers.NewItemImageController(itemRepo, imageRepo, configs.Storage, configs.ImageMaxBytes(), configs.ImageThumbnailSize())
 
 	e.POST("/api/v1/items", itemController.CreateItem, middlewares.JWTAuth, middlewares.AdminAuthz)
 	e.GET("/api/v1/items", itemController.GetAllItems)
+	e.GET("/api/v1/items/:id", itemController.GetItemByID)
 	e.PUT("/api/v1/items/:id", itemController.EditItem, middlewares.JWTAuth, middlewares.AdminAuthz)
 	e.DELETE("/api/v1/items/:id", itemController.DeleteItem, middlewares.JWTAuth, middlewares.AdminAuthz)
+
+	e.GET("/api/v1/items/export", itemImportController.ExportItems, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.POST("/api/v1/items/imports", itemImportController.ImportItems, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.GET("/api/v1/items/imports/:jobId", itemImportController.GetImportJob, middlewares.JWTAuth, middlewares.AdminAuthz)
+
+	e.POST("/api/v1/items/:id/variants", itemVariantController.CreateItemVariant, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.PUT("/api/v1/items/:id/variants/:variantId", itemVariantController.EditItemVariant, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.DELETE("/api/v1/items/:id/variants/:variantId", itemVariantController.DeleteItemVariant, middlewares.JWTAuth, middlewares.AdminAuthz)
+
+	e.GET("/api/v1/items/:id/stock-movements", stockMovementController.GetStockMovements, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.POST("/api/v1/items/:id/stock-movements", stockMovementController.AdjustStock, middlewares.JWTAuth, middlewares.AdminAuthz)
+
+	e.POST("/api/v1/items/:id/images", itemImageController.UploadItemImages, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.PUT("/api/v1/items/:id/images/order", itemImageController.ReorderItemImages, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.PUT("/api/v1/items/:id/images/:imageId/primary", itemImageController.SetPrimaryItemImage, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.DELETE("/api/v1/items/:id/images/:imageId", itemImageController.DeleteItemImage, middlewares.JWTAuth, middlewares.AdminAuthz)
 }
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"net/http"
+	"ordent/configs"
+	"ordent/controllers"
+	"ordent/middlewares"
+	"ordent/repositories"
+
+	"github.com/labstack/echo/v4"
+)
+
+func PaymentRoutes(e *echo.Echo) {
+	txManager := repositories.NewTxManager(configs.DB)
+	itemRepo := repositories.NewItemRepository(configs.DB)
+	variantRepo := repositories.NewItemVariantRepository(configs.DB)
+	stockMovementRepo := repositories.NewStockMovementRepository(configs.DB)
+	transactionRepo := repositories.NewTransactionRepository(configs.DB)
+	paymentEventRepo := repositories.NewPaymentEventRepository(configs.DB)
+	paymentRefundRepo := repositories.NewPaymentRefundRepository(configs.DB)
+	couponRepo := repositories.NewCouponRepository(configs.DB)
+
+	paymentController := controllers.NewPaymentController(txManager, configs.PaymentProvider, itemRepo, variantRepo, stockMovementRepo, transactionRepo, paymentEventRepo, paymentRefundRepo, couponRepo, configs.Notifier)
+
+	e.POST("/api/v1/payments/webhook", paymentController.HandleWebhook)
+	e.POST("/api/v1/transactions/:id/refunds", paymentController.RefundTransaction, middlewares.JWTAuth, middlewares.AdminAuthz)
+
+	// The stand-in gateway lets anyone settle any charge, so it is only
+	// served when PAYMENT_MOCK_SERVER opts in for local development.
+	if configs.MockPaymentServer != nil {
+		e.Any("/mock-payments/*", echo.WrapHandler(http.StripPrefix("/mock-payments", configs.MockPaymentServer)))
+	}
+}
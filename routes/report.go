@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"ordent/configs"
+	"ordent/controllers"
+	"ordent/middlewares"
+	"ordent/repositories"
+
+	"github.com/labstack/echo/v4"
+)
+
+func ReportRoutes(e *echo.Echo) {
+	reportRepo := repositories.NewReportRepository(configs.DB)
+
+	reportController := controllers.NewReportController(reportRepo)
+
+	e.GET("/api/v1/admin/reports/summary", reportController.GetSalesSummary, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.GET("/api/v1/admin/reports/sales", reportController.GetSalesReport, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.GET("/api/v1/admin/reports/top-items", reportController.GetTopItemsReport, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.GET("/api/v1/admin/reports/customers", reportController.GetCustomerReport, middlewares.JWTAuth, middlewares.AdminAuthz)
+}
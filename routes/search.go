@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"ordent/configs"
+	"ordent/controllers"
+	"ordent/repositories"
+
+	"github.com/labstack/echo/v4"
+)
+
+func SearchRoutes(e *echo.Echo) {
+	itemRepo := repositories.NewItemRepository(configs.DB)
+
+	searchController := controllers.NewSearchController(configs.Searcher, itemRepo, configs.Storage)
+
+	e.GET("/api/v1/search", searchController.SearchItems)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"ordent/configs"
+	"os"
+
+	"github.com/labstack/echo/v4"
+)
+
+func StorageRoutes(e *echo.Echo) {
+	// Local files are served here unless STORAGE_PUBLIC_URL points elsewhere,
+	// e.g. to a web server or CDN in front of the directory.
+	if configs.LocalStorage != nil && os.Getenv("STORAGE_PUBLIC_URL") == "" {
+		e.Static("/uploads", configs.LocalStorage.Dir())
+	}
+
+	// The stand-in checks signatures over the full path, so it is mounted
+	// without stripping the prefix.
+	if configs.MockS3Server != nil {
+		e.Any("/mock-s3/*", echo.WrapHandler(configs.MockS3Server))
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"ordent/configs"
+	"ordent/controllers"
+	"ordent/middlewares"
+	"ordent/repositories"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TagRoutes(e *echo.Echo) {
+	tagRepo := repositories.NewTagRepository(configs.DB)
+
+	tagController := controllers.NewTagController(tagRepo)
+
+	e.POST("/api/v1/tags", tagController.CreateTag, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.GET("/api/v1/tags", tagController.GetAllTags)
+	e.PUT("/api/v1/tags/:id", tagController.EditTag, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.DELETE("/api/v1/tags/:id", tagController.DeleteTag, middlewares.JWTAuth, middlewares.AdminAuthz)
+}
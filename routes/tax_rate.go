@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"ordent/configs"
+	"ordent/controllers"
+	"ordent/middlewares"
+	"ordent/repositories"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TaxRateRoutes(e *echo.Echo) {
+	taxRateRepo := repositories.NewTaxRateRepository(configs.DB)
+
+	taxRateController := controllers.NewTaxRateController(taxRateRepo)
+
+	e.POST("/api/v1/tax-rates", taxRateController.CreateTaxRate, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.GET("/api/v1/tax-rates", taxRateController.GetAllTaxRates, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.PUT("/api/v1/tax-rates/:id", taxRateController.EditTaxRate, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.DELETE("/api/v1/tax-rates/:id", taxRateController.DeleteTaxRate, middlewares.JWTAuth, middlewares.AdminAuthz)
+}
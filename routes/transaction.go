@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"context"
 	"ordent/configs"
 	"ordent/controllers"
 	"ordent/middlewares"
@@ -10,11 +11,27 @@ import (
 )
 
 func TransactionRoutes(e *echo.Echo) {
+	txManager := repositories.NewTxManager(configs.DB)
 	transactionRepo := repositories.NewTransactionRepository(configs.DB)
 	itemRepo := repositories.NewItemRepository(configs.DB)
+	variantRepo := repositories.NewItemVariantRepository(configs.DB)
+	stockMovementRepo := repositories.NewStockMovementRepository(configs.DB)
 	transactionDetailRepo := repositories.NewTransactionDetailRepository(configs.DB)
+	couponRepo := repositories.NewCouponRepository(configs.DB)
+	taxRateRepo := repositories.NewTaxRateRepository(configs.DB)
+	addressRepo := repositories.NewAddressRepository(configs.DB)
+	idempotencyKeyRepo := repositories.NewIdempotencyKeyRepository(configs.DB)
 
-	transactionController := controllers.NewTransactionController(itemRepo, transactionRepo, transactionDetailRepo)
+	orderExpiryWorker := controllers.NewOrderExpiryWorker(txManager, itemRepo, variantRepo, stockMovementRepo, transactionRepo, couponRepo, configs.PendingOrderTTL())
+	go orderExpiryWorker.Run(context.Background())
 
-	e.POST("/api/v1/transactions", transactionController.CreateTransaction, middlewares.JWTAuth, middlewares.ClientAuthz)
+	transactionController := controllers.NewTransactionController(txManager, configs.PaymentProvider, itemRepo, variantRepo, stockMovementRepo, transactionRepo, transactionDetailRepo, couponRepo, taxRateRepo, addressRepo, configs.ShippingRateProvider, configs.Notifier)
+
+	e.GET("/api/v1/transactions", transactionController.GetMyTransactions, middlewares.JWTAuth, middlewares.ClientAuthz)
+	e.GET("/api/v1/transactions/:id", transactionController.GetTransactionByID, middlewares.JWTAuth)
+	e.POST("/api/v1/transactions/:id/cancel", transactionController.CancelTransaction, middlewares.JWTAuth)
+	e.GET("/api/v1/admin/transactions", transactionController.GetAllTransactions, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.GET("/api/v1/admin/transactions/:id", transactionController.GetAdminTransaction, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.PUT("/api/v1/admin/transactions/:id/fulfillment", transactionController.UpdateFulfillment, middlewares.JWTAuth, middlewares.AdminAuthz)
+	e.POST("/api/v1/transactions", transactionController.CreateTransaction, middlewares.JWTAuth, middlewares.ClientAuthz, middlewares.Idempotency(idempotencyKeyRepo, configs.IdempotencyKeyTTL()))
 }
@@ -11,10 +11,13 @@ import (
 
 func UserRoutes(e *echo.Echo) {
 	userRepo := repositories.NewUserRepository(configs.DB)
+	passwordResetRepo := repositories.NewPasswordResetRepository(configs.DB)
 
-	userController := controllers.NewUserController(userRepo)
+	userController := controllers.NewUserController(userRepo, passwordResetRepo, configs.Notifier, configs.PasswordResetURL(), configs.PasswordResetTTL())
 
 	e.GET("/api/v1/myprofiles", userController.MyProfile, middlewares.JWTAuth, middlewares.ClientAuthz)
 	e.POST("/api/v1/register", userController.RegisterUser)
 	e.POST("/api/v1/login", userController.LoginUser)
+	e.POST("/api/v1/password/forgot", userController.RequestPasswordReset)
+	e.POST("/api/v1/password/reset", userController.ResetPassword)
 }
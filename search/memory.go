@@ -0,0 +1,98 @@
+package search
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// MemoryIndex is an inverted index kept in process memory. It suits tests,
+// SQLite setups and single-instance deployments; every instance holds its own
+// copy, so it must be filled from the database at start.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	docs     map[uuid.UUID]Document
+	postings map[string]map[uuid.UUID]struct{}
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		docs:     map[uuid.UUID]Document{},
+		postings: map[string]map[uuid.UUID]struct{}{},
+	}
+}
+
+// Index adds the document, replacing an earlier version of it.
+func (m *MemoryIndex) Index(ctx context.Context, doc Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(doc.ID)
+	m.docs[doc.ID] = doc
+	for _, word := range tokenize(doc.Name) {
+		ids, ok := m.postings[word.text]
+		if !ok {
+			ids = map[uuid.UUID]struct{}{}
+			m.postings[word.text] = ids
+		}
+		ids[doc.ID] = struct{}{}
+	}
+	return nil
+}
+
+func (m *MemoryIndex) Remove(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(id)
+	return nil
+}
+
+func (m *MemoryIndex) remove(id uuid.UUID) {
+	doc, ok := m.docs[id]
+	if !ok {
+		return
+	}
+	delete(m.docs, id)
+	for _, word := range tokenize(doc.Name) {
+		ids := m.postings[word.text]
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(m.postings, word.text)
+		}
+	}
+}
+
+// Search looks each term up against the whole vocabulary, so prefix and
+// fuzzy matches are found as well as exact ones, and ranks the documents
+// that contain any matching word.
+func (m *MemoryIndex) Search(ctx context.Context, query Query) (*Result, error) {
+	terms := queryTerms(query.Text)
+	if len(terms) == 0 {
+		return &Result{Hits: []Hit{}}, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	candidates := map[uuid.UUID]struct{}{}
+	for word, ids := range m.postings {
+		for _, term := range terms {
+			if matchWeight(term, word) == 0 {
+				continue
+			}
+			for id := range ids {
+				candidates[id] = struct{}{}
+			}
+			break
+		}
+	}
+
+	docs := make([]Document, 0, len(candidates))
+	for id := range candidates {
+		docs = append(docs, m.docs[id])
+	}
+
+	return rank(docs, terms, query), nil
+}
@@ -0,0 +1,244 @@
+package search
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMatchWeight(t *testing.T) {
+	tests := []struct {
+		name string
+		term string
+		word string
+		want float64
+	}{
+		{"exact", "shirt", "shirt", exactWeight},
+		{"prefix", "shi", "shirt", prefixWeight},
+		{"one letter is not a prefix", "s", "shirt", 0},
+		{"swapped letters", "shrit", "shirt", fuzzyWeight},
+		{"missing letter", "sweter", "sweater", fuzzyWeight},
+		{"two typos in a long term", "kyboardd", "keyboard", fuzzyWeight - fuzzyPenalty},
+		{"two typos in a short term", "swtaer", "sweater", 0},
+		{"short terms must be exact", "cap", "cat", 0},
+		{"unrelated", "shoe", "shirt", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchWeight(tt.term, tt.word); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("matchWeight(%q, %q) = %v, want %v", tt.term, tt.word, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	shirt := Document{ID: uuid.New(), Name: "Shirt"}
+	hanger := Document{ID: uuid.New(), Name: "Shirt hanger with clips"}
+	blueShirt := Document{ID: uuid.New(), Name: "Red & Blue Shirt"}
+	creme := Document{ID: uuid.New(), Name: "Café Crème"}
+	sweater := Document{ID: uuid.New(), Name: "Wool Sweater"}
+	docs := []Document{hanger, sweater, creme, blueShirt, shirt}
+
+	tests := []struct {
+		name string
+		text string
+		want []Hit
+	}{
+		{
+			name: "whole words rank above names with more words",
+			text: "shirt",
+			want: []Hit{
+				{ID: shirt.ID, Score: 1.1, Highlight: "<mark>Shirt</mark>"},
+				{ID: blueShirt.ID, Score: 1.0333, Highlight: "Red &amp; Blue <mark>Shirt</mark>"},
+				{ID: hanger.ID, Score: 1.025, Highlight: "<mark>Shirt</mark> hanger with clips"},
+			},
+		},
+		{
+			name: "prefix",
+			text: "swea",
+			want: []Hit{
+				{ID: sweater.ID, Score: 0.8, Highlight: "Wool <mark>Sweater</mark>"},
+			},
+		},
+		{
+			name: "typo",
+			text: "sweter",
+			want: []Hit{
+				{ID: sweater.ID, Score: 0.65, Highlight: "Wool <mark>Sweater</mark>"},
+			},
+		},
+		{
+			name: "every term counts",
+			text: "blue shirt",
+			want: []Hit{
+				{ID: blueShirt.ID, Score: 1.0667, Highlight: "Red &amp; <mark>Blue</mark> <mark>Shirt</mark>"},
+				{ID: shirt.ID, Score: 0.6, Highlight: "<mark>Shirt</mark>"},
+				{ID: hanger.ID, Score: 0.525, Highlight: "<mark>Shirt</mark> hanger with clips"},
+			},
+		},
+		{
+			name: "highlight offsets are in bytes of the original name",
+			text: "creme",
+			want: []Hit{
+				{ID: creme.ID, Score: 0.65, Highlight: "Café <mark>Crème</mark>"},
+			},
+		},
+		{
+			name: "no match",
+			text: "lamp",
+			want: []Hit{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := rank(docs, queryTerms(tt.text), Query{})
+			assertHits(t, result, tt.want)
+			if result.Total != len(tt.want) {
+				t.Errorf("total = %d, want %d", result.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestRankPages(t *testing.T) {
+	var docs []Document
+	for _, name := range []string{"Mug A", "Mug B", "Mug C"} {
+		docs = append(docs, Document{ID: uuid.New(), Name: name})
+	}
+
+	tests := []struct {
+		name   string
+		query  Query
+		wanted []Document
+	}{
+		{"first page", Query{Limit: 2}, docs[:2]},
+		{"last page", Query{Limit: 2, Offset: 2}, docs[2:]},
+		{"past the end", Query{Limit: 2, Offset: 3}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := rank(docs, []string{"mug"}, tt.query)
+			if result.Total != len(docs) {
+				t.Errorf("total = %d, want %d", result.Total, len(docs))
+			}
+			if len(result.Hits) != len(tt.wanted) {
+				t.Fatalf("got %d hits, want %d", len(result.Hits), len(tt.wanted))
+			}
+			for i, doc := range tt.wanted {
+				if result.Hits[i].ID != doc.ID {
+					t.Errorf("hit %d is %q, want %q", i, result.Hits[i].Name, doc.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryIndexSearch(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemoryIndex()
+
+	keyboard := Document{ID: uuid.New(), Name: "Mechanical Keyboard"}
+	mouse := Document{ID: uuid.New(), Name: "Wireless Mouse"}
+	pad := Document{ID: uuid.New(), Name: "Mouse Pad"}
+	for _, doc := range []Document{keyboard, mouse, pad} {
+		if err := index.Index(ctx, doc); err != nil {
+			t.Fatalf("index %q: %v", doc.Name, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []Hit
+	}{
+		{
+			name: "exact",
+			text: "mouse",
+			want: []Hit{
+				{ID: pad.ID, Score: 1.05, Highlight: "<mark>Mouse</mark> Pad"},
+				{ID: mouse.ID, Score: 1.05, Highlight: "Wireless <mark>Mouse</mark>"},
+			},
+		},
+		{
+			name: "prefix",
+			text: "mech",
+			want: []Hit{
+				{ID: keyboard.ID, Score: 0.8, Highlight: "<mark>Mechanical</mark> Keyboard"},
+			},
+		},
+		{
+			name: "typo",
+			text: "keybaord",
+			want: []Hit{
+				{ID: keyboard.ID, Score: 0.65, Highlight: "Mechanical <mark>Keyboard</mark>"},
+			},
+		},
+		{
+			name: "empty query",
+			text: " - ",
+			want: []Hit{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := index.Search(ctx, Query{Text: tt.text})
+			if err != nil {
+				t.Fatalf("search: %v", err)
+			}
+			assertHits(t, result, tt.want)
+		})
+	}
+}
+
+func TestMemoryIndexReindexAndRemove(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemoryIndex()
+
+	doc := Document{ID: uuid.New(), Name: "Desk Lamp"}
+	if err := index.Index(ctx, doc); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+
+	doc.Name = "Floor Lamp"
+	if err := index.Index(ctx, doc); err != nil {
+		t.Fatalf("reindex: %v", err)
+	}
+	assertSearch(t, index, "desk", []Hit{})
+	assertSearch(t, index, "floor", []Hit{{ID: doc.ID, Score: 1.05, Highlight: "<mark>Floor</mark> Lamp"}})
+
+	if err := index.Remove(ctx, doc.ID); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	assertSearch(t, index, "lamp", []Hit{})
+}
+
+func assertSearch(t *testing.T, index *MemoryIndex, text string, want []Hit) {
+	t.Helper()
+
+	result, err := index.Search(context.Background(), Query{Text: text})
+	if err != nil {
+		t.Fatalf("search %q: %v", text, err)
+	}
+	assertHits(t, result, want)
+}
+
+// assertHits compares the ID, score and highlight of each hit in order.
+func assertHits(t *testing.T, result *Result, want []Hit) {
+	t.Helper()
+
+	if len(result.Hits) != len(want) {
+		t.Fatalf("got %d hits %+v, want %d", len(result.Hits), result.Hits, len(want))
+	}
+	for i, hit := range result.Hits {
+		if hit.ID != want[i].ID || hit.Score != want[i].Score || hit.Highlight != want[i].Highlight {
+			t.Errorf("hit %d = {%s %v %q}, want {%s %v %q}", i, hit.Name, hit.Score, hit.Highlight, want[i].ID, want[i].Score, want[i].Highlight)
+		}
+	}
+}
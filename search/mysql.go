@@ -0,0 +1,96 @@
+package search
+
+import (
+	"context"
+	"ordent/models"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// FullTextIndexName is the FULLTEXT index on items.name the MySQL
+	// searcher relies on.
+	FullTextIndexName = "idx_items_name_fulltext"
+
+	// minFullTextTerm is InnoDB's default innodb_ft_min_token_size; shorter
+	// terms are matched with LIKE instead.
+	minFullTextTerm = 3
+
+	// maxCandidates bounds how many rows MySQL hands over for ranking.
+	maxCandidates = 500
+)
+
+// MySQLSearcher uses the FULLTEXT index on items.name to find candidates and
+// ranks them like MemoryIndex does. Each term is also searched by its first
+// three letters, so most typos still find the item; a typo within the first
+// three letters does not. At most maxCandidates rows are ranked, which also
+// caps Total.
+type MySQLSearcher struct {
+	db *gorm.DB
+}
+
+func NewMySQLSearcher(db *gorm.DB) *MySQLSearcher {
+	return &MySQLSearcher{db: db}
+}
+
+// Index does nothing: MySQL keeps the FULLTEXT index in step with the items
+// table.
+func (s *MySQLSearcher) Index(ctx context.Context, doc Document) error {
+	return nil
+}
+
+// Remove does nothing: deleted items are filtered out by deleted_at.
+func (s *MySQLSearcher) Remove(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (s *MySQLSearcher) Search(ctx context.Context, query Query) (*Result, error) {
+	terms := queryTerms(query.Text)
+	if len(terms) == 0 {
+		return &Result{Hits: []Hit{}}, nil
+	}
+
+	var fullText []string
+	var conditions []string
+	var vars []interface{}
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < minFullTextTerm {
+			conditions = append(conditions, "name LIKE ?")
+			vars = append(vars, "%"+escapeLike(term)+"%")
+			continue
+		}
+
+		fullText = append(fullText, term+"*")
+		if maxEdits(term) > 0 {
+			fullText = append(fullText, string([]rune(term)[:minFullTextTerm])+"*")
+		}
+	}
+
+	candidates := s.db.WithContext(ctx).Model(&models.Item{}).Select("id", "name").Limit(maxCandidates)
+	if len(fullText) > 0 {
+		against := strings.Join(fullText, " ")
+		conditions = append(conditions, "MATCH(name) AGAINST(? IN BOOLEAN MODE)")
+		vars = append(vars, against)
+		candidates = candidates.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "MATCH(name) AGAINST(? IN BOOLEAN MODE) DESC",
+			Vars: []interface{}{against},
+		}})
+	}
+	candidates = candidates.Where(strings.Join(conditions, " OR "), vars...)
+
+	var docs []Document
+	if err := candidates.Scan(&docs).Error; err != nil {
+		return nil, err
+	}
+
+	return rank(docs, terms, query), nil
+}
+
+// escapeLike escapes the LIKE wildcards in a term.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,234 @@
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxQueryTerms bounds the work one query can cause.
+	maxQueryTerms = 10
+
+	exactWeight  = 1.0
+	prefixWeight = 0.75
+	fuzzyWeight  = 0.6
+	// fuzzyPenalty is taken off fuzzyWeight for every edit beyond the first.
+	fuzzyPenalty = 0.15
+	// coverageWeight rewards names that consist mostly of matched words, so
+	// "Shirt" ranks above "Shirt hanger with clips" for the query "shirt".
+	coverageWeight = 0.1
+)
+
+// token is a lowercased word and where it sits in the original text.
+type token struct {
+	text  string
+	start int
+	end   int
+}
+
+// tokenize splits text into runs of letters and digits.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			tokens = append(tokens, token{text: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{text: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// queryTerms returns the distinct words of a query, at most maxQueryTerms.
+func queryTerms(text string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, t := range tokenize(text) {
+		if seen[t.text] {
+			continue
+		}
+		seen[t.text] = true
+		terms = append(terms, t.text)
+		if len(terms) == maxQueryTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// maxEdits is how many typos a term of this length may contain and still
+// match. Short terms must be spelled right, or they would match nearly
+// everything.
+func maxEdits(term string) int {
+	switch n := utf8.RuneCountInString(term); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// matchWeight tells how well a query term matches a word of a name, or 0
+// when it does not match.
+func matchWeight(term string, word string) float64 {
+	if word == term {
+		return exactWeight
+	}
+	if utf8.RuneCountInString(term) >= 2 && strings.HasPrefix(word, term) {
+		return prefixWeight
+	}
+
+	allowed := maxEdits(term)
+	if allowed == 0 {
+		return 0
+	}
+	if edits := editDistance(term, word, allowed); edits <= allowed {
+		return fuzzyWeight - fuzzyPenalty*float64(edits-1)
+	}
+	return 0
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of
+// neighbouring letters that turn a into b. It gives up and returns limit+1
+// once the distance is known to exceed limit.
+func editDistance(a string, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > limit || -diff > limit {
+		return limit + 1
+	}
+
+	previous2 := make([]int, len(rb)+1)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+
+	return previous[len(rb)]
+}
+
+// score rates how well the name matches the terms and returns the matched
+// words so they can be highlighted. A score of 0 means no term matched.
+func score(name string, terms []string) (float64, []token) {
+	words := tokenize(name)
+	if len(words) == 0 || len(terms) == 0 {
+		return 0, nil
+	}
+
+	matched := make([]bool, len(words))
+	total := 0.0
+	for _, term := range terms {
+		best, bestIndex := 0.0, -1
+		for i, word := range words {
+			if weight := matchWeight(term, word.text); weight > best {
+				best, bestIndex = weight, i
+			}
+		}
+		if bestIndex >= 0 {
+			total += best
+			matched[bestIndex] = true
+		}
+	}
+	if total == 0 {
+		return 0, nil
+	}
+
+	var spans []token
+	for i, word := range words {
+		if matched[i] {
+			spans = append(spans, word)
+		}
+	}
+
+	relevance := total/float64(len(terms)) + coverageWeight*float64(len(spans))/float64(len(words))
+	return math.Round(relevance*10000) / 10000, spans
+}
+
+// highlight escapes the name for HTML and wraps the spans in <mark> tags.
+func highlight(name string, spans []token) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(html.EscapeString(name[last:span.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(name[span.start:span.end]))
+		b.WriteString("</mark>")
+		last = span.end
+	}
+	b.WriteString(html.EscapeString(name[last:]))
+	return b.String()
+}
+
+// rank scores the documents against the terms, drops the ones that do not
+// match and returns the requested page. Ties are broken by name and then ID
+// so pages are stable.
+func rank(docs []Document, terms []string, query Query) *Result {
+	hits := []Hit{}
+	for _, doc := range docs {
+		relevance, spans := score(doc.Name, terms)
+		if relevance == 0 {
+			continue
+		}
+		hits = append(hits, Hit{
+			ID:        doc.ID,
+			Name:      doc.Name,
+			Score:     relevance,
+			Highlight: highlight(doc.Name, spans),
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Name != hits[j].Name {
+			return hits[i].Name < hits[j].Name
+		}
+		return hits[i].ID.String() < hits[j].ID.String()
+	})
+
+	result := &Result{Total: len(hits)}
+	if query.Offset >= len(hits) {
+		result.Hits = []Hit{}
+		return result
+	}
+	end := len(hits)
+	if query.Limit > 0 {
+		end = min(query.Offset+query.Limit, len(hits))
+	}
+	result.Hits = hits[query.Offset:end]
+	return result
+}
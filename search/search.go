@@ -0,0 +1,48 @@
+// Package search finds catalog items by name. Results are ranked by how well
+// the item name matches the query: whole words score higher than prefixes,
+// and prefixes higher than words within a small edit distance of a query
+// term, so typos still find the item.
+package search
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Document is what gets indexed for an item.
+type Document struct {
+	ID   uuid.UUID
+	Name string
+}
+
+// Query asks for one page of results.
+type Query struct {
+	Text   string
+	Limit  int
+	Offset int
+}
+
+// Hit is one matching item. Highlight is the item name, HTML-escaped, with
+// the matched words wrapped in <mark> tags.
+type Hit struct {
+	ID        uuid.UUID
+	Name      string
+	Score     float64
+	Highlight string
+}
+
+// Result is one page of hits and the number of matches across all pages.
+type Result struct {
+	Hits  []Hit
+	Total int
+}
+
+// Searcher indexes items and searches them. Index and Remove must be called
+// whenever an item is created, renamed or deleted; implementations backed by
+// the items table itself may treat them as no-ops.
+type Searcher interface {
+	Index(ctx context.Context, doc Document) error
+	Remove(ctx context.Context, id uuid.UUID) error
+	Search(ctx context.Context, query Query) (*Result, error)
+}
@@ -0,0 +1,39 @@
+// Package shipping prices the delivery of an order. Providers quote a fee
+// for a parcel going to a destination; the table provider in this package
+// works from a configured rate table without calling anything external.
+package shipping
+
+import (
+	"context"
+	"errors"
+	"ordent/money"
+)
+
+// ErrNoRate is returned when a provider does not ship the parcel to the
+// destination, e.g. an unknown zone or a parcel above every weight bracket.
+var ErrNoRate = errors.New("no shipping rate for this destination")
+
+type Destination struct {
+	Country    string
+	Province   string
+	City       string
+	PostalCode string
+}
+
+type Parcel struct {
+	WeightGrams int
+}
+
+type Quote struct {
+	// Service names the rate that was used, e.g. the zone of a table rate.
+	Service string
+	Fee     money.Money
+}
+
+// RateProvider is the ShippingRateProvider used at checkout. Quote is called
+// while the order's items are locked, so implementations should answer
+// quickly.
+type RateProvider interface {
+	Name() string
+	Quote(ctx context.Context, destination Destination, parcel Parcel) (*Quote, error)
+}
@@ -0,0 +1,142 @@
+package shipping
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"ordent/money"
+	"sort"
+	"strings"
+)
+
+// Zone groups destinations that ship at the same rates. A zone matches a
+// destination whose country is in Countries and, when Provinces is set, whose
+// province is in Provinces; an empty list matches anything. Zones are tried
+// in order, so a catch-all zone belongs last.
+type Zone struct {
+	Name      string       `json:"name"`
+	Countries []string     `json:"countries"`
+	Provinces []string     `json:"provinces"`
+	Rates     []WeightRate `json:"rates"`
+	// PerKgAbove is charged for every started kilogram above the heaviest
+	// bracket. When it is zero, heavier parcels are not shipped to the zone.
+	PerKgAbove money.Money `json:"per_kg_above"`
+}
+
+// WeightRate is the fee for parcels up to UpToGrams. A zero UpToGrams matches
+// any weight, which makes a flat rate.
+type WeightRate struct {
+	UpToGrams int         `json:"up_to_grams"`
+	Fee       money.Money `json:"fee"`
+}
+
+// TableProvider quotes from a fixed table of zones and weight brackets.
+type TableProvider struct {
+	zones []Zone
+}
+
+func NewTableProvider(zones []Zone) (*TableProvider, error) {
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("shipping table has no zones")
+	}
+
+	normalized := make([]Zone, len(zones))
+	for i, zone := range zones {
+		if zone.Name == "" {
+			return nil, fmt.Errorf("shipping zone %d has no name", i)
+		}
+		if len(zone.Rates) == 0 {
+			return nil, fmt.Errorf("shipping zone %q has no rates", zone.Name)
+		}
+		if zone.PerKgAbove.IsNegative() {
+			return nil, fmt.Errorf("shipping zone %q has a negative per_kg_above", zone.Name)
+		}
+
+		rates := make([]WeightRate, len(zone.Rates))
+		copy(rates, zone.Rates)
+		for _, rate := range rates {
+			if rate.UpToGrams < 0 || rate.Fee.IsNegative() {
+				return nil, fmt.Errorf("shipping zone %q has a negative rate", zone.Name)
+			}
+		}
+
+		// Lightest bracket first, the unbounded one last.
+		sort.SliceStable(rates, func(a, b int) bool {
+			if rates[a].UpToGrams == 0 || rates[b].UpToGrams == 0 {
+				return rates[b].UpToGrams == 0 && rates[a].UpToGrams != 0
+			}
+			return rates[a].UpToGrams < rates[b].UpToGrams
+		})
+
+		zone.Rates = rates
+		normalized[i] = zone
+	}
+
+	return &TableProvider{zones: normalized}, nil
+}
+
+// NewFlatRateProvider charges fee for every parcel to every destination.
+func NewFlatRateProvider(fee money.Money) *TableProvider {
+	return &TableProvider{zones: []Zone{{
+		Name:  "flat",
+		Rates: []WeightRate{{Fee: fee}},
+	}}}
+}
+
+// LoadTable reads a JSON list of zones, as accepted by NewTableProvider.
+func LoadTable(r io.Reader) (*TableProvider, error) {
+	var zones []Zone
+	if err := json.NewDecoder(r).Decode(&zones); err != nil {
+		return nil, fmt.Errorf("decode shipping table: %w", err)
+	}
+	return NewTableProvider(zones)
+}
+
+func (p *TableProvider) Name() string {
+	return "table"
+}
+
+func (p *TableProvider) Quote(ctx context.Context, destination Destination, parcel Parcel) (*Quote, error) {
+	for _, zone := range p.zones {
+		if !matches(zone.Countries, destination.Country) || !matches(zone.Provinces, destination.Province) {
+			continue
+		}
+
+		fee, ok := zone.fee(parcel.WeightGrams)
+		if !ok {
+			return nil, ErrNoRate
+		}
+		return &Quote{Service: zone.Name, Fee: fee}, nil
+	}
+
+	return nil, ErrNoRate
+}
+
+func (z Zone) fee(weightGrams int) (money.Money, bool) {
+	for _, rate := range z.Rates {
+		if rate.UpToGrams == 0 || weightGrams <= rate.UpToGrams {
+			return rate.Fee, true
+		}
+	}
+
+	if z.PerKgAbove.IsZero() {
+		return 0, false
+	}
+
+	heaviest := z.Rates[len(z.Rates)-1]
+	extraKg := (weightGrams - heaviest.UpToGrams + 999) / 1000
+	return heaviest.Fee.Add(z.PerKgAbove.Mul(int64(extraKg))), true
+}
+
+func matches(allowed []string, value string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, candidate := range allowed {
+		if strings.EqualFold(strings.TrimSpace(candidate), strings.TrimSpace(value)) {
+			return true
+		}
+	}
+	return false
+}
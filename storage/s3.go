@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type S3Config struct {
+	// Endpoint is the base URL of the S3-compatible service, e.g.
+	// https://s3.eu-west-1.amazonaws.com or http://localhost:9000.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses objects as <endpoint>/<bucket>/<key> instead of
+	// <bucket>.<endpoint host>/<key>. Most self-hosted services need it.
+	PathStyle bool
+	// PublicURL is where the bucket can be read publicly, e.g. a CDN. It
+	// defaults to the bucket's own address.
+	PublicURL string
+}
+
+// S3Storage keeps files in a bucket of any service that speaks the S3 API.
+// Requests are signed with AWS Signature Version 4.
+type S3Storage struct {
+	config    S3Config
+	bucketURL *url.URL
+	publicURL string
+	client    *http.Client
+}
+
+func NewS3Storage(config S3Config) (*S3Storage, error) {
+	if config.Endpoint == "" || config.Bucket == "" || config.AccessKey == "" || config.SecretKey == "" {
+		return nil, fmt.Errorf("s3 storage needs an endpoint, bucket, access key and secret key")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	endpoint, err := url.Parse(strings.TrimRight(config.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", config.Endpoint)
+	}
+
+	bucketURL := *endpoint
+	if config.PathStyle {
+		bucketURL.Path += "/" + config.Bucket
+	} else {
+		bucketURL.Host = config.Bucket + "." + endpoint.Host
+	}
+
+	publicURL := strings.TrimRight(config.PublicURL, "/")
+	if publicURL == "" {
+		publicURL = bucketURL.String()
+	}
+
+	return &S3Storage{
+		config:    config,
+		bucketURL: &bucketURL,
+		publicURL: publicURL,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Put uploads the file with a long cache lifetime; keys are never reused for
+// different content.
+func (s *S3Storage) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Cache-Control", "public, max-age=31536000, immutable")
+
+	return s.do(req, body)
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	return s.do(req, nil)
+}
+
+func (s *S3Storage) URL(key string) string {
+	return s.publicURL + "/" + escapeKey(key)
+}
+
+func (s *S3Storage) newRequest(ctx context.Context, method string, key string, body []byte) (*http.Request, error) {
+	objectURL := *s.bucketURL
+	objectURL.RawPath = objectURL.EscapedPath() + "/" + escapeKey(key)
+	objectURL.Path = objectURL.Path + "/" + key
+
+	return http.NewRequestWithContext(ctx, method, objectURL.String(), bytes.NewReader(body))
+}
+
+// do signs and sends the request. A missing object is not an error for
+// DELETE, matching S3 itself.
+func (s *S3Storage) do(req *http.Request, body []byte) error {
+	signV4(req, sha256Hex(body), s.config.Region, s.config.AccessKey, s.config.SecretKey, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if req.Method == http.MethodDelete && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(detail)))
+}
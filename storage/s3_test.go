@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testRegion    = "eu-west-1"
+	testAccessKey = "test-access-key"
+	testSecretKey = "test-secret-key"
+)
+
+// newTestS3 serves an S3StandIn and returns storage pointed at it with the
+// given secret key.
+func newTestS3(t *testing.T, secretKey string) (*S3Storage, *httptest.Server) {
+	t.Helper()
+
+	server := httptest.NewServer(NewS3StandIn("/s3", testRegion, testAccessKey, testSecretKey))
+	t.Cleanup(server.Close)
+
+	return newTestS3Client(t, server, secretKey), server
+}
+
+func newTestS3Client(t *testing.T, server *httptest.Server, secretKey string) *S3Storage {
+	t.Helper()
+
+	s3, err := NewS3Storage(S3Config{
+		Endpoint:  server.URL + "/s3",
+		Region:    testRegion,
+		Bucket:    "media",
+		AccessKey: testAccessKey,
+		SecretKey: secretKey,
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("new s3 storage: %v", err)
+	}
+	return s3
+}
+
+// get downloads the file from its public URL.
+func get(t *testing.T, url string) (int, string, []byte) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("get %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read %s: %v", url, err)
+	}
+	return resp.StatusCode, resp.Header.Get("Content-Type"), body
+}
+
+func TestS3StoragePutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	s3, _ := newTestS3(t, testSecretKey)
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"plain key", "items/123/photo.jpg"},
+		{"key that needs escaping", "items/123/summer sale+1 (2).jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte("image bytes for " + tt.key)
+			if err := s3.Put(ctx, tt.key, body, "image/jpeg"); err != nil {
+				t.Fatalf("put: %v", err)
+			}
+
+			status, contentType, got := get(t, s3.URL(tt.key))
+			if status != http.StatusOK || contentType != "image/jpeg" || !bytes.Equal(got, body) {
+				t.Fatalf("get = %d %q %q, want 200 image/jpeg %q", status, contentType, got, body)
+			}
+
+			if err := s3.Delete(ctx, tt.key); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+			if status, _, _ := get(t, s3.URL(tt.key)); status != http.StatusNotFound {
+				t.Errorf("get after delete = %d, want 404", status)
+			}
+
+			if err := s3.Delete(ctx, tt.key); err != nil {
+				t.Errorf("deleting a missing file: %v", err)
+			}
+		})
+	}
+}
+
+func TestS3StandInRejectsBadSignature(t *testing.T) {
+	ctx := context.Background()
+	s3, server := newTestS3(t, testSecretKey)
+	if err := s3.Put(ctx, "items/1/kept.jpg", []byte("kept"), "image/jpeg"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	wrongKey := newTestS3Client(t, server, "wrong-secret-key")
+
+	t.Run("put with the wrong secret key", func(t *testing.T) {
+		err := wrongKey.Put(ctx, "items/1/new.jpg", []byte("new"), "image/jpeg")
+		if err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+			t.Fatalf("put = %v, want SignatureDoesNotMatch", err)
+		}
+		if status, _, _ := get(t, s3.URL("items/1/new.jpg")); status != http.StatusNotFound {
+			t.Errorf("rejected put was stored: get = %d", status)
+		}
+	})
+
+	t.Run("delete with the wrong secret key", func(t *testing.T) {
+		err := wrongKey.Delete(ctx, "items/1/kept.jpg")
+		if err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+			t.Fatalf("delete = %v, want SignatureDoesNotMatch", err)
+		}
+		if status, _, _ := get(t, s3.URL("items/1/kept.jpg")); status != http.StatusOK {
+			t.Errorf("rejected delete removed the file: get = %d", status)
+		}
+	})
+
+	t.Run("body changed after signing", func(t *testing.T) {
+		req, err := s3.newRequest(ctx, http.MethodPut, "items/1/kept.jpg", nil)
+		if err != nil {
+			t.Fatalf("new request: %v", err)
+		}
+		signV4(req, sha256Hex([]byte("signed")), testRegion, testAccessKey, testSecretKey, time.Now())
+		req.Body = io.NopCloser(strings.NewReader("tampered"))
+		req.ContentLength = int64(len("tampered"))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("put: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("tampered put = %d, want 403", resp.StatusCode)
+		}
+		if _, _, got := get(t, s3.URL("items/1/kept.jpg")); string(got) != "kept" {
+			t.Errorf("tampered put replaced the file with %q", got)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage keeps uploaded files and tells where the public can download them.
+// Keys are slash-separated relative paths such as "items/<id>/<file>.jpg".
+type Storage interface {
+	// Put stores body under key, replacing what was there.
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	// Delete removes the file. Deleting a missing file is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL is the public address of the file.
+	URL(key string) string
+}
+
+// validKey rejects keys that are empty, absolute or step outside the storage
+// root.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
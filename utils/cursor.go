@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a list ordered by (created_at, id). It is handed
+// to clients as an opaque string.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func EncodeCursor(cursor Cursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// ParseDateParam accepts either a date (2006-01-02) or an RFC 3339 timestamp.
+// isDateOnly tells the caller whether the value covers a whole day.
+func ParseDateParam(value string) (parsed time.Time, isDateOnly bool, err error) {
+	if parsed, err = time.Parse("2006-01-02", value); err == nil {
+		return parsed, true, nil
+	}
+
+	parsed, err = time.Parse(time.RFC3339, value)
+	return parsed, false, err
+}
+
+// SortCursor marks a position in a list ordered by (Sort, id), where Sort
+// names the sort column. Value is the last row's sort key, formatted by the
+// caller. A cursor is only valid for the sort it was created with.
+type SortCursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+func EncodeSortCursor(cursor SortCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeSortCursor(value string) (*SortCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor SortCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.Sort == "" || cursor.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
@@ -14,9 +14,22 @@ import (
 // @Property message string "A brief message explaining the error"
 // @Property detail string "Detailed explanation of the error"
 type APIError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Detail  string `json:"detail,omitempty"`
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Detail  string      `json:"detail,omitempty"`
+	Errors  []LineError `json:"errors,omitempty"`
+}
+
+// LineError describes what is wrong with some lines of a request that
+// contains a list, so every problem can be reported at once.
+type LineError struct {
+	// Lines are the zero-based positions of the offending lines.
+	Lines     []int  `json:"lines"`
+	ItemID    string `json:"item_id,omitempty"`
+	VariantID string `json:"variant_id,omitempty"`
+	Message   string `json:"message"`
+	Requested int    `json:"requested,omitempty"`
+	Available *int   `json:"available,omitempty"`
 }
 
 func (e *APIError) Error() string {
@@ -63,6 +76,44 @@ func NewForbiddenError(message string) *APIError {
 	}
 }
 
+func NewConflictError(message string) *APIError {
+	return &APIError{
+		Code:    http.StatusConflict,
+		Message: message,
+		Detail:  "Conflict",
+	}
+}
+
+func NewUnprocessableEntityError(message string) *APIError {
+	return &APIError{
+		Code:    http.StatusUnprocessableEntity,
+		Message: message,
+		Detail:  "Unprocessable Entity",
+	}
+}
+
+func NewPayloadTooLargeError(message string) *APIError {
+	return &APIError{
+		Code:    http.StatusRequestEntityTooLarge,
+		Message: message,
+		Detail:  "Payload Too Large",
+	}
+}
+
+func NewUnsupportedMediaTypeError(message string) *APIError {
+	return &APIError{
+		Code:    http.StatusUnsupportedMediaType,
+		Message: message,
+		Detail:  "Unsupported Media Type",
+	}
+}
+
+// WithLineErrors attaches per-line problems to the error.
+func (e *APIError) WithLineErrors(lineErrors []LineError) *APIError {
+	e.Errors = lineErrors
+	return e
+}
+
 func HandlerError(c echo.Context, err *APIError) error {
 	return c.JSON(err.Code, err)
 }